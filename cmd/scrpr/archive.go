@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// archiveWriter bundles the per-URL outputs of a batch into one file
+type archiveWriter interface {
+	Add(name string, data []byte) error
+	Close() error
+}
+
+// archiveKind returns the archive format implied by an output path, or ""
+// when the path is not an archive
+func archiveKind(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// newArchiveWriter creates the archive at path in the given format
+func newArchiveWriter(path, kind string) (archiveWriter, error) {
+	f, err := createOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "zip":
+		return &zipArchive{file: f, zw: zip.NewWriter(f)}, nil
+	case "tar.gz":
+		gz := gzip.NewWriter(f)
+		return &tarArchive{file: f, gz: gz, tw: tar.NewWriter(gz)}, nil
+	case "tar":
+		return &tarArchive{file: f, tw: tar.NewWriter(f)}, nil
+	}
+	f.Close()
+	return nil, fmt.Errorf("unsupported archive format: %s", kind)
+}
+
+type zipArchive struct {
+	file io.Closer
+	zw   *zip.Writer
+}
+
+func (a *zipArchive) Add(name string, data []byte) error {
+	w, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: stampTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return closeAll(a.zw, a.file)
+}
+
+type tarArchive struct {
+	file io.Closer
+	gz   *gzip.Writer // nil for plain tar
+	tw   *tar.Writer
+}
+
+func (a *tarArchive) Add(name string, data []byte) error {
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  stampTime(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	if a.gz != nil {
+		return closeAll(a.tw, a.gz, a.file)
+	}
+	return closeAll(a.tw, a.file)
+}
+
+// closeAll closes every layer innermost first and returns the first error
+func closeAll(closers ...io.Closer) error {
+	var first error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
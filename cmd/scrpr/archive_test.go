@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestArchiveKind(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"out.zip", "zip"},
+		{"OUT.ZIP", "zip"},
+		{"out.tar.gz", "tar.gz"},
+		{"out.tgz", "tar.gz"},
+		{"out.tar", "tar"},
+		{"out.gz", ""},
+		{"out.txt", ""},
+		{"out/", ""},
+	}
+	for _, tt := range tests {
+		if got := archiveKind(tt.path); got != tt.want {
+			t.Errorf("archiveKind(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// readArchive returns the entries of an archive written by archiveWriter
+func readArchive(t *testing.T, path, kind string) map[string]string {
+	t.Helper()
+	entries := make(map[string]string)
+	if kind == "zip" {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries[f.Name] = string(data)
+		}
+		return entries
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if kind == "tar.gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[h.Name] = string(data)
+	}
+}
+
+func TestArchiveWriter(t *testing.T) {
+	for _, kind := range []string{"zip", "tar", "tar.gz"} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out."+kind)
+			a, err := newArchiveWriter(path, kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{
+				"example.com_a.md":     "# A\n",
+				"sub/example.com_b.md": "# B\n",
+				"empty.md":             "",
+			}
+			for _, name := range []string{"example.com_a.md", "sub/example.com_b.md", "empty.md"} {
+				if err := a.Add(name, []byte(want[name])); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := a.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := readArchive(t, path, kind)
+			if len(got) != len(want) {
+				t.Errorf("archive holds %d entries, want %d", len(got), len(want))
+			}
+			for name, data := range want {
+				if got[name] != data {
+					t.Errorf("%s: got %q, want %q", name, got[name], data)
+				}
+			}
+		})
+	}
+
+	if _, err := newArchiveWriter(filepath.Join(t.TempDir(), "out.rar"), "rar"); err == nil {
+		t.Error("expected an unknown archive format to fail")
+	}
+}
+
+func TestRun_ArchiveHoldsEachURLAndTheManifest(t *testing.T) {
+	server := articleServer(t)
+	path := filepath.Join(t.TempDir(), "out.zip")
+	if err := runScrpr(t, "--no-js", "--format", "markdown", "-o", path, server.URL+"/a", server.URL+"/b"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	entries := readArchive(t, path, "zip")
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if len(names) != 3 || names[2] != manifestFile {
+		t.Fatalf("archive holds %q, want two pages and %s", names, manifestFile)
+	}
+	for i, name := range []string{"a", "b"} {
+		if !strings.HasSuffix(names[i], "_"+name+".md") || !strings.Contains(entries[names[i]], "Marker "+name) {
+			t.Errorf("entry %s does not hold page %s:\n%s", names[i], name, entries[names[i]])
+		}
+	}
+	if !strings.Contains(entries[manifestFile], `"file": "`+names[0]+`"`) {
+		t.Errorf("manifest does not list %s:\n%s", names[0], entries[manifestFile])
+	}
+}
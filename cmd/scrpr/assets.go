@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/byteowlz/scrpr/internal/epub"
+)
+
+const (
+	// assetDir holds the images of directory and archive output
+	assetDir = "assets"
+	// maxAssetSize bounds a single saved image
+	maxAssetSize = 10 << 20
+)
+
+// assetStore saves the images of a batch once per content hash, so a logo or
+// figure repeated across pages is downloaded once per URL and stored once
+// per content, and every article links the shared file
+type assetStore struct {
+	client  *http.Client
+	write   func(name string, data []byte) error
+	byURL   map[string]string // image URL -> asset name ("" = failed)
+	written map[string]bool   // asset names stored in this run
+	saved   int
+	reused  int
+}
+
+func newAssetStore(write func(name string, data []byte) error) *assetStore {
+	return &assetStore{
+		client:  httpClient("image"),
+		write:   write,
+		byURL:   make(map[string]string),
+		written: make(map[string]bool),
+	}
+}
+
+// dirAssetWriter writes assets below dir, keeping files a previous run
+// already stored under the same content hash
+func dirAssetWriter(dir string) func(name string, data []byte) error {
+	return func(name string, data []byte) error {
+		full := filepath.Join(dir, filepath.FromSlash(sealedName(name)))
+		if _, err := os.Stat(full); err == nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+		data, err := sealData(data)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(full, data)
+	}
+}
+
+// localize saves the images of a result and points them at the stored
+// files, relative to the result's own file name. Images that cannot be
+// downloaded keep their URL.
+func (a *assetStore) localize(result *ProcessResult, fileName string) {
+	base, _ := url.Parse(result.URL)
+	fileDir := path.Dir(fileName)
+	rewriteImages(result, outputFormat, func(src string) string {
+		ref, err := url.Parse(src)
+		if err != nil {
+			return src
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if ref.Scheme != "http" && ref.Scheme != "https" {
+			return src
+		}
+		name := a.save(ref.String())
+		if name == "" {
+			return src
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(fileDir), filepath.FromSlash(name))
+		if err != nil {
+			return src
+		}
+		return filepath.ToSlash(rel)
+	})
+}
+
+// save downloads an image and stores it under its content hash, returning
+// the asset name or "" on failure
+func (a *assetStore) save(imageURL string) string {
+	if name, ok := a.byURL[imageURL]; ok {
+		return name
+	}
+	data, mediaType, err := downloadImage(a.client, imageURL, maxAssetSize)
+	if err != nil {
+		a.byURL[imageURL] = ""
+		if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "Not saving image %s: %v\n", imageURL, err)
+		}
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	name := path.Join(assetDir, hex.EncodeToString(sum[:8])+epub.ImageExtension(mediaType))
+	if a.written[name] {
+		a.reused++
+	} else {
+		if err := a.write(name, data); err != nil {
+			a.byURL[imageURL] = ""
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Error saving image %s: %v\n", imageURL, err)
+			}
+			return ""
+		}
+		a.written[name] = true
+		a.saved++
+	}
+	a.byURL[imageURL] = name
+	return name
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/audit"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// auditLog records the requests of the run with --audit-log (nil = off)
+var auditLog *audit.Log
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check audit logs written with --audit-log",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify FILE",
+	Short: "Check that no entry of an audit log was edited, dropped or reordered",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAuditVerify,
+}
+
+func init() {
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	defer f.Close()
+	n, err := audit.Verify(f)
+	var chain *audit.ChainError
+	if errors.As(err, &chain) {
+		return exitError(ExitProcessError, "%s: chain broken at %v (%d entries before it are intact)", args[0], chain, n)
+	}
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "%s: %d entries, chain intact\n", args[0], n)
+	}
+	return nil
+}
+
+// httpClient is a client for the requests a run makes besides fetching its
+// pages, such as images and feeds; with --audit-log they are recorded as
+// kind
+func httpClient(kind string) *http.Client {
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	if auditLog != nil {
+		client.Transport = &audit.Transport{Log: auditLog, Kind: kind}
+	}
+	return client
+}
+
+// auditPage records the fetch of a page with the robots decision taken on
+// it; a failed write only warns, as the page was fetched either way
+func auditPage(url, method string, start time.Time, result *fetcher.FetchResult, robots string, err error) {
+	if auditLog == nil {
+		return
+	}
+	e := audit.Entry{Time: start, Kind: "page", Method: method, URL: url, Robots: robots}
+	if result != nil {
+		e.Status = result.Status
+		e.Bytes = int64(len(result.HTML))
+		e.Attempts = result.Attempts
+		if result.FinalURL != url {
+			e.FinalURL = result.FinalURL
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+		var status *fetcher.StatusError
+		if errors.As(err, &status) {
+			e.Status = status.Code
+		}
+	}
+	recordAudit(e)
+}
+
+// auditBackend records a page extracted through an API backend
+func auditBackend(url, backend string, start time.Time, content int, err error) {
+	if auditLog == nil {
+		return
+	}
+	e := audit.Entry{Time: start, Kind: backend, Method: http.MethodGet, URL: url, Bytes: int64(content)}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	recordAudit(e)
+}
+
+func recordAudit(e audit.Entry) {
+	if err := auditLog.Record(e); err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
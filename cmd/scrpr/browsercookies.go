@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	cookies "github.com/byteowlz/scrpr/internal/browser"
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// browserCookies reads the cookies of the user's browser for the pages of a
+// run with --browser or browser.cookies.enabled (nil = none are sent)
+var browserCookies *cookies.CookieExtractor
+
+// setBrowserCookies reads --browser and the [browser.cookies] config
+func setBrowserCookies(cmd *cobra.Command, cfg *config.Config) error {
+	if !cmd.Flags().Changed("browser") {
+		if !cfg.Browser.Cookies.Enabled {
+			return nil
+		}
+		browser = cfg.Browser.Default
+	}
+	switch cookies.BrowserType(browser) {
+	case cookies.BrowserAuto, cookies.BrowserChrome, cookies.BrowserFirefox, cookies.BrowserSafari, cookies.BrowserZen:
+	default:
+		return fmt.Errorf("invalid browser %q (available: auto, chrome, firefox, safari, zen)", browser)
+	}
+	if cfg.Browser.Cookies.CacheTTL < 0 {
+		return fmt.Errorf("browser.cookies.cache_ttl must not be negative")
+	}
+
+	browserCookies = cookies.NewCookieExtractor(cookies.BrowserType(browser), cfg.Browser.Paths)
+	browserCookies.SetDomainFilter(cfg.Browser.Cookies.Domains, cfg.Browser.Cookies.Exclude)
+	browserCookies.SetCacheTTL(time.Duration(cfg.Browser.Cookies.CacheTTL) * time.Second)
+	if cfg.Browser.Cookies.File != "" {
+		browserCookies.SetCookieFile(cfg.Browser.Cookies.File)
+	}
+	return nil
+}
+
+// cookiesFor returns the browser cookies for rawURL's host; a store that
+// cannot be read only costs the page its cookies
+func (ro *RunOptions) cookiesFor(rawURL string) []*http.Cookie {
+	if ro.Cookies == nil {
+		return nil
+	}
+	found, err := ro.Cookies.ExtractCookies(rawURL)
+	if err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: browser cookies for %s: %v\n", rawURL, err)
+	}
+	return found
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// runBudget stops the intake of a run after --max-requests URLs or once
+// --max-duration has passed. URLs already started always finish.
+type runBudget struct {
+	maxRequests int
+	maxDuration time.Duration
+	deadline    time.Time
+	requests    int
+}
+
+func newRunBudget(maxRequests int, maxDuration time.Duration) *runBudget {
+	b := &runBudget{maxRequests: maxRequests, maxDuration: maxDuration}
+	if maxDuration > 0 {
+		b.deadline = time.Now().Add(maxDuration)
+	}
+	return b
+}
+
+// take reserves the next request; when the budget is spent it returns why
+// instead
+func (b *runBudget) take() string {
+	if b.maxRequests > 0 && b.requests >= b.maxRequests {
+		return fmt.Sprintf("request budget of %d reached", b.maxRequests)
+	}
+	if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+		return fmt.Sprintf("time budget of %s reached", b.maxDuration)
+	}
+	b.requests++
+	return ""
+}
+
+// parseMaxDuration reads max_duration from the config ("" = unlimited)
+func parseMaxDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// skipRemaining reports the URLs from start on as not processed, in the
+// manifest and on stderr; positions are their places in the input
+func skipRemaining(urls []string, positions []int, start int, index *manifest, skipped skipTally, reason string) {
+	if index != nil {
+		for i := start; i < len(urls); i++ {
+			index.skip(positions[i], urls[i], skipBudget, reason)
+		}
+	}
+	skipped[skipBudget] += len(urls) - start
+	if quiet {
+		return
+	}
+	if progress && len(urls) > 1 {
+		fmt.Fprintln(os.Stderr)
+	}
+	fmt.Fprintf(os.Stderr, "Stopped: %s; %d of %d URLs not processed\n", reason, len(urls)-start, len(urls))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+func TestRunBudget_Take(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRequests int
+		maxDuration time.Duration
+		taken       int    // before the budget runs out (-1 = never)
+		reason      string // once it has
+	}{
+		{name: "unlimited", taken: -1},
+		{name: "requests", maxRequests: 3, taken: 3, reason: "request budget of 3 reached"},
+		{name: "duration", maxDuration: time.Nanosecond, taken: 0, reason: "time budget of 1ns reached"},
+		{name: "duration left", maxDuration: time.Hour, taken: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRunBudget(tt.maxRequests, tt.maxDuration)
+			time.Sleep(time.Millisecond)
+			for i := 0; i < 10; i++ {
+				reason := b.take()
+				if tt.taken < 0 || i < tt.taken {
+					if reason != "" {
+						t.Fatalf("request %d refused: %s", i+1, reason)
+					}
+					continue
+				}
+				if reason != tt.reason {
+					t.Fatalf("request %d: reason %q, want %q", i+1, reason, tt.reason)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMaxDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"90s", 90 * time.Second, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMaxDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseMaxDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRun_MaxRequestsSkipsTheRest(t *testing.T) {
+	server := articleServer(t)
+	dir := filepath.Join(t.TempDir(), "out") + "/"
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c", server.URL + "/d"}
+	// A budget that stops the batch makes it a partial success
+	err := runScrpr(t, append([]string{"--no-js", "--max-requests", "2", "-o", dir}, urls...)...)
+	var ee *exitErr
+	if !errors.As(err, &ee) || ee.code != ExitPartialError {
+		t.Fatalf("run returned %v, want exit code %d", err, ExitPartialError)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m schema.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ok", "ok", "skipped", "skipped"}
+	if len(m.Entries) != len(want) {
+		t.Fatalf("manifest has %d entries, want %d", len(m.Entries), len(want))
+	}
+	for i, e := range m.Entries {
+		if e.Status != want[i] || e.URL != urls[i] {
+			t.Errorf("entry %d: %s %s, want %s %s", i+1, e.URL, e.Status, urls[i], want[i])
+		}
+		if e.Status == "skipped" && (e.Skip != skipBudget || !strings.Contains(e.Error, "request budget of 2")) {
+			t.Errorf("entry %d: skip %q (%s), want %q", i+1, e.Skip, e.Error, skipBudget)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/store"
+)
+
+// cacheDir returns the response cache directory: cache.dir, else
+// responses/ in the cache directory
+func cacheDir(cfg *config.Config) (string, error) {
+	if cfg.Cache.Dir != "" {
+		return expandHome(cfg.Cache.Dir), nil
+	}
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "responses"), nil
+}
+
+// apiCacheDir returns the directory of cached API backend results: api/ in
+// cache.dir, else in the cache directory
+func apiCacheDir(cfg *config.Config) (string, error) {
+	if cfg.Cache.Dir != "" {
+		return filepath.Join(expandHome(cfg.Cache.Dir), "api"), nil
+	}
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "api"), nil
+}
+
+// openAPICache opens the cache of Jina and Tavily results for this run
+func openAPICache(cfg *config.Config) (*store.ResponseCache, error) {
+	dir, err := apiCacheDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewResponseCache(dir, time.Duration(cfg.Cache.APITTL)*time.Second)
+}
+
+// lazyCache opens a cache the first time it is used, so a run that never
+// needs it writes nothing. A cache that cannot be opened is reported once
+// and the run goes on without it.
+type lazyCache struct {
+	name  string // for the warning
+	open  func() (*store.ResponseCache, error)
+	once  sync.Once
+	cache *store.ResponseCache
+}
+
+func newLazyCache(name string, open func() (*store.ResponseCache, error)) *lazyCache {
+	return &lazyCache{name: name, open: open}
+}
+
+// get returns the cache, or nil when there is none or it failed to open
+func (l *lazyCache) get() *store.ResponseCache {
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() {
+		var err error
+		if l.cache, err = l.open(); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v; running without the %s\n", err, l.name)
+		}
+	})
+	return l.cache
+}
+
+// Close closes the cache if it was opened
+func (l *lazyCache) Close() {
+	l.once.Do(func() {}) // a later get opens nothing
+	if l.cache != nil {
+		l.cache.Close()
+	}
+}
+
+// apiCacheVariant describes the request options that shape an API result
+func apiCacheVariant(backend, format string, cfg *config.Config) string {
+	variant := []string{"backend=" + backend, "format=" + format}
+	if backend == "tavily" {
+		variant = append(variant, "depth="+cfg.Extraction.Tavily.ExtractDepth)
+	}
+	return strings.Join(variant, "\n")
+}
+
+// openResponseCache opens the cache for this run
+func openResponseCache(cfg *config.Config, ttl time.Duration) (*store.ResponseCache, error) {
+	dir, err := cacheDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewResponseCache(dir, ttl)
+}
+
+// cacheVariant describes the fetch options that shape a response, so pages
+// fetched with another user agent, language or referer are cached apart
+func cacheVariant(opts fetcher.FetchOptions) string {
+	device := ""
+	if opts.Device != nil {
+		device = opts.Device.Name
+	}
+	variant := []string{
+		"mode=" + string(opts.Mode),
+		"ua=" + opts.UserAgent,
+		"browser=" + opts.BrowserAgent,
+		"device=" + device,
+		"lang=" + opts.AcceptLanguage,
+		"referer=" + opts.Referer,
+	}
+	// Pages seen with browser cookies (logged in) are kept apart from
+	// anonymous ones
+	if len(opts.Cookies) > 0 {
+		variant = append(variant, "cookies=browser")
+	}
+	return strings.Join(variant, "\n")
+}
+
+// cachedRedirects converts the redirect chain stored with a cached response
+func cachedRedirects(hops []store.CachedRedirect) []fetcher.Redirect {
+	var chain []fetcher.Redirect
+	for _, hop := range hops {
+		chain = append(chain, fetcher.Redirect(hop))
+	}
+	return chain
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/extractor"
+	"github.com/byteowlz/scrpr/internal/store"
+)
+
+// countingBackend is an API backend that counts its calls
+type countingBackend struct {
+	calls int
+}
+
+func (b *countingBackend) Name() string      { return "jina" }
+func (b *countingBackend) IsAvailable() bool { return true }
+
+func (b *countingBackend) Extract(ctx context.Context, url, format string) (*extractor.ExtractResult, error) {
+	b.calls++
+	return &extractor.ExtractResult{URL: url, Title: "Title", Content: "Content as " + format}, nil
+}
+
+func TestAPICacheVariant(t *testing.T) {
+	cfg := config.Default()
+	cfg.Extraction.Tavily.ExtractDepth = "advanced"
+	tests := []struct {
+		backend, format, want string
+	}{
+		{"jina", "markdown", "backend=jina\nformat=markdown"},
+		{"jina", "text", "backend=jina\nformat=text"},
+		{"tavily", "markdown", "backend=tavily\nformat=markdown\ndepth=advanced"},
+	}
+	for _, tt := range tests {
+		if got := apiCacheVariant(tt.backend, tt.format, cfg); got != tt.want {
+			t.Errorf("apiCacheVariant(%s, %s) = %q, want %q", tt.backend, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestAPICacheDir(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.Dir = "/var/cache/scrpr"
+	if got, _ := apiCacheDir(cfg); got != filepath.FromSlash("/var/cache/scrpr/api") {
+		t.Errorf("with cache.dir: %s", got)
+	}
+	if got, _ := cacheDir(cfg); got != "/var/cache/scrpr" {
+		t.Errorf("response cache with cache.dir: %s", got)
+	}
+}
+
+func TestLazyCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "api")
+	opened := 0
+	open := func() (*store.ResponseCache, error) {
+		opened++
+		return store.NewResponseCache(dir, time.Hour)
+	}
+
+	unused := newLazyCache("API cache", open)
+	unused.Close()
+	if unused.get() != nil || opened != 0 {
+		t.Error("a cache closed unused was opened")
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("an unused cache created %s", dir)
+	}
+
+	used := newLazyCache("API cache", open)
+	if used.get() == nil || used.get() == nil || opened != 1 {
+		t.Errorf("cache opened %d times, want once", opened)
+	}
+	used.Close()
+
+	var none *lazyCache
+	if none.get() != nil {
+		t.Error("a nil lazyCache returned a cache")
+	}
+}
+
+func TestExtractCached(t *testing.T) {
+	defer func(saved bool) { quiet = saved }(quiet)
+	quiet = true
+	cfg := config.Default()
+	cfg.Cache.Dir = t.TempDir()
+	ctx := context.Background()
+	const url = "https://example.com/a"
+
+	backend := &countingBackend{}
+	ro := &RunOptions{Config: cfg, APICache: newLazyCache("API cache", func() (*store.ResponseCache, error) {
+		return openAPICache(cfg)
+	})}
+	defer ro.APICache.Close()
+
+	tests := []struct {
+		name    string
+		format  string
+		refresh bool
+		source  string
+		calls   int // of the backend so far
+	}{
+		{name: "first", format: "markdown", source: sourceNetwork, calls: 1},
+		{name: "again", format: "markdown", source: sourceCache, calls: 1},
+		{name: "other format", format: "text", source: sourceNetwork, calls: 2},
+		{name: "refresh", format: "markdown", refresh: true, source: sourceNetwork, calls: 3},
+		{name: "after refresh", format: "markdown", source: sourceCache, calls: 3},
+	}
+	for _, tt := range tests {
+		ro.RefreshCache = tt.refresh
+		result, source, err := extractCached(ctx, ro, backend, url, tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if source != tt.source || backend.calls != tt.calls {
+			t.Errorf("%s: source %s after %d calls, want %s after %d", tt.name, source, backend.calls, tt.source, tt.calls)
+		}
+		if result.Title != "Title" || result.Content != "Content as "+tt.format || result.URL != url {
+			t.Errorf("%s: result %+v", tt.name, result)
+		}
+	}
+
+	// A cache that cannot be opened leaves every call to the API
+	broken := &RunOptions{Config: cfg, APICache: newLazyCache("API cache", func() (*store.ResponseCache, error) {
+		return nil, errors.New("read-only file system")
+	})}
+	backend.calls = 0
+	for range 2 {
+		if _, source, err := extractCached(ctx, broken, backend, url, "markdown"); err != nil || source != sourceNetwork {
+			t.Errorf("without a cache: source %s, error %v", source, err)
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("without a cache the API was called %d times, want 2", backend.calls)
+	}
+}
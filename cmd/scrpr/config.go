@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+)
+
+// noConfigWrite makes commands that would write to the config directory
+// fail, for read-only homes and CI
+var noConfigWrite bool
+
+var configInitForce bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+	Long: `scrpr runs on defaults until a config file exists and never writes one on
+its own. config init creates the commented example, at --config or at
+config.toml in the config directory (see scrpr paths).`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the config file with commented defaults",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigInit,
+}
+
+func init() {
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "replace an existing config file")
+	configCmd.AddCommand(configInitCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	if err := checkConfigWrite("creating the config file"); err != nil {
+		return err
+	}
+	path := cfgFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultConfigPath(); err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+	}
+	if _, err := os.Stat(path); err == nil && !configInitForce {
+		return exitError(ExitInvalidInput, "%s already exists; use --force to replace it", path)
+	}
+	if err := config.Default().CreateExampleConfig(path); err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Created config file: %s\n", path)
+	}
+	return nil
+}
+
+// checkConfigWrite refuses a change to the config directory, named by what,
+// under --no-config-write
+func checkConfigWrite(what string) error {
+	if noConfigWrite {
+		return exitError(ExitConfigError, "--no-config-write forbids %s", what)
+	}
+	return nil
+}
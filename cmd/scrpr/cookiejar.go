@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// cookieJar holds the cookies responses set during the run (nil =
+// --no-keep-cookies)
+var cookieJar *fetcher.CookieJar
+
+// openCookieJar starts the run's cookie jar, from --cookie-jar when given
+func openCookieJar(path string) error {
+	cookieJar = fetcher.NewCookieJar()
+	if path == "" {
+		return nil
+	}
+	return cookieJar.LoadCookies(expandHome(path))
+}
+
+// saveCookieJar keeps the run's cookies for the next run; the run's output
+// is written by then, so a failure is only reported
+func saveCookieJar(path string) {
+	if err := cookieJar.SaveCookies(expandHome(path)); err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
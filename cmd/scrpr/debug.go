@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+// extractionDebug dumps the artifacts of one URL for --debug-extraction: the
+// raw HTML, the article after each processor stage and the final output,
+// numbered in pipeline order, plus timing.json
+type extractionDebug struct {
+	dir    string
+	url    string
+	start  time.Time
+	stages []debugStage
+}
+
+type debugStage struct {
+	Stage string  `json:"stage"`
+	File  string  `json:"file,omitempty"`
+	Bytes int     `json:"bytes"`
+	MS    float64 `json:"ms"`
+}
+
+// newExtractionDebug creates the artifact directory for url under root
+func newExtractionDebug(root, url string) (*extractionDebug, error) {
+	name := urlToFilename(url, "text")
+	dir := filepath.Join(root, name[:len(name)-len(path.Ext(name))])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("debug extraction: %w", err)
+	}
+	return &extractionDebug{dir: dir, url: url, start: time.Now()}, nil
+}
+
+// add writes one stage's artifact; ext is empty for stages without one
+func (d *extractionDebug) add(stage, ext, data string, took time.Duration) {
+	st := debugStage{Stage: stage, Bytes: len(data), MS: float64(took.Microseconds()) / 1000}
+	if ext != "" {
+		st.File = fmt.Sprintf("%02d-%s%s", len(d.stages), stage, ext)
+		if err := os.WriteFile(filepath.Join(d.dir, st.File), []byte(data), 0644); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: debug extraction: %v\n", err)
+		}
+	}
+	d.stages = append(d.stages, st)
+}
+
+// addTrace writes the processor stages recorded in trace
+func (d *extractionDebug) addTrace(trace *processor.Trace) {
+	for _, st := range trace.Stages {
+		d.add(st.Name, ".html", st.HTML, st.Duration)
+	}
+}
+
+// finish writes timing.json; err is the failure that ended the pipeline
+func (d *extractionDebug) finish(err error) {
+	report := struct {
+		URL     string       `json:"url"`
+		Stages  []debugStage `json:"stages"`
+		TotalMS float64      `json:"total_ms"`
+		Error   string       `json:"error,omitempty"`
+	}{
+		URL:     d.url,
+		Stages:  d.stages,
+		TotalMS: float64(time.Since(d.start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	if writeErr := os.WriteFile(filepath.Join(d.dir, "timing.json"), append(data, '\n'), 0644); writeErr != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: debug extraction: %v\n", writeErr)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// deterministicSeed seeds random choices under --deterministic when --seed
+// is not given
+const deterministicSeed = 1
+
+// stampEpoch is the time stamped into outputs under --deterministic
+var stampEpoch time.Time
+
+// setupDeterministic prepares --deterministic: timestamps become
+// SOURCE_DATE_EPOCH, as for reproducible builds, or the Unix epoch
+func setupDeterministic() error {
+	stampEpoch = time.Unix(0, 0).UTC()
+	if env := os.Getenv("SOURCE_DATE_EPOCH"); env != "" {
+		secs, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", env)
+		}
+		stampEpoch = time.Unix(secs, 0).UTC()
+	}
+	return nil
+}
+
+// stampTime is the time written into outputs: manifests, archive entries,
+// dated filenames and directories
+func stampTime() time.Time {
+	if deterministic {
+		return stampEpoch
+	}
+	return time.Now()
+}
+
+// resultTiming reports the stage durations of result; zero under
+// --deterministic, where they would differ between identical runs
+func resultTiming(result *ProcessResult) jsonTiming {
+	if deterministic {
+		return jsonTiming{}
+	}
+	return jsonTiming{
+		FetchMS:   result.FetchTime.Milliseconds(),
+		ProcessMS: result.ProcessTime.Milliseconds(),
+		TotalMS:   (result.FetchTime + result.ProcessTime).Milliseconds(),
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	cookies "github.com/byteowlz/scrpr/internal/browser"
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/crawl"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/paths"
+	"github.com/byteowlz/scrpr/internal/seal"
+	"github.com/byteowlz/scrpr/internal/watch"
+)
+
+var doctorURL string
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check config, API keys, cookie stores, Chrome and network access",
+	Args:  cobra.NoArgs,
+	RunE:  runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorURL, "url", "https://example.com/", "URL fetched to check network egress")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "FAIL"
+)
+
+// doctorCheck is one line of the `scrpr doctor` report
+type doctorCheck struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Hint   string // what to do about a warning or failure
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, cfgErr := loadConfig()
+
+	checks := []doctorCheck{checkConfigFile(cfgErr)}
+	if cfgErr != nil {
+		cfg = config.Default()
+	}
+	checks = append(checks, checkConfigValues(cfg)...)
+	checks = append(checks, checkBackend(cfg))
+	checks = append(checks, checkCookieStores(cfg)...)
+	checks = append(checks, checkChrome())
+	if cfg.Extraction.OCR {
+		checks = append(checks, checkOCR(cfg))
+	}
+	checks = append(checks, checkNetwork(cfg))
+	checks = append(checks, checkDirs(cfg)...)
+
+	if failed := printChecks(checks); failed > 0 {
+		return exitError(ExitConfigError, "%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// printChecks prints a report of checks and returns how many failed
+func printChecks(checks []doctorCheck) int {
+	failed := 0
+	for _, c := range checks {
+		fmt.Printf("%-5s %-14s %s\n", c.Status, c.Name, c.Detail)
+		if c.Hint != "" && c.Status != checkOK {
+			fmt.Printf("%-5s %-14s -> %s\n", "", "", c.Hint)
+		}
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+func checkConfigFile(err error) doctorCheck {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		path = getDefaultConfigPath()
+	}
+	if err != nil {
+		return doctorCheck{"config", checkFail, err.Error(), "fix the file, or regenerate it with scrpr config init --force: " + path}
+	}
+	if _, statErr := os.Stat(path); statErr != nil {
+		return doctorCheck{"config", checkWarn, "no config file, using defaults", "run scrpr config init to create " + path}
+	}
+	return doctorCheck{"config", checkOK, path, ""}
+}
+
+// checkConfigValues catches values that parse but are rejected at run time
+func checkConfigValues(cfg *config.Config) []doctorCheck {
+	var problems []string
+	switch cfg.Extraction.Backend {
+	case "", "readability", "tavily", "jina":
+	default:
+		problems = append(problems, fmt.Sprintf("extraction.backend %q is not readability, tavily or jina", cfg.Extraction.Backend))
+	}
+	switch cfg.Output.DefaultFormat {
+	case "", "text", "markdown", "json", "html", "epub":
+	default:
+		problems = append(problems, fmt.Sprintf("output.default_format %q is unknown", cfg.Output.DefaultFormat))
+	}
+	switch cookies.BrowserType(cfg.Browser.Default) {
+	case "", cookies.BrowserAuto, cookies.BrowserChrome, cookies.BrowserFirefox, cookies.BrowserSafari, cookies.BrowserZen:
+	default:
+		problems = append(problems, fmt.Sprintf("browser.default %q is not auto, chrome, firefox, safari or zen", cfg.Browser.Default))
+	}
+	if _, err := parseRenderMode(cfg.Extraction.EnableJavaScript); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := fetcher.ParseWaitStrategy(cfg.Extraction.WaitUntil); err != nil {
+		problems = append(problems, "extraction.wait_until: "+err.Error())
+	}
+	if ex := cfg.Extraction; ex.Scroll < 0 || ex.ScrollMaxHeight < 0 || ex.ScrollTimeout < 0 {
+		problems = append(problems, "extraction.scroll, scroll_max_height and scroll_timeout cannot be negative")
+	}
+	for _, f := range cfg.Extraction.EvalJS {
+		if _, err := os.Stat(expandHome(f)); err != nil {
+			problems = append(problems, "extraction.eval_js: "+err.Error())
+		}
+	}
+	if _, err := seal.New(cfg.Output.Encrypt); err != nil {
+		problems = append(problems, "output.encrypt: "+err.Error())
+	}
+	if cfg.Network.MobileDevice != "" {
+		if _, err := fetcher.LookupDevice(cfg.Network.MobileDevice); err != nil {
+			problems = append(problems, "network.mobile_device: "+err.Error())
+		}
+	}
+	if cfg.Network.Viewport != "" {
+		if _, err := fetcher.ParseViewport(cfg.Network.Viewport); err != nil {
+			problems = append(problems, "network.viewport: "+err.Error())
+		}
+	}
+	if err := fetcher.SetAddressFamily(cfg.Network.AddressFamily); err != nil {
+		problems = append(problems, "network.address_family: "+err.Error())
+	}
+	if cfg.Network.Timeout < 0 || cfg.Network.ConnectTimeout < 0 || cfg.Network.TLSHandshakeTimeout < 0 || cfg.Network.ResponseHeaderTimeout < 0 {
+		problems = append(problems, "network timeouts must not be negative")
+	}
+	if cfg.Output.Template != "" {
+		if _, err := loadTemplate(cfg.Output.Template); err != nil {
+			problems = append(problems, "output.template: "+err.Error())
+		}
+	}
+	if cfg.Output.FilenameTemplate != "" {
+		if _, err := parseFilenameTemplate(cfg.Output.FilenameTemplate); err != nil {
+			problems = append(problems, "output.filename_template: "+err.Error())
+		}
+	}
+	if _, err := newJunkFilter(cfg.Junk); err != nil {
+		problems = append(problems, "junk: "+err.Error())
+	}
+	if _, err := newBackendRoutes(cfg.Extraction.Sites); err != nil {
+		problems = append(problems, "extraction: "+err.Error())
+	}
+	if _, err := newWaitRoutes(cfg.Extraction.Sites); err != nil {
+		problems = append(problems, "extraction: "+err.Error())
+	}
+	if _, err := newOutputRoutes(cfg.Output.Routes, ""); err != nil {
+		problems = append(problems, "output.routes: "+err.Error())
+	}
+	if _, err := newLabelRules(cfg.Output.Labels); err != nil {
+		problems = append(problems, "output.labels: "+err.Error())
+	}
+	if _, err := newBannerRules(cfg.Extraction); err != nil {
+		problems = append(problems, "extraction: "+err.Error())
+	}
+	if _, err := newExpandRules(cfg.Expand); err != nil {
+		problems = append(problems, "expand: "+err.Error())
+	}
+	if _, err := crawl.NewScope(nil, crawl.ScopeRules(cfg.Crawl)); err != nil {
+		problems = append(problems, "crawl: "+err.Error())
+	}
+	if err := watch.Thresholds(cfg.Watch).Validate(); err != nil {
+		problems = append(problems, "watch: "+err.Error())
+	}
+	if len(cfg.Output.Fields) > 0 {
+		if _, err := parseFields(strings.Join(cfg.Output.Fields, ",")); err != nil {
+			problems = append(problems, "output.fields: "+err.Error())
+		}
+	}
+	if cfg.Output.Where != "" {
+		if _, err := compileWhere(cfg.Output.Where); err != nil {
+			problems = append(problems, "output.where: "+err.Error())
+		}
+	}
+
+	if len(problems) == 0 {
+		return []doctorCheck{{"config values", checkOK, "valid", ""}}
+	}
+	checks := make([]doctorCheck, len(problems))
+	for i, p := range problems {
+		checks[i] = doctorCheck{"config values", checkFail, p, ""}
+	}
+	return checks
+}
+
+func checkBackend(cfg *config.Config) doctorCheck {
+	switch cfg.Extraction.Backend {
+	case "tavily":
+		if cfg.Extraction.Tavily.APIKey == "" && os.Getenv("TAVILY_API_KEY") == "" {
+			return doctorCheck{"backend", checkFail, "tavily: no API key", "set extraction.tavily.api_key or TAVILY_API_KEY"}
+		}
+		return doctorCheck{"backend", checkOK, "tavily: API key set", ""}
+	case "jina":
+		if cfg.Extraction.Jina.APIKey == "" && os.Getenv("JINA_API_KEY") == "" {
+			return doctorCheck{"backend", checkOK, "jina: no API key (anonymous rate limits)", ""}
+		}
+		return doctorCheck{"backend", checkOK, "jina: API key set", ""}
+	}
+	return doctorCheck{"backend", checkOK, "readability (local, no key needed)", ""}
+}
+
+func checkCookieStores(cfg *config.Config) []doctorCheck {
+	ce := cookies.NewCookieExtractor(cookies.BrowserType(cfg.Browser.Default), cfg.Browser.Paths)
+	var checks []doctorCheck
+
+	available := ce.DetectAvailableBrowsers()
+	if len(available) == 0 {
+		checks = append(checks, doctorCheck{"cookies", checkWarn, "no browser profile found", "set browser.paths if your browser keeps its profile elsewhere"})
+	} else {
+		names := make([]string, len(available))
+		for i, b := range available {
+			names[i] = string(b)
+		}
+		checks = append(checks, doctorCheck{"cookies", checkOK, "browsers: " + strings.Join(names, ", "), ""})
+	}
+
+	if runtime.GOOS == "darwin" {
+		if err := cookies.SafariStatus(); errors.Is(err, cookies.ErrSafariFullDiskAccess) {
+			status := checkWarn
+			if cfg.Browser.Default == string(cookies.BrowserSafari) && cfg.Browser.Cookies.File == "" {
+				status = checkFail
+			}
+			checks = append(checks, doctorCheck{"safari", status, "cookie store not readable", "grant your terminal Full Disk Access, or set browser.cookies.file"})
+		}
+	}
+
+	if path := cfg.Browser.Cookies.File; path != "" {
+		if f, err := os.Open(expandHome(path)); err != nil {
+			checks = append(checks, doctorCheck{"cookie file", checkFail, err.Error(), "fix browser.cookies.file"})
+		} else {
+			f.Close()
+			checks = append(checks, doctorCheck{"cookie file", checkOK, path, ""})
+		}
+	}
+	return checks
+}
+
+// chromeNames are the executables chromedp looks for, in its order
+var chromeNames = []string{
+	"headless_shell",
+	"headless-shell",
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"google-chrome-stable",
+	"google-chrome-beta",
+	"google-chrome-unstable",
+	"/usr/bin/google-chrome",
+	"/usr/local/bin/chrome",
+	"/snap/bin/chromium",
+	"chrome",
+}
+
+func checkChrome() doctorCheck {
+	if path, ok := findChrome(); ok {
+		return doctorCheck{"chrome", checkOK, path, ""}
+	}
+	return doctorCheck{"chrome", checkWarn, "not found; JS rendering is unavailable", "install Chrome or Chromium to use --js"}
+}
+
+// findChrome returns the browser JS mode would launch
+func findChrome() (string, bool) {
+	candidates := chromeNames
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = append([]string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}, candidates...)
+	case "windows":
+		candidates = append([]string{
+			"chrome.exe",
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+		}, candidates...)
+	}
+	for _, name := range candidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func checkOCR(cfg *config.Config) doctorCheck {
+	engine, err := newOCREngine(cfg)
+	if err != nil {
+		return doctorCheck{"ocr", checkFail, err.Error(), "install tesseract or fix extraction.ocr_command"}
+	}
+	path, _ := exec.LookPath(engine.Program())
+	return doctorCheck{"ocr", checkOK, path, ""}
+}
+
+func checkNetwork(cfg *config.Config) doctorCheck {
+	deadline := 10 * time.Second
+	if cfg.Network.Timeout > 0 {
+		deadline = time.Duration(cfg.Network.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, doctorURL, nil)
+	if err != nil {
+		return doctorCheck{"network", checkFail, err.Error(), "pass a valid --url"}
+	}
+	req.Header.Set("User-Agent", "scrpr/"+version)
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return doctorCheck{"network", checkFail, err.Error(), "check connectivity, DNS and HTTP(S)_PROXY settings"}
+	}
+	resp.Body.Close()
+	return doctorCheck{"network", checkOK, fmt.Sprintf("%s %s in %v", doctorURL, resp.Status, time.Since(start).Round(time.Millisecond)), ""}
+}
+
+// checkDirs verifies scrpr can write where it keeps data
+func checkDirs(cfg *config.Config) []doctorCheck {
+	var checks []doctorCheck
+	if dataDir, err := config.DataDir(); err != nil {
+		checks = append(checks, doctorCheck{"data dir", checkFail, err.Error(), ""})
+	} else {
+		checks = append(checks, checkWritable("data dir", dataDir))
+	}
+	checks = append(checks, checkConfigDir(filepath.Dir(getDefaultConfigPath())))
+	if cfg.Output.SaveRaw != "" {
+		checks = append(checks, checkWritable("raw store", cfg.Output.SaveRaw))
+	}
+	if dir := cfg.Extraction.SiteConfigDir; dir != "" {
+		if fi, err := os.Stat(expandHome(dir)); err != nil || !fi.IsDir() {
+			checks = append(checks, doctorCheck{"site configs", checkFail, "not a directory: " + dir, "clone https://github.com/fivefilters/ftr-site-config or fix extraction.site_config_dir"})
+		} else {
+			checks = append(checks, doctorCheck{"site configs", checkOK, dir, ""})
+		}
+	}
+	if cfg.Cache.Enabled {
+		if dir, err := cacheDir(cfg); err != nil {
+			checks = append(checks, doctorCheck{"response cache", checkFail, err.Error(), ""})
+		} else {
+			checks = append(checks, checkWritable("response cache", dir))
+		}
+	}
+	if cfg.Cache.API {
+		if dir, err := apiCacheDir(cfg); err != nil {
+			checks = append(checks, doctorCheck{"API cache", checkFail, err.Error(), ""})
+		} else {
+			checks = append(checks, checkWritable("API cache", dir))
+		}
+	}
+	return checks
+}
+
+// checkConfigDir checks the config directory without creating it: runs only
+// read it, and a read-only one merely keeps config init and rules from
+// writing there
+func checkConfigDir(dir string) doctorCheck {
+	if _, err := os.Stat(dir); err != nil {
+		return doctorCheck{"config dir", checkOK, "missing, nothing to read", ""}
+	}
+	if noConfigWrite {
+		return doctorCheck{"config dir", checkOK, dir, ""}
+	}
+	if check := checkWritable("config dir", dir); check.Status != checkOK {
+		check.Status = checkWarn
+		check.Hint = "scrpr config init and scrpr rules cannot write here; runs are unaffected"
+		return check
+	}
+	return doctorCheck{"config dir", checkOK, dir, ""}
+}
+
+func checkWritable(name, dir string) doctorCheck {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return doctorCheck{name, checkFail, err.Error(), "fix the permissions of " + dir}
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return doctorCheck{name, checkFail, err.Error(), "fix the permissions of " + dir}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return doctorCheck{name, checkOK, dir, ""}
+}
+
+func expandHome(path string) string {
+	return paths.ExpandHome(path)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// markdownImageRe matches ![alt](src "title"); group 2 is the source
+var markdownImageRe = regexp.MustCompile(`(!\[[^\]]*\]\()([^)\s]+)((?:\s+"[^"]*")?\))`)
+
+// imageEmbedder downloads the images of one article and turns them into
+// data URIs, within the per-image and per-article size limits
+type imageEmbedder struct {
+	client    *http.Client
+	base      *url.URL
+	maxImage  int
+	remaining int               // bytes left in the article's budget
+	embedded  map[string]string // source URL -> data URI
+}
+
+func newImageEmbedder(pageURL string, maxImage, maxTotal int) *imageEmbedder {
+	base, _ := url.Parse(pageURL)
+	return &imageEmbedder{
+		client:    httpClient("image"),
+		base:      base,
+		maxImage:  maxImage,
+		remaining: maxTotal,
+		embedded:  make(map[string]string),
+	}
+}
+
+// embedResultImages replaces the image URLs of markdown and HTML content with
+// data URIs. Images that fail to download or exceed a limit keep their URL.
+func embedResultImages(result *ProcessResult, ro *RunOptions) {
+	e := newImageEmbedder(result.URL, ro.EmbedMaxImage, ro.EmbedMaxTotal)
+	rewriteImages(result, ro.Format, e.dataURI)
+}
+
+// rewriteImages replaces each image source of markdown and HTML content with
+// replace(src); sources replace returns unchanged are left alone
+func rewriteImages(result *ProcessResult, format string, replace func(src string) string) {
+	switch format {
+	case "markdown":
+		result.Content = markdownImageRe.ReplaceAllStringFunc(result.Content, func(img string) string {
+			m := markdownImageRe.FindStringSubmatch(img)
+			return m[1] + replace(m[2]) + m[3]
+		})
+	case "html":
+		result.Content = rewriteHTMLImages(result.Content, replace)
+	}
+}
+
+func rewriteHTMLImages(body string, replace func(src string) string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body
+	}
+	doc.Find("img[src]").Each(func(i int, s *goquery.Selection) {
+		src := s.AttrOr("src", "")
+		if dst := replace(src); dst != src {
+			s.SetAttr("src", dst)
+			s.RemoveAttr("srcset")
+		}
+	})
+	html, err := doc.Find("body").Html()
+	if err != nil {
+		return body
+	}
+	return html
+}
+
+// dataURI returns src as a data URI, or src unchanged when it cannot be
+// embedded
+func (e *imageEmbedder) dataURI(src string) string {
+	ref, err := url.Parse(src)
+	if err != nil {
+		return src
+	}
+	if e.base != nil {
+		ref = e.base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return src
+	}
+	if uri, ok := e.embedded[ref.String()]; ok {
+		return uri
+	}
+
+	limit := min(e.maxImage, e.remaining)
+	if limit <= 0 {
+		e.skip(ref, fmt.Errorf("article exceeds %d KB of embedded images", embedMaxTotalKB))
+		return src
+	}
+	data, mediaType, err := downloadImage(e.client, ref.String(), limit)
+	if err != nil {
+		e.skip(ref, err)
+		return src
+	}
+	e.remaining -= len(data)
+
+	uri := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	e.embedded[ref.String()] = uri
+	return uri
+}
+
+func (e *imageEmbedder) skip(ref *url.URL, err error) {
+	if verbose && !quiet {
+		fmt.Fprintf(os.Stderr, "Not embedding image %s: %v\n", ref, err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/seal"
+)
+
+// sealer encrypts the files of the run with --encrypt and decrypts the
+// --from-raw store with --identity (nil = neither)
+var sealer *seal.Sealer
+
+// setEncryption reads --encrypt and --identity. Encryption turns off the
+// response and API caches, which would keep pages in the clear.
+func setEncryption(cmd *cobra.Command, cfg *config.Config) error {
+	if !cmd.Flags().Changed("encrypt") {
+		encryptTo = cfg.Output.Encrypt
+	}
+	if len(encryptTo) == 0 && identityFile == "" {
+		return nil
+	}
+	var err error
+	if sealer, err = seal.New(encryptTo); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	if identityFile != "" {
+		if err := sealer.LoadIdentities(identityFile); err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+	}
+	if !sealer.CanSeal() {
+		return nil
+	}
+
+	switch {
+	case appendOutput:
+		return exitError(ExitInvalidInput, "--append cannot add to an encrypted file")
+	case debugExtractionDir != "":
+		return exitError(ExitInvalidInput, "--debug-extraction writes pages in the clear and cannot be combined with --encrypt")
+	case jsonEnvelope:
+		return exitError(ExitInvalidInput, "--json-envelope cannot be combined with --encrypt")
+	case cmd.Flags().Changed("cache") && useCache, cmd.Flags().Changed("cache-ttl"):
+		return exitError(ExitInvalidInput, "the response cache keeps pages in the clear and cannot be combined with --encrypt")
+	}
+	noCache = true
+	return nil
+}
+
+// encrypting reports whether the run encrypts what it writes
+func encrypting() bool {
+	return sealer != nil && sealer.CanSeal()
+}
+
+// sealedName is the name a file scrpr names itself is written under: with
+// .age appended when encrypting
+func sealedName(name string) string {
+	if encrypting() {
+		return name + seal.Ext
+	}
+	return name
+}
+
+// sealData encrypts the content of a file when encrypting
+func sealData(data []byte) ([]byte, error) {
+	if !encrypting() {
+		return data, nil
+	}
+	return sealer.Seal(data)
+}
+
+// writeSealed writes a result file of directory output, encrypted when
+// encrypting
+func writeSealed(path string, data []byte) error {
+	data, err := sealData(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// sealedFile encrypts what is written to a file; Close ends the encrypted
+// stream, then closes the file
+type sealedFile struct {
+	io.WriteCloser
+	file io.Closer
+}
+
+func (f *sealedFile) Close() error {
+	return closeAll(f.WriteCloser, f.file)
+}
+
+// createOutput creates a file named by the user, such as an archive or a
+// route's file, encrypting what is written to it when encrypting. The name
+// is kept: the user chose it.
+func createOutput(path string) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypting() {
+		return f, nil
+	}
+	w, err := sealer.Writer(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &sealedFile{WriteCloser: w, file: f}, nil
+}
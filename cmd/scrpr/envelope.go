@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/byteowlz/scrpr/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// The --json-envelope object, documented in pkg/schema
+type (
+	resultEnvelope = schema.Envelope
+	envelopeError  = schema.EnvelopeError
+	envelopeSkip   = schema.EnvelopeSkip
+)
+
+// envelope collects the outcome of the single URL in --json-envelope mode
+var envelope *resultEnvelope
+
+// errorClasses names the built-in exit codes in envelope errors
+var errorClasses = map[int]string{
+	ExitNetworkError: "network",
+	ExitProcessError: "process",
+	ExitInvalidInput: "invalid_input",
+	ExitConfigError:  "config",
+	ExitFileIOError:  "file_io",
+	ExitPartialError: "partial",
+}
+
+// runEnvelope runs with all diagnostics moved into one JSON object on stdout
+func runEnvelope(cmd *cobra.Command, args []string) error {
+	quiet = true
+	envelope = &resultEnvelope{SchemaVersion: schema.Version, Status: "ok"}
+	defer func() { envelope = nil }() // the next run in the process may not want one
+	if len(args) > 0 {
+		envelope.URL = args[0]
+	}
+
+	err := runURLs(cmd, args)
+
+	var ee *exitErr
+	if err != nil {
+		if !errors.As(err, &ee) {
+			ee = &exitErr{code: ExitInvalidInput, msg: err.Error()}
+		}
+		envelope.ExitCode = mapExitCode(ee.code)
+		if envelope.Error == nil {
+			envelope.Error = &envelopeError{Class: errorClasses[ee.code], Message: ee.msg}
+		}
+	}
+	if envelope.Skip != nil && envelope.Error == nil {
+		envelope.Status = "skipped"
+	}
+	if envelope.Error != nil {
+		envelope.Status = "error"
+		if envelope.Error.Class == "" && ee != nil {
+			envelope.Error.Class = errorClasses[ee.code]
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if encErr := enc.Encode(envelope); encErr != nil {
+		return exitError(ExitFileIOError, "failed to write envelope: %v", encErr)
+	}
+	return err
+}
+
+// setEnvelopeResult records the processed URL or its failure
+func setEnvelopeResult(url string, result *ProcessResult, err error) {
+	envelope.URL = url
+	if err != nil {
+		envelope.Error = &envelopeError{Class: errorClasses[failureCode(err)], Message: err.Error()}
+		return
+	}
+	out := newJSONResult(result)
+	envelope.Result = &out
+}
+
+// setEnvelopeSkip records that the URL was skipped; the run still succeeds
+func setEnvelopeSkip(url, reason string, err error) {
+	envelope.URL = url
+	envelope.Skip = &envelopeSkip{Reason: reason, Message: err.Error()}
+}
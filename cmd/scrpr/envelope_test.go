@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, selftestPage("Page", "", "<p>Marker page.</p>\n"+selftestParagraphs(3)))
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name     string
+		args     []string
+		url      string // reported in the envelope
+		status   string
+		class    string // of the error
+		skip     string // reason
+		exitCode int
+	}{
+		{name: "ok", args: []string{server.URL + "/page"}, url: server.URL + "/page", status: "ok"},
+		{name: "http error", args: []string{server.URL + "/missing"}, url: server.URL + "/missing", status: "error", class: "network", exitCode: ExitNetworkError},
+		{name: "non-html", args: []string{server.URL + "/file.pdf"}, url: server.URL + "/file.pdf", status: "skipped", skip: skipNonHTML},
+		{name: "with output", args: []string{"-o", t.TempDir() + "/out.md", server.URL + "/page"}, url: server.URL + "/page", status: "error", class: "invalid_input", exitCode: ExitInvalidInput},
+		{name: "two urls", args: []string{server.URL + "/page", server.URL + "/file.pdf"}, url: server.URL + "/page", status: "error", class: "invalid_input", exitCode: ExitInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			out := captureOutput(t, &os.Stdout, func() {
+				err = runScrpr(t, append([]string{"--no-js", "--json-envelope"}, tt.args...)...)
+			})
+			if (err != nil) != (tt.exitCode != 0) {
+				t.Errorf("run returned %v", err)
+			}
+
+			var got resultEnvelope
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("stdout is not one JSON object: %v\n%s", err, out)
+			}
+			if got.SchemaVersion == 0 {
+				t.Error("schema_version is not set")
+			}
+			if got.Status != tt.status || got.ExitCode != tt.exitCode {
+				t.Errorf("status %q, exit_code %d; want %q, %d", got.Status, got.ExitCode, tt.status, tt.exitCode)
+			}
+			if got.URL != tt.url {
+				t.Errorf("url %q, want %q", got.URL, tt.url)
+			}
+			switch {
+			case tt.class != "":
+				if got.Error == nil || got.Error.Class != tt.class || got.Error.Message == "" {
+					t.Errorf("error %+v, want class %q", got.Error, tt.class)
+				}
+				if got.Result != nil {
+					t.Errorf("failed run has a result")
+				}
+			case tt.skip != "":
+				if got.Skip == nil || got.Skip.Reason != tt.skip || got.Error != nil {
+					t.Errorf("skip %+v, error %+v; want reason %q", got.Skip, got.Error, tt.skip)
+				}
+			default:
+				if got.Error != nil || got.Result == nil || !strings.Contains(got.Result.Content, "Marker page.") {
+					t.Errorf("error %+v, result %+v", got.Error, got.Result)
+				}
+			}
+		})
+	}
+}
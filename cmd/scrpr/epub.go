@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/byteowlz/scrpr/internal/epub"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+const maxEpubImageSize = 5 << 20 // 5MB per embedded image
+
+// addEpubChapter appends a result to the book, embedding its images when
+// --epub-images is set
+func addEpubChapter(book *epub.Book, result *ProcessResult) {
+	body := result.Content
+	if epubImages {
+		body = embedEpubImages(book, body, result.URL)
+	}
+
+	book.AddChapter(epub.Chapter{
+		Title:  result.Title,
+		URL:    result.URL,
+		Author: result.Author,
+		HTML:   body,
+	})
+}
+
+// embedEpubImages downloads every <img> in the chapter, stores it in the book
+// and rewrites the src to the embedded copy. Images that fail to download
+// keep their remote URL.
+func embedEpubImages(book *epub.Book, body, pageURL string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body
+	}
+
+	base, _ := url.Parse(pageURL)
+	client := httpClient("image")
+
+	doc.Find("img[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		ref, err := url.Parse(src)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if ref.Scheme != "http" && ref.Scheme != "https" {
+			return
+		}
+
+		data, mediaType, err := downloadImage(client, ref.String(), maxEpubImageSize)
+		if err != nil {
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Skipping image %s: %v\n", ref, err)
+			}
+			return
+		}
+		s.SetAttr("src", book.AddImage(data, mediaType))
+		s.RemoveAttr("srcset")
+	})
+
+	html, err := doc.Find("body").Html()
+	if err != nil {
+		return body
+	}
+	return html
+}
+
+// downloadImage fetches an image of at most maxSize bytes and returns it with
+// its media type
+func downloadImage(client *http.Client, imageURL string, maxSize int) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, "", fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxSize {
+		return nil, "", fmt.Errorf("image exceeds %d bytes", maxSize)
+	}
+
+	mediaType := http.DetectContentType(data)
+	if !strings.HasPrefix(mediaType, "image/") {
+		// SVG sniffs as text/xml; trust the server for it
+		if ct := resp.Header.Get("Content-Type"); strings.HasPrefix(ct, "image/svg+xml") {
+			mediaType = "image/svg+xml"
+		} else {
+			return nil, "", fmt.Errorf("not an image: %s", mediaType)
+		}
+	}
+	return data, mediaType, nil
+}
+
+// newEpubBook creates the book for this run
+func newEpubBook() *epub.Book {
+	title := epubTitle
+	if title == "" {
+		title = "scrpr export " + stampTime().Format("2006-01-02")
+	}
+	book := epub.New(title)
+	if locale := fetcher.LocaleFromAcceptLanguage(langHeader); locale != "" {
+		book.Language = locale
+	}
+	return book
+}
+
+// writeEpub finalizes book metadata and writes the container. Without
+// --epub-title a single-article book takes the article's title, and a book
+// whose chapters share an author is attributed to them.
+func writeEpub(book *epub.Book, w io.Writer) error {
+	if epubTitle == "" && len(book.Chapters) == 1 && book.Chapters[0].Title != "" {
+		book.Title = book.Chapters[0].Title
+	}
+	if len(book.Chapters) > 0 {
+		author := book.Chapters[0].Author
+		for _, ch := range book.Chapters[1:] {
+			if ch.Author != author {
+				author = ""
+				break
+			}
+		}
+		book.Author = author
+	}
+	return book.Write(w)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// Values of --fail-on
+const (
+	failOnPartial = "partial" // partial code when only some URLs fail
+	failOnAny     = "any"     // any failed URL exits with its error code
+	failOnNone    = "none"    // URL failures never fail the run
+)
+
+// exitCodeMap translates the built-in exit codes to those set in [exit_codes]
+var exitCodeMap = map[int]int{}
+
+func setExitCodes(c config.ExitCodesConfig) error {
+	codes := map[int]int{
+		ExitNetworkError: c.Network,
+		ExitProcessError: c.Process,
+		ExitInvalidInput: c.InvalidInput,
+		ExitConfigError:  c.Config,
+		ExitFileIOError:  c.FileIO,
+		ExitPartialError: c.Partial,
+	}
+	for builtin, code := range codes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("exit code %d for class %d is outside 0-255", code, builtin)
+		}
+	}
+	exitCodeMap = codes
+	return nil
+}
+
+// mapExitCode returns the configured exit code for a built-in one
+func mapExitCode(code int) int {
+	if mapped, ok := exitCodeMap[code]; ok {
+		return mapped
+	}
+	return code
+}
+
+func parseFailOn(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case failOnPartial, failOnAny, failOnNone:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid --fail-on %q (available: partial, any, none)", s)
+}
+
+// failureCode classifies a per-URL error as a network or processing failure
+func failureCode(err error) int {
+	// The fetch worked; there was nothing to extract
+	var unsupported *fetcher.UnsupportedContentError
+	if errors.As(err, &unsupported) {
+		return ExitProcessError
+	}
+	var paused *HostPausedError
+	if errors.As(err, &paused) {
+		return ExitNetworkError
+	}
+	// A file:// URL that cannot be read
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return ExitFileIOError
+	}
+	errStr := err.Error()
+	if strings.Contains(errStr, "failed to fetch") || strings.Contains(errStr, "HTTP error") || strings.Contains(errStr, "dial") {
+		return ExitNetworkError
+	}
+	return ExitProcessError
+}
+
+// batchResult applies --fail-on to a run where some URLs failed; code is the
+// class of the last failure
+func batchResult(successCount, code int) error {
+	switch failOn {
+	case failOnNone:
+		return nil
+	case failOnAny:
+		return &exitErr{code: code}
+	}
+	if successCount > 0 {
+		return &exitErr{code: ExitPartialError}
+	}
+	return &exitErr{code: code}
+}
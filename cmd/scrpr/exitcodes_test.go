@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+func TestParseFailOn(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"partial", failOnPartial, false},
+		{" ANY ", failOnAny, false},
+		{"None", failOnNone, false},
+		{"", "", true},
+		{"some", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseFailOn(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseFailOn(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFailureCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unsupported content", fmt.Errorf("failed to fetch content: %w", &fetcher.UnsupportedContentError{ContentType: "application/zip", Size: -1}), ExitProcessError},
+		{"host paused", &HostPausedError{Host: "example.com"}, ExitNetworkError},
+		{"unreadable file", fmt.Errorf("failed to fetch content: %w", &os.PathError{Op: "open", Path: "/missing", Err: os.ErrNotExist}), ExitFileIOError},
+		{"fetch", errors.New("failed to fetch content: connection refused"), ExitNetworkError},
+		{"status", errors.New("HTTP error: 503 Service Unavailable"), ExitNetworkError},
+		{"dial", errors.New("dial tcp: lookup example.invalid: no such host"), ExitNetworkError},
+		{"extraction", errors.New("no content extracted"), ExitProcessError},
+	}
+	for _, tt := range tests {
+		if got := failureCode(tt.err); got != tt.want {
+			t.Errorf("%s: failureCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBatchResult(t *testing.T) {
+	defer func(saved string) { failOn = saved }(failOn)
+
+	tests := []struct {
+		failOn    string
+		successes int
+		want      int // exit code, -1 = the run succeeds
+	}{
+		{failOnPartial, 2, ExitPartialError},
+		{failOnPartial, 0, ExitNetworkError},
+		{failOnAny, 2, ExitNetworkError},
+		{failOnAny, 0, ExitNetworkError},
+		{failOnNone, 2, -1},
+		{failOnNone, 0, -1},
+	}
+	for _, tt := range tests {
+		failOn = tt.failOn
+		err := batchResult(tt.successes, ExitNetworkError)
+		var exit *exitErr
+		switch {
+		case tt.want < 0 && err != nil:
+			t.Errorf("--fail-on %s with %d successes: got %v, want success", tt.failOn, tt.successes, err)
+		case tt.want >= 0 && (!errors.As(err, &exit) || exit.code != tt.want):
+			t.Errorf("--fail-on %s with %d successes: got %v, want exit code %d", tt.failOn, tt.successes, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values of --name-by
+const (
+	nameByURL   = "url"
+	nameByTitle = "title"
+)
+
+var isoDateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// snapshotFilenames names files below the date directory of --snapshot
+// unless --filename-template is set
+const snapshotFilenames = "{{.Host}}/{{.Slug}}"
+
+// filenameData is what --filename-template renders against
+type filenameData struct {
+	Host      string // example.com
+	Path      string // URL path as slugged segments: blog/my-post
+	Slug      string // last path segment: my-post
+	Title     string
+	TitleSlug string // slugged title, falling back to Slug
+	Date      string // published date (YYYY-MM-DD), or today
+	Index     int    // 1-based position in the input
+	Ext       string // extension for --format, with dot
+	Label     string // first label, slugged ("" = unlabeled)
+	Labels    []string
+}
+
+// fileNamer names the files of one run's directory and archive output
+type fileNamer struct {
+	template *template.Template // --filename-template (nil = by nameBy)
+	nameBy   string
+	format   string
+	used     map[string]bool // so two URLs of the run never share a file
+}
+
+func newFileNamer(nameBy, format string, tmpl *template.Template) *fileNamer {
+	return &fileNamer{template: tmpl, nameBy: nameBy, format: format, used: make(map[string]bool)}
+}
+
+// reserve keeps name from results, for the run's own files such as the
+// manifest
+func (n *fileNamer) reserve(name string) {
+	n.used[name] = true
+}
+
+func parseFilenameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("filename").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// filePath picks the file for a result in directory mode and creates its
+// subdirectories
+func (n *fileNamer) filePath(dir string, index int, rawURL string, result *ProcessResult) (string, error) {
+	name, err := n.fileName(index, rawURL, result)
+	if err != nil {
+		return "", err
+	}
+
+	full := filepath.Join(dir, filepath.FromSlash(sealedName(name)))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	return full, nil
+}
+
+// fileName returns the slash-separated name for a result: the
+// --filename-template when set, the slugged title with --name-by title, else
+// the mangled URL. A -2, -3, ... suffix keeps files from one run apart.
+func (n *fileNamer) fileName(index int, rawURL string, result *ProcessResult) (string, error) {
+	name := urlToFilename(rawURL, n.format)
+	if n.template == nil && n.nameBy == nameByTitle {
+		if slug := slugify(result.Title); slug != "" {
+			name = slug + path.Ext(name)
+		}
+	}
+	if n.template != nil {
+		data := newFilenameData(index, rawURL, result, fileExtension(n.format))
+		var b strings.Builder
+		if err := n.template.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("filename template: %w", err)
+		}
+		name = sanitizeRelPath(b.String())
+		if name == "" {
+			name = data.Slug
+		}
+		if path.Ext(name) == "" {
+			name += data.Ext
+		}
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; n.used[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	n.used[name] = true
+	return name, nil
+}
+
+func newFilenameData(index int, rawURL string, result *ProcessResult, ext string) filenameData {
+	data := filenameData{
+		Index:  index,
+		Title:  result.Title,
+		Ext:    ext,
+		Date:   stampTime().Format("2006-01-02"),
+		Labels: result.Labels,
+	}
+	if len(result.Labels) > 0 {
+		data.Label = slugify(result.Labels[0])
+	}
+	if date := isoDateRe.FindString(result.Metadata["date"]); date != "" {
+		data.Date = date
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		data.Host = u.Hostname()
+		var segments []string
+		for _, seg := range strings.Split(u.Path, "/") {
+			seg = strings.TrimSuffix(seg, path.Ext(seg))
+			if s := slugify(seg); s != "" {
+				segments = append(segments, s)
+			}
+		}
+		data.Path = strings.Join(segments, "/")
+		if len(segments) > 0 {
+			data.Slug = segments[len(segments)-1]
+		}
+	}
+	if data.Host == "" {
+		data.Host = "unknown"
+	}
+	if data.Slug == "" {
+		data.Slug = "index"
+	}
+	if data.Path == "" {
+		data.Path = data.Slug
+	}
+
+	data.TitleSlug = slugify(result.Title)
+	if data.TitleSlug == "" {
+		data.TitleSlug = data.Slug
+	}
+	return data
+}
+
+// slugify lowercases s and joins its words with dashes, keeping letters and
+// digits of any script
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > 80 {
+		slug = strings.TrimRight(truncateUTF8(slug, 80), "-")
+	}
+	return slug
+}
+
+// sanitizeRelPath keeps a rendered filename inside the output directory:
+// no absolute paths, no .. segments, no characters filesystems reject
+func sanitizeRelPath(name string) string {
+	replacer := strings.NewReplacer("\\", "/", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_", "\x00", "")
+	var segments []string
+	for _, seg := range strings.Split(replacer.Replace(name), "/") {
+		seg = strings.TrimSpace(seg)
+		if seg == "" || seg == "." || seg == ".." {
+			continue
+		}
+		segments = append(segments, truncateUTF8(seg, 200))
+	}
+	return strings.Join(segments, "/")
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestFileNamer_FileName(t *testing.T) {
+	mustTemplate := func(text string) *template.Template {
+		tmpl, err := parseFilenameTemplate(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tmpl
+	}
+	result := func(title, date string, labels ...string) *ProcessResult {
+		return &ProcessResult{Title: title, Metadata: map[string]string{"date": date}, Labels: labels}
+	}
+
+	tests := []struct {
+		name   string
+		namer  *fileNamer
+		url    string
+		result *ProcessResult
+		want   string
+	}{
+		{"by url", newFileNamer(nameByURL, "markdown", nil), "https://example.com/blog/post?id=1", result("", ""), "example.com_blog_post_id_1.md"},
+		{"by title", newFileNamer(nameByTitle, "text", nil), "https://example.com/p/1", result("Hello, World!", ""), "hello-world.txt"},
+		{"by title without one", newFileNamer(nameByTitle, "json", nil), "https://example.com/p/1", result("", ""), "example.com_p_1.json"},
+		{"template", newFileNamer(nameByURL, "markdown", mustTemplate("{{.Host}}/{{.Date}}-{{.Slug}}")), "https://example.com/blog/My%20Post.html", result("", "2024-05-01T10:00:00Z"), "example.com/2024-05-01-my-post.md"},
+		{"template path and title", newFileNamer(nameByURL, "text", mustTemplate("{{.Path}}/{{.TitleSlug}}")), "https://example.com/a/b", result("Über Alles", ""), "a/b/über-alles.txt"},
+		{"template own extension", newFileNamer(nameByURL, "markdown", mustTemplate("{{.Index}}.mdx")), "https://example.com/", result("", ""), "3.mdx"},
+		{"template label", newFileNamer(nameByURL, "markdown", mustTemplate("{{.Label}}/{{.Slug}}")), "https://example.com/", result("", "", "Go News", "tech"), "go-news/index.md"},
+		{"template escaping the directory", newFileNamer(nameByURL, "text", mustTemplate("../../{{.Host}}/..//x:y")), "https://example.com/", result("", ""), "example.com/x_y.txt"},
+		{"template rendering nothing", newFileNamer(nameByURL, "text", mustTemplate("{{.Label}}")), "https://example.com/post", result("", ""), "post.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.namer.fileName(3, tt.url, tt.result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileNamer_Collisions(t *testing.T) {
+	names := newFileNamer(nameByTitle, "markdown", nil)
+	names.reserve(manifestFile)
+	names.reserve("index.md")
+
+	var got []string
+	for _, title := range []string{"Same", "Same", "Same", "Other", "Index", "Manifest"} {
+		name, err := names.fileName(1, "https://example.com/", &ProcessResult{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, name)
+	}
+	want := []string{"same.md", "same-2.md", "same-3.md", "other.md", "index-2.md", "manifest.md"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Every run starts over
+	name, _ := newFileNamer(nameByTitle, "markdown", nil).fileName(1, "https://example.com/", &ProcessResult{Title: "Same"})
+	if name != "same.md" {
+		t.Errorf("a new namer gave %q, want same.md", name)
+	}
+}
+
+func TestFileNamer_FilePathCreatesDirectories(t *testing.T) {
+	dir := t.TempDir()
+	tmpl, err := parseFilenameTemplate(snapshotFilenames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := newFileNamer(nameByURL, "markdown", tmpl).filePath(dir, 1, "https://example.com/docs/intro", &ProcessResult{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "example.com", "intro.md"); path != want {
+		t.Errorf("got %s, want %s", path, want)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		t.Errorf("directory of %s not created: %v", path, err)
+	}
+}
+
+func TestParseFilenameTemplate_Invalid(t *testing.T) {
+	if _, err := parseFilenameTemplate("{{.Host"); err == nil {
+		t.Error("expected an unclosed action to fail")
+	}
+	tmpl, err := parseFilenameTemplate("{{.Nope}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFileNamer(nameByURL, "text", tmpl).fileName(1, "https://example.com/", &ProcessResult{}); err == nil {
+		t.Error("expected an unknown field to fail")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Hello, World!", "hello-world"},
+		{"  --Go 1.22 -- released  ", "go-1-22-released"},
+		{"日本語 タイトル", "日本語-タイトル"},
+		{"!!!", ""},
+		{strings.Repeat("word ", 30), strings.TrimRight(strings.Repeat("word-", 16), "-")},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.in); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// hostStateFile keeps host health between runs, below the cache directory
+const hostStateFile = "hosts.json"
+
+// HostPausedError reports a URL not fetched because its host asked for a
+// pause (Retry-After) that outlasts the adaptive delay
+type HostPausedError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostPausedError) Error() string {
+	return fmt.Sprintf("%s asked for a pause until %s (Retry-After)", e.Host, e.Until.Local().Format(time.TimeOnly))
+}
+
+var httpStatusRe = regexp.MustCompile(`HTTP error: (\d{3})`)
+
+// processURLPaced waits for a free slot of url's host and out the delay of a
+// struggling host before processing url, and records how the request went,
+// so only slow or failing hosts are slowed down
+func processURLPaced(url string, ro *RunOptions) (*ProcessResult, error) {
+	health := ro.HostHealth
+	if health == nil || ro.RawSource != nil || ro.StdinHTML != nil || fetcher.IsFileURL(url) {
+		return processURL(url, ro)
+	}
+	release := health.Acquire(url)
+	defer release()
+	ready := health.Ready(url)
+	wait := time.Until(ready)
+	if wait > health.MaxDelay() {
+		return nil, &HostPausedError{Host: urlHost(url), Until: ready}
+	}
+	if wait > 0 {
+		if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "Host struggling, waiting %v: %s\n", wait.Round(time.Millisecond), url)
+		}
+		time.Sleep(wait)
+	}
+	start := time.Now()
+	result, err := processURL(url, ro)
+	health.Observe(url, time.Since(start), hostOverloaded(err))
+	var statusErr *fetcher.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		health.Pause(url, statusErr.RetryAfter)
+	}
+	return result, err
+}
+
+func hostStatePath() (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hostStateFile), nil
+}
+
+// loadHostState and saveHostState carry host health over between runs;
+// failures only cost the memory of earlier runs, so they are logged
+func loadHostState(path string) {
+	if err := hostHealth.LoadState(path); err != nil && verbose && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+func saveHostState(path string) {
+	if err := hostHealth.SaveState(path); err != nil && verbose && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// hostOverloaded reports whether err says the host is struggling: network
+// errors, 429 and 5xx responses. Other client errors such as 404 and failed
+// extractions say nothing about the host's health.
+func hostOverloaded(err error) bool {
+	if err == nil {
+		return false
+	}
+	if m := httpStatusRe.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code == 429 || code >= 500
+	}
+	return failureCode(err) == ExitNetworkError
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// inputAttributes are the keys an input line may set after its URL, as in
+// `https://example.com/pricing,label=competitor,priority=10`
+var inputAttributes = []string{"label", "priority"}
+
+// inputLine is a parsed line of the input file or stdin
+type inputLine struct {
+	URL      string
+	Labels   []string
+	Priority int // higher is fetched earlier; 0 when not given
+}
+
+// parseInputLine splits an input line into its URL and the attributes
+// after it. URLs may contain commas themselves, so the URL ends at the first
+// comma that starts a known attribute.
+func parseInputLine(line string) (inputLine, error) {
+	end := len(line)
+	for _, key := range inputAttributes {
+		if i := strings.Index(line, ","+key+"="); i >= 0 && i < end {
+			end = i
+		}
+	}
+	in := inputLine{URL: strings.TrimSpace(line[:end])}
+	if end == len(line) {
+		return in, nil
+	}
+
+	for _, attr := range strings.Split(line[end+1:], ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		switch key {
+		case "label":
+			label, err := checkLabel(value)
+			if err != nil {
+				return inputLine{}, err
+			}
+			if !slices.Contains(in.Labels, label) {
+				in.Labels = append(in.Labels, label)
+			}
+		case "priority":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return inputLine{}, fmt.Errorf("priority %q is not a whole number", value)
+			}
+			in.Priority = n
+		default:
+			return inputLine{}, fmt.Errorf("unknown attribute %q (available: %s)", attr, strings.Join(inputAttributes, ", "))
+		}
+	}
+	return in, nil
+}
+
+// inputPriorities holds the priorities the input gave URLs; a URL listed
+// more than once keeps the highest
+var inputPriorities = make(map[string]int)
+
+func addInputPriority(rawURL string, priority int) {
+	if old, ok := inputPriorities[rawURL]; !ok || priority > old {
+		inputPriorities[rawURL] = priority
+	}
+}
+
+// prioritize reorders urls so higher priorities come first, keeping the
+// input order among equals, and returns the 1-based input position of each
+// URL in the new order. Without priorities the order is unchanged.
+func prioritize(urls []string) []int {
+	type entry struct {
+		url      string
+		position int
+		priority int
+	}
+	entries := make([]entry, len(urls))
+	for i, u := range urls {
+		entries[i] = entry{url: u, position: i + 1, priority: inputPriorities[u]}
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return cmp.Compare(b.priority, a.priority)
+	})
+
+	positions := make([]int, len(urls))
+	for i, e := range entries {
+		urls[i] = e.url
+		positions[i] = e.position
+	}
+	return positions
+}
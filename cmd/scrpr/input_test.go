@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseInputLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    inputLine
+		wantErr bool
+	}{
+		{line: "https://example.com/a", want: inputLine{URL: "https://example.com/a"}},
+		{line: "  https://example.com/a  ", want: inputLine{URL: "https://example.com/a"}},
+		{line: "https://example.com/a,b?c=1,2", want: inputLine{URL: "https://example.com/a,b?c=1,2"}},
+		{line: "https://example.com/pricing,label=competitor,priority=10",
+			want: inputLine{URL: "https://example.com/pricing", Labels: []string{"competitor"}, Priority: 10}},
+		{line: "https://example.com/a,b,priority=-2,label=x, label=y ,label=x",
+			want: inputLine{URL: "https://example.com/a,b", Labels: []string{"x", "y"}, Priority: -2}},
+		{line: "https://example.com/a,label=", wantErr: true},
+		{line: "https://example.com/a,priority=high", wantErr: true},
+		{line: "https://example.com/a,label=x,lang=en", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseInputLine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInputLine(%q) error %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if got.URL != tt.want.URL || !slices.Equal(got.Labels, tt.want.Labels) || got.Priority != tt.want.Priority {
+			t.Errorf("parseInputLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	defer func(saved map[string]int) { inputPriorities = saved }(inputPriorities)
+	inputPriorities = make(map[string]int)
+	addInputPriority("c", 5)
+	addInputPriority("d", 1)
+	addInputPriority("d", 9) // listed twice: the highest wins
+	addInputPriority("e", 5)
+	addInputPriority("e", 2)
+	addInputPriority("f", -1)
+
+	urls := []string{"a", "b", "c", "d", "e", "f"}
+	positions := prioritize(urls)
+	if want := []string{"d", "c", "e", "a", "b", "f"}; !slices.Equal(urls, want) {
+		t.Errorf("order %q, want %q", urls, want)
+	}
+	if want := []int{4, 3, 5, 1, 2, 6}; !slices.Equal(positions, want) {
+		t.Errorf("positions %v, want %v", positions, want)
+	}
+}
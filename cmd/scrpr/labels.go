@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/config"
+)
+
+// inputLabels holds the labels the input file gave each URL
+var inputLabels = make(map[string][]string)
+
+// labelRules are the [[output.labels]] entries
+var labelRules []labelRule
+
+// labelRule labels the URLs matching a pattern
+type labelRule struct {
+	re     *regexp.Regexp
+	labels []string
+}
+
+// newLabelRules checks the [[output.labels]] entries; patterns are written
+// as for [[output.routes]]
+func newLabelRules(entries []config.OutputLabelConfig) ([]labelRule, error) {
+	var rules []labelRule
+	for _, e := range entries {
+		match := strings.TrimSpace(e.Match)
+		if match == "" {
+			return nil, fmt.Errorf("label rule without match pattern")
+		}
+		var labels []string
+		for _, label := range e.Labels {
+			label, err := checkLabel(label)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", match, err)
+			}
+			labels = append(labels, label)
+		}
+		if len(labels) == 0 {
+			return nil, fmt.Errorf("label rule %q has no labels", match)
+		}
+		rules = append(rules, labelRule{re: compileRoutePattern(match), labels: labels})
+	}
+	return rules, nil
+}
+
+// checkLabel trims a label and rejects the ones that cannot round-trip
+// through an input line
+func checkLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", fmt.Errorf("empty label")
+	}
+	if strings.ContainsAny(label, ",\n") {
+		return "", fmt.Errorf("label %q contains a comma", label)
+	}
+	return label, nil
+}
+
+// addInputLabels records the labels of an input line; a URL listed more than
+// once gets the labels of every line
+func addInputLabels(rawURL string, labels []string) {
+	for _, label := range labels {
+		if !slices.Contains(inputLabels[rawURL], label) {
+			inputLabels[rawURL] = append(inputLabels[rawURL], label)
+		}
+	}
+}
+
+// urlLabels returns the labels of a URL: those from the input file, then
+// those of every matching [[output.labels]] rule, without duplicates
+func urlLabels(rawURL string) []string {
+	labels := slices.Clone(inputLabels[rawURL])
+	if len(labelRules) == 0 {
+		return labels
+	}
+	target := routeTarget(rawURL)
+	for _, r := range labelRules {
+		if !r.re.MatchString(target) {
+			continue
+		}
+		for _, label := range r.labels {
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/byteowlz/scrpr/internal/config"
+)
+
+func TestCheckLabel(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"news", "news", false},
+		{"  two words ", "two words", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"a,b", "", true},
+		{"a\nb", "", true},
+	}
+	for _, tt := range tests {
+		got, err := checkLabel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("checkLabel(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewLabelRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry config.OutputLabelConfig
+	}{
+		{"no pattern", config.OutputLabelConfig{Labels: []string{"news"}}},
+		{"no labels", config.OutputLabelConfig{Match: "example.com"}},
+		{"bad label", config.OutputLabelConfig{Match: "example.com", Labels: []string{"a,b"}}},
+	}
+	for _, tt := range tests {
+		if _, err := newLabelRules([]config.OutputLabelConfig{tt.entry}); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
+
+func TestURLLabels(t *testing.T) {
+	defer func(labels map[string][]string, rules []labelRule) {
+		inputLabels, labelRules = labels, rules
+	}(inputLabels, labelRules)
+
+	inputLabels = make(map[string][]string)
+	addInputLabels("https://example.com/blog/a", []string{"watch", "news"})
+	addInputLabels("https://example.com/blog/a", []string{"news", "later"}) // a second line
+	var err error
+	labelRules, err = newLabelRules([]config.OutputLabelConfig{
+		{Match: "example.com", Labels: []string{"news", "site"}},
+		{Match: "example.com/blog/*", Labels: []string{" blog "}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://example.com/blog/a", []string{"watch", "news", "later", "site", "blog"}},
+		{"https://www.example.com/about", []string{"news", "site"}},
+		{"https://example.org/", nil},
+	}
+	for _, tt := range tests {
+		if got := urlLabels(tt.url); !slices.Equal(got, tt.want) {
+			t.Errorf("urlLabels(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	if got := inputLabels["https://example.com/blog/a"]; len(got) != 3 {
+		t.Errorf("urlLabels changed the input labels to %q", got)
+	}
+}
@@ -2,33 +2,42 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/byteowlz/scrpr/internal/audit"
 	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/crawl"
+	"github.com/byteowlz/scrpr/internal/epub"
 	"github.com/byteowlz/scrpr/internal/extractor"
 	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/ordered"
+	"github.com/byteowlz/scrpr/internal/paths"
 	"github.com/byteowlz/scrpr/internal/processor"
+	"github.com/byteowlz/scrpr/internal/store"
 )
 
 // Exit codes for granular error handling
 const (
-	ExitSuccess       = 0
-	ExitNetworkError  = 1
-	ExitProcessError  = 2
-	ExitInvalidInput  = 3
-	ExitConfigError   = 4
-	ExitFileIOError   = 5
-	ExitPartialError  = 6 // some URLs failed, some succeeded
+	ExitSuccess      = 0
+	ExitNetworkError = 1
+	ExitProcessError = 2
+	ExitInvalidInput = 3
+	ExitConfigError  = 4
+	ExitFileIOError  = 5
+	ExitPartialError = 6 // some URLs failed, some succeeded
 )
 
 var (
@@ -39,9 +48,37 @@ var (
 	browserAgent       string
 	javascript         bool
 	noJS               bool
+	waitForSelector    string
+	waitForText        string
+	waitUntil          string
+	scrollRounds       int
+	scrollUntilStable  bool
+	scrollMaxHeight    int
+	scrollTimeout      int
+	evalJSFiles        []string
+	headful            bool
+	devtools           bool
+	slowMo             time.Duration
 	skipBanners        bool
+	printMedia         bool
+	useOCR             bool
+	noTranscripts      bool
+	robotsPolicy       string
+	auditLogPath       string
 	timeout            int
+	connectTimeout     int
+	tlsTimeout         int
+	forceIPv4          bool
+	forceIPv6          bool
+	headerTimeout      int
+	jsTimeout          int
+	processTimeout     int
 	concurrency        int
+	unordered          bool
+	appendOutput       bool
+	snapshot           bool
+	watchChanges       bool
+	readStdinHTML      bool
 	batchSize          int
 	progress           bool
 	separator          string
@@ -51,20 +88,132 @@ var (
 	verbose            bool
 	quiet              bool
 	file               string
+	sitemapSources     []string
 	continueOnError    bool
 	noFollowRedirects  bool
+	maxRedirects       int
 	delay              float64
+	maxRequests        int
+	maxDownloadMB      int
+	maxDuration        time.Duration
 	extractBackend     string
+	siteConfigDir      string
+	textLanguage       string
+	prefetchDNS        bool
+	langHeader         string
+	timezoneID         string
+	referer            string
+	noStickyUA         bool
+	requestMethodFlag  string
+	requestData        string
+	requestContentType string
+	seed               int64
+	deterministic      bool
+	whereSpec          string
+	impersonateTLS     bool
+	stealth            bool
+	noKeepCookies      bool
+	cookieJarFile      string
+	noAdaptiveDelay    bool
+	mobileName         string
+	viewportSize       string
+	epubTitle          string
+	epubImages         bool
+	embedImages        bool
+	saveImages         bool
+	embedMaxImageKB    int
+	embedMaxTotalKB    int
+	maxContentChars    int
+	maxContentTokens   int
+	frontMatter        bool
+	tableOfContents    bool
+	templateSpec       string
+	filenameSpec       string
+	fieldsSpec         string
+	writeIndex         bool
+	renderOutput       bool
+	failOn             string
+	jsonEnvelope       bool
+	nameBy             string
+	fileExt            string
+	flavorName         string
+	keepJunk           bool
+	noExpand           bool
+	debugExtractionDir string
+	saveRawDir         string
+	fromRawDir         string
+	encryptTo          []string
+	identityFile       string
+	useCache           bool
+	noCache            bool
+	refreshCache       bool
+	cacheTTL           time.Duration
 )
 
-const version = "1.1.0"
+// markdownFlavor is parsed from --markdown-flavor
+var markdownFlavor = processor.FlavorGFM
+
+// junkFilter is built from the [junk] config; nil with --keep-junk
+var junkFilter *processor.JunkFilter
+
+// siteConfigs serves ftr-site-config rules from --site-config; nil without
+var siteConfigs *processor.SiteConfigs
+
+// bannerRules are the cookie banner buttons and action of the config
+var bannerRules *fetcher.BannerRules
+
+// expandRules is built from the [expand] config; nil with --no-expand
+var expandRules *fetcher.ExpandRules
+
+// rawStore and rawSource are opened in run() when --save-raw/--from-raw are set
+var (
+	rawStore  *store.RawStore
+	rawSource *store.RawStore
+)
+
+// stdinHTML is the page read from stdin with --stdin-html; it replaces the
+// fetch of the run's single URL
+var stdinHTML []byte
+
+// stdinPageURL stands for the page of --stdin-html when no URL names it
+const stdinPageURL = "file:///dev/stdin"
+
+// responseCache is opened in run() with --cache or cache.enabled
+var responseCache *store.ResponseCache
+
+// apiCache keeps Jina and Tavily results unless cache.api is off
+var apiCache *lazyCache
+
+// mobileDevice is resolved from --mobile in run()
+var mobileDevice *fetcher.Device
+
+// viewport is --viewport, parsed (nil = the device's or Chrome's)
+var viewport *fetcher.Viewport
+
+// uaSelector is shared by every fetcher in a run so sticky agents hold per host
+var uaSelector *fetcher.UserAgentSelector
+
+// httpFetcher serves every static fetch of a run, so URLs on the same host
+// reuse pooled connections
+var httpFetcher *fetcher.SimpleFetcher
+
+// hostHealth paces struggling hosts; nil with --no-adaptive-delay
+var hostHealth *fetcher.HostHealth
+
+// Build metadata, overridden at release time with -ldflags "-X main.version=..."
+var (
+	version = "1.1.0"
+	commit  = ""
+	date    = ""
+)
 
 var rootCmd = &cobra.Command{
-	Use:     "scrpr [urls...]",
-	Short:   "Extract main content from websites",
-	Long:    `scrpr is a CLI tool that extracts the main content from websites.
+	Use:   "scrpr [urls...]",
+	Short: "Extract main content from websites",
+	Long: `scrpr is a CLI tool that extracts the main content from websites.
 It supports multiple extraction backends, browser cookie integration, and pipe operations.`,
 	Version:       version,
+	Args:          cobra.ArbitraryArgs,
 	RunE:          run,
 	SilenceErrors: true,
 	SilenceUsage:  true,
@@ -73,50 +222,136 @@ It supports multiple extraction backends, browser cookie integration, and pipe o
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		if exitErr, ok := err.(*exitErr); ok {
-			os.Exit(exitErr.code)
+			os.Exit(mapExitCode(exitErr.code))
 		}
-		os.Exit(ExitInvalidInput)
+		os.Exit(mapExitCode(ExitInvalidInput))
 	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: $XDG_CONFIG_HOME/scrpr/config.toml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: config.toml in the config directory, see scrpr paths)")
+	rootCmd.PersistentFlags().BoolVar(&noConfigWrite, "no-config-write", false, "fail rather than create or change files in the config directory")
 
 	// Input/Output flags
 	rootCmd.Flags().StringVarP(&file, "file", "f", "", "read URLs from file (one per line)")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output to file or directory (default: stdout)")
-	rootCmd.Flags().StringVar(&outputFormat, "format", "text", "output format (text|markdown)")
+	rootCmd.Flags().StringArrayVar(&sitemapSources, "sitemap-delta", nil, "extract the URLs a sitemap gained since the last run (repeatable)")
+	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output to file, directory or archive (.zip, .tar.gz) (default: stdout)")
+	rootCmd.Flags().BoolVar(&appendOutput, "append", false, "add results to the end of the -o file instead of replacing it")
+	rootCmd.Flags().BoolVar(&snapshot, "snapshot", false, "write outputs under -o DIR/<date>/<host>/<slug> for archives of repeated runs")
+	rootCmd.Flags().BoolVar(&watchChanges, "watch", false, "compare each page with earlier --watch runs and warn when its text shrinks far below the usual length")
+	rootCmd.Flags().StringVar(&outputFormat, "format", "text", "output format (text|markdown|json|html|epub)")
+	rootCmd.Flags().BoolVar(&jsonEnvelope, "json-envelope", false, "for a single URL, print one JSON object with status, error and result, even on failure")
+	rootCmd.Flags().StringVar(&debugExtractionDir, "debug-extraction", "", "dump raw, per-stage and final output with timings per URL into this directory")
+	rootCmd.Flags().BoolVar(&renderOutput, "render", false, "style markdown with ANSI colors when stdout is a terminal")
 	rootCmd.Flags().StringVar(&separator, "separator", "---", "output separator for multiple URLs")
 	rootCmd.Flags().BoolVar(&nullSeparator, "null-separator", false, "use null byte separator (for xargs -0)")
+	rootCmd.Flags().StringVar(&whereSpec, "where", "", "only emit results matching an expression, e.g. 'length > 500 && lang == \"en\" && !paywalled'")
+	rootCmd.Flags().StringVar(&fieldsSpec, "fields", "", "comma-separated output components: "+strings.Join(availableFields, ","))
+	rootCmd.Flags().StringVar(&templateSpec, "template", "", "Go text/template for each result (inline or file path)")
+	rootCmd.Flags().BoolVar(&writeIndex, "index-md", false, "also write index.md linking every file in directory or archive output")
+	rootCmd.Flags().StringVar(&fileExt, "extension", "", "file extension in directory and archive output, e.g. .markdown (default: per format)")
+	rootCmd.Flags().StringVar(&nameBy, "name-by", nameByURL, "file names in directory mode: url or title (falls back to url)")
+	rootCmd.Flags().StringVar(&filenameSpec, "filename-template", "", "Go template for file names in directory mode, e.g. \"{{.Host}}/{{.Slug}}\"")
+	rootCmd.Flags().BoolVar(&frontMatter, "front-matter", false, "start markdown output with a YAML front matter block")
+	rootCmd.Flags().BoolVar(&tableOfContents, "toc", false, "insert a linked table of contents at the top of markdown output")
+	rootCmd.Flags().BoolVar(&keepJunk, "keep-junk", false, "keep newsletter signups, cookie notices, share bars and related posts")
+	rootCmd.Flags().StringVar(&flavorName, "markdown-flavor", string(processor.FlavorGFM), "markdown dialect: gfm, commonmark or obsidian")
+	rootCmd.Flags().StringVar(&epubTitle, "epub-title", "", "book title for --format epub")
+	rootCmd.Flags().BoolVar(&epubImages, "epub-images", false, "download and embed images in --format epub")
+	rootCmd.Flags().BoolVar(&embedImages, "embed-images", false, "embed images as data URIs in markdown and html output")
+	rootCmd.Flags().BoolVar(&saveImages, "save-images", false, "save images once per content hash under assets/ in directory and archive output")
+	rootCmd.Flags().IntVar(&embedMaxImageKB, "embed-max-image", 1024, "largest image --embed-images embeds, in KB")
+	rootCmd.Flags().IntVar(&embedMaxTotalKB, "embed-max-total", 10240, "embedded image budget per article, in KB")
+	rootCmd.Flags().IntVar(&maxContentChars, "max-content-chars", 0, "truncate content at a paragraph or sentence past N characters (0 = unlimited)")
+	rootCmd.Flags().IntVar(&maxContentTokens, "max-content-tokens", 0, "truncate content past an estimated N LLM tokens (0 = unlimited)")
+	rootCmd.Flags().StringVar(&saveRawDir, "save-raw", "", "store fetched HTML (zstd-compressed) in directory")
+	rootCmd.Flags().StringVar(&fromRawDir, "from-raw", "", "reprocess HTML from a --save-raw directory instead of fetching")
+	rootCmd.Flags().StringArrayVar(&encryptTo, "encrypt", nil, "encrypt output files and the --save-raw store to an age recipient, age:age1... (repeatable)")
+	rootCmd.Flags().StringVar(&identityFile, "identity", "", "age identity file to read an encrypted --from-raw store with")
+	rootCmd.Flags().BoolVar(&readStdinHTML, "stdin-html", false, "extract HTML piped to stdin; an optional URL names the page for links and metadata")
+	rootCmd.Flags().BoolVar(&useCache, "cache", false, "reuse fetched pages from the on-disk response cache")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", time.Hour, "how long cached responses are reused; implies --cache")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "neither read nor write the response and API caches")
+	rootCmd.Flags().BoolVar(&refreshCache, "refresh", false, "fetch again instead of reusing cached pages and API results, updating the caches")
 
 	// Parallel processing flags
-	rootCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 5, "max concurrent requests")
+	rootCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 5, "URLs processed at once")
 	rootCmd.Flags().IntVar(&batchSize, "batch-size", 0, "process URLs in batches of N (0 = all at once)")
 	rootCmd.Flags().BoolVar(&progress, "progress", false, "show progress bar for multiple URLs")
+	rootCmd.Flags().BoolVar(&unordered, "unordered", false, "write outputs as they complete instead of in input order")
 
 	// Browser integration flags
-	rootCmd.Flags().StringVarP(&browser, "browser", "b", "auto", "browser for cookie extraction (chrome|firefox|safari|zen)")
+	rootCmd.Flags().StringVarP(&browser, "browser", "b", "auto", "send the cookies of a browser (auto|chrome|firefox|safari|zen)")
 
 	// Rendering flags
 	rootCmd.Flags().BoolVar(&javascript, "javascript", false, "force JavaScript rendering")
 	rootCmd.Flags().BoolVar(&noJS, "no-js", false, "disable JavaScript rendering")
-	rootCmd.Flags().BoolVar(&skipBanners, "skip-banners", true, "skip cookie banner dismissal")
-	rootCmd.Flags().IntVar(&timeout, "timeout", 30, "request timeout in seconds")
+	rootCmd.Flags().BoolVar(&skipBanners, "skip-banners", true, "dismiss cookie banners in JS mode (--skip-banners=false to keep them)")
+	rootCmd.Flags().BoolVar(&noExpand, "no-expand", false, "do not click read-more buttons and accordions in JS mode")
+	rootCmd.Flags().BoolVar(&useOCR, "ocr", false, "read large images with OCR when a page has little text")
+	rootCmd.Flags().BoolVar(&noTranscripts, "no-transcripts", false, "do not add the feed transcript to podcast episode pages")
+	rootCmd.Flags().StringVar(&auditLogPath, "audit-log", "", "append every request to a hash-chained JSONL audit log")
+	rootCmd.Flags().StringVar(&robotsPolicy, "robots-meta", robotsIgnore, "robots meta tags and X-Robots-Tag: ignore, or respect to skip noindex and noarchive pages")
+	rootCmd.Flags().BoolVar(&printMedia, "print-media", false, "render with the print stylesheet in JS mode, dropping what it hides")
+	rootCmd.Flags().IntVar(&timeout, "timeout", 30, "total fetch timeout in seconds")
+	rootCmd.Flags().IntVar(&connectTimeout, "connect-timeout", 10, "connection (dial) timeout in seconds")
+	rootCmd.Flags().IntVar(&tlsTimeout, "tls-timeout", 10, "TLS handshake timeout in seconds")
+	rootCmd.Flags().IntVar(&headerTimeout, "header-timeout", 15, "time to wait for response headers in seconds")
+	rootCmd.Flags().IntVar(&jsTimeout, "js-timeout", 15, "JavaScript rendering timeout in seconds")
+	rootCmd.Flags().StringVar(&waitForSelector, "wait-for", "", "render in JS mode and wait until this CSS selector is visible")
+	rootCmd.Flags().StringVar(&waitForText, "wait-for-text", "", "render in JS mode and wait until the page shows this text")
+	rootCmd.Flags().StringVar(&waitUntil, "wait", "load", "when a rendered page counts as loaded: load, domcontentloaded, network-idle or network-idle:<duration>")
+	rootCmd.Flags().IntVar(&scrollRounds, "scroll", 0, "render in JS mode and scroll to the bottom N times, for infinite-scroll and lazy content")
+	rootCmd.Flags().BoolVar(&scrollUntilStable, "scroll-until-stable", false, "render in JS mode and scroll until the page stops growing (at most --scroll times when set)")
+	rootCmd.Flags().IntVar(&scrollMaxHeight, "scroll-max-height", 100000, "stop scrolling once the page is this tall in pixels (0 = unlimited)")
+	rootCmd.Flags().IntVar(&scrollTimeout, "scroll-timeout", 10, "seconds of scrolling per page, on top of --js-timeout")
+	rootCmd.Flags().StringArrayVar(&evalJSFiles, "eval-js", nil, "render in JS mode and run this script file in the page before it is read (repeatable)")
+	rootCmd.Flags().BoolVar(&headful, "headful", false, "render in a visible Chrome window, slowed down, logging the page console to stderr")
+	rootCmd.Flags().BoolVar(&devtools, "devtools", false, "like --headful, with DevTools open in each tab")
+	rootCmd.Flags().DurationVar(&slowMo, "slow-mo", 0, "pause before each step of a rendered page, e.g. 500ms (default 250ms with --headful)")
+	rootCmd.Flags().IntVar(&processTimeout, "process-timeout", 10, "content processing timeout in seconds")
 
 	// Content processing flags
 	rootCmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "include page metadata in output")
 	rootCmd.Flags().StringVar(&userAgent, "user-agent", "", "custom user agent string")
-	rootCmd.Flags().StringVar(&browserAgent, "browser-agent", "", "browser agent type (auto|chrome|firefox|safari|edge)")
+	rootCmd.Flags().StringVar(&browserAgent, "browser-agent", "", "browser agent type (auto|chrome|firefox|safari|edge) or custom pool name")
+	rootCmd.Flags().StringVar(&mobileName, "mobile", "", "emulate a mobile device: "+strings.Join(fetcher.DeviceNames(), ", ")+" (use --mobile=NAME)")
+	rootCmd.Flags().Lookup("mobile").NoOptDefVal = fetcher.DefaultMobileDevice
+	rootCmd.Flags().StringVar(&viewportSize, "viewport", "", "window size of rendered pages as WIDTHxHEIGHT, e.g. 390x844; with --mobile, the device's screen")
+	rootCmd.Flags().BoolVar(&impersonateTLS, "impersonate", false, "present the TLS fingerprint of the user agent's browser instead of Go's")
+	rootCmd.Flags().BoolVar(&stealth, "stealth", false, "hide the marks of headless Chrome (webdriver flag, plugins, WebGL renderer) in JS mode")
+	rootCmd.Flags().BoolVar(&noStickyUA, "no-sticky-ua", false, "pick a new user agent per request instead of one per host")
+	rootCmd.Flags().BoolVar(&deterministic, "deterministic", false, "reproducible output: fixed seed, no retry jitter, one URL at a time, input order and normalized timestamps")
+	rootCmd.Flags().Int64Var(&seed, "seed", 0, "seed for random choices such as user agents, for reproducible runs (0 = random)")
+	rootCmd.Flags().StringVar(&langHeader, "lang-header", "", "Accept-Language header, also used as the JS locale (default \"en-US,en;q=0.9\")")
+	rootCmd.Flags().StringVarP(&requestMethodFlag, "method", "X", "", "HTTP method of static fetches (default GET, or POST with --data)")
+	rootCmd.Flags().StringVar(&requestData, "data", "", "request body to send, or @file to read it from a file")
+	rootCmd.Flags().StringVar(&requestContentType, "content-type", "", "Content-Type of --data (default application/x-www-form-urlencoded)")
+	rootCmd.Flags().BoolVar(&noKeepCookies, "no-keep-cookies", false, "do not send cookies set by earlier responses with later requests")
+	rootCmd.Flags().StringVar(&cookieJarFile, "cookie-jar", "", "file that keeps cookies set by responses between runs")
+	rootCmd.Flags().StringVar(&referer, "referer", "", "Referer URL to send, or \"auto\" to present the site's homepage")
+	rootCmd.Flags().StringVar(&timezoneID, "timezone", "", "IANA timezone to emulate in JS mode (e.g. Europe/Berlin)")
 
 	// Pipeline flags
+	rootCmd.Flags().StringVar(&failOn, "fail-on", failOnPartial, "when failed URLs fail the run: partial, any or none")
 	rootCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "continue processing remaining URLs on error")
 	rootCmd.Flags().BoolVar(&noFollowRedirects, "no-follow-redirects", false, "disable following HTTP redirects")
-	rootCmd.Flags().Float64Var(&delay, "delay", 0, "delay in seconds between requests (rate limiting)")
+	rootCmd.Flags().IntVar(&maxRedirects, "max-redirects", fetcher.DefaultMaxRedirects, "fail a fetch after following this many redirects")
+	rootCmd.Flags().Float64Var(&delay, "delay", 0, "delay in seconds between request starts (rate limiting)")
+	rootCmd.Flags().BoolVar(&noAdaptiveDelay, "no-adaptive-delay", false, "do not slow down requests to slow or failing hosts")
+	rootCmd.Flags().IntVar(&maxDownloadMB, "max-download-size", 5, "abort responses larger than this many MB (0 = unlimited)")
+	rootCmd.Flags().IntVar(&maxRequests, "max-requests", 0, "stop taking URLs after N requests and report the rest as skipped (0 = unlimited)")
+	rootCmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "stop taking URLs once the run has lasted this long, e.g. 30m (0 = unlimited)")
+	rootCmd.Flags().BoolVar(&prefetchDNS, "prefetch", false, "resolve hosts and prime TLS sessions before processing a batch")
+	rootCmd.Flags().BoolVarP(&forceIPv4, "ipv4", "4", false, "connect over IPv4 only")
+	rootCmd.Flags().BoolVarP(&forceIPv6, "ipv6", "6", false, "connect over IPv6 only")
 
 	// Extraction backend flags
 	rootCmd.Flags().StringVarP(&extractBackend, "extract-backend", "B", "", "extraction backend (readability, tavily, jina)")
+	rootCmd.Flags().StringVar(&siteConfigDir, "site-config", "", "directory of FiveFilters ftr-site-config files to apply per site")
+	rootCmd.Flags().StringVar(&textLanguage, "language", "", "language whose line-joining and hyphenation rules clean the text, e.g. de (default: detect)")
 
 	// System flags
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose logging")
@@ -127,55 +362,27 @@ func initConfig() {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
-		configHome := os.Getenv("XDG_CONFIG_HOME")
-		if configHome == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				if !quiet {
-					fmt.Fprintf(os.Stderr, "Error finding home directory: %v\n", err)
-				}
-				return
+		configDir, err := paths.Dir(paths.Config)
+		if err != nil {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
-			configHome = filepath.Join(home, ".config")
+			return
 		}
-
-		configDir := filepath.Join(configHome, "scrpr")
 		viper.AddConfigPath(configDir)
 		viper.SetConfigType("toml")
 		viper.SetConfigName("config")
-
-		// Create config directory if it doesn't exist
-		// Handle broken symlinks by removing them first
-		if fi, lstatErr := os.Lstat(configDir); lstatErr == nil {
-			if fi.Mode()&os.ModeSymlink != 0 {
-				if _, statErr := os.Stat(configDir); os.IsNotExist(statErr) {
-					os.Remove(configDir) // broken symlink
-				}
-			}
-		}
-		if mkdirErr := os.MkdirAll(configDir, 0755); mkdirErr != nil && !os.IsExist(mkdirErr) {
-			if !quiet {
-				fmt.Fprintf(os.Stderr, "Error creating config directory: %v\n", mkdirErr)
-			}
-		}
 	}
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("SCRPR")
 
 	if err := viper.ReadInConfig(); err != nil {
+		// A missing config means defaults; nothing is written on a run, so
+		// read-only homes and CI work. `scrpr config init` creates the file.
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Auto-create config on first run
-			configPath := getDefaultConfigPath()
-			if configPath != "" {
-				cfg := config.Default()
-				if createErr := cfg.CreateExampleConfig(configPath); createErr == nil {
-					if !quiet {
-						fmt.Fprintf(os.Stderr, "Created config file: %s\n", configPath)
-					}
-					// Re-read the newly created config
-					viper.ReadInConfig()
-				}
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "No config file, using defaults (scrpr config init creates one)\n")
 			}
 		} else if verbose && !quiet {
 			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
@@ -186,56 +393,523 @@ func initConfig() {
 }
 
 func getDefaultConfigPath() string {
-	configHome := os.Getenv("XDG_CONFIG_HOME")
-	if configHome == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return ""
-		}
-		configHome = filepath.Join(home, ".config")
-	}
-	return filepath.Join(configHome, "scrpr", "config.toml")
+	path, _ := config.DefaultConfigPath()
+	return path
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	if jsonEnvelope {
+		return runEnvelope(cmd, args)
+	}
+	return runURLs(cmd, args)
+}
+
+// resetRunState clears what an earlier run in the process resolved, so
+// settings a run leaves off do not carry over from the one before
+func resetRunState() {
+	junkFilter, siteConfigs, bannerRules, expandRules = nil, nil, nil, nil
+	rawStore, rawSource, responseCache, apiCache = nil, nil, nil, nil
+	stdinHTML, mobileDevice, hostHealth, cookieJar, sealer = nil, nil, nil, nil, nil
+	backendRoutes, ocrEngine, outputTemplate, outputFields = nil, nil, nil, nil
+	browserCookies = nil
+	whereFilter, labelRules = nil, nil
+	inputLabels = make(map[string][]string)
+	inputPriorities = make(map[string]int)
+	sitemapDeltas, sitemapOutcomes = nil, make(map[string]bool)
+}
+
+func runURLs(cmd *cobra.Command, args []string) error {
+	resetRunState()
+
 	// Load configuration
 	cfg, err := loadConfig()
 	if err != nil {
 		return exitError(ExitConfigError, "failed to load config: %v", err)
 	}
 
+	if !cmd.Flags().Changed("fail-on") && cfg.ExitCodes.FailOn != "" {
+		failOn = cfg.ExitCodes.FailOn
+	}
+	if failOn, err = parseFailOn(failOn); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+
 	// Apply config defaults if CLI flags not explicitly set
 	if !cmd.Flags().Changed("delay") && cfg.Network.Delay > 0 {
 		delay = float64(cfg.Network.Delay)
 	}
+	if !cmd.Flags().Changed("max-download-size") {
+		maxDownloadMB = cfg.Network.MaxDownloadSizeMB
+	}
+	if maxDownloadMB < 0 {
+		return exitError(ExitInvalidInput, "--max-download-size must not be negative")
+	}
+	if !cmd.Flags().Changed("max-requests") && cfg.Network.MaxRequests > 0 {
+		maxRequests = cfg.Network.MaxRequests
+	}
+	if !cmd.Flags().Changed("max-duration") {
+		d, err := parseMaxDuration(cfg.Network.MaxDuration)
+		if err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+		maxDuration = d
+	}
+	if maxRequests < 0 || maxDuration < 0 {
+		return exitError(ExitInvalidInput, "--max-requests and --max-duration must not be negative")
+	}
+	if !cmd.Flags().Changed("no-adaptive-delay") && !cfg.Network.AdaptiveDelay {
+		noAdaptiveDelay = true
+	}
 	if !cmd.Flags().Changed("concurrency") {
 		concurrency = cfg.Parallel.MaxConcurrency
 	}
+	if !noAdaptiveDelay {
+		maxHostDelay := cfg.Network.MaxHostDelay
+		if maxHostDelay <= 0 {
+			maxHostDelay = 30
+		}
+		hostHealth = fetcher.NewHostHealth(time.Duration(maxHostDelay) * time.Second)
+		hostHealth.SetHostLimit(concurrency)
+		if cfg.Network.PersistHostState {
+			if path, err := hostStatePath(); err == nil {
+				loadHostState(path)
+				defer saveHostState(path)
+			}
+		}
+	}
+	if !cmd.Flags().Changed("unordered") {
+		unordered = cfg.Parallel.Unordered
+	}
 	if !cmd.Flags().Changed("continue-on-error") {
 		continueOnError = !cfg.Parallel.FailFast
 	}
+	if deterministic {
+		if cmd.Flags().Changed("unordered") && unordered {
+			return exitError(ExitInvalidInput, "--unordered cannot be combined with --deterministic")
+		}
+		unordered = false
+		concurrency = 1 // seeded choices follow the input order
+		if err := setupDeterministic(); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+		if seed == 0 {
+			seed = deterministicSeed
+		}
+	}
 	if !cmd.Flags().Changed("progress") {
 		progress = cfg.Parallel.ShowProgress
 	}
 	if !cmd.Flags().Changed("no-follow-redirects") && !cfg.Network.FollowRedirects {
 		noFollowRedirects = true
 	}
+	if !cmd.Flags().Changed("max-redirects") && cfg.Network.MaxRedirects > 0 {
+		maxRedirects = cfg.Network.MaxRedirects
+	}
+	if maxRedirects < 1 {
+		return exitError(ExitInvalidInput, "--max-redirects must be at least 1 (use --no-follow-redirects to follow none)")
+	}
 	if !cmd.Flags().Changed("format") && cfg.Output.DefaultFormat != "" {
 		outputFormat = cfg.Output.DefaultFormat
 	}
 	if !cmd.Flags().Changed("extract-backend") && cfg.Extraction.Backend != "" {
 		extractBackend = cfg.Extraction.Backend
 	}
+	// Per-site routes apply only when no backend was given on the command line
+	if !cmd.Flags().Changed("extract-backend") {
+		if backendRoutes, err = newBackendRoutes(cfg.Extraction.Sites); err != nil {
+			return exitError(ExitConfigError, "extraction: %v", err)
+		}
+	}
+	if !cmd.Flags().Changed("lang-header") {
+		langHeader = cfg.Network.AcceptLanguage
+	}
+	if !cmd.Flags().Changed("referer") {
+		referer = cfg.Network.Referer
+	}
+	if !cmd.Flags().Changed("timezone") {
+		timezoneID = cfg.Network.Timezone
+	}
+	if !cmd.Flags().Changed("print-media") && cfg.Extraction.PrintMedia {
+		printMedia = true
+	}
+	if !cmd.Flags().Changed("prefetch") && cfg.Network.PrefetchDNS {
+		prefetchDNS = true
+	}
+	if err := setupRequest(); err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	family := cfg.Network.AddressFamily
+	switch {
+	case forceIPv4 && forceIPv6:
+		return exitError(ExitInvalidInput, "--ipv4 and --ipv6 cannot be combined")
+	case forceIPv4:
+		family = fetcher.FamilyIPv4
+	case forceIPv6:
+		family = fetcher.FamilyIPv6
+	}
+	if err := fetcher.SetAddressFamily(family); err != nil {
+		return exitError(ExitConfigError, "invalid network.address_family: %v", err)
+	}
+	if !cmd.Flags().Changed("timeout") && cfg.Network.Timeout > 0 {
+		timeout = cfg.Network.Timeout
+	}
+	if !cmd.Flags().Changed("connect-timeout") && cfg.Network.ConnectTimeout > 0 {
+		connectTimeout = cfg.Network.ConnectTimeout
+	}
+	if !cmd.Flags().Changed("tls-timeout") && cfg.Network.TLSHandshakeTimeout > 0 {
+		tlsTimeout = cfg.Network.TLSHandshakeTimeout
+	}
+	if !cmd.Flags().Changed("header-timeout") && cfg.Network.ResponseHeaderTimeout > 0 {
+		headerTimeout = cfg.Network.ResponseHeaderTimeout
+	}
+	if !cmd.Flags().Changed("js-timeout") && cfg.Extraction.JSTimeout > 0 {
+		jsTimeout = cfg.Extraction.JSTimeout
+	}
+	if err := setRenderMode(cmd, cfg); err != nil {
+		return err
+	}
+	defer stopRenderer()
+	if !cmd.Flags().Changed("process-timeout") && cfg.Extraction.ProcessTimeout > 0 {
+		processTimeout = cfg.Extraction.ProcessTimeout
+	}
+
+	if !cmd.Flags().Changed("front-matter") && cfg.Output.FrontMatter {
+		frontMatter = true
+	}
+	if !cmd.Flags().Changed("toc") && cfg.Output.TOC {
+		tableOfContents = true
+	}
+
+	if !cmd.Flags().Changed("no-sticky-ua") && !cfg.Network.StickyUserAgent {
+		noStickyUA = true
+	}
+
+	if !cmd.Flags().Changed("mobile") && cfg.Network.MobileDevice != "" {
+		mobileName = cfg.Network.MobileDevice
+	}
+	if mobileName != "" {
+		if mobileDevice, err = fetcher.LookupDevice(mobileName); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("viewport") {
+		viewportSize = cfg.Network.Viewport
+	}
+	viewport = nil
+	if viewportSize != "" {
+		if viewport, err = fetcher.ParseViewport(viewportSize); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+
+	if seed != 0 {
+		uaSelector = fetcher.NewSeededUserAgentSelector(seed)
+	} else {
+		uaSelector = fetcher.NewUserAgentSelector()
+	}
+	uaSelector.SetPools(loadUserAgentPools(cfg))
+	uaSelector.SetSticky(!noStickyUA)
+
+	if !cmd.Flags().Changed("impersonate") && cfg.Network.ImpersonateTLS {
+		impersonateTLS = true
+	}
+	if !cmd.Flags().Changed("stealth") && cfg.Network.Stealth {
+		stealth = true
+	}
+	if !cmd.Flags().Changed("no-keep-cookies") && !cfg.Network.KeepCookies {
+		noKeepCookies = true
+	}
+	if !cmd.Flags().Changed("cookie-jar") && !cmd.Flags().Changed("no-keep-cookies") {
+		cookieJarFile = cfg.Network.CookieJar
+	}
+	if cookieJarFile != "" {
+		if cmd.Flags().Changed("no-keep-cookies") && noKeepCookies {
+			return exitError(ExitInvalidInput, "--cookie-jar cannot be combined with --no-keep-cookies")
+		}
+		noKeepCookies = false // a jar file keeps cookies
+	}
+	if err := setBrowserCookies(cmd, cfg); err != nil {
+		return exitError(ExitConfigError, "%v", err)
+	}
+	if !noKeepCookies {
+		if err := openCookieJar(cookieJarFile); err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		if cookieJarFile != "" {
+			defer saveCookieJar(cookieJarFile)
+		}
+	}
+	httpFetcher = newHTTPFetcher()
+
+	// --render reads articles as markdown unless a format is asked for
+	if !cmd.Flags().Changed("render") && cfg.Output.Render {
+		renderOutput = true
+	}
+	if renderOutput && !cmd.Flags().Changed("format") {
+		outputFormat = "markdown"
+	}
+
+	switch outputFormat {
+	case "text", "markdown", "json", "html", "epub":
+	default:
+		return exitError(ExitInvalidInput, "unknown output format: %s (available: text, markdown, json, html, epub)", outputFormat)
+	}
+	if renderOutput {
+		switch outputFormat {
+		case "json", "html", "epub":
+			if cmd.Flags().Changed("render") {
+				return exitError(ExitInvalidInput, "--render needs text or markdown output, not %s", outputFormat)
+			}
+			renderOutput = false
+		}
+		// Styling is for reading in a terminal; pipes and files get plain output
+		if outputFile != "" || os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout) {
+			renderOutput = false
+		}
+	}
+	if !cmd.Flags().Changed("fields") && len(cfg.Output.Fields) > 0 {
+		fieldsSpec = strings.Join(cfg.Output.Fields, ",")
+	}
+	if !cmd.Flags().Changed("where") {
+		whereSpec = cfg.Output.Where
+	}
+	if whereSpec != "" {
+		if whereFilter, err = compileWhere(whereSpec); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("ocr") && cfg.Extraction.OCR {
+		useOCR = true
+	}
+	if !cmd.Flags().Changed("no-transcripts") && !cfg.Extraction.PodcastTranscripts {
+		noTranscripts = true
+	}
+	if !cmd.Flags().Changed("robots-meta") && cfg.Extraction.RobotsMeta != "" {
+		robotsPolicy = cfg.Extraction.RobotsMeta
+	}
+	if robotsPolicy, err = parseRobotsPolicy(robotsPolicy); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	if useOCR {
+		if ocrEngine, err = newOCREngine(cfg); err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+	}
+	if fieldsSpec != "" {
+		// HTML and EPUB keep their own layout
+		if outputFormat == "html" || outputFormat == "epub" {
+			return exitError(ExitInvalidInput, "--fields cannot be combined with --format %s (use text, markdown or json)", outputFormat)
+		}
+		if outputFields, err = parseFields(fieldsSpec); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("template") && cfg.Output.Template != "" {
+		templateSpec = cfg.Output.Template
+	}
+	if templateSpec != "" {
+		if outputFormat == "epub" {
+			return exitError(ExitInvalidInput, "--template cannot be combined with --format epub")
+		}
+		if outputTemplate, err = loadTemplate(templateSpec); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("index-md") && cfg.Output.IndexMarkdown {
+		writeIndex = true
+	}
+	if !cmd.Flags().Changed("extension") && cfg.Output.Extension != "" {
+		fileExt = cfg.Output.Extension
+	}
+	if fileExt, err = parseExtension(fileExt); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	if !cmd.Flags().Changed("markdown-flavor") && cfg.Output.MarkdownFlavor != "" {
+		flavorName = cfg.Output.MarkdownFlavor
+	}
+	if markdownFlavor, err = processor.ParseMarkdownFlavor(flavorName); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	if cfg.Junk.Enabled && !keepJunk {
+		if junkFilter, err = newJunkFilter(cfg.Junk); err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("site-config") {
+		siteConfigDir = cfg.Extraction.SiteConfigDir
+	}
+	if siteConfigDir != "" {
+		dir := expandHome(siteConfigDir)
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return exitError(ExitConfigError, "site config directory not found: %s", siteConfigDir)
+		}
+		siteConfigs = processor.NewSiteConfigs(dir)
+	}
+	if !cmd.Flags().Changed("language") {
+		textLanguage = cfg.Extraction.Language
+	}
+	if !cmd.Flags().Changed("skip-banners") {
+		skipBanners = cfg.Extraction.SkipCookieBanners
+	}
+	if bannerRules, err = newBannerRules(cfg.Extraction); err != nil {
+		return exitError(ExitConfigError, "%v", err)
+	}
+	if cfg.Expand.Enabled && !noExpand {
+		if expandRules, err = newExpandRules(cfg.Expand); err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+	}
+	if !cmd.Flags().Changed("name-by") && cfg.Output.NameBy != "" {
+		nameBy = cfg.Output.NameBy
+	}
+	switch nameBy {
+	case nameByURL, nameByTitle:
+	default:
+		return exitError(ExitInvalidInput, "invalid --name-by %q (available: url, title)", nameBy)
+	}
+	if !cmd.Flags().Changed("filename-template") && cfg.Output.FilenameTemplate != "" {
+		filenameSpec = cfg.Output.FilenameTemplate
+	}
+	names := newFileNamer(nameBy, outputFormat, nil)
+	if filenameSpec != "" {
+		if names.template, err = parseFilenameTemplate(filenameSpec); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	}
+	if frontMatter && outputFormat != "markdown" {
+		if cmd.Flags().Changed("front-matter") {
+			return exitError(ExitInvalidInput, "--front-matter requires --format markdown")
+		}
+		frontMatter = false // config default only applies to markdown
+	}
+	if !cmd.Flags().Changed("embed-images") && cfg.Output.EmbedImages {
+		embedImages = true
+	}
+	if !cmd.Flags().Changed("embed-max-image") && cfg.Output.EmbedMaxImageKB > 0 {
+		embedMaxImageKB = cfg.Output.EmbedMaxImageKB
+	}
+	if !cmd.Flags().Changed("embed-max-total") && cfg.Output.EmbedMaxTotalKB > 0 {
+		embedMaxTotalKB = cfg.Output.EmbedMaxTotalKB
+	}
+	if embedImages && outputFormat != "markdown" && outputFormat != "html" {
+		if cmd.Flags().Changed("embed-images") {
+			return exitError(ExitInvalidInput, "--embed-images requires --format markdown or html (use --epub-images for epub)")
+		}
+		embedImages = false
+	}
+	if !cmd.Flags().Changed("save-images") && cfg.Output.SaveImages {
+		saveImages = true
+	}
+	if saveImages && outputFormat != "markdown" && outputFormat != "html" {
+		if cmd.Flags().Changed("save-images") {
+			return exitError(ExitInvalidInput, "--save-images requires --format markdown or html (use --epub-images for epub)")
+		}
+		saveImages = false
+	}
+	if saveImages && embedImages {
+		switch {
+		case cmd.Flags().Changed("save-images") && cmd.Flags().Changed("embed-images"):
+			return exitError(ExitInvalidInput, "--save-images and --embed-images cannot be combined")
+		case cmd.Flags().Changed("embed-images"):
+			saveImages = false
+		default:
+			embedImages = false
+		}
+	}
+	if embedMaxImageKB <= 0 || embedMaxTotalKB <= 0 {
+		return exitError(ExitInvalidInput, "--embed-max-image and --embed-max-total must be positive")
+	}
+	if !cmd.Flags().Changed("max-content-chars") && cfg.Output.MaxContentChars > 0 {
+		maxContentChars = cfg.Output.MaxContentChars
+	}
+	if !cmd.Flags().Changed("max-content-tokens") && cfg.Output.MaxContentTokens > 0 {
+		maxContentTokens = cfg.Output.MaxContentTokens
+	}
+	if maxContentChars < 0 || maxContentTokens < 0 {
+		return exitError(ExitInvalidInput, "--max-content-chars and --max-content-tokens must not be negative")
+	}
+	contentLimit = contentCharLimit(maxContentChars, maxContentTokens)
+	if contentLimit > 0 && outputFormat == "epub" {
+		if cmd.Flags().Changed("max-content-chars") || cmd.Flags().Changed("max-content-tokens") {
+			return exitError(ExitInvalidInput, "--max-content-chars and --max-content-tokens do not apply to --format epub")
+		}
+		contentLimit = 0
+	}
+	if tableOfContents && outputFormat != "markdown" {
+		if cmd.Flags().Changed("toc") {
+			return exitError(ExitInvalidInput, "--toc requires --format markdown")
+		}
+		tableOfContents = false
+	}
+
+	if !cmd.Flags().Changed("audit-log") && cfg.Logging.AuditLog != "" {
+		auditLogPath = cfg.Logging.AuditLog
+	}
+	if auditLogPath != "" {
+		if auditLog, err = audit.Open(auditLogPath); err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		defer func() {
+			if err := auditLog.Close(); err != nil && !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close audit log: %v\n", err)
+			}
+		}()
+	}
+	if err := setEncryption(cmd, cfg); err != nil {
+		return err
+	}
 
 	// Collect URLs from various sources
-	urls, err := collectURLs(args)
-	if err != nil {
+	var urls []string
+	if readStdinHTML {
+		if urls, err = readStdinPage(args); err != nil {
+			return exitError(ExitInvalidInput, "%v", err)
+		}
+	} else if urls, err = collectURLs(args); err != nil {
 		return exitError(ExitInvalidInput, "failed to collect URLs: %v", err)
 	}
+	if len(sitemapSources) > 0 {
+		if readStdinHTML {
+			return exitError(ExitInvalidInput, "--sitemap-delta cannot be combined with --stdin-html")
+		}
+		if _, err := crawl.NewScope(nil, crawl.ScopeRules(cfg.Crawl)); err != nil {
+			return exitError(ExitConfigError, "invalid crawl config: %v", err)
+		}
+		added, err := collectSitemapURLs(context.Background(), sitemapSources, crawl.ScopeRules(cfg.Crawl))
+		if err != nil {
+			return exitError(ExitNetworkError, "%v", err)
+		}
+		defer saveSitemapDeltas()
+		if len(urls) == 0 && len(added) == 0 {
+			if verbose && !quiet {
+				fmt.Fprintln(os.Stderr, "No new URLs in the sitemaps")
+			}
+			return nil
+		}
+		urls = append(urls, added...)
+	}
 
 	if len(urls) == 0 {
 		return exitError(ExitInvalidInput, "no URLs provided")
 	}
+	// High priority URLs are fetched first, so a budget cuts the least
+	// important; outputs still go out in input order
+	positions := prioritize(urls)
+	if envelope != nil {
+		envelope.URL = urls[0]
+		switch {
+		case len(urls) > 1:
+			return exitError(ExitInvalidInput, "--json-envelope takes a single URL, got %d", len(urls))
+		case outputFile != "":
+			return exitError(ExitInvalidInput, "--json-envelope writes to stdout and cannot be combined with --output")
+		case outputFormat == "epub":
+			return exitError(ExitInvalidInput, "--json-envelope cannot be combined with --format epub")
+		}
+	}
 
 	if verbose && !quiet {
 		fmt.Fprintf(os.Stderr, "Processing %d URLs\n", len(urls))
@@ -244,145 +918,617 @@ func run(cmd *cobra.Command, args []string) error {
 	// Set up output writer
 	var output io.Writer = os.Stdout
 	var outputDir string
-	var singleFileOutput *os.File
+	var singleFileOutput *syncedFile
+	// sealedOutput encrypts output, ahead of the file or stdout
+	var sealedOutput io.WriteCloser
+	firstSeparator := "" // goes before the first result appended to an existing file
+	var archive archiveWriter
+	var index *manifest
+	var assets *assetStore
 
 	if outputFile != "" {
 		// Check if output is a directory (ends with / or already exists as dir)
 		info, statErr := os.Stat(outputFile)
-		if (statErr == nil && info.IsDir()) || strings.HasSuffix(outputFile, "/") {
-			// Directory mode: each URL gets its own file
+		if snapshot || (statErr == nil && info.IsDir()) || strings.HasSuffix(outputFile, "/") {
+			if outputFormat == "epub" {
+				return exitError(ExitInvalidInput, "epub output bundles all URLs into one file; use -o book.epub")
+			}
+			// Directory mode: each URL gets its own file, indexed by a manifest
 			outputDir = outputFile
+			if snapshot {
+				// Each day's run gets its own directory and manifest
+				outputDir = filepath.Join(outputFile, stampTime().Format("2006-01-02"))
+				if names.template == nil {
+					names.template, _ = parseFilenameTemplate(snapshotFilenames)
+				}
+			}
 			if err := os.MkdirAll(outputDir, 0755); err != nil {
 				return exitError(ExitFileIOError, "failed to create output directory: %v", err)
 			}
+			index = newManifest()
+			names.reserve(manifestFile)
+			if writeIndex {
+				names.reserve(indexFile)
+			}
+			if saveImages {
+				assets = newAssetStore(dirAssetWriter(outputDir))
+			}
+		} else if kind := archiveKind(outputFile); kind != "" && outputFormat != "epub" {
+			// Archive mode: each URL gets its own entry plus a manifest
+			archive, err = newArchiveWriter(outputFile, kind)
+			if err != nil {
+				return exitError(ExitFileIOError, "failed to create archive %s: %v", outputFile, err)
+			}
+			defer archive.Close()
+			index = newManifest()
+			names.reserve(manifestFile)
+			if writeIndex {
+				names.reserve(indexFile)
+			}
+			if saveImages {
+				assets = newAssetStore(archive.Add)
+			}
 		} else {
 			// Single file mode
-			singleFileOutput, err = os.Create(outputFile)
+			if appendOutput && outputFormat == "epub" {
+				return exitError(ExitInvalidInput, "--append cannot add to an epub")
+			}
+			var existing bool
+			singleFileOutput, existing, err = openOutputFile(outputFile, appendOutput)
 			if err != nil {
 				return exitError(ExitFileIOError, "failed to create output file %s: %v", outputFile, err)
 			}
 			defer singleFileOutput.Close()
 			output = singleFileOutput
+			if encrypting() {
+				if sealedOutput, err = sealer.Writer(singleFileOutput); err != nil {
+					return exitError(ExitFileIOError, "%v", err)
+				}
+				output = sealedOutput
+			}
+			if existing && outputFormat != "json" {
+				if nullSeparator {
+					firstSeparator = "\x00"
+				} else {
+					firstSeparator = "\n" + separator + "\n"
+				}
+			}
+		}
+	}
+	if snapshot && outputDir == "" {
+		return exitError(ExitInvalidInput, "--snapshot requires -o with an output directory")
+	}
+	// Redirected stdout lands in a file; a terminal keeps nothing
+	if outputFile == "" && encrypting() && !isTerminal(os.Stdout) {
+		if sealedOutput, err = sealer.Writer(os.Stdout); err != nil {
+			return exitError(ExitFileIOError, "%v", err)
 		}
+		output = sealedOutput
+	}
+	if appendOutput && singleFileOutput == nil {
+		return exitError(ExitInvalidInput, "--append requires -o with a single output file")
+	}
+
+	if saveImages && assets == nil {
+		if cmd.Flags().Changed("save-images") {
+			return exitError(ExitInvalidInput, "--save-images requires directory or archive output")
+		}
+		saveImages = false
+	}
+
+	// Routes fan results out of stdout, a file or a directory; archives,
+	// EPUBs and envelopes keep everything together
+	var routes outputRoutes
+	if archive == nil && envelope == nil && outputFormat != "epub" {
+		if routes, err = newOutputRoutes(cfg.Output.Routes, outputDir); err != nil {
+			return exitError(ExitConfigError, "invalid output.routes: %v", err)
+		}
+		defer routes.close()
+	}
+	if labelRules, err = newLabelRules(cfg.Output.Labels); err != nil {
+		return exitError(ExitConfigError, "invalid output.labels: %v", err)
+	}
+
+	if !cmd.Flags().Changed("save-raw") && cfg.Output.SaveRaw != "" {
+		saveRawDir = cfg.Output.SaveRaw
+	}
+	if saveRawDir != "" {
+		rawStore, err = store.NewRawStore(saveRawDir)
+		if err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		defer rawStore.Close()
+		if encrypting() {
+			rawStore.SetSealer(sealer)
+		}
+	}
+	if fromRawDir != "" {
+		if _, statErr := os.Stat(fromRawDir); statErr != nil {
+			return exitError(ExitFileIOError, "raw store not found: %v", statErr)
+		}
+		rawSource, err = store.NewRawStore(fromRawDir)
+		if err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		defer rawSource.Close()
+		if identityFile != "" {
+			rawSource.SetSealer(sealer)
+		}
+	} else if identityFile != "" {
+		return exitError(ExitInvalidInput, "--identity requires --from-raw")
+	}
+
+	if !cmd.Flags().Changed("cache") && cfg.Cache.Enabled {
+		useCache = true
+	}
+	if cmd.Flags().Changed("cache-ttl") {
+		useCache = true
+	} else if cfg.Cache.TTL > 0 {
+		cacheTTL = time.Duration(cfg.Cache.TTL) * time.Second
+	}
+	if noCache && cmd.Flags().Changed("cache") && useCache {
+		return exitError(ExitInvalidInput, "--cache and --no-cache cannot be combined")
+	}
+	if cacheTTL <= 0 {
+		return exitError(ExitInvalidInput, "--cache-ttl must be positive")
+	}
+	if useCache && !noCache {
+		responseCache, err = openResponseCache(cfg, cacheTTL)
+		if err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		defer responseCache.Close()
+	}
+	if cfg.Cache.API && !noCache {
+		if cfg.Cache.APITTL <= 0 {
+			return exitError(ExitConfigError, "cache.api_ttl must be positive")
+		}
+		// Opened by the first API call; runs without one write nothing
+		apiCache = newLazyCache("API cache", func() (*store.ResponseCache, error) {
+			return openAPICache(cfg)
+		})
+		defer apiCache.Close()
+	}
+
+	if prefetchDNS && len(urls) > 1 {
+		warmupHosts(urls, cfg)
+	}
+
+	var watched *watchRun
+	if watchChanges {
+		if watched, err = openWatchRun(cfg); err != nil {
+			return exitError(ExitConfigError, "%v", err)
+		}
+		defer watched.save()
+	}
+
+	ro := newRunOptions(cfg, names)
+
+	var book *epub.Book
+	var chapters []*ProcessResult // by input position, added once all are in
+	if outputFormat == "epub" {
+		book = newEpubBook()
+		chapters = make([]*ProcessResult, len(urls))
 	}
 
 	hadError := false
+	failCode := ExitNetworkError
 	successCount := 0
-
-	// Process URLs
-	for i, url := range urls {
-		if verbose && !quiet {
-			fmt.Fprintf(os.Stderr, "Processing [%d/%d]: %s\n", i+1, len(urls), url)
+	budget := newRunBudget(maxRequests, maxDuration)
+	stopped := ""
+	skipped := skipTally{}
+
+	// Outputs go out in input order; each URL hands over its output, or nil
+	// when it has none, so the ones after it are not held back
+	out := ordered.NewWriter(&prefixWriter{w: output, prefix: firstSeparator}, int64(cfg.Parallel.MaxMemoryMB)<<20/4, unordered)
+	defer out.Close()
+
+	// Up to --concurrency URLs are processed at once, started in priority
+	// order and --delay apart; each result is handled here as it finishes
+	workers := max(concurrency, 1)
+	finished := make(chan processedURL, len(urls))
+	started, running, handled := 0, 0, 0
+	halted := false // by a failure without --continue-on-error
+	var nextStart time.Time
+	for {
+		for stopped == "" && !halted && started < len(urls) && running < workers && !time.Now().Before(nextStart) {
+			if stopped = budget.take(); stopped != "" {
+				skipRemaining(urls, positions, started, index, skipped, stopped)
+				break
+			}
+			i, url := started, urls[started]
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Processing [%d/%d]: %s\n", i+1, len(urls), url)
+			}
+			go func() {
+				result, err := processURLPaced(url, ro)
+				finished <- processedURL{i: i, result: result, err: err}
+			}()
+			started++
+			running++
+			if delay > 0 {
+				nextStart = time.Now().Add(time.Duration(delay*1000) * time.Millisecond)
+			}
+		}
+		var next <-chan time.Time // when the next URL may start
+		if stopped == "" && !halted && started < len(urls) && running < workers {
+			next = time.After(time.Until(nextStart))
+		}
+		if running == 0 && next == nil {
+			break
+		}
+		var done processedURL
+		select {
+		case done = <-finished:
+		case <-next:
+			continue
 		}
+		running--
+		handled++
 
 		// Show progress
 		if progress && !quiet && len(urls) > 1 {
-			pct := float64(i) / float64(len(urls)) * 100
-			fmt.Fprintf(os.Stderr, "\r[%3.0f%%] %d/%d URLs processed", pct, i, len(urls))
+			pct := float64(handled) / float64(len(urls)) * 100
+			fmt.Fprintf(os.Stderr, "\r[%3.0f%%] %d/%d URLs processed", pct, handled, len(urls))
 		}
 
-		result, err := processURL(url, cfg)
+		url, result, err := urls[done.i], done.result, done.err
+		pos := positions[done.i] // in the input
+		if err == nil {
+			result.Labels = urlLabels(url)
+			err = checkWhere(result)
+		}
+		if reason := skipReason(err); reason != "" {
+			// Left out on purpose, which is not a failure of the run
+			skipped[reason]++
+			markSitemapURL(url, true)
+			out.Put(pos-1, nil)
+			if index != nil {
+				index.skip(pos, url, reason, err.Error())
+			}
+			if envelope != nil {
+				setEnvelopeSkip(url, reason, err)
+			}
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Skipped %s: %v\n", url, err)
+			}
+			continue
+		}
 		if err != nil {
+			markSitemapURL(url, false)
+			out.Put(pos-1, nil)
+			if index != nil {
+				index.add(pos, url, "", nil, err)
+			}
+			if envelope != nil {
+				setEnvelopeResult(url, nil, err)
+			}
 			hadError = true
+			failCode = failureCode(err)
 			if !quiet {
 				fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", url, err)
 			}
 			if !continueOnError {
-				// Determine exit code based on error type
-				errStr := err.Error()
-				if strings.Contains(errStr, "failed to fetch") || strings.Contains(errStr, "HTTP error") || strings.Contains(errStr, "dial") {
-					return exitError(ExitNetworkError, "")
+				if failOn != failOnNone {
+					return exitError(failCode, "")
 				}
-				return exitError(ExitProcessError, "")
+				// The URLs in flight still finish; the outputs of the run
+				// are written as usual
+				halted = true
 			}
 			continue
 		}
 
 		successCount++
+		watched.check(url, result)
 
 		// Write output
-		if outputDir != "" {
+		if route := routes.match(url); route != nil {
+			// Routed: the result goes to the route's directory or file
+			out.Put(pos-1, nil)
+			filePath, err := route.write(pos, url, result, ro)
+			if err != nil {
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "Error writing routed output for %s: %v\n", url, err)
+				}
+				if index != nil {
+					index.add(pos, url, "", result, err)
+				}
+				hadError = true
+				failCode = ExitFileIOError
+				if !continueOnError {
+					return exitError(ExitFileIOError, "")
+				}
+				continue
+			}
+			if index != nil {
+				name, relErr := filepath.Rel(outputDir, filePath)
+				if relErr != nil {
+					name = filePath
+				}
+				index.add(pos, url, filepath.ToSlash(name), result, nil)
+			}
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Routed: %s\n", filePath)
+			}
+		} else if book != nil {
+			// EPUB collects chapters and is written once after the loop
+			chapters[pos-1] = result
+		} else if archive != nil {
+			// Archive mode: add each URL as its own entry
+			name, err := ro.Names.fileName(pos, url, result)
+			if err != nil {
+				return exitError(ExitFileIOError, "%v", err)
+			}
+			if assets != nil {
+				assets.localize(result, name)
+			}
+			rendered, err := ro.renderResult(result, true)
+			if err != nil {
+				return exitError(ExitProcessError, "failed to render %s: %v", url, err)
+			}
+			if err := archive.Add(name, []byte(rendered)); err != nil {
+				return exitError(ExitFileIOError, "failed to write %s to archive: %v", name, err)
+			}
+			index.add(pos, url, name, result, nil)
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Archived: %s\n", name)
+			}
+		} else if outputDir != "" {
 			// Directory mode: write each URL to its own file
-			filename := urlToFilename(url, outputFormat)
-			filePath := filepath.Join(outputDir, filename)
-			if err := os.WriteFile(filePath, []byte(result.Content), 0644); err != nil {
+			filePath, err := ro.Names.filePath(outputDir, pos, url, result)
+			if err != nil {
+				return exitError(ExitFileIOError, "%v", err)
+			}
+			name, _ := filepath.Rel(outputDir, filePath)
+			if assets != nil {
+				assets.localize(result, filepath.ToSlash(name))
+			}
+			rendered, err := ro.renderResult(result, true)
+			if err != nil {
+				return exitError(ExitProcessError, "failed to render %s: %v", url, err)
+			}
+			if err := writeSealed(filePath, []byte(rendered)); err != nil {
 				if !quiet {
 					fmt.Fprintf(os.Stderr, "Error writing file %s: %v\n", filePath, err)
 				}
+				index.add(pos, url, "", result, err)
 				hadError = true
+				failCode = ExitFileIOError
 				if !continueOnError {
 					return exitError(ExitFileIOError, "")
 				}
 				continue
 			}
+			index.add(pos, url, filepath.ToSlash(name), result, nil)
 			if verbose && !quiet {
 				fmt.Fprintf(os.Stderr, "Saved: %s\n", filePath)
 			}
+		} else if envelope != nil {
+			// Envelope mode: written by runEnvelope once the run is over
+			setEnvelopeResult(url, result, nil)
 		} else {
 			// Single output mode
-			fmt.Fprint(output, result.Content)
+			rendered, err := ro.renderResult(result, false)
+			if err != nil {
+				return exitError(ExitProcessError, "failed to render %s: %v", url, err)
+			}
+			if renderOutput {
+				rendered = processor.RenderANSI(rendered)
+			}
 
-			// Add separator for multiple URLs (but not after the last one)
-			if len(urls) > 1 && i < len(urls)-1 {
+			// JSON emits one object per line; other formats use separators
+			// between URLs (but not after the last one)
+			if outputFormat == "json" {
+				rendered += "\n"
+			} else if pos < len(urls) {
 				if nullSeparator {
-					fmt.Fprint(output, "\x00")
+					rendered += "\x00"
 				} else {
-					fmt.Fprintf(output, "\n%s\n", separator)
+					rendered += "\n" + separator + "\n"
 				}
 			}
+			if err := out.Put(pos-1, []byte(rendered)); err != nil {
+				return exitError(ExitFileIOError, "failed to write output: %v", err)
+			}
 		}
 
-		// Rate limiting delay between requests
-		if delay > 0 && i < len(urls)-1 {
-			time.Sleep(time.Duration(delay*1000) * time.Millisecond)
-		}
+		markSitemapURL(url, true)
 	}
 
 	// Final progress line
-	if progress && !quiet && len(urls) > 1 {
+	if progress && !quiet && len(urls) > 1 && stopped == "" {
 		fmt.Fprintf(os.Stderr, "\r[100%%] %d/%d URLs processed\n", len(urls), len(urls))
 	}
+	skipped.report(len(urls))
+
+	if assets != nil && verbose && !quiet {
+		fmt.Fprintf(os.Stderr, "Images: %d saved, %d shared\n", assets.saved, assets.reused)
+	}
+
+	if archive != nil {
+		err := writeManifest(index, archive.Add)
+		if err == nil {
+			err = archive.Close()
+		}
+		if err != nil {
+			return exitError(ExitFileIOError, "failed to write archive: %v", err)
+		}
+	} else if outputDir != "" {
+		err := writeManifest(index, func(name string, data []byte) error {
+			data, err := sealData(data)
+			if err != nil {
+				return err
+			}
+			return writeFileAtomic(filepath.Join(outputDir, sealedName(name)), data)
+		})
+		if err != nil {
+			return exitError(ExitFileIOError, "failed to write manifest: %v", err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return exitError(ExitFileIOError, "failed to write output: %v", err)
+	}
+	if err := routes.close(); err != nil {
+		return exitError(ExitFileIOError, "failed to write routed output: %v", err)
+	}
+
+	for _, result := range chapters {
+		if result != nil {
+			addEpubChapter(book, result)
+		}
+	}
+	if book != nil && len(book.Chapters) > 0 {
+		if err := writeEpub(book, output); err != nil {
+			return exitError(ExitFileIOError, "failed to write epub: %v", err)
+		}
+	}
+	if sealedOutput != nil {
+		if err := sealedOutput.Close(); err != nil {
+			return exitError(ExitFileIOError, "failed to encrypt output: %v", err)
+		}
+	}
+	if singleFileOutput != nil {
+		if err := singleFileOutput.Close(); err != nil {
+			return exitError(ExitFileIOError, "failed to write output file: %v", err)
+		}
+	}
 
-	if hadError && successCount > 0 {
-		return &exitErr{code: ExitPartialError, msg: ""}
-	} else if hadError && successCount == 0 {
-		return &exitErr{code: ExitNetworkError, msg: ""}
+	if hadError {
+		return batchResult(successCount, failCode)
+	}
+	if stopped != "" && failOn != failOnNone {
+		return &exitErr{code: ExitPartialError}
 	}
 
 	return nil
 }
 
+// processedURL is what a worker of runURLs hands back for urls[i]
+type processedURL struct {
+	i      int
+	result *ProcessResult
+	err    error
+}
+
+// warmupHosts resolves all batch hosts up front and reports dead ones before
+// any fetch is attempted
+func warmupHosts(urls []string, cfg *config.Config) {
+	start := time.Now()
+	statuses := fetcher.Warmup(context.Background(), urls, fetcher.WarmupOptions{
+		Concurrency: concurrency,
+		TLSHosts:    cfg.Network.WarmupTLSHosts,
+		Timeout:     time.Duration(connectTimeout) * time.Second,
+	})
+
+	if quiet {
+		return
+	}
+
+	dead := 0
+	for _, st := range statuses {
+		if st.Err != nil {
+			dead++
+			fmt.Fprintf(os.Stderr, "Warmup: %s unreachable (%d URLs): %v\n", st.Host, st.URLs, st.Err)
+		} else if verbose {
+			primed := ""
+			if st.TLSPrimed {
+				primed = ", TLS primed"
+			}
+			fmt.Fprintf(os.Stderr, "Warmup: %s -> %s (%d URLs%s)\n", st.Host, strings.Join(st.Addrs, ", "), st.URLs, primed)
+		}
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Warmup: resolved %d/%d hosts in %v\n", len(statuses)-dead, len(statuses), time.Since(start).Round(time.Millisecond))
+	}
+}
+
 func loadConfig() (*config.Config, error) {
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
 		return nil, err
 	}
+	if err := setExitCodes(cfg.ExitCodes); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
-func processURL(url string, cfg *config.Config) (*ProcessResult, error) {
+// loadUserAgentPools combines the dataset refreshed by `scrpr ua update`
+// with the custom pools from config
+func loadUserAgentPools(cfg *config.Config) map[fetcher.UserAgentType][]string {
+	path := ""
+	if dataDir, err := config.DataDir(); err == nil {
+		path = filepath.Join(dataDir, fetcher.UserAgentDatasetFile)
+	}
+	pools, err := fetcher.UserAgentPools(path, cfg.Network.UserAgentPools)
+	if err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return pools
+}
+
+func processURL(url string, ro *RunOptions) (*ProcessResult, error) {
+	result, err := extractURL(url, ro)
+	if err != nil {
+		return nil, err
+	}
+	truncateResult(result, ro.ContentLimit, ro.Format)
+	if ro.EmbedImages {
+		embedResultImages(result, ro)
+	}
+	return result, nil
+}
+
+// extractURL runs the selected backend, falling back to Jina when local
+// extraction fails and no backend was chosen
+func extractURL(url string, ro *RunOptions) (*ProcessResult, error) {
 	if verbose && !quiet {
 		fmt.Fprintf(os.Stderr, "Fetching: %s\n", url)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+	// Each stage applies its own deadline; see stageTimeouts
+	ctx := context.Background()
 
 	// Check if we should use an alternative extraction backend
-	backend := extractBackend
+	backend := ro.backendFor(url)
+	if ro.StdinHTML != nil || fetcher.IsFileURL(url) || !fetcher.IsPlainGet(ro.Fetch) {
+		// Remote backends cannot see local HTML or send a request body
+		return processURLLocal(ctx, url, ro)
+	}
 	if backend == "" || backend == "readability" {
-		result, err := processURLLocal(ctx, url, cfg)
+		result, err := processURLLocal(ctx, url, ro)
 		if err == nil {
 			return result, nil
 		}
+		// A video or archive is no better through Jina, and a page asking
+		// not to be kept is not fetched again elsewhere
+		var unsupported *fetcher.UnsupportedContentError
+		var robots *RobotsError
+		if errors.As(err, &unsupported) || errors.As(err, &robots) {
+			return nil, err
+		}
 
-		// Auto-escalate to Jina on local failure if no backend was explicitly chosen
+		// Auto-escalate to Jina on local failure if no backend was explicitly chosen.
+		// Jina fetches from outside the EU, past consent walls.
+		var wall *fetcher.ConsentWallError
+		isWall := errors.As(err, &wall)
 		if backend == "" && !quiet {
-			fmt.Fprintf(os.Stderr, "Local extraction failed for %s, trying Jina fallback...\n", url)
+			if isWall {
+				fmt.Fprintf(os.Stderr, "Consent wall (%s) for %s, trying Jina fallback...\n", wall.Provider, url)
+			} else {
+				fmt.Fprintf(os.Stderr, "Local extraction failed for %s, trying Jina fallback...\n", url)
+			}
 		}
 		if backend == "" {
-			jinaResult, jinaErr := processURLBackend(ctx, url, cfg, "jina")
+			jinaResult, jinaErr := processURLBackend(ctx, url, ro, "jina")
 			if jinaErr == nil {
+				jinaResult.Provenance.Fallback = "local extraction failed: " + err.Error()
+				if isWall {
+					jinaResult.Metadata = map[string]string{"consent_wall": wall.Provider, "consent_action": "fallback:jina"}
+					jinaResult.Provenance.Fallback = "consent wall: " + wall.Provider
+				}
 				return jinaResult, nil
 			}
 			// Return original error if Jina also fails
@@ -391,89 +1537,314 @@ func processURL(url string, cfg *config.Config) (*ProcessResult, error) {
 		return nil, err
 	}
 
-	return processURLBackend(ctx, url, cfg, backend)
+	return processURLBackend(ctx, url, ro, backend)
 }
 
-// processURLLocal uses the built-in readability extraction
-func processURLLocal(ctx context.Context, url string, cfg *config.Config) (*ProcessResult, error) {
-	// Create fetcher and processor
-	simpleFetcher := fetcher.NewSimpleFetcher()
+// newJunkFilter compiles the [junk] config
+func newJunkFilter(junk config.JunkConfig) (*processor.JunkFilter, error) {
+	opts := processor.JunkOptions{Groups: junk.Groups, Selectors: junk.Selectors, Text: junk.Text}
+	for _, site := range junk.Sites {
+		opts.Sites = append(opts.Sites, processor.JunkSite(site))
+	}
+	return processor.NewJunkFilter(opts)
+}
 
-	// Configure redirect policy
-	if noFollowRedirects {
-		simpleFetcher.SetFollowRedirects(false)
+// newExpandRules compiles the [expand] config
+func newExpandRules(expand config.ExpandConfig) (*fetcher.ExpandRules, error) {
+	var sites []fetcher.ExpandSite
+	for _, site := range expand.Sites {
+		sites = append(sites, fetcher.ExpandSite(site))
 	}
+	return fetcher.NewExpandRules(expand.Selectors, sites)
+}
 
-	contentProcessor := processor.NewContentProcessor()
+// newBannerRules compiles the cookie banner settings of [extraction]
+func newBannerRules(extraction config.ExtractionConfig) (*fetcher.BannerRules, error) {
+	return fetcher.NewBannerRules(extraction.BannerSelectors, fetcher.BannerAction(extraction.BannerAction))
+}
 
-	// Determine browser agent - CLI flag takes precedence over config
-	effectiveBrowserAgent := cfg.Network.BrowserAgent
-	if browserAgent != "" {
-		effectiveBrowserAgent = browserAgent
-	}
-	if userAgent != "" {
-		effectiveBrowserAgent = ""
+// processURLLocal uses the built-in readability extraction
+func processURLLocal(ctx context.Context, url string, ro *RunOptions) (_ *ProcessResult, err error) {
+	var dbg *extractionDebug
+	if ro.DebugDir != "" {
+		if dbg, err = newExtractionDebug(ro.DebugDir, url); err != nil {
+			return nil, err
+		}
+		defer func() { dbg.finish(err) }()
 	}
 
+	contentProcessor := processor.NewContentProcessor()
+	contentProcessor.SetTOC(ro.TOC)
+	contentProcessor.SetMarkdownFlavor(ro.Flavor)
+
 	// Fetch content
-	fetchOpts := fetcher.FetchOptions{
-		Mode:         fetcher.FetchModeStatic,
-		Timeout:      time.Duration(timeout) * time.Second,
-		UserAgent:    userAgent,
-		BrowserAgent: effectiveBrowserAgent,
-		Cookies:      nil,
-		Format:       outputFormat,
-	}
+	fetchOpts := ro.fetchOptionsFor(url)
+	fetchCtx, cancelFetch := context.WithTimeout(ctx, ro.Timeout)
+	defer cancelFetch()
 
-	fetchResult, err := simpleFetcher.FetchStatic(ctx, url, fetchOpts)
+	fetchStart := time.Now()
+	fetchResult, source, err := fetchOrLoadRaw(fetchCtx, ro, url, fetchOpts)
 	if err != nil {
+		var tooLarge *fetcher.ResponseTooLargeError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("failed to fetch content: %w (see --max-download-size)", err)
+		}
 		return nil, fmt.Errorf("failed to fetch content: %w", err)
 	}
 
+	// Mobile mode follows the page's own pointer to its mobile variant
+	if fetchOpts.Device != nil && !isImageContent(fetchResult.ContentType) {
+		if alt := fetcher.MobileAlternate(fetchResult.HTML, url); alt != "" {
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Using mobile variant: %s\n", alt)
+			}
+			if altResult, altSource, altErr := fetchOrLoadRaw(fetchCtx, ro, alt, ro.followUpOptions(fetchOpts, alt)); altErr == nil {
+				fetchResult, source = altResult, altSource
+			} else if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Mobile variant failed, keeping desktop page: %v\n", altErr)
+			}
+		}
+	}
+
+	// The site config may point at a single-page version of a paginated article
+	site := ro.SiteConfigs.For(urlHost(url))
+	if single := site.SinglePageURL(fetchResult.HTML, url); single != "" && !isImageContent(fetchResult.ContentType) {
+		if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "Using single-page version: %s\n", single)
+		}
+		if singleResult, singleSource, singleErr := fetchOrLoadRaw(fetchCtx, ro, single, ro.followUpOptions(fetchOpts, single)); singleErr == nil {
+			fetchResult, source = singleResult, singleSource
+		} else if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "Single-page version failed, keeping the first page: %v\n", singleErr)
+		}
+	}
+	fetchDuration := time.Since(fetchStart)
+	if dbg != nil {
+		dbg.add("raw", ".html", fetchResult.HTML, fetchDuration)
+	}
+	if fetchResult.ConsentWall != "" {
+		return nil, &fetcher.ConsentWallError{Provider: fetchResult.ConsentWall}
+	}
+
 	// Short-circuit image responses
 	if isImageContent(fetchResult.ContentType) {
 		return &ProcessResult{
-			URL:     url,
-			Title:   fetchResult.Title,
-			Content: fmt.Sprintf("Image content detected (%s). scrpr extracts text content only.", fetchResult.ContentType),
+			URL:        url,
+			Title:      fetchResult.Title,
+			Content:    fmt.Sprintf("Image content detected (%s). scrpr extracts text content only.", fetchResult.ContentType),
+			Backend:    "readability",
+			FetchTime:  fetchDuration,
+			Provenance: fetchProvenance(fetchResult, source),
+			FinalURL:   fetchResult.FinalURL,
+			Redirects:  fetchResult.Redirects,
 		}, nil
 	}
 
-	// Process content
+	// Process content. JSON and templated output always carry metadata.
 	processOpts := processor.ProcessOptions{
 		RemoveAds:        true,
 		CleanHTML:        true,
 		MinContentLength: 100,
-		IncludeMetadata:  includeMetadata,
+		IncludeMetadata:  ro.CollectMetadata,
 		MetadataFields:   []string{"title", "author", "description", "date"},
 	}
+	switch {
+	case ro.AllMetadata:
+		processOpts.MetadataFields = []string{"title", "author", "description", "date", "url", "image", "hero_image", "keywords", "site_name", "favicon"}
+	case ro.FrontMatter:
+		processOpts.IncludeMetadata = true
+		processOpts.MetadataFields = []string{"title", "author", "date", "keywords"}
+	}
+
+	processOpts.Junk = ro.Junk
+	processOpts.Site = site
+	processOpts.Language = ro.Language
+	if dbg != nil {
+		processOpts.Trace = &processor.Trace{}
+	}
+
+	processCtx, cancelProcess := context.WithTimeout(ctx, ro.ProcessTimeout)
+	defer cancelProcess()
 
-	processed, err := contentProcessor.Process(fetchResult.HTML, url, processOpts)
+	processStart := time.Now()
+	processed, err := contentProcessor.ProcessContext(processCtx, strings.NewReader(fetchResult.HTML), url, processOpts)
+	if dbg != nil && processCtx.Err() == nil {
+		// On timeout the processor may still be writing the trace
+		dbg.addTrace(processOpts.Trace)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to process content: %w", err)
 	}
+	if ro.OCR != nil {
+		applyOCR(ctx, ro, processed, cmp.Or(fetchResult.FinalURL, url))
+	}
+	if ro.Transcripts {
+		applyTranscript(ctx, processed, fetchResult.HTML, cmp.Or(fetchResult.FinalURL, url))
+	}
+	if robots := fetcher.ParseRobots(fetchResult.HTML, fetchResult.RobotsTag).String(); robots != "" {
+		processed.Metadata["robots"] = robots
+	}
+	formatStart := time.Now()
 
 	// Format output
 	var content string
-	switch outputFormat {
+	switch ro.Format {
 	case "markdown":
-		content = contentProcessor.ToMarkdown(processed, includeMetadata, true)
-	case "text":
+		if ro.FrontMatter {
+			content = contentProcessor.ToMarkdownWithFrontMatter(processed, url, true)
+		} else if ro.MarkdownBody {
+			// --fields lays out title and metadata itself
+			content = contentProcessor.ToMarkdownBody(processed, true)
+		} else {
+			content = contentProcessor.ToMarkdown(processed, ro.IncludeMetadata, true)
+		}
+	case "text", "json":
 		content = contentProcessor.ToText(processed, 0)
+	case "html", "epub":
+		content = contentProcessor.ToHTML(processed)
 	default:
 		content = processed.TextContent
 	}
+	if dbg != nil {
+		dbg.add("output", fileExtension(ro.Format), content, time.Since(formatStart))
+	}
+
+	if fetchResult.FinalURL != "" && fetchResult.FinalURL != url && processOpts.IncludeMetadata {
+		processed.Metadata["final_url"] = fetchResult.FinalURL
+	}
 
 	return &ProcessResult{
-		URL:     url,
-		Title:   processed.Title,
-		Content: content,
+		URL:         url,
+		FinalURL:    fetchResult.FinalURL,
+		Redirects:   fetchResult.Redirects,
+		Title:       processed.Title,
+		Author:      processed.Author,
+		Excerpt:     processed.Excerpt,
+		Content:     content,
+		Text:        contentProcessor.ToText(processed, 0),
+		HTML:        contentProcessor.ToHTML(processed),
+		Metadata:    processed.Metadata,
+		Language:    processed.Language,
+		Paywalled:   processed.Paywalled,
+		Images:      processed.Images,
+		Media:       processed.Media,
+		Links:       processed.Links,
+		UsedJS:      fetchResult.UsedJS,
+		Backend:     "readability",
+		FetchTime:   fetchDuration,
+		ProcessTime: time.Since(processStart),
+		Provenance:  fetchProvenance(fetchResult, source),
 	}, nil
 }
 
+// maxResponseSize converts --max-download-size to the fetcher's byte limit
+func maxResponseSize() int64 {
+	if maxDownloadMB == 0 {
+		return -1
+	}
+	return int64(maxDownloadMB) << 20
+}
+
+// fetchOrLoadRaw serves HTML from the --from-raw store or the response cache
+// when available and otherwise fetches it, saving the response to the cache
+// and the --save-raw store. The source names where the HTML came from.
+func fetchOrLoadRaw(ctx context.Context, ro *RunOptions, url string, opts fetcher.FetchOptions) (*fetcher.FetchResult, string, error) {
+	if ro.StdinHTML != nil {
+		return &fetcher.FetchResult{
+			HTML:        string(ro.StdinHTML),
+			URL:         url,
+			ContentType: "text/html",
+			FinalURL:    url,
+		}, sourceStdin, nil
+	}
+	if fetcher.IsFileURL(url) {
+		result, err := fetcher.FetchFile(url, opts)
+		return result, sourceFile, err
+	}
+
+	// A response to a request with a body answers only that request
+	plain := fetcher.IsPlainGet(opts)
+	if ro.RawSource != nil && plain {
+		html, err := ro.RawSource.Get(url)
+		if err == nil {
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Loaded raw HTML: %s\n", ro.RawSource.Path(url))
+			}
+			result := &fetcher.FetchResult{
+				HTML:        string(html),
+				URL:         url,
+				ContentType: "text/html",
+			}
+			if err := checkRobots(result, ro.RobotsPolicy); err != nil {
+				return nil, "", err
+			}
+			return result, sourceRawStore, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+
+	variant := cacheVariant(opts)
+	if ro.ResponseCache != nil && plain && !ro.RefreshCache {
+		cached, err := ro.ResponseCache.Get(url, variant)
+		if err == nil {
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Cache hit: %s (fetched %s)\n", url, cached.Fetched.Format(time.RFC3339))
+			}
+			result := &fetcher.FetchResult{
+				HTML:        cached.Body,
+				URL:         url,
+				ContentType: cached.ContentType,
+				FinalURL:    cached.URL,
+				Redirects:   cachedRedirects(cached.Redirects),
+				RobotsTag:   cached.RobotsTag,
+			}
+			if err := checkRobots(result, ro.RobotsPolicy); err != nil {
+				return nil, "", err
+			}
+			return result, sourceCache, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	start := time.Now()
+	result, err := fetchPage(ctx, ro.HTTP, url, opts)
+	if err != nil {
+		auditPage(url, opts.Method, start, nil, "", err)
+		return nil, "", err
+	}
+	robots, err := robotsDecision(result, ro.RobotsPolicy)
+	auditPage(url, opts.Method, start, result, robots, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Consent interstitials are not the page; fetch again next time
+	if ro.ResponseCache != nil && plain && result.ConsentWall == "" {
+		entry := &store.CachedResponse{URL: result.FinalURL, ContentType: result.ContentType, Fetched: time.Now(), Body: result.HTML, RobotsTag: result.RobotsTag}
+		for _, r := range result.Redirects {
+			entry.Redirects = append(entry.Redirects, store.CachedRedirect(r))
+		}
+		if err := ro.ResponseCache.Put(url, variant, entry); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if ro.RawStore != nil && plain && !isImageContent(result.ContentType) {
+		if err := ro.RawStore.Put(url, []byte(result.HTML)); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return result, sourceNetwork, nil
+}
+
 // processURLBackend uses an API-based extraction backend (tavily or jina)
-func processURLBackend(ctx context.Context, url string, cfg *config.Config, backendName string) (*ProcessResult, error) {
+func processURLBackend(ctx context.Context, url string, ro *RunOptions, backendName string) (*ProcessResult, error) {
 	var backend extractor.Backend
+	cfg := ro.Config
 
 	switch backendName {
 	case "tavily":
@@ -487,7 +1858,7 @@ func processURLBackend(ctx context.Context, url string, cfg *config.Config, back
 		backend = extractor.NewTavilyBackend(
 			apiKey,
 			cfg.Extraction.Tavily.ExtractDepth,
-			time.Duration(timeout)*time.Second,
+			ro.Timeout,
 		)
 
 	case "jina":
@@ -495,31 +1866,137 @@ func processURLBackend(ctx context.Context, url string, cfg *config.Config, back
 		if envKey := os.Getenv("JINA_API_KEY"); envKey != "" {
 			apiKey = envKey
 		}
-		backend = extractor.NewJinaBackend(
-			apiKey,
-			time.Duration(timeout)*time.Second,
-		)
+		backend = extractor.NewJinaBackend(apiKey, ro.Timeout)
 
 	default:
 		return nil, fmt.Errorf("unknown extraction backend: %s (available: readability, tavily, jina)", backendName)
 	}
 
-	result, err := backend.Extract(ctx, url, outputFormat)
+	backendCtx, cancel := context.WithTimeout(ctx, ro.Timeout)
+	defer cancel()
+
+	// Backends only produce text or markdown; JSON wraps the text form
+	format := ro.Format
+	switch format {
+	case "json":
+		format = "text"
+	case "html", "epub":
+		return nil, fmt.Errorf("%s backend does not support %s output", backendName, format)
+	}
+
+	start := time.Now()
+	result, source, err := extractCached(backendCtx, ro, backend, url, format)
 	if err != nil {
 		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
+	content := result.Content
+	if ro.TOC {
+		if toc := processor.TableOfContents(content, ro.Flavor); toc != "" {
+			content = toc + "\n" + content
+		}
+	}
+	if ro.FrontMatter {
+		content = processor.FrontMatter{Title: result.Title, URL: url}.String() + "\n" + content
+	}
+
 	return &ProcessResult{
-		URL:     result.URL,
-		Title:   result.Title,
-		Content: result.Content,
+		URL:        result.URL,
+		Title:      result.Title,
+		Content:    content,
+		Text:       result.Content,
+		Backend:    backendName,
+		FetchTime:  time.Since(start),
+		Provenance: apiProvenance(backendName, source),
 	}, nil
 }
 
+// extractCached returns the backend's result for url from the API cache, or
+// calls the API and caches what it returns
+func extractCached(ctx context.Context, ro *RunOptions, backend extractor.Backend, url, format string) (*extractor.ExtractResult, string, error) {
+	variant := apiCacheVariant(backend.Name(), format, ro.Config)
+	cache := ro.APICache.get()
+	if cache != nil && !ro.RefreshCache {
+		cached, err := cache.Get(url, variant)
+		if err == nil {
+			if verbose && !quiet {
+				fmt.Fprintf(os.Stderr, "Cache hit: %s from %s (fetched %s)\n", url, backend.Name(), cached.Fetched.Format(time.RFC3339))
+			}
+			return &extractor.ExtractResult{URL: cached.URL, Title: cached.Title, Content: cached.Body}, sourceCache, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	start := time.Now()
+	result, err := backend.Extract(ctx, url, format)
+	if err != nil {
+		auditBackend(url, backend.Name(), start, 0, err)
+		return nil, "", err
+	}
+	auditBackend(url, backend.Name(), start, len(result.Content), nil)
+	if cache != nil {
+		entry := &store.CachedResponse{URL: result.URL, ContentType: "text/" + format, Fetched: time.Now(), Title: result.Title, Body: result.Content}
+		if err := cache.Put(url, variant, entry); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return result, sourceNetwork, nil
+}
+
+// newHTTPFetcher configures the run's static fetcher from flags and config
+func newHTTPFetcher() *fetcher.SimpleFetcher {
+	sf := fetcher.NewSimpleFetcher()
+	sf.SetTimeouts(stageTimeouts())
+	sf.SetImpersonate(impersonateTLS)
+	sf.SetUserAgentSelector(uaSelector)
+	sf.SetMaxRedirects(maxRedirects)
+	sf.SetJitter(!deterministic)
+	if cookieJar != nil {
+		sf.SetCookieJar(cookieJar)
+	}
+	if noFollowRedirects {
+		sf.SetFollowRedirects(false)
+	}
+	return sf
+}
+
+// stageTimeouts builds the per-stage fetch timeouts from flags and config
+func stageTimeouts() fetcher.Timeouts {
+	return fetcher.Timeouts{
+		Connect:        time.Duration(connectTimeout) * time.Second,
+		TLSHandshake:   time.Duration(tlsTimeout) * time.Second,
+		ResponseHeader: time.Duration(headerTimeout) * time.Second,
+		Total:          time.Duration(timeout) * time.Second,
+		Render:         time.Duration(jsTimeout) * time.Second,
+	}
+}
+
+// ProcessResult is what a URL produces; --template renders against it
 type ProcessResult struct {
-	URL     string
-	Title   string
-	Content string
+	URL         string
+	FinalURL    string             // after redirects; "" when not known (API backends)
+	Redirects   []fetcher.Redirect // followed redirects, first hop first
+	Title       string
+	Author      string
+	Excerpt     string
+	Content     string // formatted per --format
+	Text        string // plain text, regardless of --format
+	HTML        string // cleaned article HTML (readability backend only)
+	Metadata    map[string]string
+	Images      []string
+	Links       []processor.Link
+	Media       []processor.Media
+	UsedJS      bool
+	Backend     string
+	FetchTime   time.Duration
+	ProcessTime time.Duration
+	Provenance  provenance
+	Language    string   // primary language of the text ("" = unknown)
+	Paywalled   bool     // the page marks its content as not free to read
+	Labels      []string // from the input file and [[output.labels]]
+	Watch       string   // verdict of --watch against earlier runs ("" = not watched)
 }
 
 // isImageContent checks if a Content-Type header indicates an image
@@ -547,7 +2024,7 @@ func collectURLs(args []string) ([]string, error) {
 	}
 
 	// Read URLs from stdin if no args and no file specified, or if stdin has data
-	if len(args) == 0 && file == "" {
+	if len(args) == 0 && file == "" && len(sitemapSources) == 0 {
 		stdinURLs, err := readURLsFromStdin()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read URLs from stdin: %w", err)
@@ -567,6 +2044,32 @@ func collectURLs(args []string) ([]string, error) {
 	return cleanURLs, nil
 }
 
+// readStdinPage reads the page of --stdin-html and returns the URL it is
+// processed as: the single argument, or stdinPageURL
+func readStdinPage(args []string) ([]string, error) {
+	if len(args) > 1 || file != "" {
+		return nil, fmt.Errorf("--stdin-html takes at most one URL, naming the page")
+	}
+	if isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("--stdin-html reads HTML from stdin, but nothing is piped in")
+	}
+	pageURL := stdinPageURL
+	if len(args) == 1 {
+		if pageURL = strings.TrimSpace(args[0]); !isValidURL(pageURL) {
+			return nil, fmt.Errorf("invalid page URL %q", pageURL)
+		}
+	}
+	html, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTML from stdin: %w", err)
+	}
+	if len(bytes.TrimSpace(html)) == 0 {
+		return nil, fmt.Errorf("--stdin-html got no HTML on stdin")
+	}
+	stdinHTML = html
+	return []string{pageURL}, nil
+}
+
 func readURLsFromFile(filename string) ([]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -574,16 +2077,13 @@ func readURLsFromFile(filename string) ([]string, error) {
 	}
 	defer file.Close()
 
-	var urls []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			urls = append(urls, line)
-		}
-	}
+	return readURLList(file, true)
+}
 
-	return urls, scanner.Err()
+// isTerminal reports whether f is a character device such as a TTY
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
 }
 
 func readURLsFromStdin() ([]string, error) {
@@ -595,20 +2095,33 @@ func readURLsFromStdin() ([]string, error) {
 
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		// Data is being piped in
-		var urls []string
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line != "" {
-				urls = append(urls, line)
-			}
-		}
-		return urls, scanner.Err()
+		return readURLList(os.Stdin, false)
 	}
 
 	return nil, nil
 }
 
+// readURLList reads one URL per line, with optional attributes such as
+// labels and a priority after it; comments skips lines starting with #
+func readURLList(r io.Reader, comments bool) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || (comments && strings.HasPrefix(line, "#")) {
+			continue
+		}
+		in, err := parseInputLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		addInputLabels(in.URL, in.Labels)
+		addInputPriority(in.URL, in.Priority)
+		urls = append(urls, in.URL)
+	}
+	return urls, scanner.Err()
+}
+
 func isValidURL(url string) bool {
 	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "file://")
 }
@@ -619,6 +2132,7 @@ func urlToFilename(rawURL string, format string) string {
 	name := rawURL
 	name = strings.TrimPrefix(name, "https://")
 	name = strings.TrimPrefix(name, "http://")
+	name = strings.TrimPrefix(name, "file://")
 
 	// Replace unsafe chars
 	replacer := strings.NewReplacer(
@@ -632,21 +2146,50 @@ func urlToFilename(rawURL string, format string) string {
 	)
 	name = replacer.Replace(name)
 
-	// Trim trailing underscores
-	name = strings.TrimRight(name, "_")
-
-	// Add extension
-	ext := ".txt"
-	if format == "markdown" {
-		ext = ".md"
-	}
+	// Trim underscores left by the root of file paths and trailing slashes
+	name = strings.Trim(name, "_")
 
 	// Truncate if too long
 	if len(name) > 200 {
 		name = name[:200]
 	}
 
-	return name + ext
+	return name + fileExtension(format)
+}
+
+// formatExtensions are the default file extensions per output format
+var formatExtensions = map[string]string{
+	"text":     ".txt",
+	"markdown": ".md",
+	"json":     ".json",
+	"html":     ".html",
+	"epub":     ".epub",
+}
+
+// fileExtension returns --extension when set, else the format's default
+func fileExtension(format string) string {
+	if fileExt != "" {
+		return fileExt
+	}
+	if ext, ok := formatExtensions[format]; ok {
+		return ext
+	}
+	return ".txt"
+}
+
+// parseExtension normalizes --extension to a leading-dot suffix
+func parseExtension(ext string) (string, error) {
+	ext = strings.TrimSpace(ext)
+	if ext == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if ext == "." || strings.ContainsAny(ext, `/\`) {
+		return "", fmt.Errorf("invalid --extension %q", ext)
+	}
+	return ext, nil
 }
 
 type exitErr struct {
@@ -665,6 +2208,3 @@ func exitError(code int, format string, args ...interface{}) *exitErr {
 	}
 	return &exitErr{code: code, msg: msg}
 }
-
-// Unused import guard - sync and sync.WaitGroup will be used when parallel is fully implemented
-var _ = sync.WaitGroup{}
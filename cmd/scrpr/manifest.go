@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+// manifestFile and indexFile are written alongside directory and archive
+// outputs
+const (
+	manifestFile = "manifest.json"
+	indexFile    = "index.md"
+)
+
+// manifest lists every URL of a batch and where its output went; see
+// schema.Manifest
+type manifest struct {
+	schema.Manifest
+}
+
+type manifestEntry = schema.ManifestEntry
+
+func newManifest() *manifest {
+	return &manifest{schema.Manifest{
+		SchemaVersion: schema.Version,
+		Generated:     stampTime().UTC().Format(time.RFC3339),
+		Format:        outputFormat,
+		Entries:       []manifestEntry{},
+	}}
+}
+
+// add records a URL; file is empty and err set when it produced no output
+func (m *manifest) add(index int, url, file string, result *ProcessResult, err error) {
+	entry := manifestEntry{
+		Index:   index,
+		URL:     url,
+		File:    file,
+		Fetched: stampTime().UTC().Format(time.RFC3339),
+		Status:  "ok",
+		Labels:  urlLabels(url),
+	}
+	if result != nil {
+		entry.Title = result.Title
+		if result.FinalURL != url {
+			entry.FinalURL = result.FinalURL
+		}
+		entry.Provenance = &result.Provenance
+		entry.Watch = result.Watch
+	}
+	if err != nil {
+		entry.Status = "error"
+		entry.Error = err.Error()
+	}
+	m.Entries = append(m.Entries, entry)
+}
+
+// skip records a URL that produced no output on purpose: the run stopped
+// before fetching it, or it was not a document
+func (m *manifest) skip(index int, url, reason, detail string) {
+	m.Entries = append(m.Entries, manifestEntry{Index: index, URL: url, Status: "skipped", Skip: reason, Error: detail, Labels: urlLabels(url)})
+}
+
+func (m *manifest) marshal() ([]byte, error) {
+	// Priorities may have fetched URLs out of input order
+	slices.SortStableFunc(m.Entries, func(a, b manifestEntry) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// markdown renders the manifest as a linked index.md
+func (m *manifest) markdown() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Index\n\nGenerated %s, %d URLs.\n\n", m.Generated, len(m.Entries))
+	for _, e := range m.Entries {
+		labels := ""
+		if len(e.Labels) > 0 {
+			labels = " `" + strings.Join(e.Labels, "` `") + "`"
+		}
+		if e.Status == "skipped" {
+			fmt.Fprintf(&b, "%d. <%s>%s (skipped, %s: %s)\n", e.Index, e.URL, labels, e.Skip, e.Error)
+			continue
+		}
+		if e.Status != "ok" {
+			fmt.Fprintf(&b, "%d. <%s>%s (failed: %s)\n", e.Index, e.URL, labels, e.Error)
+			continue
+		}
+		title := e.Title
+		if title == "" {
+			title = e.URL
+		}
+		fmt.Fprintf(&b, "%d. [%s](<%s>) - <%s>%s\n", e.Index, escapeLinkText(title), e.File, e.URL, labels)
+	}
+	return []byte(b.String())
+}
+
+// escapeLinkText keeps brackets in titles from closing the markdown link
+func escapeLinkText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "\n", " ").Replace(s)
+}
+
+// writeManifest stores manifest.json, and index.md with --index-md, through
+// write
+func writeManifest(m *manifest, write func(name string, data []byte) error) error {
+	data, err := m.marshal()
+	if err != nil {
+		return err
+	}
+	if err := write(manifestFile, data); err != nil {
+		return err
+	}
+	if writeIndex {
+		return write(indexFile, m.markdown())
+	}
+	return nil
+}
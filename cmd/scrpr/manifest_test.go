@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+func testManifest() *manifest {
+	m := newManifest()
+	m.Generated = "2024-05-01T10:00:00Z"
+	result := &ProcessResult{Title: "Second [draft]", FinalURL: "https://example.com/b/", Watch: "new"}
+	m.add(2, "https://example.com/b", "example.com_b.md", result, nil)
+	m.skip(3, "https://example.com/c.zip", skipNonHTML, "not an HTML document")
+	m.add(1, "https://example.com/a", "", nil, errors.New("HTTP error: 404"))
+	m.Entries[0].Labels = []string{"news", "go"}
+	return m
+}
+
+func TestManifest_Marshal(t *testing.T) {
+	data, err := testManifest().marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got schema.Manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != schema.Version || got.Generated != "2024-05-01T10:00:00Z" {
+		t.Errorf("schema_version %d, generated %q", got.SchemaVersion, got.Generated)
+	}
+
+	tests := []schema.ManifestEntry{
+		{Index: 1, URL: "https://example.com/a", Status: "error", Error: "HTTP error: 404"},
+		{Index: 2, URL: "https://example.com/b", FinalURL: "https://example.com/b/", File: "example.com_b.md", Title: "Second [draft]", Status: "ok", Labels: []string{"news", "go"}, Watch: "new"},
+		{Index: 3, URL: "https://example.com/c.zip", Status: "skipped", Skip: skipNonHTML, Error: "not an HTML document"},
+	}
+	if len(got.Entries) != len(tests) {
+		t.Fatalf("%d entries, want %d", len(got.Entries), len(tests))
+	}
+	for i, want := range tests {
+		e := got.Entries[i]
+		if e.Index != want.Index || e.URL != want.URL || e.FinalURL != want.FinalURL || e.File != want.File ||
+			e.Title != want.Title || e.Status != want.Status || e.Skip != want.Skip || e.Error != want.Error ||
+			!slices.Equal(e.Labels, want.Labels) || e.Watch != want.Watch {
+			t.Errorf("entry %d:\ngot  %+v\nwant %+v", i+1, e, want)
+		}
+	}
+}
+
+func TestManifest_Markdown(t *testing.T) {
+	m := testManifest()
+	m.marshal() // sorts the entries
+	want := "# Index\n\nGenerated 2024-05-01T10:00:00Z, 3 URLs.\n\n" +
+		"1. <https://example.com/a> (failed: HTTP error: 404)\n" +
+		"2. [Second \\[draft\\]](<example.com_b.md>) - <https://example.com/b> `news` `go`\n" +
+		"3. <https://example.com/c.zip> (skipped, non_html: not an HTML document)\n"
+	if got := string(m.markdown()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	defer func(saved bool) { writeIndex = saved }(writeIndex)
+
+	for _, index := range []bool{false, true} {
+		writeIndex = index
+		var names []string
+		err := writeManifest(testManifest(), func(name string, data []byte) error {
+			names = append(names, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{manifestFile}
+		if index {
+			want = append(want, indexFile)
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("--index-md %v wrote %q, want %q", index, names, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/ocr"
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+const (
+	// maxOCRImages bounds the images read per page
+	maxOCRImages = 5
+	// maxOCRImageSize bounds a downloaded scan
+	maxOCRImageSize = 20 << 20
+)
+
+// ocrEngine is set with --ocr or extraction.ocr
+var ocrEngine *ocr.Engine
+
+// ocrMinText is the length of extracted text, in characters, below which a
+// page with large images is read with OCR
+var ocrMinText int
+
+// newOCREngine sets up --ocr from the config; the command must be installed
+func newOCREngine(cfg *config.Config) (*ocr.Engine, error) {
+	engine, err := ocr.New(cmp.Or(cfg.Extraction.OCRCommand, ocr.DefaultCommand), 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extraction.ocr_command: %w", err)
+	}
+	if _, err := exec.LookPath(engine.Program()); err != nil {
+		return nil, fmt.Errorf("--ocr needs %s, which is not installed (install tesseract or set extraction.ocr_command)", engine.Program())
+	}
+	ocrMinText = cmp.Or(cfg.Extraction.OCRMinText, 200)
+	return engine, nil
+}
+
+// applyOCR reads the large images of a page whose text came out shorter
+// than ro.OCRMinText and appends what they say to its content. Images that
+// fail to download or read are left out.
+func applyOCR(ctx context.Context, ro *RunOptions, processed *processor.ProcessedContent, pageURL string) {
+	if utf8.RuneCountInString(strings.TrimSpace(processed.TextContent)) >= ro.OCRMinText || len(processed.Images) == 0 {
+		return
+	}
+	base, _ := url.Parse(pageURL)
+	client := httpClient("image")
+
+	var texts []string
+	tried := 0
+	seen := make(map[string]bool)
+	for _, src := range processed.Images {
+		if tried == maxOCRImages {
+			break
+		}
+		ref, err := url.Parse(src)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if (ref.Scheme != "http" && ref.Scheme != "https") || seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+
+		data, _, err := downloadImage(client, ref.String(), maxOCRImageSize)
+		if err != nil || !ocr.Large(data) {
+			continue
+		}
+		tried++
+		text, err := ro.OCR.Recognize(ctx, data)
+		if err != nil {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			continue
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+	if verbose && !quiet {
+		fmt.Fprintf(os.Stderr, "OCR read %d of %d images for %s\n", len(texts), tried, pageURL)
+	}
+
+	text := strings.Join(texts, "\n\n")
+	processed.TextContent = strings.TrimSpace(processed.TextContent + "\n\n" + text)
+	processed.Content += ocrHTML(text)
+	processed.Length = utf8.RuneCountInString(processed.TextContent)
+	processed.Metadata["ocr_images"] = strconv.Itoa(len(texts))
+}
+
+// ocrHTML lays out OCR text as paragraphs, one per blank-line separated
+// block, so every output format carries it
+func ocrHTML(text string) string {
+	var b strings.Builder
+	b.WriteString(`<div class="ocr">`)
+	for _, para := range strings.Split(text, "\n\n") {
+		lines := strings.Fields(strings.ReplaceAll(para, "\n", " "))
+		if len(lines) == 0 {
+			continue
+		}
+		b.WriteString("<p>" + html.EscapeString(strings.Join(lines, " ")) + "</p>")
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
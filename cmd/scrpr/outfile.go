@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// outputSyncInterval is how often single-file output is flushed to disk, so
+// a long run that dies near the end keeps the results written before
+const outputSyncInterval = 5 * time.Second
+
+// syncedFile is the single output file of a run. Results are written as
+// they complete and fsynced at most every outputSyncInterval.
+type syncedFile struct {
+	*os.File
+	synced time.Time
+}
+
+// openOutputFile creates the output file, or with appendTo opens it for
+// appending; existing reports that it already had content
+func openOutputFile(path string, appendTo bool) (f *syncedFile, existing bool, err error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendTo {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	if appendTo {
+		if info, err := file.Stat(); err == nil {
+			existing = info.Size() > 0
+		}
+	}
+	return &syncedFile{File: file, synced: time.Now()}, existing, nil
+}
+
+func (f *syncedFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err == nil && time.Since(f.synced) >= outputSyncInterval {
+		err = f.File.Sync()
+		f.synced = time.Now()
+	}
+	return n, err
+}
+
+// Close syncs what is left and closes the file
+func (f *syncedFile) Close() error {
+	err := f.File.Sync()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// prefixWriter writes prefix ahead of the first output, such as the
+// separator from the results already in an appended file
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if p.prefix != "" && len(b) > 0 {
+		if _, err := io.WriteString(p.w, p.prefix); err != nil {
+			return 0, err
+		}
+		p.prefix = ""
+	}
+	return p.w.Write(b)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenOutputFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		create   bool   // the file exists before
+		before   string // and holds this
+		appendTo bool
+		existing bool
+		after    string
+	}{
+		{name: "create", after: "new"},
+		{name: "replace", create: true, before: "old", after: "new"},
+		{name: "append to nothing", appendTo: true, after: "new"},
+		{name: "append to empty", create: true, appendTo: true, after: "new"},
+		{name: "append", create: true, before: "old", appendTo: true, existing: true, after: "oldnew"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "out.md")
+		if tt.create {
+			if err := os.WriteFile(path, []byte(tt.before), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, existing, err := openOutputFile(path, tt.appendTo)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		f.Write([]byte("new"))
+		if err := f.Close(); err != nil {
+			t.Fatalf("%s: close: %v", tt.name, err)
+		}
+		data, _ := os.ReadFile(path)
+		if existing != tt.existing || string(data) != tt.after {
+			t.Errorf("%s: existing %v, file %q; want %v, %q", tt.name, existing, data, tt.existing, tt.after)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &prefixWriter{w: &buf, prefix: "\n---\n"}
+	for _, s := range []string{"", "one", "two"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := buf.String(), "\n---\nonetwo"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestRun_Append(t *testing.T) {
+	server := articleServer(t)
+	dir := t.TempDir()
+
+	markdown := filepath.Join(dir, "feed.md")
+	for _, name := range []string{"first", "second"} {
+		if err := runScrpr(t, "--no-js", "--append", "-o", markdown, server.URL+"/"+name); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+	data, err := os.ReadFile(markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, ok := strings.Cut(string(data), "\n---\n")
+	if !ok || !strings.Contains(first, "Marker first.") || !strings.Contains(second, "Marker second.") {
+		t.Errorf("appended markdown is not first, separator, second:\n%s", data)
+	}
+
+	jsonl := filepath.Join(dir, "feed.jsonl")
+	for _, name := range []string{"first", "second"} {
+		if err := runScrpr(t, "--no-js", "--format", "json", "--append", "-o", jsonl, server.URL+"/"+name); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+	data, err = os.ReadFile(jsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("appended JSON has %d lines, want 2:\n%s", len(lines), data)
+	}
+	for i, line := range lines {
+		var r struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("line %d is not a JSON object: %v", i+1, err)
+		}
+	}
+
+	if err := runScrpr(t, "--no-js", "--append", "-o", dir+"/", server.URL+"/first"); err == nil {
+		t.Error("--append with an output directory was accepted")
+	}
+}
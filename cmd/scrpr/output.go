@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/processor"
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+// outputTemplate is parsed from --template; when set it replaces the
+// format's own layout
+var outputTemplate *template.Template
+
+// templateFuncs are available to --template in addition to the builtins
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// loadTemplate parses spec as a template file when it names one, otherwise
+// as an inline template in which \n and \t escapes are expanded
+func loadTemplate(spec string) (*template.Template, error) {
+	text := spec
+	if info, err := os.Stat(spec); err == nil && !info.IsDir() {
+		data, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		text = string(data)
+	} else {
+		text = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(text)
+	}
+
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// The --format json result and its parts, documented in pkg/schema
+type (
+	jsonResult = schema.Result
+	jsonLink   = schema.Link
+	jsonMedia  = schema.Media
+	jsonTiming = schema.Timing
+)
+
+// outputFields holds the --fields components in the order requested; empty
+// means the format's default layout
+var outputFields []string
+
+// availableFields lists the --fields components. timing, used_js, backend
+// and provenance only appear in JSON.
+var availableFields = []string{"url", "final_url", "redirects", "title", "author", "date", "description", "excerpt", "content", "metadata", "links", "images", "media", "timing", "used_js", "backend", "provenance", "labels"}
+
+// parseFields validates a comma-separated --fields list
+func parseFields(spec string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(availableFields, field) {
+			return nil, fmt.Errorf("unknown field: %s (available: %s)", field, strings.Join(availableFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// renderResult produces the final output for a result in the selected format,
+// or through --template when one is given.
+// JSON is compact for streams (one object per line) and indented for files.
+func (ro *RunOptions) renderResult(result *ProcessResult, indent bool) (string, error) {
+	if ro.Template != nil {
+		var b strings.Builder
+		if err := ro.Template.Execute(&b, result); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	if ro.Format != "json" {
+		if len(ro.Fields) > 0 {
+			return renderFields(result, ro.Fields, ro.Format == "markdown"), nil
+		}
+		return result.Content, nil
+	}
+	if len(ro.Fields) > 0 {
+		return renderJSONFields(result, ro.Fields, indent)
+	}
+
+	out := newJSONResult(result)
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(out, "", "  ")
+	} else {
+		data, err = json.Marshal(out)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func newJSONResult(result *ProcessResult) jsonResult {
+	out := jsonResult{
+		SchemaVersion: schema.Version,
+		URL:           result.URL,
+		FinalURL:      result.FinalURL,
+		Redirects:     make([]schema.Redirect, 0, len(result.Redirects)),
+		Title:         result.Title,
+		Content:       result.Content,
+		Metadata:      result.Metadata,
+		Images:        result.Images,
+		Links:         make([]jsonLink, 0, len(result.Links)),
+		Media:         newJSONMedia(result.Media),
+		Timing:        resultTiming(result),
+		UsedJS:        result.UsedJS,
+		Backend:       result.Backend,
+		Labels:        labelsOf(result),
+		Provenance:    result.Provenance,
+	}
+	if out.Metadata == nil {
+		out.Metadata = map[string]string{}
+	}
+	if out.Images == nil {
+		out.Images = []string{}
+	}
+	for _, r := range result.Redirects {
+		out.Redirects = append(out.Redirects, schema.Redirect(r))
+	}
+	for _, link := range result.Links {
+		out.Links = append(out.Links, jsonLink{Text: link.Text, URL: link.URL})
+	}
+	return out
+}
+
+// redirectsOf returns the redirect chain, empty rather than null in JSON
+func redirectsOf(result *ProcessResult) []fetcher.Redirect {
+	if result.Redirects == nil {
+		return []fetcher.Redirect{}
+	}
+	return result.Redirects
+}
+
+func newJSONMedia(media []processor.Media) []jsonMedia {
+	out := make([]jsonMedia, 0, len(media))
+	for _, m := range media {
+		out = append(out, jsonMedia{Type: m.Type, URL: m.URL, Title: m.Title, Provider: m.Provider})
+	}
+	return out
+}
+
+// fieldValue returns the JSON value of a --fields component
+func fieldValue(result *ProcessResult, field string) any {
+	switch field {
+	case "url":
+		return result.URL
+	case "final_url":
+		return result.FinalURL
+	case "redirects":
+		return redirectsOf(result)
+	case "title":
+		return result.Title
+	case "author":
+		if result.Author != "" {
+			return result.Author
+		}
+		return result.Metadata["author"]
+	case "date", "description":
+		return result.Metadata[field]
+	case "excerpt":
+		return result.Excerpt
+	case "content":
+		return result.Content
+	case "metadata":
+		if result.Metadata == nil {
+			return map[string]string{}
+		}
+		return result.Metadata
+	case "links":
+		links := make([]jsonLink, 0, len(result.Links))
+		for _, link := range result.Links {
+			links = append(links, jsonLink{Text: link.Text, URL: link.URL})
+		}
+		return links
+	case "images":
+		if result.Images == nil {
+			return []string{}
+		}
+		return result.Images
+	case "media":
+		return newJSONMedia(result.Media)
+	case "timing":
+		return resultTiming(result)
+	case "used_js":
+		return result.UsedJS
+	case "backend":
+		return result.Backend
+	case "provenance":
+		return result.Provenance
+	case "labels":
+		return labelsOf(result)
+	}
+	return nil
+}
+
+// labelsOf returns the labels of a result, empty rather than null in JSON
+func labelsOf(result *ProcessResult) []string {
+	if result.Labels == nil {
+		return []string{}
+	}
+	return result.Labels
+}
+
+// renderJSONFields emits only the selected fields, in the requested order
+// (a map would sort the keys)
+func renderJSONFields(result *ProcessResult, fields []string, indent bool) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"schema_version":%d`, schema.Version)
+	for _, field := range fields {
+		value, err := json.Marshal(fieldValue(result, field))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, ",%q:%s", field, value)
+	}
+	b.WriteByte('}')
+
+	if !indent {
+		return b.String(), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+var fieldLabels = map[string]string{
+	"url":         "URL",
+	"final_url":   "Final URL",
+	"author":      "Author",
+	"date":        "Date",
+	"description": "Description",
+	"excerpt":     "Excerpt",
+}
+
+// renderFields lays out the selected fields as text or markdown blocks
+func renderFields(result *ProcessResult, fields []string, markdown bool) string {
+	label := func(name, value string) string {
+		if markdown {
+			return fmt.Sprintf("**%s:** %s", name, value)
+		}
+		return fmt.Sprintf("%s: %s", name, value)
+	}
+	list := func(heading string, items []string) string {
+		if markdown {
+			return "## " + heading + "\n\n- " + strings.Join(items, "\n- ")
+		}
+		return heading + ":\n  " + strings.Join(items, "\n  ")
+	}
+
+	var blocks []string
+	for _, field := range fields {
+		switch field {
+		case "title":
+			if result.Title == "" {
+				continue
+			}
+			if markdown {
+				blocks = append(blocks, "# "+result.Title)
+			} else {
+				blocks = append(blocks, result.Title)
+			}
+		case "url", "final_url", "author", "date", "description", "excerpt":
+			if value, _ := fieldValue(result, field).(string); value != "" {
+				blocks = append(blocks, label(fieldLabels[field], value))
+			}
+		case "metadata":
+			keys := make([]string, 0, len(result.Metadata))
+			for key := range result.Metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var lines []string
+			for _, key := range keys {
+				lines = append(lines, label(key, result.Metadata[key]))
+			}
+			if len(lines) > 0 {
+				sep := "\n"
+				if markdown {
+					sep = "  \n"
+				}
+				blocks = append(blocks, strings.Join(lines, sep))
+			}
+		case "content":
+			if content := strings.TrimSpace(result.Content); content != "" {
+				blocks = append(blocks, content)
+			}
+		case "links":
+			var items []string
+			for _, link := range result.Links {
+				if markdown {
+					items = append(items, fmt.Sprintf("[%s](%s)", link.Text, link.URL))
+				} else {
+					items = append(items, fmt.Sprintf("%s <%s>", link.Text, link.URL))
+				}
+			}
+			if len(items) > 0 {
+				blocks = append(blocks, list("Links", items))
+			}
+		case "redirects":
+			var items []string
+			for _, r := range result.Redirects {
+				items = append(items, fmt.Sprintf("%d %s", r.Status, r.URL))
+			}
+			if len(items) > 0 {
+				blocks = append(blocks, list("Redirects", items))
+			}
+		case "labels":
+			if len(result.Labels) > 0 {
+				blocks = append(blocks, label("Labels", strings.Join(result.Labels, ", ")))
+			}
+		case "images":
+			if len(result.Images) > 0 {
+				blocks = append(blocks, list("Images", result.Images))
+			}
+		case "media":
+			var items []string
+			for _, m := range result.Media {
+				if markdown {
+					items = append(items, fmt.Sprintf("%s: [%s](%s)", m.Type, m.Title, m.URL))
+				} else {
+					items = append(items, fmt.Sprintf("%s: %s <%s>", m.Type, m.Title, m.URL))
+				}
+			}
+			if len(items) > 0 {
+				blocks = append(blocks, list("Media", items))
+			}
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
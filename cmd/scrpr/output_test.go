@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/processor"
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+func testResult() *ProcessResult {
+	return &ProcessResult{
+		URL:         "https://example.com/post",
+		FinalURL:    "https://example.com/post/",
+		Title:       "A Post",
+		Author:      "Ann Author",
+		Content:     "Body text.",
+		Metadata:    map[string]string{"date": "2024-05-01", "description": "About things"},
+		Links:       []processor.Link{{Text: "Home", URL: "https://example.com/"}},
+		Backend:     "readability",
+		FetchTime:   120 * time.Millisecond,
+		ProcessTime: 30 * time.Millisecond,
+	}
+}
+
+func TestRenderResult_JSON(t *testing.T) {
+	ro := &RunOptions{Format: "json"}
+	tests := []struct {
+		name   string
+		result *ProcessResult
+		indent bool
+	}{
+		{"compact", testResult(), false},
+		{"indented", testResult(), true},
+		{"empty result", &ProcessResult{URL: "https://example.com/"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ro.renderResult(tt.result, tt.indent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(out, "\n"); tt.indent == (lines == 0) {
+				t.Errorf("indent %v, but the output has %d newlines", tt.indent, lines)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("invalid JSON %s: %v", out, err)
+			}
+			if got["schema_version"] != float64(schema.Version) || got["url"] != tt.result.URL {
+				t.Errorf("schema_version %v, url %v", got["schema_version"], got["url"])
+			}
+			// Lists and maps are empty, never null
+			for _, key := range []string{"redirects", "metadata", "images", "links", "media", "labels"} {
+				if got[key] == nil {
+					t.Errorf("%s is null or missing", key)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderResult_JSONTiming(t *testing.T) {
+	out, err := (&RunOptions{Format: "json"}).renderResult(testResult(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got schema.Result
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := schema.Timing{FetchMS: 120, ProcessMS: 30, TotalMS: 150}
+	if got.Timing != want {
+		t.Errorf("timing %+v, want %+v", got.Timing, want)
+	}
+	if len(got.Links) != 1 || got.Links[0].URL != "https://example.com/" {
+		t.Errorf("links %+v", got.Links)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "post.tmpl")
+	if err := os.WriteFile(file, []byte("# {{.Title}}\n\n{{.Content}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, spec, want string
+	}{
+		{"inline with escapes", `{{.Title}}\t{{.URL}}\n`, "A Post\thttps://example.com/post\n"},
+		{"file", file, "# A Post\n\nBody text.\n"},
+		{"functions", `{{upper .Title}} {{lower .Author}} [{{trim "  x "}}]`, "A POST ann author [x]"},
+		{"metadata", `{{.Metadata.date}} {{index .Metadata "description"}}`, "2024-05-01 About things"},
+		{"missing key", `[{{.Metadata.nope}}]`, "[]"},
+		{"labels", `{{join .Labels ", "}}`, "news, go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := loadTemplate(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := testResult()
+			result.Labels = []string{"news", "go"}
+			// The template replaces the layout of any format
+			for _, format := range []string{"text", "markdown", "json"} {
+				got, err := (&RunOptions{Format: format, Template: tmpl}).renderResult(result, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("--format %s: got %q, want %q", format, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTemplate_Invalid(t *testing.T) {
+	if _, err := loadTemplate("{{.Title"); err == nil {
+		t.Error("expected an unclosed action to fail")
+	}
+	tmpl, err := loadTemplate("{{.Nope}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&RunOptions{Template: tmpl}).renderResult(testResult(), false); err == nil {
+		t.Error("expected an unknown field to fail at render time")
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"title,url", []string{"title", "url"}, false},
+		{" Content , LINKS,,content ", []string{"content", "links"}, false},
+		{"", nil, false},
+		{"title,body", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFields(tt.spec)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseFields(%q) = %q, %v; want %q, error %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRenderJSONFields(t *testing.T) {
+	result := testResult()
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{[]string{"title", "url"}, `{"schema_version":1,"title":"A Post","url":"https://example.com/post"}`},
+		{[]string{"author", "date", "description"}, `{"schema_version":1,"author":"Ann Author","date":"2024-05-01","description":"About things"}`},
+		{[]string{"links", "images", "labels", "redirects"}, `{"schema_version":1,"links":[{"text":"Home","url":"https://example.com/"}],"images":[],"labels":[],"redirects":[]}`},
+		{[]string{"timing", "used_js", "backend"}, `{"schema_version":1,"timing":{"fetch_ms":120,"process_ms":30,"total_ms":150},"used_js":false,"backend":"readability"}`},
+	}
+	for _, tt := range tests {
+		got, err := renderJSONFields(result, tt.fields, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Replace(tt.want, `"schema_version":1`, fmt.Sprintf(`"schema_version":%d`, schema.Version), 1)
+		if got != want {
+			t.Errorf("fields %v:\ngot  %s\nwant %s", tt.fields, got, want)
+		}
+	}
+
+	indented, err := renderJSONFields(result, []string{"url", "title"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(indented, "\n  \"url\": ") || strings.Index(indented, `"url"`) > strings.Index(indented, `"title"`) {
+		t.Errorf("indented output not in the requested order:\n%s", indented)
+	}
+}
+
+func TestRenderFields(t *testing.T) {
+	result := testResult()
+	result.Metadata["author"] = "Ann"
+	tests := []struct {
+		name     string
+		fields   []string
+		markdown bool
+		want     string
+	}{
+		{"text", []string{"title", "url", "content"}, false, "A Post\n\nURL: https://example.com/post\n\nBody text."},
+		{"markdown", []string{"title", "url", "content"}, true, "# A Post\n\n**URL:** https://example.com/post\n\nBody text."},
+		{"order", []string{"content", "title"}, false, "Body text.\n\nA Post"},
+		{"links", []string{"links"}, false, "Links:\n  Home <https://example.com/>"},
+		{"markdown links", []string{"links"}, true, "## Links\n\n- [Home](https://example.com/)"},
+		{"metadata", []string{"metadata"}, true, "**author:** Ann  \n**date:** 2024-05-01  \n**description:** About things"},
+		{"empty fields left out", []string{"excerpt", "images", "labels", "title"}, false, "A Post"},
+	}
+	for _, tt := range tests {
+		if got := renderFields(result, tt.fields, tt.markdown); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
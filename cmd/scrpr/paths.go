@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/paths"
+)
+
+var pathsJSON bool
+
+var pathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Print where scrpr keeps its config, caches and data",
+	Args:  cobra.NoArgs,
+	RunE:  runPaths,
+}
+
+func init() {
+	pathsCmd.Flags().BoolVar(&pathsJSON, "json", false, "print the locations as JSON")
+	rootCmd.AddCommand(pathsCmd)
+}
+
+// pathEntry is one location of the `scrpr paths` report
+type pathEntry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Source string `json:"source,omitempty"` // what set the location, e.g. $XDG_CACHE_HOME or cache.dir
+	Target string `json:"target,omitempty"` // where a symlink leads
+	Exists bool   `json:"exists"`
+}
+
+func runPaths(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v; showing the default locations\n", err)
+		}
+		cfg = config.Default()
+	}
+	entries, err := effectivePaths(cfg)
+	if err != nil {
+		return exitError(ExitConfigError, "%v", err)
+	}
+
+	if pathsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for _, e := range entries {
+		var notes []string
+		if e.Source != "" {
+			notes = append(notes, e.Source)
+		}
+		if e.Target != "" {
+			notes = append(notes, "-> "+e.Target)
+		}
+		if !e.Exists {
+			notes = append(notes, "missing")
+		}
+		line := fmt.Sprintf("%-14s %s", e.Name, e.Path)
+		if len(notes) > 0 {
+			line += "  (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// effectivePaths lists the locations a run with cfg reads and writes
+func effectivePaths(cfg *config.Config) ([]pathEntry, error) {
+	configDir, err := config.ConfigDir(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	configFile, configSource := cfgFile, "--config"
+	if configFile == "" {
+		configFile, configSource = filepath.Join(configDir, "config.toml"), ""
+	}
+	configDirSource := paths.Source(paths.Config)
+	if cfgFile != "" {
+		configDirSource = "--config"
+	}
+	cacheBase, err := paths.Dir(paths.Cache)
+	if err != nil {
+		return nil, err
+	}
+	dataBase, err := paths.Dir(paths.Data)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := rulesDir()
+	if err != nil {
+		return nil, err
+	}
+	responses, err := cacheDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	api, err := apiCacheDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := hostStatePath()
+	if err != nil {
+		return nil, err
+	}
+	sitemaps, err := sitemapStateDir()
+	if err != nil {
+		return nil, err
+	}
+	watchHistory, err := watchHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	cacheSource := ""
+	if cfg.Cache.Dir != "" {
+		cacheSource = "cache.dir"
+	}
+
+	entries := []pathEntry{
+		newPathEntry("config", configDir, configDirSource),
+		newPathEntry("config file", configFile, configSource),
+		newPathEntry("rules", rules, ""),
+		newPathEntry("cache", cacheBase, paths.Source(paths.Cache)),
+		newPathEntry("responses", responses, cacheSource),
+		newPathEntry("api cache", api, cacheSource),
+		newPathEntry("host health", hosts, ""),
+		newPathEntry("data", dataBase, paths.Source(paths.Data)),
+		newPathEntry("user agents", filepath.Join(dataBase, fetcher.UserAgentDatasetFile), ""),
+		newPathEntry("sitemaps", sitemaps, ""),
+		newPathEntry("watch history", watchHistory, ""),
+	}
+	if cfg.Output.SaveRaw != "" {
+		entries = append(entries, newPathEntry("raw store", cfg.Output.SaveRaw, "output.save_raw"))
+	}
+	return entries, nil
+}
+
+func newPathEntry(name, path, source string) pathEntry {
+	e := pathEntry{Name: name, Path: path, Source: source}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSymlink != 0 {
+			e.Target, _ = filepath.EvalSymlinks(path)
+			if e.Target == "" {
+				e.Target, _ = os.Readlink(path)
+			}
+		}
+		_, err = os.Stat(path)
+		e.Exists = err == nil
+	}
+	return e
+}
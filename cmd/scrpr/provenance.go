@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/pkg/schema"
+)
+
+// Sources of the HTML a result was extracted from
+const (
+	sourceNetwork  = "network"
+	sourceCache    = "cache"
+	sourceRawStore = "raw-store"
+	sourceFile     = "file"  // a file:// URL
+	sourceStdin    = "stdin" // --stdin-html
+)
+
+// provenance records how a result was produced; see schema.Provenance
+type provenance = schema.Provenance
+
+// apiProvenance describes a result of an extraction API, called now or
+// reused from the API cache
+func apiProvenance(backend, source string) provenance {
+	p := provenance{
+		Fetcher:  backend,
+		Source:   source,
+		Mode:     "api",
+		Backend:  backend,
+		CacheHit: source == sourceCache,
+	}
+	if source == sourceNetwork {
+		p.Attempts = 1
+	}
+	return p
+}
+
+// fetchProvenance describes a local fetch whose HTML came from source
+func fetchProvenance(fr *fetcher.FetchResult, source string) provenance {
+	p := provenance{
+		Fetcher:  "http",
+		Source:   source,
+		Mode:     string(fetcher.FetchModeStatic),
+		Backend:  "readability",
+		CacheHit: source == sourceCache,
+	}
+	switch {
+	case source == sourceFile || source == sourceStdin:
+		p.Fetcher = "local"
+	case fr.UsedJS:
+		p.Fetcher = "chrome"
+		p.Mode = string(fetcher.FetchModeJS)
+	}
+	if source == sourceNetwork {
+		p.UserAgent = fr.UserAgent
+		p.Proxy = fr.Proxy
+		p.Attempts = fr.Attempts
+	}
+	return p
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// renderMode is how the run uses Chrome: static never starts it, auto
+// renders pages that look built by scripts, js renders every page
+var renderMode = fetcher.FetchModeStatic
+
+// waitStrategy is --wait, parsed
+var waitStrategy fetcher.WaitStrategy
+
+// scrollConfig is how rendered pages are scrolled (nil = not at all)
+var scrollConfig *fetcher.ScrollConfig
+
+// pageScripts are the --eval-js scripts, read
+var pageScripts []fetcher.PageScript
+
+// renderer renders pages in Chrome; it starts on the first page that needs
+// it and keeps Chrome running, in the browser pool, until the run ends
+var renderer struct {
+	pool     config.BrowserPoolConfig
+	once     sync.Once
+	fetcher  *fetcher.ContentFetcher
+	browsers *fetcher.BrowserPool
+	debug    fetcher.DebugOptions
+	rendered atomic.Bool // a page was rendered, so Chrome works
+	failed   atomic.Bool // rendering failed before any page was; auto mode stops trying
+}
+
+// parseRenderMode reads extraction.enable_javascript
+func parseRenderMode(s string) (fetcher.FetchMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return fetcher.FetchModeAuto, nil
+	case "always":
+		return fetcher.FetchModeJS, nil
+	case "never":
+		return fetcher.FetchModeStatic, nil
+	}
+	return "", fmt.Errorf("invalid extraction.enable_javascript %q (available: auto, always, never)", s)
+}
+
+// setRenderMode sets the run's render mode, wait, scroll and script options
+// from flags and config. --javascript, the wait and scroll flags and
+// --eval-js render every page; --no-js none.
+func setRenderMode(cmd *cobra.Command, cfg *config.Config) error {
+	var err error
+	if renderMode, err = parseRenderMode(cfg.Extraction.EnableJavaScript); err != nil {
+		return exitError(ExitConfigError, "%v", err)
+	}
+	renderer.pool = cfg.Parallel.BrowserPool
+
+	waitFlags := cmd.Flags().Changed("wait-for") || cmd.Flags().Changed("wait-for-text") || cmd.Flags().Changed("wait")
+	scrollFlags := cmd.Flags().Changed("scroll") || cmd.Flags().Changed("scroll-until-stable")
+	debugFlags := headful || devtools || slowMo > 0
+	pageFlags := waitFlags || scrollFlags || len(evalJSFiles) > 0 || debugFlags
+	switch {
+	case noJS && (javascript || pageFlags):
+		return exitError(ExitInvalidInput, "--no-js cannot be combined with --javascript, --wait-for, --wait-for-text, --wait, --scroll, --eval-js, --headful, --devtools or --slow-mo")
+	case noJS:
+		renderMode = fetcher.FetchModeStatic
+	case javascript || pageFlags:
+		renderMode = fetcher.FetchModeJS
+	}
+
+	if !cmd.Flags().Changed("wait-for") {
+		waitForSelector = cfg.Extraction.WaitForSelector
+	}
+	if !cmd.Flags().Changed("wait-for-text") {
+		waitForText = cfg.Extraction.WaitForText
+	}
+	if !cmd.Flags().Changed("wait") && cfg.Extraction.WaitUntil != "" {
+		waitUntil = cfg.Extraction.WaitUntil
+	}
+	if waitForSelector != "" {
+		if _, err := cascadia.Compile(waitForSelector); err != nil {
+			return exitError(ExitInvalidInput, "invalid --wait-for selector %q: %v", waitForSelector, err)
+		}
+	}
+	if waitStrategy, err = fetcher.ParseWaitStrategy(waitUntil); err != nil {
+		return exitError(ExitInvalidInput, "%v", err)
+	}
+	// Per-site waits apply only when none was given on the command line
+	waitRoutes = nil
+	if !cmd.Flags().Changed("wait") {
+		if waitRoutes, err = newWaitRoutes(cfg.Extraction.Sites); err != nil {
+			return exitError(ExitConfigError, "extraction: %v", err)
+		}
+	}
+
+	if err := setDebug(cmd); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("eval-js") {
+		evalJSFiles = cfg.Extraction.EvalJS
+	}
+	var files []string
+	for _, f := range evalJSFiles {
+		files = append(files, expandHome(f))
+	}
+	if pageScripts, err = fetcher.LoadPageScripts(files); err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	return setScroll(cmd, cfg)
+}
+
+// setScroll reads the scroll flags and their extraction.scroll* settings
+func setScroll(cmd *cobra.Command, cfg *config.Config) error {
+	if !cmd.Flags().Changed("scroll") {
+		scrollRounds = cfg.Extraction.Scroll
+	}
+	if !cmd.Flags().Changed("scroll-until-stable") {
+		scrollUntilStable = cfg.Extraction.ScrollUntilStable
+	}
+	if !cmd.Flags().Changed("scroll-max-height") {
+		scrollMaxHeight = cfg.Extraction.ScrollMaxHeight
+	}
+	if !cmd.Flags().Changed("scroll-timeout") {
+		scrollTimeout = cfg.Extraction.ScrollTimeout
+	}
+	if scrollRounds < 0 || scrollMaxHeight < 0 || scrollTimeout < 0 {
+		return exitError(ExitInvalidInput, "--scroll, --scroll-max-height and --scroll-timeout cannot be negative")
+	}
+	if scrollRounds == 0 && !scrollUntilStable {
+		scrollConfig = nil
+		return nil
+	}
+	scrollConfig = &fetcher.ScrollConfig{
+		Rounds:      scrollRounds,
+		UntilStable: scrollUntilStable,
+		MaxHeight:   scrollMaxHeight,
+		Timeout:     time.Duration(scrollTimeout) * time.Second,
+	}
+	return nil
+}
+
+// defaultSlowMo paces a shown window so its steps can be followed
+const defaultSlowMo = 250 * time.Millisecond
+
+// setDebug reads --headful, --devtools and --slow-mo. A shown window logs
+// the page console, as does --verbose.
+func setDebug(cmd *cobra.Command) error {
+	if slowMo < 0 {
+		return exitError(ExitInvalidInput, "--slow-mo cannot be negative")
+	}
+	shown := headful || devtools
+	if shown && !cmd.Flags().Changed("slow-mo") {
+		slowMo = defaultSlowMo
+	}
+	renderer.debug = fetcher.DebugOptions{Headful: shown, DevTools: devtools, SlowMo: slowMo}
+	if (shown || verbose) && !quiet {
+		renderer.debug.Console = os.Stderr
+	}
+	return nil
+}
+
+func startRenderer() {
+	cf := fetcher.NewContentFetcher()
+	cf.SetTimeouts(stageTimeouts())
+	cf.SetUserAgentSelector(uaSelector)
+	cf.SetDebug(renderer.debug)
+	if pool := renderer.pool; pool.Enabled {
+		renderer.browsers = fetcher.NewBrowserPool(fetcher.BrowserPoolOptions{
+			Browsers:         pool.Instances,
+			Tabs:             pool.Tabs,
+			MaxNavigations:   pool.MaxNavigations,
+			AllocatorOptions: renderer.debug.AllocatorOptions(),
+		})
+		cf.SetBrowserPool(renderer.browsers)
+	}
+	renderer.fetcher = cf
+}
+
+// stopRenderer stops the Chrome instances of the run
+func stopRenderer() {
+	if renderer.browsers != nil {
+		renderer.browsers.Close()
+	}
+}
+
+// render fetches url in Chrome
+func render(ctx context.Context, url string, opts fetcher.FetchOptions) (*fetcher.FetchResult, error) {
+	result, err := renderer.fetcher.Render(ctx, url, opts)
+	if err == nil {
+		renderer.rendered.Store(true)
+	}
+	return result, err
+}
+
+// fetchPage fetches url statically or in Chrome, as opts.Mode says. In auto
+// mode a page that cannot be rendered is taken as fetched.
+func fetchPage(ctx context.Context, sf *fetcher.SimpleFetcher, url string, opts fetcher.FetchOptions) (*fetcher.FetchResult, error) {
+	// The browser only navigates with GET
+	if opts.Mode == fetcher.FetchModeStatic || !fetcher.IsPlainGet(opts) {
+		return sf.FetchStatic(ctx, url, opts)
+	}
+	renderer.once.Do(startRenderer)
+	if opts.Mode == fetcher.FetchModeJS {
+		return render(ctx, url, opts)
+	}
+
+	result, err := sf.FetchStatic(ctx, url, opts)
+	if err != nil || renderer.failed.Load() || !renderer.fetcher.NeedsRendering(result) {
+		return result, err
+	}
+	rendered, err := render(ctx, url, opts)
+	if err != nil {
+		switch {
+		case !renderer.rendered.Load():
+			// Most likely Chrome is missing; say so once
+			if !renderer.failed.Swap(true) && !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: JavaScript rendering failed, taking pages as fetched: %v\n", err)
+			}
+		case !quiet:
+			fmt.Fprintf(os.Stderr, "Warning: rendering %s failed, taking it as fetched: %v\n", url, err)
+		}
+		return result, nil
+	}
+	// The browser does not report what the static fetch saw of the response
+	rendered.Status = result.Status
+	rendered.RobotsTag = result.RobotsTag
+	rendered.Attempts += result.Attempts
+	return rendered, nil
+}
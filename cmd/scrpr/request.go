@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// requestBody is the payload of --data, sent with every static fetch (nil =
+// none)
+var requestBody []byte
+
+// setupRequest checks --method, --data and --content-type and loads the
+// request body. --data without --method posts, as in curl.
+func setupRequest() error {
+	if requestData == "" {
+		if requestContentType != "" {
+			return fmt.Errorf("--content-type needs --data")
+		}
+	} else if strings.HasPrefix(requestData, "@") {
+		body, err := os.ReadFile(requestData[1:])
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		requestBody = body
+	} else {
+		requestBody = []byte(requestData)
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(requestMethodFlag))
+	switch {
+	case method == "" && requestBody != nil:
+		method = http.MethodPost
+	case method == "":
+		method = http.MethodGet
+	case strings.IndexFunc(method, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0:
+		return fmt.Errorf("invalid request method %q", requestMethodFlag)
+	}
+	requestMethodFlag = method
+	return nil
+}
+
+// followUpOptions are opts for a page the fetched page points to, such as
+// its mobile or single-page version; those are plain GETs with the browser
+// cookies of their own host
+func (ro *RunOptions) followUpOptions(opts fetcher.FetchOptions, rawURL string) fetcher.FetchOptions {
+	opts.Method, opts.Body, opts.ContentType = "", nil, ""
+	opts.Cookies = ro.cookiesFor(rawURL)
+	return opts
+}
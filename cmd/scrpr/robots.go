@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// Robots meta policies for --robots-meta
+const (
+	robotsIgnore  = "ignore"  // extract every page, recording its directives
+	robotsRespect = "respect" // skip pages marked noindex or noarchive
+)
+
+func parseRobotsPolicy(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case robotsIgnore, robotsRespect:
+		return s, nil
+	case "":
+		return robotsIgnore, nil
+	}
+	return "", fmt.Errorf("invalid --robots-meta %q (available: ignore, respect)", s)
+}
+
+// RobotsError reports a page whose robots meta tag or X-Robots-Tag header
+// asks not to be kept; with --robots-meta respect it is skipped, not failed
+type RobotsError struct {
+	Directives string // e.g. "noindex, nofollow"
+}
+
+func (e *RobotsError) Error() string {
+	return fmt.Sprintf("robots directives forbid keeping the page (%s)", e.Directives)
+}
+
+// checkRobots returns a *RobotsError when the policy is respect and
+// the page is marked noindex or noarchive. It runs before the response is
+// cached or stored, so such pages leave nothing on disk.
+func checkRobots(result *fetcher.FetchResult, policy string) error {
+	_, err := robotsDecision(result, policy)
+	return err
+}
+
+// robotsDecision is checkRobots with the decision taken, for the audit log:
+// "allowed", "skipped: <directives>" or "ignored: <directives>"
+func robotsDecision(result *fetcher.FetchResult, policy string) (string, error) {
+	if isImageContent(result.ContentType) {
+		return "allowed", nil
+	}
+	robots := fetcher.ParseRobots(result.HTML, result.RobotsTag)
+	switch {
+	case !robots.Forbids():
+		return "allowed", nil
+	case policy == robotsRespect:
+		return "skipped: " + robots.String(), &RobotsError{Directives: robots.String()}
+	default:
+		return "ignored: " + robots.String(), nil
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/config"
+)
+
+// outputRoute sends the results of matching URLs to a directory or a file
+// instead of the run's output ([[output.routes]])
+type outputRoute struct {
+	match string
+	re    *regexp.Regexp
+	dir   string         // set for directory routes: one file per URL
+	path  string         // set for file routes: all results in one file
+	file  io.WriteCloser // opened on first use
+}
+
+// outputRoutes are ordered most specific first
+type outputRoutes []*outputRoute
+
+// newOutputRoutes checks the [[output.routes]] entries; relative
+// destinations are resolved against base (the output directory, or the
+// working directory when base is empty)
+func newOutputRoutes(entries []config.OutputRouteConfig, base string) (outputRoutes, error) {
+	var routes outputRoutes
+	for _, e := range entries {
+		match := strings.TrimSpace(e.Match)
+		if match == "" {
+			return nil, fmt.Errorf("route without match pattern")
+		}
+		if e.To == "" {
+			return nil, fmt.Errorf("route %q has no destination", match)
+		}
+		r := &outputRoute{match: match, re: compileRoutePattern(match)}
+		dest := expandHome(e.To)
+		if !filepath.IsAbs(dest) && base != "" {
+			dest = filepath.Join(base, dest)
+		}
+		if info, err := os.Stat(dest); strings.HasSuffix(e.To, "/") || (err == nil && info.IsDir()) {
+			r.dir = dest
+		} else {
+			r.path = dest
+		}
+		routes = append(routes, r)
+	}
+	// The longest pattern is the most specific; among equals the later
+	// entry wins, as with [[extraction.sites]]
+	slices.Reverse(routes)
+	slices.SortStableFunc(routes, func(a, b *outputRoute) int {
+		return cmp.Compare(len(b.match), len(a.match))
+	})
+	return routes, nil
+}
+
+// compileRoutePattern turns a route pattern into a regexp: * matches any
+// run of characters and a pattern without a slash matches a whole host
+func compileRoutePattern(match string) *regexp.Regexp {
+	if !strings.Contains(match, "/") {
+		match += "/*"
+	}
+	match = strings.TrimPrefix(strings.ToLower(match), "www.")
+	expr := strings.ReplaceAll(regexp.QuoteMeta(match), `\*`, ".*")
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// routeTarget is what route patterns match against: the URL without its
+// scheme and fragment, with the host lowercased and www. dropped
+func routeTarget(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	target := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + u.EscapedPath()
+	if u.EscapedPath() == "" {
+		target += "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return target
+}
+
+// match returns the route for a URL, nil when the run's output applies
+func (rs outputRoutes) match(rawURL string) *outputRoute {
+	if len(rs) == 0 {
+		return nil
+	}
+	target := routeTarget(rawURL)
+	for _, r := range rs {
+		if r.re.MatchString(target) {
+			return r
+		}
+	}
+	return nil
+}
+
+// write stores a result at the route's destination and returns the path it
+// went to
+func (r *outputRoute) write(index int, rawURL string, result *ProcessResult, ro *RunOptions) (string, error) {
+	if r.dir != "" {
+		filePath, err := ro.Names.filePath(r.dir, index, rawURL, result)
+		if err != nil {
+			return "", err
+		}
+		rendered, err := ro.renderResult(result, true)
+		if err != nil {
+			return "", err
+		}
+		return filePath, writeSealed(filePath, []byte(rendered))
+	}
+
+	rendered, err := ro.renderResult(result, false)
+	if err != nil {
+		return "", err
+	}
+	// Results in one file are laid out as on stdout
+	if r.file == nil {
+		if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
+		}
+		if r.file, err = createOutput(r.path); err != nil {
+			return "", err
+		}
+	} else if ro.Format != "json" {
+		if nullSeparator {
+			rendered = "\x00" + rendered
+		} else {
+			rendered = "\n" + separator + "\n" + rendered
+		}
+	}
+	if ro.Format == "json" {
+		rendered += "\n"
+	}
+	_, err = io.WriteString(r.file, rendered)
+	return r.path, err
+}
+
+// close closes the route files
+func (rs outputRoutes) close() error {
+	var firstErr error
+	for _, r := range rs {
+		if r.file != nil {
+			if err := r.file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			r.file = nil
+		}
+	}
+	return firstErr
+}
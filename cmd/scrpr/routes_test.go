@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/byteowlz/scrpr/internal/config"
+)
+
+func TestRouteTarget(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://Example.com/Blog/Post", "example.com/Blog/Post"},
+		{"https://www.example.com", "example.com/"},
+		{"http://example.com/a?b=1#top", "example.com/a?b=1"},
+		{"https://example.com:8080/a%20b", "example.com:8080/a%20b"},
+		{"://bad", ""},
+	}
+	for _, tt := range tests {
+		if got := routeTarget(tt.in); got != tt.want {
+			t.Errorf("routeTarget(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOutputRoutes_Match(t *testing.T) {
+	base := t.TempDir()
+	routes, err := newOutputRoutes([]config.OutputRouteConfig{
+		{Match: "example.com", To: "site/"},
+		{Match: "example.com/blog/*", To: "blog/"},
+		{Match: "*.example.org/docs/*", To: "docs.md"},
+		{Match: "example.com", To: "later/"}, // same pattern: the later entry wins
+	}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string // destination, "" = no route
+	}{
+		{"https://example.com/", filepath.Join(base, "later")},
+		{"https://www.EXAMPLE.com/about", filepath.Join(base, "later")},
+		{"https://example.com/blog/post", filepath.Join(base, "blog")},
+		{"https://api.example.org/docs/intro", filepath.Join(base, "docs.md")},
+		{"https://example.org/docs/intro", ""},
+		{"https://example.net/", ""},
+	}
+	for _, tt := range tests {
+		r := routes.match(tt.url)
+		got := ""
+		if r != nil {
+			got = r.dir + r.path
+		}
+		if got != tt.want {
+			t.Errorf("match(%q) routes to %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNewOutputRoutes(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name    string
+		route   config.OutputRouteConfig
+		dir     string
+		path    string
+		wantErr bool
+	}{
+		{name: "directory", route: config.OutputRouteConfig{Match: "a.com", To: "a/"}, dir: filepath.Join(base, "a")},
+		{name: "existing directory", route: config.OutputRouteConfig{Match: "b.com", To: "."}, dir: base},
+		{name: "file", route: config.OutputRouteConfig{Match: "c.com", To: "c.md"}, path: filepath.Join(base, "c.md")},
+		{name: "absolute", route: config.OutputRouteConfig{Match: "d.com", To: "/tmp/d.md"}, path: "/tmp/d.md"},
+		{name: "no pattern", route: config.OutputRouteConfig{Match: " ", To: "e/"}, wantErr: true},
+		{name: "no destination", route: config.OutputRouteConfig{Match: "f.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		routes, err := newOutputRoutes([]config.OutputRouteConfig{tt.route}, base)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (routes[0].dir != tt.dir || routes[0].path != tt.path) {
+			t.Errorf("%s: dir %q, path %q; want %q, %q", tt.name, routes[0].dir, routes[0].path, tt.dir, tt.path)
+		}
+	}
+}
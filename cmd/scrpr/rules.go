@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// maxRulePackSize bounds a rule pack download
+const maxRulePackSize = 1 << 20
+
+var rulesForce bool
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage site rule packs",
+	Long: `Rule packs are shareable TOML files of per-site rules: junk selectors and
+keep lists ([[junk.sites]]), read-more selectors ([[expand.sites]]) and
+extraction backend routes ([[extraction.sites]]), named and versioned by a
+[pack] table. Installed packs live in the rules directory next to the config
+file and are layered under it, so the config wins on conflicting rules.`,
+}
+
+var rulesInstallCmd = &cobra.Command{
+	Use:   "install <url|path>",
+	Short: "Install or upgrade a rule pack",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRulesInstall,
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed rule packs",
+	Args:  cobra.NoArgs,
+	RunE:  runRulesList,
+}
+
+var rulesRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an installed rule pack",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRulesRemove,
+}
+
+func init() {
+	rulesInstallCmd.Flags().BoolVar(&rulesForce, "force", false, "reinstall the same version or downgrade")
+	rulesCmd.AddCommand(rulesInstallCmd, rulesListCmd, rulesRemoveCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+func runRulesInstall(cmd *cobra.Command, args []string) error {
+	if err := checkConfigWrite("installing rule packs"); err != nil {
+		return err
+	}
+	source := args[0]
+	data, err := readRulePack(source)
+	if err != nil {
+		return exitError(ExitNetworkError, "%v", err)
+	}
+	pack, err := config.ParseRulePack(data)
+	if err != nil {
+		return exitError(ExitInvalidInput, "%s: %v", source, err)
+	}
+	if err := checkRulePack(pack); err != nil {
+		return exitError(ExitInvalidInput, "%s: %v", source, err)
+	}
+
+	dir, err := rulesDir()
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	path := filepath.Join(dir, pack.Name+".toml")
+
+	action, previous := "Installed", ""
+	if installed, err := os.ReadFile(path); err == nil {
+		if old, err := config.ParseRulePack(installed); err == nil {
+			cmp, _ := config.CompareVersions(pack.Version, old.Version)
+			switch {
+			case cmp == 0 && !rulesForce:
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "Rule pack %s %s is already installed\n", pack.Name, pack.Version)
+				}
+				return nil
+			case cmp < 0 && !rulesForce:
+				return exitError(ExitInvalidInput, "rule pack %s %s is older than the installed %s; use --force to downgrade", pack.Name, pack.Version, old.Version)
+			case cmp > 0:
+				action = "Upgraded"
+			case cmp < 0:
+				action = "Downgraded"
+			default:
+				action = "Reinstalled"
+			}
+			if cmp != 0 {
+				previous = " (was " + old.Version + ")"
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return exitError(ExitFileIOError, "failed to create rules directory: %v", err)
+	}
+	header := fmt.Sprintf("# Installed by scrpr rules install from %s on %s\n", source, time.Now().Format("2006-01-02"))
+	if err := writeFileAtomic(path, append([]byte(header), data...)); err != nil {
+		return exitError(ExitFileIOError, "failed to save rule pack: %v", err)
+	}
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "%s rule pack %s %s%s: %s in %s\n", action, pack.Name, pack.Version, previous, rulePackSummary(pack), path)
+	}
+	return nil
+}
+
+func runRulesList(cmd *cobra.Command, args []string) error {
+	dir, err := rulesDir()
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	if len(paths) == 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "No rule packs installed in %s\n", dir)
+		}
+		return nil
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return exitError(ExitFileIOError, "%v", err)
+		}
+		pack, err := config.ParseRulePack(data)
+		if err != nil {
+			fmt.Printf("%s\tinvalid: %v\n", filepath.Base(path), err)
+			continue
+		}
+		line := fmt.Sprintf("%s\t%s\t%s", pack.Name, pack.Version, rulePackSummary(pack))
+		if pack.Description != "" {
+			line += "\t" + pack.Description
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runRulesRemove(cmd *cobra.Command, args []string) error {
+	if err := checkConfigWrite("removing rule packs"); err != nil {
+		return err
+	}
+	dir, err := rulesDir()
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	name := args[0]
+	if strings.ContainsAny(name, `/\`) {
+		return exitError(ExitInvalidInput, "invalid rule pack name %q", name)
+	}
+	path := filepath.Join(dir, strings.TrimSuffix(name, ".toml")+".toml")
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return exitError(ExitInvalidInput, "rule pack %s is not installed", name)
+		}
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Removed rule pack %s\n", name)
+	}
+	return nil
+}
+
+// rulesDir is the rules directory next to the config file in use
+func rulesDir() (string, error) {
+	dir, err := config.ConfigDir(cfgFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, config.RulesDir), nil
+}
+
+// readRulePack loads a pack from an http(s) URL or a local path
+func readRulePack(source string) ([]byte, error) {
+	if u, err := url.Parse(source); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		data, err := os.ReadFile(expandHome(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule pack: %w", err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule pack URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "scrpr/"+version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download rule pack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download rule pack: HTTP %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRulePackSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule pack: %w", err)
+	}
+	if len(data) > maxRulePackSize {
+		return nil, fmt.Errorf("rule pack exceeds %d bytes", maxRulePackSize)
+	}
+	return data, nil
+}
+
+// checkRulePack compiles a pack's rules the way a run would, so a broken
+// pack is rejected at install instead of failing every later run
+func checkRulePack(pack *config.RulePack) error {
+	if _, err := newJunkFilter(config.JunkConfig{Sites: pack.Junk}); err != nil {
+		return fmt.Errorf("junk: %w", err)
+	}
+	if _, err := newExpandRules(config.ExpandConfig{Sites: pack.Expand}); err != nil {
+		return fmt.Errorf("expand: %w", err)
+	}
+	if _, err := newBackendRoutes(pack.Extraction); err != nil {
+		return fmt.Errorf("extraction: %w", err)
+	}
+	if _, err := newWaitRoutes(pack.Extraction); err != nil {
+		return fmt.Errorf("extraction: %w", err)
+	}
+	return nil
+}
+
+func rulePackSummary(pack *config.RulePack) string {
+	var parts []string
+	for _, section := range []struct {
+		name  string
+		count int
+	}{{"junk", len(pack.Junk)}, {"expand", len(pack.Expand)}, {"extraction", len(pack.Extraction)}} {
+		if section.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", section.count, section.name))
+		}
+	}
+	return strings.Join(parts, ", ") + " site rules"
+}
+
+// backendRoutes maps hosts to the backend of their [[extraction.sites]]
+// entry; nil when --extract-backend is given
+var backendRoutes map[string]string
+
+// newBackendRoutes checks the [[extraction.sites]] entries and indexes them
+// by host; later entries win, so the main config overrides rule packs
+func newBackendRoutes(sites []config.ExtractionSiteConfig) (map[string]string, error) {
+	routes := make(map[string]string, len(sites))
+	for _, site := range sites {
+		host := strings.TrimPrefix(strings.ToLower(site.Host), "www.")
+		if host == "" {
+			return nil, fmt.Errorf("extraction site rule without host")
+		}
+		switch site.Backend {
+		case "":
+			if site.Wait == "" {
+				return nil, fmt.Errorf("extraction site rule for %s sets neither backend nor wait", site.Host)
+			}
+			continue
+		case "readability", "tavily", "jina":
+		default:
+			return nil, fmt.Errorf("backend %q for %s is not readability, tavily or jina", site.Backend, site.Host)
+		}
+		routes[host] = site.Backend
+	}
+	return routes, nil
+}
+
+// waitRoutes maps hosts to the wait strategy of their [[extraction.sites]]
+// entry; nil when --wait is given
+var waitRoutes map[string]fetcher.WaitStrategy
+
+// newWaitRoutes indexes the wait strategies of the [[extraction.sites]]
+// entries by host, as newBackendRoutes does their backends
+func newWaitRoutes(sites []config.ExtractionSiteConfig) (map[string]fetcher.WaitStrategy, error) {
+	routes := make(map[string]fetcher.WaitStrategy)
+	for _, site := range sites {
+		if site.Wait == "" {
+			continue
+		}
+		wait, err := fetcher.ParseWaitStrategy(site.Wait)
+		if err != nil {
+			return nil, fmt.Errorf("wait for %s: %w", site.Host, err)
+		}
+		routes[strings.TrimPrefix(strings.ToLower(site.Host), "www.")] = wait
+	}
+	return routes, nil
+}
+
+// siteRoute returns the route of the most specific host in routes matching
+// the URL's host, so an entry covers its subdomains
+func siteRoute[T any](routes map[string]T, rawURL string) (T, bool) {
+	host := strings.TrimPrefix(strings.ToLower(urlHost(rawURL)), "www.")
+	for host != "" {
+		if route, ok := routes[host]; ok {
+			return route, true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	var none T
+	return none, false
+}
+
+// backendFor returns the backend routed for the URL's site, or the run's
+// backend
+func (ro *RunOptions) backendFor(rawURL string) string {
+	if backend, ok := siteRoute(ro.BackendRoutes, rawURL); ok {
+		return backend
+	}
+	return ro.Backend
+}
+
+// fetchOptionsFor returns the fetch options of a page: the run's, with the
+// browser cookies and wait strategy of its site
+func (ro *RunOptions) fetchOptionsFor(rawURL string) fetcher.FetchOptions {
+	opts := ro.Fetch
+	opts.Cookies = ro.cookiesFor(rawURL)
+	if wait, ok := siteRoute(ro.WaitRoutes, rawURL); ok {
+		opts.WaitUntil, opts.NetworkIdle = wait.Until, wait.Idle
+	}
+	return opts
+}
+
+// urlHost returns the host name of a URL, "" when it does not parse
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// runScrpr runs the command line in a fresh home with no config file
+func runScrpr(t *testing.T, args ...string) error {
+	t.Helper()
+	return runScrprIn(t, t.TempDir(), args...)
+}
+
+// runScrprIn runs the command line with home as HOME, which need not
+// exist, and the flags back at their defaults
+func runScrprIn(t *testing.T, home string, args ...string) error {
+	t.Helper()
+	t.Setenv("HOME", home)
+	for _, v := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(v, "")
+	}
+	rootCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	rootCmd.SetArgs(append([]string{"--quiet"}, args...))
+	return rootCmd.Execute()
+}
+
+// captureOutput returns what fn prints to out, os.Stdout or os.Stderr
+func captureOutput(t *testing.T, out **os.File, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := *out
+	*out = f
+	defer func() { *out = saved }()
+	fn()
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// articleServer serves an article per path that names it in its text
+func articleServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, selftestPage("Page "+name, "", fmt.Sprintf("<p>Marker %s.</p>\n", name)+selftestParagraphs(3)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun_PriorityKeepsInputOrder(t *testing.T) {
+	server := articleServer(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "urls.txt")
+	lines := []string{server.URL + "/first", server.URL + "/second,priority=5", server.URL + "/third,priority=9"}
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.txt")
+	if err := runScrpr(t, "--no-js", "-f", input, "-o", output); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	first, second, third := strings.Index(text, "Marker first"), strings.Index(text, "Marker second"), strings.Index(text, "Marker third")
+	if first < 0 || second < 0 || third < 0 {
+		t.Fatalf("output lacks a page:\n%s", text)
+	}
+	if first > second || second > third {
+		t.Errorf("output not in input order: first at %d, second at %d, third at %d", first, second, third)
+	}
+}
+
+func TestRun_ConcurrencyBoundsPagesInFlight(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			var mu sync.Mutex
+			inFlight, most := 0, 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				inFlight++
+				most = max(most, inFlight)
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				fmt.Fprint(w, selftestPage("Page "+name, "", fmt.Sprintf("<p>Marker %s.</p>\n", name)+selftestParagraphs(3)))
+			}))
+			t.Cleanup(server.Close)
+
+			var urls []string
+			for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+				urls = append(urls, server.URL+"/"+name)
+			}
+			output := filepath.Join(t.TempDir(), "out.txt")
+			args := append([]string{"--no-js", "--no-adaptive-delay", "-c", fmt.Sprint(workers), "-o", output}, urls...)
+			if err := runScrpr(t, args...); err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+
+			if most != workers {
+				t.Errorf("%d pages in flight at most, want %d", most, workers)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := -1
+			for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+				at := strings.Index(string(data), "Marker "+name+".")
+				if at < last {
+					t.Errorf("Marker %s at %d, not after the page before it at %d", name, at, last)
+				}
+				last = at
+			}
+		})
+	}
+}
+
+func TestRun_WritesNothingToHome(t *testing.T) {
+	server := articleServer(t)
+	home := t.TempDir()
+	if err := runScrprIn(t, home, "--no-js", "-o", filepath.Join(t.TempDir(), "out.txt"), server.URL+"/a"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("run wrote %s to the home directory", e.Name())
+	}
+}
+
+func TestRun_UnwritableHome(t *testing.T) {
+	server := articleServer(t)
+	// Below a regular file nothing can be created, even as root
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "out.txt")
+	if err := runScrprIn(t, filepath.Join(blocker, "home"), "--no-js", "-o", output, server.URL+"/a"); err != nil {
+		t.Fatalf("run failed with an unwritable home: %v", err)
+	}
+	if data, err := os.ReadFile(output); err != nil || !strings.Contains(string(data), "Marker a") {
+		t.Errorf("output = %q, %v; want the page", data, err)
+	}
+}
+
+func TestRun_WatchFlagsShrunkenPage(t *testing.T) {
+	paragraphs := 8
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, selftestPage("Watched", "", selftestParagraphs(paragraphs)))
+	}))
+	t.Cleanup(server.Close)
+	home := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "out") + "/"
+
+	for _, want := range []struct {
+		paragraphs int
+		verdict    string
+	}{{8, "new"}, {8, "unchanged"}, {1, "anomaly"}} {
+		paragraphs = want.paragraphs
+		if err := runScrprIn(t, home, "--no-js", "--watch", "-o", dir, server.URL+"/page"); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m struct {
+			Entries []struct {
+				Watch string `json:"watch"`
+			} `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Entries) != 1 || m.Entries[0].Watch != want.verdict {
+			t.Fatalf("with %d paragraphs, manifest entries = %+v; want watch %q", want.paragraphs, m.Entries, want.verdict)
+		}
+	}
+}
+
+func TestRun_SitemapDeltaFollowsCrawlScope(t *testing.T) {
+	var mu sync.Mutex
+	paths := []string{"/blog/old"}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sitemap.txt" {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range paths {
+				fmt.Fprintln(w, server.URL+p)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, selftestPage("Page", "", fmt.Sprintf("<p>Marker %s.</p>\n", r.URL.Path)+selftestParagraphs(3)))
+	}))
+	t.Cleanup(server.Close)
+
+	home := t.TempDir()
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "[crawl]\npath_prefixes = [\"/blog/\"]\nmax_pages_per_host = 1\n"
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func() string {
+		t.Helper()
+		output := filepath.Join(t.TempDir(), "out.txt")
+		if err := runScrprIn(t, home, "--config", configFile, "--no-js", "-o", output, "--sitemap-delta", server.URL+"/sitemap.txt"); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		data, _ := os.ReadFile(output)
+		return string(data)
+	}
+
+	if out := run(); out != "" {
+		t.Fatalf("first run extracted %q; want it to only record the sitemap", out)
+	}
+	mu.Lock()
+	paths = append(paths, "/tag/go", "/blog/first", "/blog/second")
+	mu.Unlock()
+	for _, want := range []string{"/blog/first", "/blog/second"} {
+		out := run()
+		if !strings.Contains(out, "Marker "+want) || strings.Count(out, "Marker ") != 1 {
+			t.Errorf("run extracted %q; want only %s", out, want)
+		}
+	}
+	if out := run(); out != "" {
+		t.Errorf("last run extracted %q; want nothing in scope left", out)
+	}
+}
+
+func TestRun_TwiceInOneProcess(t *testing.T) {
+	server := articleServer(t)
+	url := server.URL + "/a"
+	names := func(dir string) []string {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	first := filepath.Join(t.TempDir(), "first") + "/"
+	if err := runScrpr(t, "--no-js", "-o", first, url); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	// Nothing of these settings may reach the next run
+	filtered := filepath.Join(t.TempDir(), "filtered") + "/"
+	runScrpr(t, "--no-js", "--ipv6", "--where", `host == "example.com"`, "-o", filtered, url)
+	captureOutput(t, &os.Stdout, func() { runScrpr(t, "--no-js", "--json-envelope", url) })
+	second := filepath.Join(t.TempDir(), "second") + "/"
+	if err := runScrpr(t, "--no-js", "-o", second, url); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+
+	if got, want := names(second), names(first); !slices.Equal(got, want) {
+		t.Errorf("second run wrote %v, want the files of the first %v", got, want)
+	}
+}
+
+func TestRun_SendsBrowserCookiesOfTheSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name+"="+c.Value)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, selftestPage("Page", "", fmt.Sprintf("<p>Cookies [%s].</p>\n", strings.Join(names, " "))+selftestParagraphs(3)))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	cookieFile := filepath.Join(dir, "cookies.txt")
+	cookies := "# Netscape HTTP Cookie File\n" +
+		"127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tfixture\n" +
+		".example.com\tTRUE\t/\tFALSE\t0\tother\tsite\n"
+	if err := os.WriteFile(cookieFile, []byte(cookies), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		args    []string
+		cookies string
+	}{
+		{"off by default", "", nil, "Cookies []"},
+		{"--browser", "", []string{"--browser", "firefox"}, "Cookies [session=fixture]"},
+		{"config", "enabled = true\n", nil, "Cookies [session=fixture]"},
+		{"excluded host", "exclude = [\"127.0.0.1\"]\n", []string{"--browser", "auto"}, "Cookies []"},
+		{"other domains only", "domains = [\"example.com\"]\n", []string{"--browser", "auto"}, "Cookies []"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.toml")
+			config := fmt.Sprintf("[browser.cookies]\nfile = %q\n%s", cookieFile, tt.config)
+			if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(t.TempDir(), "out.txt")
+			args := append([]string{"--config", configFile, "--no-js", "-o", output}, tt.args...)
+			if err := runScrpr(t, append(args, server.URL+"/members")...); err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.cookies) {
+				t.Errorf("page shows %q, want %q", data, tt.cookies)
+			}
+		})
+	}
+}
+
+func TestRun_FailOnNoneStillWritesTheManifest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "gone", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, selftestPage("Page", "", "<p>Marker.</p>\n"+selftestParagraphs(3)))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	err := runScrpr(t, "--no-js", "--fail-on", "none", "--continue-on-error=false", "-c", "1", "-o", dir+"/",
+		server.URL+"/broken", server.URL+"/never")
+	if err != nil {
+		t.Fatalf("run with --fail-on none failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		t.Fatalf("no manifest after the first failure: %v", err)
+	}
+	var m struct{ Entries []manifestEntry }
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 1 || m.Entries[0].Status != "error" {
+		t.Errorf("manifest lists %+v, want only the failed URL", m.Entries)
+	}
+}
+
+func TestRun_FieldsRejectHTML(t *testing.T) {
+	server := articleServer(t)
+	for _, format := range []string{"html", "epub"} {
+		err := runScrpr(t, "--no-js", "--format", format, "--fields", "title", "-o", filepath.Join(t.TempDir(), "out"), server.URL+"/a")
+		var exit *exitErr
+		if !errors.As(err, &exit) || exit.code != ExitInvalidInput {
+			t.Errorf("--format %s --fields: got %v, want an invalid input error", format, err)
+		}
+	}
+}
+
+func TestRun_SnapshotWritesADatedDirectory(t *testing.T) {
+	server := articleServer(t)
+	t.Setenv("SOURCE_DATE_EPOCH", "1748736000") // 2025-06-01
+	tests := []struct {
+		name  string
+		args  []string
+		files []string // below the date directory, in lexical order
+	}{
+		{name: "host and slug", files: []string{"127.0.0.1/a.md", "127.0.0.1/b.md", manifestFile}},
+		{name: "filename template", args: []string{"--filename-template", "{{.Index}}-{{.Slug}}"}, files: []string{"1-a.md", "2-b.md", manifestFile}},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(t.TempDir(), "news") // a snapshot is always a directory
+		args := append([]string{"--no-js", "--deterministic", "--format", "markdown", "--snapshot", "-o", dir}, tt.args...)
+		if err := runScrpr(t, append(args, server.URL+"/a", server.URL+"/b")...); err != nil {
+			t.Fatalf("%s: run failed: %v", tt.name, err)
+		}
+		var files []string
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(filepath.Join(dir, "2025-06-01"), path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return err
+		})
+		if !slices.Equal(files, tt.files) {
+			t.Errorf("%s: wrote %q, want %q", tt.name, files, tt.files)
+		}
+	}
+
+	if err := runScrpr(t, "--no-js", "--snapshot", server.URL+"/a"); err == nil {
+		t.Error("--snapshot without -o was accepted")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"text/template"
+	"time"
+
+	cookies "github.com/byteowlz/scrpr/internal/browser"
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/ocr"
+	"github.com/byteowlz/scrpr/internal/processor"
+	"github.com/byteowlz/scrpr/internal/store"
+)
+
+// RunOptions are the settings and shared resources URLs are extracted with.
+// The flags and config resolve into them once per job; from processURLPaced
+// down the pipeline reads only them, so jobs with different options can run
+// side by side in one process. Logging, the audit log and Chrome stay shared
+// by the process, as does the address family of --ipv4/--ipv6, which
+// each run sets again.
+type RunOptions struct {
+	Config *config.Config
+
+	// Fetch is copied for each page; its Mode is the render mode
+	Fetch          fetcher.FetchOptions
+	HTTP           *fetcher.SimpleFetcher // shared so hosts reuse connections
+	Timeout        time.Duration          // of a page's fetches or API call
+	ProcessTimeout time.Duration
+	HostHealth     *fetcher.HostHealth      // nil = no adaptive delay
+	Cookies        *cookies.CookieExtractor // nil = no browser cookies
+	StdinHTML      []byte                   // stands in for the fetch of the job's URL
+
+	RawStore      *store.RawStore      // --save-raw
+	RawSource     *store.RawStore      // --from-raw
+	ResponseCache *store.ResponseCache // nil = not caching pages
+	APICache      *lazyCache           // nil = not caching API results
+	RefreshCache  bool
+	RobotsPolicy  string
+
+	Backend       string                          // "" = readability, falling back to Jina
+	BackendRoutes map[string]string               // host -> backend of [[extraction.sites]]
+	WaitRoutes    map[string]fetcher.WaitStrategy // host -> wait of [[extraction.sites]]
+	Junk          *processor.JunkFilter
+	SiteConfigs   *processor.SiteConfigs
+	Language      string
+	OCR           *ocr.Engine // nil = no OCR
+	OCRMinText    int
+	Transcripts   bool
+	DebugDir      string // --debug-extraction
+
+	Format          string
+	Names           *fileNamer         // files of directory and archive output
+	Template        *template.Template // --template, replacing the format's layout
+	Fields          []string           // --fields, in the order requested
+	Flavor          processor.MarkdownFlavor
+	TOC             bool
+	FrontMatter     bool
+	IncludeMetadata bool // --include-metadata: metadata in the content
+	CollectMetadata bool // metadata is extracted for the output to use
+	AllMetadata     bool // every field, for JSON, templates, --fields and envelopes
+	MarkdownBody    bool // --fields lays out title and metadata itself
+	ContentLimit    int  // characters (0 = unlimited)
+	EmbedImages     bool
+	EmbedMaxImage   int // bytes
+	EmbedMaxTotal   int // bytes per article
+}
+
+// newRunOptions collects what runURLs resolved from flags and config
+func newRunOptions(cfg *config.Config, names *fileNamer) *RunOptions {
+	// --user-agent sends one agent; the browser agent picks from pools
+	browser := cmp.Or(browserAgent, cfg.Network.BrowserAgent)
+	if userAgent != "" {
+		browser = ""
+	}
+	fullMetadata := outputFormat == "json" || outputTemplate != nil || len(outputFields) > 0 || envelope != nil
+
+	return &RunOptions{
+		Config: cfg,
+		Fetch: fetcher.FetchOptions{
+			Mode:            renderMode,
+			Timeout:         time.Duration(timeout) * time.Second,
+			RenderTimeout:   time.Duration(jsTimeout) * time.Second,
+			UserAgent:       userAgent,
+			BrowserAgent:    browser,
+			Device:          mobileDevice,
+			Viewport:        viewport,
+			AcceptLanguage:  langHeader,
+			AcceptEncoding:  cfg.Network.AcceptEncoding,
+			Referer:         referer,
+			Timezone:        timezoneID,
+			PrintMedia:      printMedia,
+			Stealth:         stealth,
+			Scroll:          scrollConfig,
+			Scripts:         pageScripts,
+			WaitForSelector: waitForSelector,
+			WaitForText:     waitForText,
+			WaitUntil:       waitStrategy.Until,
+			NetworkIdle:     waitStrategy.Idle,
+			SkipBanners:     skipBanners,
+			BannerTimeout:   time.Duration(cfg.Extraction.BannerTimeout) * time.Second,
+			Banners:         bannerRules,
+			Expand:          expandRules,
+			Format:          outputFormat,
+			MaxResponseSize: maxResponseSize(),
+			Method:          requestMethodFlag,
+			Body:            requestBody,
+			ContentType:     requestContentType,
+		},
+		HTTP:           httpFetcher,
+		Timeout:        time.Duration(timeout) * time.Second,
+		ProcessTimeout: time.Duration(processTimeout) * time.Second,
+		HostHealth:     hostHealth,
+		Cookies:        browserCookies,
+		StdinHTML:      stdinHTML,
+
+		RawStore:      rawStore,
+		RawSource:     rawSource,
+		ResponseCache: responseCache,
+		APICache:      apiCache,
+		RefreshCache:  refreshCache,
+		RobotsPolicy:  robotsPolicy,
+
+		Backend:       extractBackend,
+		BackendRoutes: backendRoutes,
+		WaitRoutes:    waitRoutes,
+		Junk:          junkFilter,
+		SiteConfigs:   siteConfigs,
+		Language:      textLanguage,
+		OCR:           ocrEngine,
+		OCRMinText:    ocrMinText,
+		Transcripts:   !noTranscripts,
+		DebugDir:      debugExtractionDir,
+
+		Format:          outputFormat,
+		Names:           names,
+		Template:        outputTemplate,
+		Fields:          outputFields,
+		Flavor:          markdownFlavor,
+		TOC:             tableOfContents,
+		FrontMatter:     frontMatter,
+		IncludeMetadata: includeMetadata,
+		CollectMetadata: includeMetadata || fullMetadata || names.template != nil || whereFilter != nil,
+		AllMetadata:     fullMetadata,
+		MarkdownBody:    len(outputFields) > 0,
+		ContentLimit:    contentLimit,
+		EmbedImages:     embedImages,
+		EmbedMaxImage:   embedMaxImageKB << 10,
+		EmbedMaxTotal:   embedMaxTotalKB << 10,
+	}
+}
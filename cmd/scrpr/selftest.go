@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Extract fixture pages from a local server to check the install",
+	Long: `selftest serves fixture pages from a local server and runs each through the
+full pipeline, as scrpr would fetch them from the web: a static article, a
+redirect, a paywalled page, a page in Latin-1, a large page and an app shell
+built by JavaScript, which needs Chrome. Nothing leaves the machine and the
+config file is not read, so a failure points at the install.`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
+
+// selftestPhrase is in the article of every fixture page
+const selftestPhrase = "The quick brown fox jumps over the lazy dog"
+
+// selftestHugeParagraphs makes the large fixture about 3 MB, under the
+// default download limit
+const selftestHugeParagraphs = 6000
+
+// selftestCase is one capability checked against a fixture path
+type selftestCase struct {
+	name  string
+	path  string
+	js    bool // rendered in Chrome
+	check func(*ProcessResult) error
+}
+
+var selftestCases = []selftestCase{
+	{name: "static page", path: "/article", check: func(r *ProcessResult) error {
+		if r.Title != "Selftest Article" {
+			return fmt.Errorf("title %q, want %q", r.Title, "Selftest Article")
+		}
+		return wantContent(r, selftestPhrase)
+	}},
+	{name: "redirect", path: "/moved", check: func(r *ProcessResult) error {
+		if len(r.Redirects) != 1 || !strings.HasSuffix(r.FinalURL, "/article") {
+			return fmt.Errorf("ended at %s after %d redirects, want /article after 1", r.FinalURL, len(r.Redirects))
+		}
+		return wantContent(r, selftestPhrase)
+	}},
+	{name: "paywall", path: "/paywall", check: func(r *ProcessResult) error {
+		if !r.Paywalled {
+			return fmt.Errorf("isAccessibleForFree=false not detected")
+		}
+		return wantContent(r, selftestPhrase)
+	}},
+	{name: "non-UTF-8", path: "/latin1", check: func(r *ProcessResult) error {
+		return wantContent(r, "Schöne Grüße aus München")
+	}},
+	{name: "large page", path: "/huge", check: func(r *ProcessResult) error {
+		if n := strings.Count(r.Content, selftestPhrase); n < selftestHugeParagraphs {
+			return fmt.Errorf("%d of %d paragraphs extracted", n, selftestHugeParagraphs)
+		}
+		return nil
+	}},
+	{name: "JS app shell", path: "/shell", js: true, check: func(r *ProcessResult) error {
+		if !r.UsedJS {
+			return fmt.Errorf("page was not rendered")
+		}
+		return wantContent(r, selftestPhrase)
+	}},
+}
+
+func wantContent(r *ProcessResult, phrase string) error {
+	if !strings.Contains(r.Content, phrase) {
+		return fmt.Errorf("content lacks %q", phrase)
+	}
+	return nil
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	server := httptest.NewServer(selftestHandler())
+	defer server.Close()
+	// Chrome is shared by the process; it starts on the first rendered page
+	uaSelector = fetcher.NewUserAgentSelector()
+	defer stopRenderer()
+
+	var checks []doctorCheck
+	for _, c := range selftestCases {
+		checks = append(checks, runSelftestCase(c, server.URL))
+	}
+	if failed := printChecks(checks); failed > 0 {
+		return exitError(ExitProcessError, "%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func runSelftestCase(c selftestCase, base string) doctorCheck {
+	mode := fetcher.FetchModeStatic
+	if c.js {
+		if _, ok := findChrome(); !ok {
+			return doctorCheck{c.name, checkWarn, "skipped, Chrome not found", "install Chrome or Chromium to use --js"}
+		}
+		mode = fetcher.FetchModeJS
+	}
+	start := time.Now()
+	result, err := processURL(base+c.path, selftestOptions(mode))
+	if err == nil {
+		err = c.check(result)
+	}
+	if err != nil {
+		return doctorCheck{c.name, checkFail, err.Error(), ""}
+	}
+	return doctorCheck{c.name, checkOK, time.Since(start).Round(time.Millisecond).String(), ""}
+}
+
+// selftestOptions runs the pipeline on the defaults, without caches, stores
+// or the user's config
+func selftestOptions(mode fetcher.FetchMode) *RunOptions {
+	cfg := config.Default()
+	timeouts := fetcher.Timeouts{
+		Connect:        time.Duration(cfg.Network.ConnectTimeout) * time.Second,
+		TLSHandshake:   time.Duration(cfg.Network.TLSHandshakeTimeout) * time.Second,
+		ResponseHeader: time.Duration(cfg.Network.ResponseHeaderTimeout) * time.Second,
+		Total:          time.Duration(cfg.Network.Timeout) * time.Second,
+		Render:         time.Duration(cfg.Extraction.JSTimeout) * time.Second,
+	}
+	sf := fetcher.NewSimpleFetcher()
+	sf.SetTimeouts(timeouts)
+	return &RunOptions{
+		Config: cfg,
+		Fetch: fetcher.FetchOptions{
+			Mode:            mode,
+			Timeout:         timeouts.Total,
+			RenderTimeout:   timeouts.Render,
+			BrowserAgent:    cfg.Network.BrowserAgent,
+			AcceptEncoding:  cfg.Network.AcceptEncoding,
+			Format:          "text",
+			MaxResponseSize: int64(cfg.Network.MaxDownloadSizeMB) << 20,
+			Method:          http.MethodGet,
+		},
+		HTTP:            sf,
+		Timeout:         timeouts.Total,
+		ProcessTimeout:  time.Duration(cfg.Extraction.ProcessTimeout) * time.Second,
+		RobotsPolicy:    robotsIgnore,
+		Format:          "text",
+		Flavor:          processor.FlavorGFM,
+		CollectMetadata: true,
+		AllMetadata:     true,
+	}
+}
+
+// selftestHandler serves the fixture pages
+func selftestHandler() http.Handler {
+	mux := http.NewServeMux()
+	article := selftestPage("Selftest Article", "", selftestParagraphs(3))
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		serveHTML(w, "text/html; charset=utf-8", article)
+	})
+	mux.Handle("/moved", http.RedirectHandler("/article", http.StatusMovedPermanently))
+	paywall := selftestPage("Members Only", `<script type="application/ld+json">{"@context":"https://schema.org","@type":"NewsArticle","isAccessibleForFree":false}</script>`, selftestParagraphs(3))
+	mux.HandleFunc("/paywall", func(w http.ResponseWriter, r *http.Request) {
+		serveHTML(w, "text/html; charset=utf-8", paywall)
+	})
+	latin1 := latin1Bytes(selftestPage("Grüße", "", "<p>Schöne Grüße aus München.</p>\n"+selftestParagraphs(3)))
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		serveHTML(w, "text/html; charset=iso-8859-1", string(latin1))
+	})
+	huge := selftestPage("A Long Read", "", selftestParagraphs(selftestHugeParagraphs))
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		serveHTML(w, "text/html; charset=utf-8", huge)
+	})
+	// An empty shell whose article a script builds, as single-page apps do
+	shell := `<!DOCTYPE html><html><head><title>App Shell</title></head><body><div id="root"></div><script>
+document.getElementById("root").innerHTML = "<article><h1>App Shell</h1>" + ` + fmt.Sprintf("%q", selftestParagraphs(3)) + ` + "</article>";
+</script></body></html>`
+	mux.HandleFunc("/shell", func(w http.ResponseWriter, r *http.Request) {
+		serveHTML(w, "text/html; charset=utf-8", shell)
+	})
+	return mux
+}
+
+func selftestPage(title, head, body string) string {
+	return fmt.Sprintf(`<!DOCTYPE html><html><head><title>%s</title>%s</head>
+<body><nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article><h1>%s</h1>
+%s
+</article><footer>Copyright Selftest</footer></body></html>`, title, head, title, body)
+}
+
+func selftestParagraphs(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "<p>%s, paragraph %d of the fixture, which is long enough for the extractor to take it for the body of an article rather than boilerplate.</p>\n", selftestPhrase, i+1)
+	}
+	return b.String()
+}
+
+// latin1Bytes encodes s, which must be Latin-1 text, as ISO-8859-1
+func latin1Bytes(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+func serveHTML(w http.ResponseWriter, contentType, body string) {
+	w.Header().Set("Content-Type", contentType)
+	fmt.Fprint(w, body)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/crawl"
+	"github.com/byteowlz/scrpr/internal/sitemap"
+)
+
+// maxChildSitemaps bounds the sitemaps of an index read in one run
+const maxChildSitemaps = 200
+
+// sitemapDelta follows one sitemap given with --sitemap-delta
+type sitemapDelta struct {
+	url   string
+	state *sitemap.State
+	fresh []string // URLs new in this run
+}
+
+var (
+	sitemapDeltas   []*sitemapDelta
+	sitemapOutcomes = make(map[string]bool) // URL -> handled; false = failed
+)
+
+// sitemapStateDir is where the sitemap states are kept
+func sitemapStateDir() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "sitemaps"), nil
+}
+
+// collectSitemapURLs reads the sitemaps and returns the URLs they gained
+// since the last run, plus the new ones earlier runs could not extract. The
+// first run of a sitemap only records what it lists. New URLs go through
+// the [crawl] scope seeded with the sitemap's host: those out of scope are
+// left out, and those past the page limit wait for the next run.
+func collectSitemapURLs(ctx context.Context, sources []string, rules crawl.ScopeRules) ([]string, error) {
+	dir, err := sitemapStateDir()
+	if err != nil {
+		return nil, err
+	}
+	client := httpClient("sitemap")
+	var urls []string
+	for _, source := range sources {
+		if !isValidURL(source) {
+			return nil, fmt.Errorf("invalid sitemap URL %q", source)
+		}
+		state, err := sitemap.LoadState(sitemap.StatePath(dir, source), source)
+		if err != nil {
+			return nil, err
+		}
+		listed, err := readSitemap(ctx, client, source, state, 0)
+		if err != nil {
+			return nil, err
+		}
+		if state.First() {
+			now := time.Now()
+			for _, u := range listed {
+				state.Done(u, now)
+			}
+			if err := state.Save(now); err != nil {
+				return nil, err
+			}
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Recorded %d URLs of %s; later runs extract the URLs it adds\n", len(listed), source)
+			}
+			continue
+		}
+		scope, err := crawl.NewScope([]string{source}, rules)
+		if err != nil {
+			return nil, err
+		}
+		d := &sitemapDelta{url: source, state: state}
+		outside := 0
+		for _, u := range state.New(listed) {
+			if ok, _ := scope.Match(u); !ok {
+				outside++
+			} else if ok, _ := scope.Admit(u); ok {
+				d.fresh = append(d.fresh, u)
+			} else {
+				state.Defer(u)
+			}
+		}
+		if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "%s: %d new URLs, %d out of crawl scope\n", source, len(d.fresh), outside)
+		}
+		sitemapDeltas = append(sitemapDeltas, d)
+		urls = append(urls, d.fresh...)
+	}
+	return urls, nil
+}
+
+// readSitemap returns the URLs of a sitemap. The children of an index are
+// read when their lastmod changed since the last run; the URLs of the
+// others are known already.
+func readSitemap(ctx context.Context, client *http.Client, source string, state *sitemap.State, depth int) ([]string, error) {
+	data, _, err := downloadDocument(ctx, client, source, sitemap.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap: %w", err)
+	}
+	sm, err := sitemap.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	var urls []string
+	for _, e := range sm.URLs {
+		urls = append(urls, e.Loc)
+	}
+	if depth > 0 {
+		return urls, nil // indexes of indexes are not followed further
+	}
+	for i, child := range sm.Sitemaps {
+		if i == maxChildSitemaps {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: %s lists %d sitemaps; reading the first %d\n", source, len(sm.Sitemaps), maxChildSitemaps)
+			}
+			break
+		}
+		if !state.ChildChanged(child.Loc, child.LastMod) {
+			continue
+		}
+		childURLs, err := readSitemap(ctx, client, child.Loc, state, depth+1)
+		if err != nil {
+			// The next run tries again, as the lastmod is not recorded
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			continue
+		}
+		state.ReadChild(child.Loc, child.LastMod)
+		urls = append(urls, childURLs...)
+	}
+	return urls, nil
+}
+
+// markSitemapURL records how a URL of the run went; handled covers results
+// and skips, which need no retry
+func markSitemapURL(u string, handled bool) {
+	if len(sitemapDeltas) > 0 {
+		sitemapOutcomes[u] = handled
+	}
+}
+
+// saveSitemapDeltas records the URLs handled in the run, so the next run
+// leaves them out. Failed ones are retried in the next runs, and those the
+// run did not get to, such as when a budget ran out, in the next run.
+func saveSitemapDeltas() {
+	now := time.Now()
+	for _, d := range sitemapDeltas {
+		for _, u := range d.fresh {
+			handled, tried := sitemapOutcomes[u]
+			switch {
+			case !tried:
+				d.state.Defer(u)
+			case handled:
+				d.state.Done(u, now)
+			default:
+				d.state.Failed(u, now)
+			}
+		}
+		if err := d.state.Save(now); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// Skip reasons: why a URL produced no output on purpose. Skips are reported
+// apart from failures, in the manifest as status "skipped" and in the
+// envelope as status "skipped", and never count towards --fail-on.
+const (
+	skipNonHTML  = "non_html" // the response was not a document
+	skipBudget   = "budget"   // --max-requests or --max-duration ran out first
+	skipFiltered = "filtered" // the result did not match --where
+	skipRobots   = "robots"   // noindex or noarchive with --robots-meta respect
+)
+
+// skipReason returns the reason err is an intentional skip rather than a
+// failure, or "" when it is a genuine failure
+func skipReason(err error) string {
+	var unsupported *fetcher.UnsupportedContentError
+	if errors.As(err, &unsupported) {
+		return skipNonHTML
+	}
+	var filtered *FilteredError
+	if errors.As(err, &filtered) {
+		return skipFiltered
+	}
+	var robots *RobotsError
+	if errors.As(err, &robots) {
+		return skipRobots
+	}
+	return ""
+}
+
+// skipTally counts the skipped URLs of a run by reason
+type skipTally map[string]int
+
+// report prints the skip counts after a batch, e.g.
+// "Skipped 3 of 20 URLs (non_html: 2, budget: 1)"
+func (t skipTally) report(total int) {
+	if quiet || total < 2 || len(t) == 0 {
+		return
+	}
+	n := 0
+	var parts []string
+	for reason, count := range t {
+		n += count
+		parts = append(parts, fmt.Sprintf("%s: %d", reason, count))
+	}
+	slices.Sort(parts)
+	fmt.Fprintf(os.Stderr, "Skipped %d of %d URLs (%s)\n", n, total, strings.Join(parts, ", "))
+}
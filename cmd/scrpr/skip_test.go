@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+func TestSkipReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"non-html", &fetcher.UnsupportedContentError{ContentType: "application/pdf", Size: -1}, skipNonHTML},
+		{"wrapped non-html", fmt.Errorf("fetch: %w", &fetcher.UnsupportedContentError{ContentType: "image/png"}), skipNonHTML},
+		{"filtered", &FilteredError{Where: `host == "example.com"`}, skipFiltered},
+		{"robots", fmt.Errorf("page: %w", &RobotsError{Directives: "noindex"}), skipRobots},
+		{"failure", errors.New("HTTP error: 500"), ""},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := skipReason(tt.err); got != tt.want {
+			t.Errorf("%s: skipReason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipTally_Report(t *testing.T) {
+	defer func(saved bool) { quiet = saved }(quiet)
+
+	tests := []struct {
+		name  string
+		tally skipTally
+		total int
+		quiet bool
+		want  string
+	}{
+		{"counts by reason", skipTally{skipNonHTML: 2, skipBudget: 1}, 20, false, "Skipped 3 of 20 URLs (budget: 1, non_html: 2)\n"},
+		{"nothing skipped", skipTally{}, 20, false, ""},
+		{"single url", skipTally{skipRobots: 1}, 1, false, ""},
+		{"quiet", skipTally{skipFiltered: 4}, 5, true, ""},
+	}
+	for _, tt := range tests {
+		quiet = tt.quiet
+		got := captureOutput(t, &os.Stderr, func() { tt.tally.report(tt.total) })
+		if got != tt.want {
+			t.Errorf("%s: report printed %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
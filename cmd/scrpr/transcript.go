@@ -0,0 +1,170 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/byteowlz/scrpr/internal/podcast"
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+const (
+	// maxFeeds bounds the feeds of a page searched for its episode
+	maxFeeds = 2
+	// maxFeedSize bounds a downloaded podcast feed; feeds of long-running
+	// shows list every episode
+	maxFeedSize = 20 << 20
+	// maxTranscriptSize bounds a downloaded transcript
+	maxTranscriptSize = 5 << 20
+)
+
+// feedCache keeps the feeds read during the run, as a batch of episodes of
+// one show would otherwise download its feed for each
+var feedCache = struct {
+	sync.Mutex
+	feeds map[string]*podcast.Feed
+}{feeds: make(map[string]*podcast.Feed)}
+
+// applyTranscript appends the transcript of a podcast episode page to its
+// content. A page is taken for an episode when it plays audio and announces
+// an RSS feed; the feed item of the page names the transcript with
+// <podcast:transcript>. Pages without one are left alone, and failures only
+// warn, as the page itself was extracted.
+func applyTranscript(ctx context.Context, processed *processor.ProcessedContent, pageHTML, pageURL string) {
+	if u, err := url.Parse(pageURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	audio := podcast.AudioLinks(pageHTML, pageURL)
+	for _, m := range processed.Media {
+		if m.Type == "audio" {
+			audio = append(audio, m.URL)
+		}
+	}
+	if len(audio) == 0 {
+		return
+	}
+	feeds := podcast.FeedLinks(pageHTML, pageURL)
+	if len(feeds) > maxFeeds {
+		feeds = feeds[:maxFeeds]
+	}
+
+	client := httpClient("podcast")
+	for _, feedURL := range feeds {
+		feed, err := readFeed(ctx, client, feedURL)
+		if err != nil {
+			warnTranscript(pageURL, err)
+			continue
+		}
+		episode := feed.Episode(pageURL, audio)
+		if episode == nil {
+			continue
+		}
+		transcript := episode.Transcript()
+		if transcript == nil {
+			return
+		}
+		transcriptURL := transcript.URL
+		if ref, err := url.Parse(strings.TrimSpace(transcriptURL)); err == nil {
+			if base, err := url.Parse(feedURL); err == nil {
+				transcriptURL = base.ResolveReference(ref).String()
+			}
+		}
+		data, contentType, err := downloadDocument(ctx, client, transcriptURL, maxTranscriptSize)
+		if err != nil {
+			warnTranscript(pageURL, err)
+			return
+		}
+		// The type the feed declares is the format; servers often send
+		// transcripts as text/plain or octet-stream
+		text, err := podcast.Text(data, cmp.Or(strings.TrimSpace(transcript.Type), contentType))
+		if err != nil {
+			warnTranscript(pageURL, err)
+			return
+		}
+		if text == "" {
+			return
+		}
+		if verbose && !quiet {
+			fmt.Fprintf(os.Stderr, "Transcript of %s from %s\n", pageURL, transcriptURL)
+		}
+		processed.TextContent = strings.TrimSpace(processed.TextContent + "\n\nTranscript\n\n" + text)
+		processed.Content += transcriptHTML(text)
+		processed.Length = utf8.RuneCountInString(processed.TextContent)
+		processed.Metadata["transcript"] = transcriptURL
+		return
+	}
+}
+
+// readFeed returns a podcast feed, from feedCache when it was read before
+func readFeed(ctx context.Context, client *http.Client, feedURL string) (*podcast.Feed, error) {
+	feedCache.Lock()
+	feed, ok := feedCache.feeds[feedURL]
+	feedCache.Unlock()
+	if ok {
+		return feed, nil
+	}
+	data, _, err := downloadDocument(ctx, client, feedURL, maxFeedSize)
+	if err != nil {
+		return nil, err
+	}
+	if feed, err = podcast.ParseFeed(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", feedURL, err)
+	}
+	feedCache.Lock()
+	feedCache.feeds[feedURL] = feed
+	feedCache.Unlock()
+	return feed, nil
+}
+
+func warnTranscript(pageURL string, err error) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: transcript of %s: %v\n", pageURL, err)
+	}
+}
+
+// transcriptHTML lays out a transcript as a section of paragraphs, so every
+// output format carries it
+func transcriptHTML(text string) string {
+	var b strings.Builder
+	b.WriteString(`<section class="transcript"><h2>Transcript</h2>`)
+	for _, para := range strings.Split(text, "\n\n") {
+		b.WriteString("<p>" + html.EscapeString(para) + "</p>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+// downloadDocument fetches a feed or transcript, up to maxSize bytes, and
+// returns it with its Content-Type
+func downloadDocument(ctx context.Context, client *http.Client, rawURL string, maxSize int) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "scrpr/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s: HTTP %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxSize {
+		return nil, "", fmt.Errorf("%s exceeds %d bytes", rawURL, maxSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
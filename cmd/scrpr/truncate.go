@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+// contentLimit is the character budget from --max-content-chars and
+// --max-content-tokens, whichever is tighter (0 = unlimited)
+var contentLimit int
+
+func contentCharLimit(chars, tokens int) int {
+	limit := chars
+	if tokens > 0 {
+		if byTokens := processor.TokensToChars(tokens); limit == 0 || byTokens < limit {
+			limit = byTokens
+		}
+	}
+	return limit
+}
+
+// truncateResult cuts the content of the format to limit characters, before
+// images are embedded, and records the original size in the metadata
+func truncateResult(result *ProcessResult, limit int, format string) {
+	if limit <= 0 {
+		return
+	}
+	original := result.Content
+	var cut bool
+	if format == "html" {
+		result.Content, cut = processor.TruncateHTML(original, limit)
+	} else {
+		result.Content, cut = processor.Truncate(original, limit)
+	}
+	if !cut {
+		return
+	}
+	result.Text, _ = processor.Truncate(result.Text, limit)
+
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["truncated"] = "true"
+	result.Metadata["original_length"] = strconv.Itoa(utf8.RuneCountInString(original))
+	result.Metadata["original_tokens"] = strconv.Itoa(processor.EstimateTokens(original))
+}
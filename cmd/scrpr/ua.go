@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/fetcher"
+)
+
+// maxUADatasetSize bounds the download in `scrpr ua update`
+const maxUADatasetSize = 2 << 20
+
+var uaUpdateURL string
+
+var uaCmd = &cobra.Command{
+	Use:   "ua",
+	Short: "Manage the user agent pool",
+}
+
+var uaUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download the curated user agent list into the data directory",
+	Args:  cobra.NoArgs,
+	RunE:  runUAUpdate,
+}
+
+func init() {
+	uaUpdateCmd.Flags().StringVar(&uaUpdateURL, "url", "", "dataset URL (default: network.user_agent_update_url)")
+	uaCmd.AddCommand(uaUpdateCmd)
+	rootCmd.AddCommand(uaCmd)
+}
+
+func runUAUpdate(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return exitError(ExitConfigError, "failed to load config: %v", err)
+	}
+
+	source := uaUpdateURL
+	if source == "" {
+		source = cfg.Network.UserAgentUpdateURL
+	}
+	if source == "" {
+		source = config.DefaultUserAgentUpdateURL
+	}
+
+	data, err := downloadUADataset(source)
+	if err != nil {
+		return exitError(ExitNetworkError, "%v", err)
+	}
+	ds, err := fetcher.ParseUserAgentDataset(data)
+	if err != nil {
+		return exitError(ExitProcessError, "%s: %v", source, err)
+	}
+
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return exitError(ExitFileIOError, "%v", err)
+	}
+	path := filepath.Join(dataDir, fetcher.UserAgentDatasetFile)
+	if err := writeFileAtomic(path, data); err != nil {
+		return exitError(ExitFileIOError, "failed to save user agent dataset: %v", err)
+	}
+
+	if !quiet {
+		families := make([]string, 0, len(ds.Agents))
+		for family, agents := range ds.Agents {
+			families = append(families, fmt.Sprintf("%s: %d", family, len(agents)))
+		}
+		sort.Strings(families)
+		fmt.Fprintf(os.Stderr, "Updated user agent pool (%s) from %s\n", strings.Join(families, ", "), source)
+		if ds.Updated != "" {
+			fmt.Fprintf(os.Stderr, "Dataset date: %s\n", ds.Updated)
+		}
+		fmt.Fprintf(os.Stderr, "Saved to %s\n", path)
+	}
+	return nil
+}
+
+func downloadUADataset(source string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dataset URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "scrpr/"+version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download user agent dataset: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download user agent dataset: HTTP %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUADatasetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user agent dataset: %w", err)
+	}
+	if len(data) > maxUADatasetSize {
+		return nil, fmt.Errorf("user agent dataset exceeds %d bytes", maxUADatasetSize)
+	}
+	return data, nil
+}
+
+// writeFileAtomic replaces path via a temp file so readers never see a
+// partial dataset
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
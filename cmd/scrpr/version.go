@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+var versionJSON bool
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Args:  cobra.NoArgs,
+	RunE:  runVersion,
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "print build information as JSON")
+	rootCmd.AddCommand(versionCmd)
+}
+
+// buildInfo describes the installed binary for scripts checking capabilities
+type buildInfo struct {
+	Version   string       `json:"version"`
+	Commit    string       `json:"commit,omitempty"`
+	BuildDate string       `json:"build_date,omitempty"`
+	GoVersion string       `json:"go_version"`
+	Platform  string       `json:"platform"`
+	Backends  []string     `json:"backends"`
+	Formats   []string     `json:"formats"`
+	Chromium  chromiumInfo `json:"chromium"`
+}
+
+type chromiumInfo struct {
+	Available bool   `json:"available"`
+	Path      string `json:"path,omitempty"`
+}
+
+func newBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Backends:  []string{"readability", "tavily", "jina"},
+		Formats:   []string{"text", "markdown", "json", "html", "epub"},
+	}
+
+	// Fall back to the VCS stamp of `go build` when not set by the release
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+
+	info.Chromium.Path, info.Chromium.Available = findChrome()
+	return info
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	info := newBuildInfo()
+	if versionJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Printf("scrpr %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("commit:    %s\n", info.Commit)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("built:     %s\n", info.BuildDate)
+	}
+	fmt.Printf("go:        %s %s\n", info.GoVersion, info.Platform)
+	chromium := "not found"
+	if info.Chromium.Available {
+		chromium = info.Chromium.Path
+	}
+	fmt.Printf("chromium:  %s\n", chromium)
+	return nil
+}
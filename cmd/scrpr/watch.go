@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/byteowlz/scrpr/internal/config"
+	"github.com/byteowlz/scrpr/internal/watch"
+)
+
+// watchHistoryFile keeps the extracted lengths of earlier --watch runs,
+// below the data directory
+const watchHistoryFile = "watch.json"
+
+// watchRun compares the results of a --watch run with earlier runs
+type watchRun struct {
+	history    *watch.History
+	thresholds watch.Thresholds
+}
+
+func watchHistoryPath() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, watchHistoryFile), nil
+}
+
+// openWatchRun loads the history of earlier runs, with the thresholds of
+// the [watch] section
+func openWatchRun(cfg *config.Config) (*watchRun, error) {
+	thresholds := watch.Thresholds(cfg.Watch)
+	if err := thresholds.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid watch config: %w", err)
+	}
+	path, err := watchHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	history, err := watch.LoadHistory(path, thresholds.Window)
+	if err != nil {
+		return nil, err
+	}
+	return &watchRun{history: history, thresholds: thresholds}, nil
+}
+
+// check classifies a result against the earlier runs of its URL and warns
+// of a length anomaly; a nil watchRun checks nothing
+func (w *watchRun) check(url string, result *ProcessResult) {
+	if w == nil {
+		return
+	}
+	r := w.history.Check(url, result.Text, w.thresholds)
+	result.Watch = string(r.Verdict)
+	switch {
+	case quiet:
+	case r.Verdict == watch.Anomaly:
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", url, r)
+	case verbose:
+		fmt.Fprintf(os.Stderr, "Watch: %s: %s\n", url, r)
+	}
+}
+
+// save keeps the run for the next ones; failing only costs that memory
+func (w *watchRun) save() {
+	if w == nil {
+		return
+	}
+	if err := w.history.Save(); err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
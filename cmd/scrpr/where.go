@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/byteowlz/scrpr/internal/expr"
+	"github.com/byteowlz/scrpr/internal/processor"
+)
+
+// whereFilter is the compiled --where expression (nil = emit every result)
+var whereFilter *expr.Expr
+
+// whereVars are the names a --where expression can use
+var whereVars = map[string]expr.Type{
+	"url":         expr.String,
+	"final_url":   expr.String,
+	"host":        expr.String,
+	"title":       expr.String,
+	"author":      expr.String,
+	"date":        expr.String,
+	"description": expr.String,
+	"site_name":   expr.String,
+	"lang":        expr.String, // declared or detected language, e.g. "en"
+	"backend":     expr.String,
+	"length":      expr.Number, // characters of plain text
+	"words":       expr.Number,
+	"links":       expr.Number,
+	"images":      expr.Number,
+	"paywalled":   expr.Bool, // marked as not free to read (schema.org isAccessibleForFree)
+	"used_js":     expr.Bool,
+	"truncated":   expr.Bool, // cut by --max-content-chars or --max-content-tokens
+}
+
+// FilteredError reports a result that did not match --where; it is skipped,
+// not failed
+type FilteredError struct {
+	Where string
+}
+
+func (e *FilteredError) Error() string {
+	return fmt.Sprintf("does not match --where %q", e.Where)
+}
+
+// compileWhere parses a --where expression
+func compileWhere(src string) (*expr.Expr, error) {
+	e, err := expr.Compile(src, whereVars)
+	if err != nil {
+		names := slices.Sorted(maps.Keys(whereVars))
+		return nil, fmt.Errorf("invalid --where: %v (names: %s)", err, strings.Join(names, ", "))
+	}
+	return e, nil
+}
+
+// checkWhere returns a *FilteredError when result does not match --where
+func checkWhere(result *ProcessResult) error {
+	if whereFilter == nil || whereFilter.Eval(whereEnv(result)) {
+		return nil
+	}
+	return &FilteredError{Where: whereFilter.String()}
+}
+
+// whereEnv is the values of whereVars for result
+func whereEnv(result *ProcessResult) map[string]any {
+	meta := result.Metadata
+	lang := result.Language
+	if lang == "" {
+		// API backends leave it to be guessed from the text
+		lang = processor.DetectLanguage("", result.Text)
+	}
+	author := result.Author
+	if author == "" {
+		author = meta["author"]
+	}
+	description := meta["description"]
+	if description == "" {
+		description = result.Excerpt
+	}
+	return map[string]any{
+		"url":         result.URL,
+		"final_url":   result.FinalURL,
+		"host":        urlHost(result.URL),
+		"title":       result.Title,
+		"author":      author,
+		"date":        meta["date"],
+		"description": description,
+		"site_name":   meta["site_name"],
+		"lang":        lang,
+		"backend":     result.Backend,
+		"length":      utf8.RuneCountInString(result.Text),
+		"words":       len(strings.Fields(result.Text)),
+		"links":       len(result.Links),
+		"images":      len(result.Images),
+		"paywalled":   result.Paywalled,
+		"used_js":     result.UsedJS,
+		"truncated":   meta["truncated"] == "true",
+	}
+}
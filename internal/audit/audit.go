@@ -0,0 +1,159 @@
+// Package audit keeps a tamper-evident record of the requests a run makes.
+// Every entry is a JSON line carrying the SHA-256 hash of the line before
+// it and its own, so editing, dropping or reordering entries breaks the
+// chain that Verify checks.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genesis is the prev hash of the first entry of a log
+var genesis = strings.Repeat("0", 64)
+
+// tailSize is how much of an existing log is read to continue its chain;
+// entries are far shorter
+const tailSize = 64 << 10
+
+// Entry is one request of the log
+type Entry struct {
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"` // page, or what an auxiliary request fetched: feed, image, ...
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	FinalURL string    `json:"final_url,omitempty"` // after redirects, when it differs
+	Status   int       `json:"status,omitempty"`    // HTTP status (0 = no response)
+	Bytes    int64     `json:"bytes"`               // body bytes read
+	Attempts int       `json:"attempts,omitempty"`  // requests sent, retries included
+	Robots   string    `json:"robots,omitempty"`    // decision on the page's robots directives
+	Error    string    `json:"error,omitempty"`
+	Prev     string    `json:"prev"` // hash of the entry before
+}
+
+// Log appends entries to a hash-chained JSONL file. Safe for concurrent use
+// within a process; two processes must not write the same log at once.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	seq  int64
+	prev string
+}
+
+// Open opens the log at path for appending, continuing the chain of the
+// entries already in it
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := &Log{f: f, prev: genesis}
+	last, err := lastLine(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	if last != nil {
+		e, hash, err := parseLine(last)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("audit log %s ends in an invalid entry: %w", path, err)
+		}
+		l.seq, l.prev = e.Seq, hash
+	}
+	return l, nil
+}
+
+// lastLine returns the last non-empty line of f, nil for an empty file
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start := max(info.Size()-tailSize, 0)
+	buf := make([]byte, info.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	buf = bytes.TrimRight(buf, "\n")
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	i := bytes.LastIndexByte(buf, '\n')
+	if i < 0 && start > 0 {
+		return nil, errors.New("last entry too long")
+	}
+	return buf[i+1:], nil
+}
+
+// Record appends e, setting its sequence number and chain hash
+func (l *Log) Record(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	e.Seq = l.seq
+	e.Prev = l.prev
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	hash := hashEntry(body)
+	line := append(body[:len(body)-1], `,"hash":"`+hash+"\"}\n"...)
+	if _, err := l.f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	l.prev = hash
+	return nil
+}
+
+// Close syncs and closes the log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// hashEntry is the hash of an entry's JSON without its hash field; the
+// entry's prev field ties it to the one before
+func hashEntry(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// parseLine splits a log line into its entry and hash, checking that the
+// hash matches the line
+func parseLine(line []byte) (*Entry, string, error) {
+	const field = `,"hash":"`
+	i := bytes.LastIndex(line, []byte(field))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", errors.New("entry has no hash")
+	}
+	hash := string(line[i+len(field) : len(line)-2])
+	body := append(bytes.Clone(line[:i]), '}')
+	if hashEntry(body) != hash {
+		return nil, "", errors.New("entry does not match its hash")
+	}
+	var e Entry
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, "", err
+	}
+	return &e, hash, nil
+}
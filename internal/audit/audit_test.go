@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, path string, urls ...string) {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range urls {
+		if err := l.Record(Entry{Kind: "page", Method: "GET", URL: u, Status: 200, Bytes: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func verifyFile(t *testing.T, path string) (int, error) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return Verify(f)
+}
+
+func TestLog_ChainsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLog(t, path, "https://a.example/1", "https://a.example/2")
+	writeLog(t, path, "https://a.example/3")
+
+	n, err := verifyFile(t, path)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 valid entries, got %d, %v", n, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.Contains(data, []byte(`"seq":3`)) {
+		t.Errorf("expected the second run to continue the sequence:\n%s", data)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLog(t, path, "https://a.example/1", "https://a.example/2", "https://a.example/3")
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	tests := []struct {
+		name string
+		log  string
+		line int
+	}{
+		{"edited", strings.Replace(string(data), `"status":200`, `"status":404`, 1), 1},
+		{"dropped", lines[0] + lines[2], 2},
+		{"reordered", lines[1] + lines[0] + lines[2], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tt.log))
+			var chain *ChainError
+			if !errors.As(err, &chain) || chain.Line != tt.line {
+				t.Errorf("expected a break at line %d, got %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func TestOpen_RejectsBrokenTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLog(t, path, "https://a.example/1")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("a.example"), []byte("b.example"), 1), 0600)
+
+	if _, err := Open(path); err == nil {
+		t.Error("expected Open to refuse a log whose last entry was edited")
+	}
+}
+
+func TestTransport_RecordsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &Transport{Log: l, Kind: "feed"}}
+	resp, err := client.Get(server.URL + "/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body.Close()
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`"kind":"feed"`, `"status":200`, `"bytes":5`, `/feed.xml"`} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("expected %s in the entry:\n%s", want, data)
+		}
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 1 {
+		t.Errorf("expected one entry, got %d", n)
+	}
+}
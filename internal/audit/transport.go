@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transport records every request sent through it as an entry of kind Kind,
+// once its body was closed
+type Transport struct {
+	Base http.RoundTripper // nil = http.DefaultTransport
+	Log  *Log
+	Kind string
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	e := Entry{Time: time.Now(), Kind: t.Kind, Method: req.Method, URL: req.URL.String()}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		_ = t.Log.Record(e)
+		return nil, err
+	}
+	e.Status = resp.StatusCode
+	resp.Body = &countingBody{ReadCloser: resp.Body, done: func(n int64) {
+		e.Bytes = n
+		_ = t.Log.Record(e)
+	}}
+	return resp, nil
+}
+
+// countingBody counts the bytes read from a response body and reports them
+// once, on Close
+type countingBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(n int64)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n) })
+	return err
+}
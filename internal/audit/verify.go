@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ChainError reports where a log's chain breaks
+type ChainError struct {
+	Line   int // 1-based
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verify reads a log and checks that every entry matches its hash, links to
+// the entry before it and follows its sequence number. It returns the
+// entries checked, and a *ChainError at the first that does not.
+func Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), tailSize)
+	prev := genesis
+	var seq int64
+	n, lineNo := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		e, hash, err := parseLine(line)
+		if err != nil {
+			return n, &ChainError{Line: lineNo, Reason: err.Error()}
+		}
+		if e.Prev != prev {
+			return n, &ChainError{Line: lineNo, Reason: "does not follow the entry before"}
+		}
+		if e.Seq != seq+1 {
+			return n, &ChainError{Line: lineNo, Reason: fmt.Sprintf("sequence %d after %d", e.Seq, seq)}
+		}
+		prev, seq = hash, e.Seq
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return n, nil
+}
@@ -2,16 +2,21 @@ package browser
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/browserutils/kooky"
 	_ "github.com/browserutils/kooky/browser/all" // Import all browser support
+	"golang.org/x/net/publicsuffix"
 )
 
 type BrowserType string
@@ -24,16 +29,67 @@ const (
 	BrowserZen     BrowserType = "zen"
 )
 
+// DefaultCookieCacheTTL is how long cookies read for a site are reused
+const DefaultCookieCacheTTL = 5 * time.Minute
+
 type CookieExtractor struct {
 	browserType BrowserType
 	customPaths map[string]string
+	domains     []string // hosts cookies may be injected for (empty = all)
+	exclude     []string // hosts and cookie domains never injected
+	cookieFile  string   // exported cookie file used when the stores yield nothing
+
+	// Cookies are read from the stores once per registrable domain and
+	// reused until the TTL expires
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	cache    map[string]cookieCacheEntry
+	load     func(site string) ([]*http.Cookie, error)
+}
+
+type cookieCacheEntry struct {
+	cookies []*http.Cookie // every cookie scoped to the site or its subdomains
+	fetched time.Time
 }
 
 func NewCookieExtractor(browserType BrowserType, customPaths map[string]string) *CookieExtractor {
-	return &CookieExtractor{
+	ce := &CookieExtractor{
 		browserType: browserType,
 		customPaths: customPaths,
+		cacheTTL:    DefaultCookieCacheTTL,
+		cache:       make(map[string]cookieCacheEntry),
 	}
+	ce.load = ce.extractForSite
+	return ce
+}
+
+// SetCacheTTL sets how long cookies read for a site are reused (0 disables
+// caching)
+func (ce *CookieExtractor) SetCacheTTL(ttl time.Duration) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.cacheTTL = ttl
+	if ttl <= 0 {
+		ce.cache = make(map[string]cookieCacheEntry)
+	}
+}
+
+// SetDomainFilter restricts cookie injection to hosts matching domains and
+// never injects for hosts, or cookies scoped to domains, matching exclude.
+// Patterns are "*", a domain (which also covers its subdomains) or
+// "*.domain" (subdomains only).
+func (ce *CookieExtractor) SetDomainFilter(domains, exclude []string) {
+	ce.domains = domains
+	ce.exclude = exclude
+}
+
+// Allowed reports whether cookies may be injected for host
+func (ce *CookieExtractor) Allowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchesAnyPattern(host, ce.exclude) {
+		return false
+	}
+	return len(ce.domains) == 0 || matchesAnyPattern(host, ce.domains)
 }
 
 func (ce *CookieExtractor) ExtractCookies(targetURL string) ([]*http.Cookie, error) {
@@ -41,29 +97,83 @@ func (ce *CookieExtractor) ExtractCookies(targetURL string) ([]*http.Cookie, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
+	host := strings.ToLower(parsedURL.Hostname())
+	if !ce.Allowed(host) {
+		return nil, nil
+	}
+
+	siteCookies, err := ce.siteCookies(registrableDomain(host))
+	if err != nil {
+		return nil, err
+	}
 
 	var cookies []*http.Cookie
+	for _, cookie := range siteCookies {
+		if ce.matchesDomain(cookie.Domain, host) {
+			cookies = append(cookies, cookie)
+		}
+	}
+	return cookies, nil
+}
+
+// siteCookies returns the cookies for a registrable domain, reading the
+// browser stores only when the cache has no fresh entry. The lock is held
+// while reading so concurrent requests for one site share a single read.
+func (ce *CookieExtractor) siteCookies(site string) ([]*http.Cookie, error) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if entry, ok := ce.cache[site]; ok && time.Since(entry.fetched) < ce.cacheTTL {
+		return entry.cookies, nil
+	}
+
+	cookies, err := ce.load(site)
+	if err != nil {
+		return nil, err
+	}
+	if ce.cacheTTL > 0 {
+		ce.cache[site] = cookieCacheEntry{cookies: cookies, fetched: time.Now()}
+	}
+	return cookies, nil
+}
 
-	if ce.browserType == BrowserAuto {
-		// Try all browsers in order of preference
-		browsers := []BrowserType{BrowserChrome, BrowserFirefox, BrowserZen, BrowserSafari}
-		for _, browser := range browsers {
-			if browserCookies, err := ce.extractFromBrowser(browser, parsedURL.Host); err == nil && len(browserCookies) > 0 {
-				cookies = append(cookies, browserCookies...)
-				break
+// extractForSite reads the cookies scoped to site or its subdomains, falling
+// back to the exported cookie file when the browser stores yield nothing
+func (ce *CookieExtractor) extractForSite(site string) ([]*http.Cookie, error) {
+	cookies, err := ce.extractFromStores(site)
+	if len(cookies) > 0 || ce.cookieFile == "" {
+		return cookies, err
+	}
+	return ce.extractFromFile(site)
+}
+
+func (ce *CookieExtractor) extractFromStores(site string) ([]*http.Cookie, error) {
+	if ce.browserType != BrowserAuto {
+		if ce.browserType == BrowserSafari {
+			if err := SafariStatus(); err != nil {
+				return nil, err
 			}
 		}
-	} else {
-		cookies, err = ce.extractFromBrowser(ce.browserType, parsedURL.Host)
-		if err != nil {
-			return nil, err
+		return ce.extractFromBrowser(ce.browserType, site)
+	}
+
+	// Try all browsers in order of preference
+	browsers := []BrowserType{BrowserChrome, BrowserFirefox, BrowserZen, BrowserSafari}
+	for _, browser := range browsers {
+		if browserCookies, err := ce.extractFromBrowser(browser, site); err == nil && len(browserCookies) > 0 {
+			return browserCookies, nil
 		}
 	}
 
-	return cookies, nil
+	// An unreadable Safari store looks exactly like a site without cookies,
+	// so say why
+	if runtime.GOOS == "darwin" && errors.Is(SafariStatus(), ErrSafariFullDiskAccess) {
+		return nil, ErrSafariFullDiskAccess
+	}
+	return nil, nil
 }
 
-func (ce *CookieExtractor) extractFromBrowser(browserType BrowserType, domain string) ([]*http.Cookie, error) {
+func (ce *CookieExtractor) extractFromBrowser(browserType BrowserType, site string) ([]*http.Cookie, error) {
 	ctx := context.Background()
 	var cookies []*http.Cookie
 
@@ -76,22 +186,38 @@ func (ce *CookieExtractor) extractFromBrowser(browserType BrowserType, domain st
 		}
 
 		// Filter by browser type and domain
-		if ce.matchesBrowserType(cookie.Browser, browserType) && ce.matchesDomain(cookie.Domain, domain) {
-			cookies = append(cookies, &http.Cookie{
-				Name:     cookie.Name,
-				Value:    cookie.Value,
-				Path:     cookie.Path,
-				Domain:   cookie.Domain,
-				Expires:  cookie.Expires,
-				Secure:   cookie.Secure,
-				HttpOnly: cookie.HttpOnly,
-			})
+		if ce.matchesBrowserType(cookie.Browser, browserType) && ce.acceptCookie(cookie, site) {
+			cookies = append(cookies, toHTTPCookie(cookie))
 		}
 	}
 
 	return cookies, nil
 }
 
+// acceptCookie reports whether a stored cookie belongs to site and is not
+// scoped to an excluded domain
+func (ce *CookieExtractor) acceptCookie(cookie *kooky.Cookie, site string) bool {
+	return withinSite(cookie.Domain, site) &&
+		!matchesAnyPattern(strings.ToLower(strings.TrimPrefix(cookie.Domain, ".")), ce.exclude)
+}
+
+func toHTTPCookie(cookie *kooky.Cookie) *http.Cookie {
+	c := &http.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		Expires:  cookie.Expires,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+	}
+	// Session cookies are stored with expiry 0, which is not a date
+	if c.Expires.Unix() <= 0 {
+		c.Expires = time.Time{}
+	}
+	return c
+}
+
 func (ce *CookieExtractor) matchesBrowserType(browser kooky.BrowserInfo, browserType BrowserType) bool {
 	if browserType == BrowserAuto {
 		return true
@@ -113,26 +239,72 @@ func (ce *CookieExtractor) matchesBrowserType(browser kooky.BrowserInfo, browser
 	return false
 }
 
+// matchesDomain reports whether a cookie set for cookieDomain may be sent to
+// targetDomain. Domain cookies must share the target's registrable domain, so
+// cookies scoped to a public suffix ("co.uk") or to an unrelated name that
+// merely ends the same way never match.
 func (ce *CookieExtractor) matchesDomain(cookieDomain, targetDomain string) bool {
+	cookieDomain = strings.ToLower(strings.TrimPrefix(cookieDomain, "."))
+	targetDomain = strings.ToLower(targetDomain)
 	if cookieDomain == "" || targetDomain == "" {
 		return false
 	}
 
-	// Remove leading dot from cookie domain
-	if strings.HasPrefix(cookieDomain, ".") {
-		cookieDomain = cookieDomain[1:]
-	}
-
 	// Exact match
 	if cookieDomain == targetDomain {
 		return true
 	}
 
-	// Subdomain match
-	if strings.HasSuffix(targetDomain, "."+cookieDomain) {
-		return true
+	// IP addresses only ever match exactly
+	if net.ParseIP(targetDomain) != nil {
+		return false
+	}
+
+	// Subdomain match, never across a public suffix
+	if !strings.HasSuffix(targetDomain, "."+cookieDomain) {
+		return false
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(cookieDomain); suffix == cookieDomain {
+		return false
 	}
+	return registrableDomain(cookieDomain) == registrableDomain(targetDomain)
+}
+
+// registrableDomain returns the eTLD+1 of host, or host itself when it has
+// none (IP addresses, localhost)
+func registrableDomain(host string) string {
+	if site, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return site
+	}
+	return host
+}
 
+// withinSite reports whether a cookie domain is the site or one of its subdomains
+func withinSite(cookieDomain, site string) bool {
+	cookieDomain = strings.ToLower(strings.TrimPrefix(cookieDomain, "."))
+	return cookieDomain == site || strings.HasSuffix(cookieDomain, "."+site)
+}
+
+// matchesAnyPattern reports whether host matches one of the domain patterns
+func matchesAnyPattern(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*":
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		default:
+			pattern = strings.TrimPrefix(pattern, ".")
+			if host == pattern || strings.HasSuffix(host, "."+pattern) {
+				return true
+			}
+		}
+	}
 	return false
 }
 
@@ -0,0 +1,170 @@
+package browser
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCookieDomainFilter(t *testing.T) {
+	ce := NewCookieExtractor(BrowserAuto, nil)
+	if !ce.Allowed("example.com") {
+		t.Error("no filter should allow every host")
+	}
+
+	ce.SetDomainFilter([]string{"example.com", "*.news.org"}, []string{"login.example.com", ".bank.com"})
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"www.Example.com", true},
+		{"login.example.com", false},
+		{"a.login.example.com", false},
+		{"news.org", false},
+		{"daily.news.org", true},
+		{"notexample.com", false},
+		{"other.com", false},
+	}
+	for _, tt := range tests {
+		if got := ce.Allowed(tt.host); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	ce.SetDomainFilter([]string{"*"}, []string{"bank.com"})
+	if !ce.Allowed("example.com") || ce.Allowed("online.bank.com") {
+		t.Error("exclude should take precedence over the wildcard")
+	}
+}
+
+func TestCookieCachePerSite(t *testing.T) {
+	ce := NewCookieExtractor(BrowserAuto, nil)
+	loads := 0
+	ce.load = func(site string) ([]*http.Cookie, error) {
+		loads++
+		return []*http.Cookie{
+			{Name: "root", Domain: "." + site},
+			{Name: "blog", Domain: "blog." + site},
+		}, nil
+	}
+
+	cookies, err := ce.ExtractCookies("https://www.example.co.uk/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Name != "root" {
+		t.Errorf("expected only the site-wide cookie for www, got %v", cookies)
+	}
+
+	cookies, _ = ce.ExtractCookies("https://blog.example.co.uk:8443/b")
+	if len(cookies) != 2 {
+		t.Errorf("expected both cookies for blog, got %v", cookies)
+	}
+	if loads != 1 {
+		t.Errorf("expected one store read for the site, got %d", loads)
+	}
+
+	ce.ExtractCookies("https://other.co.uk/")
+	if loads != 2 {
+		t.Errorf("expected a new read for another site, got %d", loads)
+	}
+
+	ce.SetCacheTTL(0)
+	ce.ExtractCookies("https://www.example.co.uk/a")
+	ce.ExtractCookies("https://www.example.co.uk/a")
+	if loads != 4 {
+		t.Errorf("expected every call to read with caching disabled, got %d", loads)
+	}
+}
+
+func TestCookieCacheExpires(t *testing.T) {
+	ce := NewCookieExtractor(BrowserAuto, nil)
+	loads := 0
+	ce.load = func(site string) ([]*http.Cookie, error) {
+		loads++
+		return nil, nil
+	}
+	ce.SetCacheTTL(time.Millisecond)
+	ce.ExtractCookies("https://example.com/")
+	time.Sleep(5 * time.Millisecond)
+	ce.ExtractCookies("https://example.com/")
+	if loads != 2 {
+		t.Errorf("expected expired entry to be re-read, got %d loads", loads)
+	}
+}
+
+func TestMatchesDomain(t *testing.T) {
+	ce := NewCookieExtractor(BrowserAuto, nil)
+	tests := []struct {
+		cookieDomain, host string
+		want               bool
+	}{
+		{"example.com", "example.com", true},
+		{".example.com", "www.example.com", true},
+		{".Example.com", "a.b.example.com", true},
+		{"www.example.com", "example.com", false},
+		{"ample.com", "example.com", false},
+		{".co.uk", "bbc.co.uk", false},
+		{"co.uk", "shop.co.uk", false},
+		{".com", "example.com", false},
+		{".bbc.co.uk", "news.bbc.co.uk", true},
+		{".github.io", "user.github.io", false},
+		{"user.github.io", "user.github.io", true},
+		{"127.0.0.1", "127.0.0.1", true},
+		{"0.0.1", "127.0.0.1", false},
+		{"localhost", "localhost", true},
+		{"", "example.com", false},
+	}
+	for _, tt := range tests {
+		if got := ce.matchesDomain(tt.cookieDomain, tt.host); got != tt.want {
+			t.Errorf("matchesDomain(%q, %q) = %v, want %v", tt.cookieDomain, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCookieFileFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	data := "# Netscape HTTP Cookie File\n" +
+		".example.com\tTRUE\t/\tFALSE\t0\tsession\tabc\n" +
+		"www.other.com\tFALSE\t/\tTRUE\t0\tid\txyz\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ce := NewCookieExtractor(BrowserAuto, nil)
+	ce.SetCookieFile(path)
+	cookies, err := ce.extractFromFile("example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Fatalf("expected only the example.com cookie, got %v", cookies)
+	}
+	if !cookies[0].Expires.IsZero() {
+		t.Errorf("expected a session cookie without expiry, got %v", cookies[0].Expires)
+	}
+
+	ce.SetCookieFile(filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := ce.extractFromFile("example.com"); err == nil {
+		t.Error("expected an error for a missing cookie file")
+	}
+}
+
+func TestCookieFileStatus(t *testing.T) {
+	dir := t.TempDir()
+	if err := cookieFileStatus([]string{filepath.Join(dir, "Cookies.binarycookies")}); err == nil || errors.Is(err, ErrSafariFullDiskAccess) {
+		t.Errorf("missing store should not report Full Disk Access, got %v", err)
+	}
+
+	path := filepath.Join(dir, "present.binarycookies")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cookieFileStatus([]string{filepath.Join(dir, "missing"), path}); err != nil {
+		t.Errorf("readable store should be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/browserutils/kooky"
+	"github.com/browserutils/kooky/browser/netscape"
+	"github.com/browserutils/kooky/browser/safari"
+)
+
+// ErrSafariFullDiskAccess is returned when macOS sandboxing hides the Safari
+// cookie store from this process
+var ErrSafariFullDiskAccess = errors.New("safari cookies need Full Disk Access: grant it to your terminal in System Settings > Privacy & Security > Full Disk Access, or export cookies and set browser.cookies.file")
+
+// safariCookiePaths lists where Safari keeps Cookies.binarycookies, newest
+// macOS layout first
+func safariCookiePaths() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{
+		filepath.Join(home, "Library", "Containers", "com.apple.Safari", "Data", "Library", "Cookies", "Cookies.binarycookies"),
+		filepath.Join(home, "Library", "Cookies", "Cookies.binarycookies"),
+	}
+}
+
+// SafariStatus reports whether the Safari cookie store can be read. It
+// returns ErrSafariFullDiskAccess when the store exists but macOS denies
+// access to it.
+func SafariStatus() error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("safari cookies are only available on macOS")
+	}
+	return cookieFileStatus(safariCookiePaths())
+}
+
+// cookieFileStatus returns nil when one of paths can be opened
+func cookieFileStatus(paths []string) error {
+	denied := false
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err == nil {
+			f.Close()
+			return nil
+		}
+		if errors.Is(err, os.ErrPermission) {
+			denied = true
+		}
+	}
+	if denied {
+		return ErrSafariFullDiskAccess
+	}
+	return fmt.Errorf("safari cookie store not found")
+}
+
+// SetCookieFile sets an exported cookie file read when the browser stores
+// yield nothing for a site. Files ending in .binarycookies are read as
+// Safari stores; anything else as a Netscape cookies.txt.
+func (ce *CookieExtractor) SetCookieFile(path string) {
+	ce.cookieFile = expandPath(path)
+}
+
+// extractFromFile reads the cookies for site from the exported cookie file
+func (ce *CookieExtractor) extractFromFile(site string) ([]*http.Cookie, error) {
+	if _, err := os.Stat(ce.cookieFile); err != nil {
+		return nil, fmt.Errorf("cookie file: %w", err)
+	}
+
+	var seq kooky.CookieSeq
+	if strings.HasSuffix(strings.ToLower(ce.cookieFile), ".binarycookies") {
+		seq = safari.TraverseCookies(ce.cookieFile)
+	} else {
+		seq, _ = netscape.TraverseCookies(ce.cookieFile)
+	}
+
+	var cookies []*http.Cookie
+	for cookie, err := range seq {
+		if err != nil {
+			continue
+		}
+		if ce.acceptCookie(cookie, site) {
+			cookies = append(cookies, toHTTPCookie(cookie))
+		}
+	}
+	return cookies, nil
+}
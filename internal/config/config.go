@@ -5,11 +5,15 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
+
+	"github.com/byteowlz/scrpr/internal/paths"
 )
 
 type Config struct {
 	Schema     string           `toml:"$schema,omitempty" mapstructure:"$schema"`
+	Include    []string         `toml:"include,omitempty" mapstructure:"include"` // config files layered under this one; globs allowed
 	Browser    BrowserConfig    `toml:"browser" mapstructure:"browser"`
 	Extraction ExtractionConfig `toml:"extraction" mapstructure:"extraction"`
 	Output     OutputConfig     `toml:"output" mapstructure:"output"`
@@ -17,6 +21,12 @@ type Config struct {
 	Parallel   ParallelConfig   `toml:"parallel" mapstructure:"parallel"`
 	Pipe       PipeConfig       `toml:"pipe" mapstructure:"pipe"`
 	Logging    LoggingConfig    `toml:"logging" mapstructure:"logging"`
+	ExitCodes  ExitCodesConfig  `toml:"exit_codes" mapstructure:"exit_codes"`
+	Junk       JunkConfig       `toml:"junk" mapstructure:"junk"`
+	Expand     ExpandConfig     `toml:"expand" mapstructure:"expand"`
+	Crawl      CrawlConfig      `toml:"crawl" mapstructure:"crawl"`
+	Cache      CacheConfig      `toml:"cache" mapstructure:"cache"`
+	Watch      WatchConfig      `toml:"watch" mapstructure:"watch"`
 }
 
 type BrowserConfig struct {
@@ -26,20 +36,55 @@ type BrowserConfig struct {
 }
 
 type BrowserCookiesConfig struct {
-	Domains []string `toml:"domains"`
-	Exclude []string `toml:"exclude"`
+	Enabled  bool     `toml:"enabled"` // send browser cookies without --browser
+	Domains  []string `toml:"domains"`
+	Exclude  []string `toml:"exclude"`
+	CacheTTL int      `toml:"cache_ttl"` // seconds to reuse cookies read for a site (0 = no cache)
+	File     string   `toml:"file"`      // exported cookies.txt or Cookies.binarycookies used as fallback
 }
 
 type ExtractionConfig struct {
-	SkipCookieBanners bool   `toml:"skip_cookie_banners"`
-	BannerTimeout     int    `toml:"banner_timeout"`
-	EnableJavaScript  string `toml:"enable_javascript"`
-	JSTimeout         int    `toml:"js_timeout"`
-	WaitForSelector   string `toml:"wait_for_selector"`
-	MinContentLength  int    `toml:"min_content_length"`
-	RemoveAds         bool   `toml:"remove_ads"`
-	CleanHTML         bool   `toml:"clean_html"`
-	Backend           string `toml:"backend"` // readability (default), tavily, jina
+	SkipCookieBanners bool     `toml:"skip_cookie_banners"`
+	BannerTimeout     int      `toml:"banner_timeout"`
+	BannerAction      string   `toml:"banner_action"`    // accept or reject
+	BannerSelectors   []string `toml:"banner_selectors"` // buttons clicked before the built-in ones
+	EnableJavaScript  string   `toml:"enable_javascript"`
+	JSTimeout         int      `toml:"js_timeout"`
+	ProcessTimeout    int      `toml:"process_timeout"`
+	WaitForSelector   string   `toml:"wait_for_selector"`
+	WaitForText       string   `toml:"wait_for_text"` // text the page must show in JS mode
+	WaitUntil         string   `toml:"wait_until"`    // load, domcontentloaded, network-idle or network-idle:<duration>
+	PrintMedia        bool     `toml:"print_media"`   // emulate @media print in JS mode
+	Scroll            int      `toml:"scroll"`        // scrolls to the bottom in JS mode, for lazy content
+	ScrollUntilStable bool     `toml:"scroll_until_stable"`
+	ScrollMaxHeight   int      `toml:"scroll_max_height"` // stop scrolling at this page height in pixels (0 = unlimited)
+	ScrollTimeout     int      `toml:"scroll_timeout"`    // seconds of scrolling, on top of js_timeout
+	EvalJS            []string `toml:"eval_js"`           // script files run in rendered pages before they are read
+	MinContentLength  int      `toml:"min_content_length"`
+	RemoveAds         bool     `toml:"remove_ads"`
+	CleanHTML         bool     `toml:"clean_html"`
+	Backend           string   `toml:"backend"` // readability (default), tavily, jina
+
+	// Directory of FiveFilters ftr-site-config files (empty = none)
+	SiteConfigDir string `toml:"site_config_dir"`
+
+	// Language whose text cleanup rules to use (empty = detect per page)
+	Language string `toml:"language"`
+
+	// OCR of large images on pages with little text
+	OCR        bool   `toml:"ocr"`
+	OCRCommand string `toml:"ocr_command"`  // {file} is the image (empty = tesseract)
+	OCRMinText int    `toml:"ocr_min_text"` // characters of text below which images are read
+
+	// Add the transcript a podcast feed links to episode pages
+	PodcastTranscripts bool `toml:"podcast_transcripts"`
+
+	// Robots meta tags and X-Robots-Tag: ignore, or respect to skip pages
+	// marked noindex or noarchive
+	RobotsMeta string `toml:"robots_meta"`
+
+	// Per-site backend routes and waits, e.g. from an installed rule pack
+	Sites []ExtractionSiteConfig `toml:"sites"`
 
 	// Tavily extraction settings
 	Tavily TavilyExtractionConfig `toml:"tavily"`
@@ -48,6 +93,14 @@ type ExtractionConfig struct {
 	Jina JinaExtractionConfig `toml:"jina"`
 }
 
+// ExtractionSiteConfig routes a host and its subdomains to an extraction
+// backend and a wait strategy when none is given on the command line
+type ExtractionSiteConfig struct {
+	Host    string `toml:"host"`
+	Backend string `toml:"backend"` // readability, tavily or jina (empty = the run's)
+	Wait    string `toml:"wait"`    // as wait_until, e.g. network-idle:800ms (empty = the run's)
+}
+
 // TavilyExtractionConfig holds Tavily Extract API settings
 type TavilyExtractionConfig struct {
 	APIKey       string `toml:"api_key"`
@@ -65,15 +118,94 @@ type OutputConfig struct {
 	MetadataFields  []string `toml:"metadata_fields"`
 	LineWidth       int      `toml:"line_width"`
 	PreserveLinks   bool     `toml:"preserve_links"`
+	SaveRaw         string   `toml:"save_raw"`        // directory for zstd-compressed raw HTML (empty = disabled)
+	Encrypt         []string `toml:"encrypt"`         // age recipients (age:age1...) output and raw HTML are encrypted to
+	FrontMatter     bool     `toml:"front_matter"`    // YAML front matter in markdown output
+	TOC             bool     `toml:"toc"`             // table of contents at the top of markdown output
+	MarkdownFlavor  string   `toml:"markdown_flavor"` // gfm, commonmark or obsidian
+	Template        string   `toml:"template"`        // Go text/template (inline or file path) for each result
+	Fields          []string `toml:"fields"`          // output components to emit (empty = format default)
+	Where           string   `toml:"where"`           // only emit results matching this expression (empty = all)
+
+	EmbedImages     bool `toml:"embed_images"` // images as data URIs in markdown and html output
+	EmbedMaxImageKB int  `toml:"embed_max_image_kb"`
+	EmbedMaxTotalKB int  `toml:"embed_max_total_kb"` // per article
+	SaveImages      bool `toml:"save_images"`        // images under assets/ in directory and archive output
+
+	MaxContentChars  int `toml:"max_content_chars"`  // truncate content past this many characters (0 = unlimited)
+	MaxContentTokens int `toml:"max_content_tokens"` // same, as an estimated LLM token count
+
+	FilenameTemplate string `toml:"filename_template"` // file names in directory mode, e.g. "{{.Host}}/{{.Slug}}"
+	NameBy           string `toml:"name_by"`           // url or title; file names when no filename_template is set
+	Extension        string `toml:"extension"`         // file extension in directory/archive output (empty = per format)
+	IndexMarkdown    bool   `toml:"index_md"`          // write index.md next to manifest.json in directory/archive output
+	Render           bool   `toml:"render"`            // ANSI-styled markdown when stdout is a terminal
+
+	Routes []OutputRouteConfig `toml:"routes"` // send matching URLs elsewhere
+	Labels []OutputLabelConfig `toml:"labels"` // label matching URLs
+}
+
+// OutputRouteConfig sends the results of URLs matching a pattern to their
+// own directory or file
+type OutputRouteConfig struct {
+	Match string `toml:"match"` // e.g. "docs.python.org/*"; * matches anything, a bare host its whole site
+	To    string `toml:"to"`    // directory when it ends in a slash, else a file
+}
+
+// OutputLabelConfig labels the results of URLs matching a pattern
+type OutputLabelConfig struct {
+	Match  string   `toml:"match"` // as in OutputRouteConfig
+	Labels []string `toml:"labels"`
 }
 
 type NetworkConfig struct {
-	Timeout         int    `toml:"timeout"`
-	UserAgent       string `toml:"user_agent"`
-	BrowserAgent    string `toml:"browser_agent"`
-	FollowRedirects bool   `toml:"follow_redirects"`
-	MaxRedirects    int    `toml:"max_redirects"`
-	Delay           int    `toml:"delay"`
+	Timeout               int    `toml:"timeout"`                 // total fetch deadline in seconds
+	ConnectTimeout        int    `toml:"connect_timeout"`         // TCP dial timeout in seconds
+	TLSHandshakeTimeout   int    `toml:"tls_handshake_timeout"`   // TLS handshake timeout in seconds
+	ResponseHeaderTimeout int    `toml:"response_header_timeout"` // time to first byte in seconds
+	UserAgent             string `toml:"user_agent"`
+	BrowserAgent          string `toml:"browser_agent"`
+	StickyUserAgent       bool   `toml:"sticky_user_agent"` // keep one user agent per host for a run
+	ImpersonateTLS        bool   `toml:"impersonate_tls"`   // TLS ClientHello of the user agent's browser instead of Go's
+	Stealth               bool   `toml:"stealth"`           // hide the marks of headless Chrome in JS mode
+	MobileDevice          string `toml:"mobile_device"`     // emulate a mobile device preset (empty = desktop)
+	Viewport              string `toml:"viewport"`          // window size of rendered pages as WIDTHxHEIGHT (empty = default)
+	AcceptLanguage        string `toml:"accept_language"`   // also sets the JS-mode locale
+	AcceptEncoding        string `toml:"accept_encoding"`   // advertised compression; responses are decoded either way
+	Referer               string `toml:"referer"`           // Referer URL, or "auto" for the site's homepage
+	Timezone              string `toml:"timezone"`          // IANA timezone emulated in JS mode
+	FollowRedirects       bool   `toml:"follow_redirects"`
+	MaxRedirects          int    `toml:"max_redirects"`
+	Delay                 int    `toml:"delay"`
+	PrefetchDNS           bool   `toml:"prefetch_dns"`     // resolve all batch hosts up front
+	WarmupTLSHosts        int    `toml:"warmup_tls_hosts"` // prime TLS sessions for the N busiest hosts
+	AddressFamily         string `toml:"address_family"`   // any, ipv4 or ipv6
+
+	// Adaptive delay: requests to hosts that turn slow or start failing are
+	// spaced out, up to max_host_delay seconds; healthy hosts are not
+	AdaptiveDelay bool `toml:"adaptive_delay"`
+	MaxHostDelay  int  `toml:"max_host_delay"`
+	// Host error rates and Retry-After pauses are kept in the cache dir,
+	// so the next run does not re-hit a host that asked for a pause
+	PersistHostState bool `toml:"persist_host_state"`
+	// Cookies set by responses (consent, sessions) are sent back to the same
+	// site for the rest of the run; cookie_jar also keeps them in a file
+	// between runs
+	KeepCookies bool   `toml:"keep_cookies"`
+	CookieJar   string `toml:"cookie_jar"`
+
+	// Responses past max_download_size_mb are aborted mid-download (0 = unlimited)
+	MaxDownloadSizeMB int `toml:"max_download_size_mb"`
+
+	// Run budget: a run stops taking URLs after max_requests or once
+	// max_duration (e.g. "30m") has passed; 0 and "" are unlimited
+	MaxRequests int    `toml:"max_requests"`
+	MaxDuration string `toml:"max_duration"`
+
+	// User agent pools: the dataset refreshed by `scrpr ua update` and
+	// custom pools selectable by name through browser_agent
+	UserAgentUpdateURL string              `toml:"user_agent_update_url"`
+	UserAgentPools     map[string][]string `toml:"user_agent_pools"`
 }
 
 type ParallelConfig struct {
@@ -81,8 +213,19 @@ type ParallelConfig struct {
 	BatchSize       int  `toml:"batch_size"`
 	ShowProgress    bool `toml:"show_progress"`
 	FailFast        bool `toml:"fail_fast"`
+	Unordered       bool `toml:"unordered"` // write outputs as they complete, not in input order
 	MaxMemoryMB     int  `toml:"max_memory_mb"`
 	CleanupInterval int  `toml:"cleanup_interval"`
+
+	BrowserPool BrowserPoolConfig `toml:"browser_pool"`
+}
+
+// BrowserPoolConfig keeps Chrome running across the JS fetches of a run
+type BrowserPoolConfig struct {
+	Enabled        bool `toml:"enabled"`
+	Instances      int  `toml:"instances"`       // Chrome processes
+	Tabs           int  `toml:"tabs"`            // pages rendered at once per instance
+	MaxNavigations int  `toml:"max_navigations"` // restart an instance after N pages (0 = never)
 }
 
 type PipeConfig struct {
@@ -93,8 +236,117 @@ type PipeConfig struct {
 }
 
 type LoggingConfig struct {
-	Level string `toml:"level"`
-	File  string `toml:"file"`
+	Level    string `toml:"level"`
+	File     string `toml:"file"`
+	AuditLog string `toml:"audit_log"` // hash-chained JSONL of every request (empty = none)
+}
+
+// ExitCodesConfig maps each error class to the process exit code
+type ExitCodesConfig struct {
+	Network      int    `toml:"network"`
+	Process      int    `toml:"process"`
+	InvalidInput int    `toml:"invalid_input"`
+	Config       int    `toml:"config"`
+	FileIO       int    `toml:"file_io"`
+	Partial      int    `toml:"partial"`
+	FailOn       string `toml:"fail_on"` // partial, any or none
+}
+
+// JunkConfig controls the pre-readability removal of newsletter signups,
+// cookie notices, share bars and related-post widgets
+type JunkConfig struct {
+	Enabled   bool             `toml:"enabled"`
+	Groups    []string         `toml:"groups"`    // built-in rule groups (empty = all)
+	Selectors []string         `toml:"selectors"` // extra CSS selectors to remove
+	Text      []string         `toml:"text"`      // extra regexps for short junk blocks
+	Sites     []JunkSiteConfig `toml:"sites"`
+}
+
+// JunkSiteConfig overrides the junk rules for a host and its subdomains
+type JunkSiteConfig struct {
+	Host      string   `toml:"host"`
+	Disabled  bool     `toml:"disabled"`
+	Groups    []string `toml:"groups"` // replaces junk.groups
+	Selectors []string `toml:"selectors"`
+	Text      []string `toml:"text"`
+	Keep      []string `toml:"keep"` // selectors never removed
+}
+
+// ExpandConfig controls the JS-mode click pass that opens read-more buttons,
+// accordions and other collapsed content before extraction
+type ExpandConfig struct {
+	Enabled   bool               `toml:"enabled"`
+	Selectors []string           `toml:"selectors"` // extra CSS selectors to click
+	Sites     []ExpandSiteConfig `toml:"sites"`
+}
+
+// ExpandSiteConfig overrides the expand pass for a host and its subdomains
+type ExpandSiteConfig struct {
+	Host      string   `toml:"host"`
+	Disabled  bool     `toml:"disabled"`
+	Selectors []string `toml:"selectors"`
+}
+
+// CrawlConfig scopes the new URLs --sitemap-delta takes from a sitemap,
+// seeded with the sitemap's host. Field order matches crawl.ScopeRules.
+type CrawlConfig struct {
+	Subdomains      string   `toml:"subdomains"`         // none, all or domain
+	PathPrefixes    []string `toml:"path_prefixes"`      // follow only paths below these (empty = all)
+	ExcludePrefixes []string `toml:"exclude_prefixes"`   // never follow paths below these
+	Include         []string `toml:"include"`            // URL regexes; one must match (empty = all)
+	Exclude         []string `toml:"exclude"`            // URL regexes never followed
+	MaxPagesPerHost int      `toml:"max_pages_per_host"` // per run (0 = unlimited)
+	FollowQuery     bool     `toml:"follow_query"`       // follow URLs with a query string
+}
+
+// CacheConfig controls the on-disk HTTP response cache
+type CacheConfig struct {
+	Enabled bool   `toml:"enabled"`
+	TTL     int    `toml:"ttl"` // seconds a cached response is reused
+	Dir     string `toml:"dir"` // empty = responses/ in the cache directory
+
+	API    bool `toml:"api"`     // keep Jina and Tavily results, which are billed per call
+	APITTL int  `toml:"api_ttl"` // seconds a cached API result is reused
+}
+
+// WatchConfig sets when a --watch extraction of a URL counts as a length
+// anomaly rather than a content change. Field order matches
+// watch.Thresholds.
+type WatchConfig struct {
+	DropRatio  float64 `toml:"drop_ratio"`  // fraction of the usual length lost
+	MinHistory int     `toml:"min_history"` // earlier runs needed before drops are flagged
+	Window     int     `toml:"window"`      // runs kept per URL; the usual length is their median
+}
+
+// DefaultUserAgentUpdateURL is the curated pool fetched by `scrpr ua update`
+const DefaultUserAgentUpdateURL = "https://raw.githubusercontent.com/byteowlz/schemas/refs/heads/main/scrpr/useragents.json"
+
+// CacheDir returns scrpr's cache directory, see paths.Dir
+func CacheDir() (string, error) {
+	return paths.Dir(paths.Cache)
+}
+
+// ConfigDir returns the directory of configFile, or of the default config
+// (see paths.Dir) when configFile is empty
+func ConfigDir(configFile string) (string, error) {
+	if configFile != "" {
+		return filepath.Dir(configFile), nil
+	}
+	return paths.Dir(paths.Config)
+}
+
+// DefaultConfigPath returns the config file used when --config is not given
+func DefaultConfigPath() (string, error) {
+	dir, err := paths.Dir(paths.Config)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// DataDir returns scrpr's data directory, see paths.Dir
+func DataDir() (string, error) {
+	return paths.Dir(paths.Data)
 }
 
 func Default() *Config {
@@ -103,42 +355,98 @@ func Default() *Config {
 			Default: "auto",
 			Paths:   map[string]string{},
 			Cookies: BrowserCookiesConfig{
-				Domains: []string{"*"},
-				Exclude: []string{},
+				Enabled:  false,
+				Domains:  []string{"*"},
+				Exclude:  []string{},
+				CacheTTL: 300,
+				File:     "",
 			},
 		},
 		Extraction: ExtractionConfig{
-			SkipCookieBanners: true,
-			BannerTimeout:     5,
-			EnableJavaScript:  "auto",
-			JSTimeout:         15,
-			WaitForSelector:   "",
-			MinContentLength:  100,
-			RemoveAds:         true,
-			CleanHTML:         true,
+			SkipCookieBanners:  true,
+			BannerTimeout:      5,
+			BannerAction:       "accept",
+			EnableJavaScript:   "auto",
+			JSTimeout:          15,
+			ProcessTimeout:     10,
+			WaitForSelector:    "",
+			WaitUntil:          "load",
+			PrintMedia:         false,
+			ScrollMaxHeight:    100000,
+			ScrollTimeout:      10,
+			MinContentLength:   100,
+			RemoveAds:          true,
+			CleanHTML:          true,
+			OCRCommand:         "tesseract {file} stdout",
+			OCRMinText:         200,
+			PodcastTranscripts: true,
+			RobotsMeta:         "ignore",
 		},
 		Output: OutputConfig{
-			DefaultFormat:   "text",
-			IncludeMetadata: false,
-			MetadataFields:  []string{"title", "author", "date", "url"},
-			LineWidth:       80,
-			PreserveLinks:   true,
+			DefaultFormat:    "text",
+			IncludeMetadata:  false,
+			MetadataFields:   []string{"title", "author", "date", "url"},
+			LineWidth:        80,
+			PreserveLinks:    true,
+			FrontMatter:      false,
+			TOC:              false,
+			MarkdownFlavor:   "gfm",
+			EmbedImages:      false,
+			EmbedMaxImageKB:  1024,
+			EmbedMaxTotalKB:  10240,
+			SaveImages:       false,
+			MaxContentChars:  0,
+			MaxContentTokens: 0,
+			Template:         "",
+			Fields:           []string{},
+			FilenameTemplate: "",
+			NameBy:           "url",
+			Extension:        "",
+			IndexMarkdown:    false,
+			Render:           false,
 		},
 		Network: NetworkConfig{
-			Timeout:         30,
-			UserAgent:       "",
-			BrowserAgent:    "auto",
-			FollowRedirects: true,
-			MaxRedirects:    10,
-			Delay:           0,
+			Timeout:               30,
+			ConnectTimeout:        10,
+			TLSHandshakeTimeout:   10,
+			ResponseHeaderTimeout: 15,
+			UserAgent:             "",
+			BrowserAgent:          "auto",
+			StickyUserAgent:       true,
+			MobileDevice:          "",
+			AcceptLanguage:        "en-US,en;q=0.9",
+			AcceptEncoding:        "gzip, deflate, br",
+			Referer:               "",
+			Timezone:              "",
+			FollowRedirects:       true,
+			MaxRedirects:          10,
+			Delay:                 0,
+			PrefetchDNS:           false,
+			WarmupTLSHosts:        5,
+			AddressFamily:         "any",
+			AdaptiveDelay:         true,
+			MaxHostDelay:          30,
+			PersistHostState:      true,
+			KeepCookies:           true,
+			MaxDownloadSizeMB:     5,
+			MaxRequests:           0,
+			MaxDuration:           "",
+			UserAgentUpdateURL:    DefaultUserAgentUpdateURL,
 		},
 		Parallel: ParallelConfig{
 			MaxConcurrency:  5,
 			BatchSize:       0,
 			ShowProgress:    true,
 			FailFast:        false,
+			Unordered:       false,
 			MaxMemoryMB:     512,
 			CleanupInterval: 30,
+			BrowserPool: BrowserPoolConfig{
+				Enabled:        true,
+				Instances:      2,
+				Tabs:           2,
+				MaxNavigations: 100,
+			},
 		},
 		Pipe: PipeConfig{
 			BufferSize:      4096,
@@ -150,47 +458,62 @@ func Default() *Config {
 			Level: "info",
 			File:  "",
 		},
+		ExitCodes: ExitCodesConfig{
+			Network:      1,
+			Process:      2,
+			InvalidInput: 3,
+			Config:       4,
+			FileIO:       5,
+			Partial:      6,
+			FailOn:       "partial",
+		},
+		Junk: JunkConfig{
+			Enabled:   true,
+			Groups:    []string{},
+			Selectors: []string{},
+			Text:      []string{},
+		},
+		Expand: ExpandConfig{
+			Enabled:   true,
+			Selectors: []string{},
+		},
+		Crawl: CrawlConfig{
+			Subdomains:      "none",
+			PathPrefixes:    []string{},
+			ExcludePrefixes: []string{},
+			Include:         []string{},
+			Exclude:         []string{},
+			MaxPagesPerHost: 0,
+			FollowQuery:     false,
+		},
+		Cache: CacheConfig{
+			Enabled: false,
+			TTL:     3600,
+			Dir:     "",
+			API:     true,
+			APITTL:  86400,
+		},
+		Watch: WatchConfig{
+			DropRatio:  0.5,
+			MinHistory: 2,
+			Window:     5,
+		},
 	}
 }
 
 func Load(configFile string) (*Config, error) {
 	cfg := Default()
 
+	configDir, err := ConfigDir(configFile)
+	if err != nil {
+		return cfg, err
+	}
 	if configFile != "" {
 		viper.SetConfigFile(configFile)
 	} else {
-		configHome := os.Getenv("XDG_CONFIG_HOME")
-		if configHome == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return cfg, fmt.Errorf("error finding home directory: %w", err)
-			}
-			configHome = filepath.Join(home, ".config")
-		}
-
-		configDir := filepath.Join(configHome, "scrpr")
 		viper.AddConfigPath(configDir)
 		viper.SetConfigType("toml")
 		viper.SetConfigName("config")
-
-		// Create config directory if it doesn't exist
-		// Handle broken symlinks by removing them first
-		if fi, err := os.Lstat(configDir); err == nil {
-			if fi.Mode()&os.ModeSymlink != 0 {
-				// It's a symlink - check if it's broken
-				if _, err := os.Stat(configDir); os.IsNotExist(err) {
-					// Broken symlink - remove it
-					os.Remove(configDir)
-				}
-			}
-		}
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			// Non-fatal: we can still use defaults
-			// Only return error if it's not "file exists" (which means dir already exists)
-			if !os.IsExist(err) {
-				return cfg, fmt.Errorf("error creating config directory: %w", err)
-			}
-		}
 	}
 
 	viper.AutomaticEnv()
@@ -203,7 +526,30 @@ func Load(configFile string) (*Config, error) {
 		}
 	}
 
-	if err := viper.Unmarshal(cfg); err != nil {
+	// Installed rule packs and included files are layered under the main
+	// file before decoding
+	v := viper.GetViper()
+	includes := viper.GetStringSlice("include")
+	rulePacks := filepath.Join(configDir, RulesDir, "*.toml")
+	if packs, _ := filepath.Glob(rulePacks); len(packs) > 0 {
+		includes = append([]string{rulePacks}, includes...)
+	}
+	if len(includes) > 0 {
+		settings, err := withIncludes(viper.AllSettings(), includes, filepath.Dir(viper.ConfigFileUsed()))
+		if err != nil {
+			return cfg, err
+		}
+		v = viper.New()
+		if err := v.MergeConfigMap(settings); err != nil {
+			return cfg, fmt.Errorf("error merging included config: %w", err)
+		}
+	}
+
+	// Decode using the toml tags: matched by field name, snake_case keys
+	// such as js_timeout would be silently ignored
+	if err := v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "toml"
+	}); err != nil {
 		return cfg, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
@@ -211,8 +557,7 @@ func Load(configFile string) (*Config, error) {
 }
 
 func (c *Config) CreateExampleConfig(configPath string) error {
-	configDir := filepath.Dir(configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := paths.Ensure(filepath.Dir(configPath)); err != nil {
 		return fmt.Errorf("error creating config directory: %w", err)
 	}
 
@@ -220,6 +565,11 @@ func (c *Config) CreateExampleConfig(configPath string) error {
 
 # scrpr configuration file
 
+# Layer other config files under this one, e.g. per-site rules or shared
+# profiles. Globs are relative to this file and applied in sorted order; this
+# file wins on conflicting values and [[junk.sites]]-style lists are joined.
+# include = ["sites/*.toml"]
+
 [browser]
 # Default browser for cookie extraction
 default = "auto"  # auto, chrome, firefox, safari, zen
@@ -231,29 +581,61 @@ firefox = ""
 safari = ""
 zen = ""
 
-# Domain patterns for cookie injection
+# Domain patterns for cookie injection: "*", "example.com" (includes
+# subdomains) or "*.example.com" (subdomains only)
 [browser.cookies]
+enabled = false  # Send the browser's cookies on every run, not only with --browser
 domains = ["*"]  # Inject cookies for all domains by default
-exclude = []     # Domains to exclude from cookie injection
+exclude = []     # Hosts, and cookie domains, never injected, e.g. ["mybank.com"]
+cache_ttl = 300  # Seconds to reuse cookies read for a site before re-reading the browser stores (0 = always re-read)
+file = ""        # Exported cookies.txt or Safari Cookies.binarycookies, read when the browser stores yield nothing
 
 [extraction]
 # Cookie banner handling
 skip_cookie_banners = true
 banner_timeout = 5  # seconds to wait for banner dismissal
+banner_action = "accept"  # accept or reject (banners without a reject button are left)
+banner_selectors = []     # Dismiss buttons tried before the built-in ones, e.g. ["#my-cmp .agree"]
 
 # JavaScript rendering
 enable_javascript = "auto"  # auto, always, never
 js_timeout = 15            # seconds to wait for JS execution
 wait_for_selector = ""     # CSS selector to wait for (optional)
+wait_for_text = ""         # Text the page must show before it is read (optional)
+wait_until = "load"        # When a page counts as loaded: load, domcontentloaded, network-idle, network-idle:800ms
+print_media = false        # Render with the print stylesheet in JS mode, dropping what it hides
+scroll = 0                 # Scroll to the bottom this many times for lazy content (infinite scroll)
+scroll_until_stable = false  # Scroll until the page stops growing (at most scroll times when set)
+scroll_max_height = 100000 # Stop scrolling once the page is this tall in pixels (0 = unlimited)
+scroll_timeout = 10        # Seconds of scrolling, on top of js_timeout
+eval_js = []               # Script files run in rendered pages before they are read, e.g. ["~/scrpr/no-overlay.js"]
 
 # Content extraction
+process_timeout = 10       # seconds allowed for readability processing
 min_content_length = 100   # Minimum content length to consider valid
 remove_ads = true          # Remove advertisement blocks
 clean_html = true          # Clean HTML before processing
+site_config_dir = ""       # FiveFilters ftr-site-config files (git clone https://github.com/fivefilters/ftr-site-config)
+language = ""              # Text cleanup rules: de, en, fr, ja, zh (empty = detect per page)
+ocr = false                # Read large images with OCR when a page has little text (scans, infographics)
+ocr_command = "tesseract {file} stdout"  # {file} is the image; prints the text on stdout
+ocr_min_text = 200         # Characters of extracted text below which images are read
+podcast_transcripts = true # Add the <podcast:transcript> of the feed to podcast episode pages
+robots_meta = "ignore"     # "respect" skips pages whose robots meta or X-Robots-Tag says noindex or noarchive
+
+# Per-site backend routes (used when -B is not given) and wait strategies (used
+# when --wait is not given); hosts include subdomains
+# [[extraction.sites]]
+# host = "paywalled.example.com"
+# backend = "jina"
+#
+# [[extraction.sites]]
+# host = "app.example.com"
+# wait = "network-idle:800ms"
 
 [output]
 # Default output format
-default_format = "text"    # text, markdown
+default_format = "text"    # text, markdown, json, html
 
 # Metadata inclusion
 include_metadata = false
@@ -262,29 +644,117 @@ metadata_fields = ["title", "author", "date", "url"]
 # Text formatting
 line_width = 80           # Max line width for text output (0 = unlimited)
 preserve_links = true     # Keep links in markdown output
+front_matter = false      # Start markdown with YAML front matter (title, url, author, date, tags)
+toc = false               # Start markdown with a linked table of contents built from the headings
+markdown_flavor = "gfm"   # gfm, commonmark (tables as HTML) or obsidian (==highlights==, [[#Heading]] links)
+embed_images = false      # Embed images as base64 data URIs in markdown and html output (self-contained files)
+embed_max_image_kb = 1024 # Images larger than this keep their URL
+embed_max_total_kb = 10240 # Embedded image budget per article; later images keep their URL
+save_images = false       # Directory/archive output: store images once per content hash under assets/
+max_content_chars = 0     # Cut content at a paragraph or sentence past this many characters (0 = unlimited)
+max_content_tokens = 0    # Same as an estimated token count (~4 characters each), for LLM context limits
+render = false            # Style markdown with ANSI colors when stdout is a terminal (plain when piped)
+fields = []               # Output components, e.g. ["title", "url", "content", "links"] (empty = format default)
+where = ""                # only emit results matching e.g. 'length > 500 && lang == "en" && !paywalled'
+template = ""             # Go text/template per result, inline or file path, e.g. "{{.Title}}\n{{.Content}}"
+
+# Directory mode file names (Go template; empty = derived from the URL).
+# Fields: Host, Path, Slug, Title, TitleSlug, Date, Index, Ext, Label, Labels
+filename_template = ""    # e.g. "{{.Host}}/{{.Date}}-{{.TitleSlug}}"
+name_by = "url"           # Without filename_template: "url", or "title" (slugged, URL name when there is none)
+extension = ""            # Override the per-format extension (.txt, .md, .json, .html), e.g. ".markdown"
+index_md = false          # Write index.md next to manifest.json in directory and archive output
+
+# Send URLs matching a pattern to their own directory (trailing slash) or
+# file; relative paths are inside the -o directory, else the working
+# directory. The most specific (longest) pattern wins. Applies to stdout,
+# file and directory output.
+# [[output.routes]]
+# match = "docs.python.org/*"
+# to = "python-docs/"
+
+# Label the results of URLs matching a pattern (written as for routes), on
+# top of labels from input file lines like "https://example.com/,label=news".
+# Every matching entry applies. Labels appear in JSON, manifest.json and
+# index.md, and in file names through {{.Label}}.
+# [[output.labels]]
+# match = "competitor.com"
+# labels = ["competitor"]
+
+# Raw HTML archive
+save_raw = ""             # Directory for zstd-compressed raw HTML (empty = disabled)
+
+# Encryption at rest: output files and the raw HTML store are encrypted to
+# these age recipients, e.g. ["age:age1..."] (empty = plaintext)
+encrypt = []
 
 [network]
 # Request settings
-timeout = 30              # seconds
+timeout = 30              # total fetch deadline in seconds
+connect_timeout = 10      # seconds to establish a connection
+tls_handshake_timeout = 10  # seconds for the TLS handshake once connected
+response_header_timeout = 15  # seconds to wait for response headers
 user_agent = ""           # Custom user agent (overrides browser_agent if set)
-browser_agent = "auto"    # Browser user agent: auto, chrome, firefox, safari, edge
+browser_agent = "auto"    # Browser user agent: auto, chrome, firefox, safari, edge, or a user_agent_pools name
+sticky_user_agent = true  # Keep the same user agent for every request to a host during a run
+impersonate_tls = false   # TLS handshake of the user agent's browser instead of Go's (HTTPS without a proxy)
+stealth = false           # Hide the marks of headless Chrome (webdriver flag, plugins, WebGL renderer) in JS mode
+mobile_device = ""        # Emulate a mobile device: iphone, iphone-se, pixel, galaxy, ipad (empty = desktop)
+viewport = ""             # Window size of rendered pages, e.g. 1280x800; with mobile_device, its screen (empty = default)
+accept_language = "en-US,en;q=0.9"  # Accept-Language header; also the JS-mode locale
+accept_encoding = "gzip, deflate, br"  # advertised compression (empty = gzip only); br, deflate, gzip and zstd are always decoded
+referer = ""              # Referer URL, or "auto" to present the site's homepage; sets Sec-Fetch-Site to match
+timezone = ""             # IANA timezone emulated in JS mode, e.g. "Europe/Berlin"
 follow_redirects = true
-max_redirects = 10
+max_redirects = 10        # fail a fetch after this many redirects; the chain is reported
+max_download_size_mb = 5  # abort responses larger than this while downloading (0 = unlimited)
 
 # Rate limiting
-delay = 0                 # seconds between requests (for multiple URLs)
+delay = 0                 # seconds between request starts (for multiple URLs)
+adaptive_delay = true     # space out requests to hosts that turn slow or return 429/5xx/network errors
+max_host_delay = 30       # longest adaptive pause between requests to one host, in seconds
+persist_host_state = true # remember struggling hosts and Retry-After pauses across runs
+keep_cookies = true       # send cookies set by earlier responses to the same site
+cookie_jar = ""           # file keeping those cookies between runs (empty = this run only)
+
+# Batch warmup
+prefetch_dns = false      # resolve all hosts concurrently before a batch
+warmup_tls_hosts = 5      # prime TLS sessions for the N busiest hosts
+address_family = "any"    # any, ipv4 or ipv6: connect over one family only, for hosts with broken AAAA (or A) records
+
+# Run budget: stop taking URLs and report the rest as skipped (exit code 6)
+max_requests = 0          # requests per run (0 = unlimited)
+max_duration = ""         # run time, e.g. "30m" or "2h" (empty = unlimited)
+
+# User agent pool, refreshed into the data directory by "scrpr ua update"
+user_agent_update_url = "https://raw.githubusercontent.com/byteowlz/schemas/refs/heads/main/scrpr/useragents.json"
+
+# Custom user agent pools, selected with browser_agent or --browser-agent <name>.
+# A pool named chrome, firefox, safari or edge replaces the built-in one.
+[network.user_agent_pools]
+# mobile = ["Mozilla/5.0 (iPhone; CPU iPhone OS 18_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Mobile/15E148 Safari/604.1"]
 
 [parallel]
 # Parallel processing settings
-max_concurrency = 5       # Maximum concurrent requests
+max_concurrency = 5       # URLs processed at once
 batch_size = 0            # Process in batches (0 = process all at once)
 show_progress = true      # Show progress bar for multiple URLs
 fail_fast = false         # Stop on first error (false = continue processing)
+unordered = false         # Write outputs as they complete instead of in input order
 
 # Resource management
-max_memory_mb = 512       # Maximum memory usage in MB
+max_memory_mb = 512       # Maximum memory usage in MB (a quarter holds early outputs,
+                          # the rest spills to a temporary file)
 cleanup_interval = 30     # Clean up resources every N seconds
 
+[parallel.browser_pool]
+# Chrome instances kept running for the JS fetches of a run; every page
+# still gets a fresh tab and browser context, so no cookies carry over
+enabled = true
+instances = 2             # Chrome processes
+tabs = 2                  # Pages rendered at once per instance
+max_navigations = 100     # Restart an instance after N pages (0 = never)
+
 [pipe]
 # Pipe handling settings
 buffer_size = 4096        # Input buffer size for reading from pipes
@@ -295,6 +765,71 @@ stream_mode = true        # Process URLs as they arrive (vs batch mode)
 [logging]
 level = "info"            # debug, info, warn, error
 file = ""                 # Log file path (empty = stderr only)
+audit_log = ""            # Hash-chained JSONL record of every request (empty = none)
+
+[exit_codes]
+# Exit code per error class, for CI systems that read them differently
+network = 1
+process = 2               # Parse/extraction error
+invalid_input = 3
+config = 4
+file_io = 5
+partial = 6               # Some URLs failed, some succeeded
+fail_on = "partial"       # partial: exit "partial" when only some URLs fail; any: any failed URL exits with its error code; none: URL failures never fail the run
+
+[junk]
+# Remove newsletter signups, cookie notices, share bars and related-post
+# widgets before extraction
+enabled = true
+groups = []               # Built-in groups: newsletter, cookie, share, related (empty = all)
+selectors = []            # Extra CSS selectors to remove, e.g. [".promo-box"]
+text = []                 # Extra regexps (case-insensitive) for short blocks to remove
+
+# Per-site overrides (host includes subdomains)
+# [[junk.sites]]
+# host = "example.com"
+# groups = ["cookie", "share"]  # replaces junk.groups for this site
+# keep = [".related-posts"]     # never remove these
+# disabled = false              # true turns junk removal off for the site
+
+[expand]
+# In JS mode, click read-more buttons, accordions and "show full text"
+# controls before extraction
+enabled = true
+selectors = []            # Extra CSS selectors to click, e.g. [".story-expand"]
+
+# Per-site overrides (host includes subdomains)
+# [[expand.sites]]
+# host = "example.com"
+# selectors = [".faq-toggle"]   # added to the built-in selectors
+# disabled = false              # true turns clicking off for the site
+
+[crawl]
+# Which new sitemap URLs --sitemap-delta extracts; the sitemap's host is the seed
+subdomains = "none"       # none (seed hosts only), all (plus their subdomains) or domain (registrable domain)
+path_prefixes = []        # Follow only paths below these, e.g. ["/docs/"] (empty = all)
+exclude_prefixes = []     # Never follow paths below these, e.g. ["/tag/", "/search"]
+include = []              # URL regexes; one must match (empty = all)
+exclude = []              # URL regexes never followed, e.g. ["\\.pdf$"]
+max_pages_per_host = 0    # Per run; the rest wait for the next run (0 = unlimited)
+follow_query = false      # Follow URLs with a query string (often endless pagination and filters)
+
+[cache]
+# On-disk HTTP response cache, so re-running a batch does not re-download it
+enabled = false           # Same as --cache
+ttl = 3600                # Seconds a cached response is reused
+dir = ""                  # Empty = responses/ in the cache directory (see scrpr paths)
+# Jina and Tavily results are cached apart from pages, so re-running a batch
+# does not bill the APIs again; --refresh fetches anew, --no-cache skips both
+api = true                # Keep API backend results in api/ of the cache directory, or of dir
+api_ttl = 86400           # Seconds a cached API result is reused
+
+[watch]
+# How --watch runs over the same URLs tell a suspicious drop in extracted
+# length (paywall, block page, extraction regression) from a genuine change
+drop_ratio = 0.5          # Flag a text this much shorter than the usual length
+min_history = 2           # Earlier runs needed before drops are flagged
+window = 5                # Runs kept per URL; the usual length is their median
 `
 
 	return os.WriteFile(configPath, []byte(exampleContent), 0644)
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// resolveIncludes expands the include patterns of a config file into file
+// paths. Relative patterns are relative to dir; the matches of each glob are
+// sorted, and the patterns keep their order. A pattern without wildcards
+// must name an existing file; a glob may match nothing.
+func resolveIncludes(patterns []string, dir string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				pattern = filepath.Join(home, pattern[2:])
+			}
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("included config not found: %s", pattern)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// withIncludes layers the included files under the main config settings:
+// includes apply in order, then the main file, so the main file wins on
+// conflicting values. Arrays of tables such as [[junk.sites]] are
+// concatenated instead, included entries first. Includes of included files
+// are not followed.
+func withIncludes(main map[string]any, patterns []string, dir string) (map[string]any, error) {
+	paths, err := resolveIncludes(patterns, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]any{}
+	for _, path := range paths {
+		v := viper.New()
+		v.SetConfigFile(path)
+		v.SetConfigType("toml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading included config %s: %w", path, err)
+		}
+		settings := v.AllSettings()
+		delete(settings, "include")
+		delete(settings, "pack")
+		mergeSettings(merged, settings)
+	}
+	mergeSettings(merged, main)
+	return merged, nil
+}
+
+// mergeSettings merges src into dst: tables merge key by key, arrays of
+// tables are appended, and any other value in src replaces dst's
+func mergeSettings(dst, src map[string]any) {
+	for key, value := range src {
+		switch v := value.(type) {
+		case map[string]any:
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeSettings(existing, v)
+				continue
+			}
+		case []any:
+			if existing, ok := dst[key].([]any); ok && isTableArray(existing) && isTableArray(v) {
+				dst[key] = append(existing, v...)
+				continue
+			}
+		case []map[string]any:
+			if existing, ok := dst[key].([]map[string]any); ok {
+				dst[key] = append(existing, v...)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+func isTableArray(items []any) bool {
+	for _, item := range items {
+		if _, ok := item.(map[string]any); !ok {
+			return false
+		}
+	}
+	return len(items) > 0
+}
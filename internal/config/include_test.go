@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_IncludesLayerUnderMainConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "sites", "b.toml"), `
+[network]
+delay = 2
+
+[[junk.sites]]
+host = "b.example"
+selectors = [".b-promo"]
+`)
+	writeConfig(t, filepath.Join(dir, "sites", "a.toml"), `
+[network]
+delay = 1
+timeout = 99
+
+[[junk.sites]]
+host = "a.example"
+disabled = true
+`)
+	main := filepath.Join(dir, "config.toml")
+	writeConfig(t, main, `
+include = ["sites/*.toml"]
+
+[network]
+delay = 5
+
+[[junk.sites]]
+host = "main.example"
+`)
+
+	cfg, err := Load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Network.Delay != 5 {
+		t.Errorf("expected the main file to win, got delay %d", cfg.Network.Delay)
+	}
+	if cfg.Network.Timeout != 99 {
+		t.Errorf("expected values only set in an include to apply, got timeout %d", cfg.Network.Timeout)
+	}
+
+	var hosts []string
+	for _, site := range cfg.Junk.Sites {
+		hosts = append(hosts, site.Host)
+	}
+	want := []string{"a.example", "b.example", "main.example"}
+	if len(hosts) != len(want) {
+		t.Fatalf("expected sites %v, got %v", want, hosts)
+	}
+	for i := range want {
+		if hosts[i] != want[i] {
+			t.Errorf("expected sites %v in order, got %v", want, hosts)
+			break
+		}
+	}
+	if !cfg.Junk.Sites[0].Disabled || cfg.Junk.Sites[1].Selectors[0] != ".b-promo" {
+		t.Errorf("included site rules were not decoded: %+v", cfg.Junk.Sites)
+	}
+}
+
+func TestResolveIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "one.toml"), "")
+
+	if _, err := resolveIncludes([]string{"missing.toml"}, dir); err == nil {
+		t.Error("expected error for a missing include without wildcards")
+	}
+	paths, err := resolveIncludes([]string{"none/*.toml", "one.toml", "*.toml"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != filepath.Join(dir, "one.toml") {
+		t.Errorf("expected one deduplicated include, got %v", paths)
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_SnakeCaseKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, `
+[extraction]
+js_timeout = 42
+min_content_length = 7
+
+[network]
+connect_timeout = 3
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Extraction.JSTimeout != 42 || cfg.Extraction.MinContentLength != 7 || cfg.Network.ConnectTimeout != 3 {
+		t.Errorf("snake_case keys not applied: js_timeout %d, min_content_length %d, connect_timeout %d",
+			cfg.Extraction.JSTimeout, cfg.Extraction.MinContentLength, cfg.Network.ConnectTimeout)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// RulesDir is the directory below the config directory that holds installed
+// rule packs; every *.toml file in it is layered under the main config
+const RulesDir = "rules"
+
+// rulePackNameRe restricts pack names to what is safe as a file name
+var rulePackNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// rulePackSections are the config tables a rule pack may contain, each only
+// with its sites list, so a pack cannot change network, output or API
+// settings
+var rulePackSections = []string{"junk", "expand", "extraction"}
+
+// RulePack is a shareable set of per-site rules: junk selectors and keep
+// lists, expand selectors and extraction backend routes. A pack is a TOML
+// file with a [pack] table naming it and [[junk.sites]], [[expand.sites]]
+// and [[extraction.sites]] entries.
+type RulePack struct {
+	Name        string
+	Version     string
+	Description string
+
+	Junk       []JunkSiteConfig
+	Expand     []ExpandSiteConfig
+	Extraction []ExtractionSiteConfig
+}
+
+// rulePackFile mirrors the TOML layout of a pack
+type rulePackFile struct {
+	Pack struct {
+		Name        string `toml:"name"`
+		Version     string `toml:"version"`
+		Description string `toml:"description"`
+	} `toml:"pack"`
+	Junk struct {
+		Sites []JunkSiteConfig `toml:"sites"`
+	} `toml:"junk"`
+	Expand struct {
+		Sites []ExpandSiteConfig `toml:"sites"`
+	} `toml:"expand"`
+	Extraction struct {
+		Sites []ExtractionSiteConfig `toml:"sites"`
+	} `toml:"extraction"`
+}
+
+// ParseRulePack reads a rule pack, rejecting packs without a valid name and
+// version and any settings other than site rules
+func ParseRulePack(data []byte) (*RulePack, error) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid rule pack: %w", err)
+	}
+	for key, value := range v.AllSettings() {
+		if key == "pack" {
+			continue
+		}
+		if !slices.Contains(rulePackSections, key) {
+			return nil, fmt.Errorf("rule pack may only contain [pack], %s site rules, found [%s]", strings.Join(rulePackSections, ", "), key)
+		}
+		table, _ := value.(map[string]any)
+		for sub := range table {
+			if sub != "sites" {
+				return nil, fmt.Errorf("rule pack may only set %s.sites, found %s.%s", key, key, sub)
+			}
+		}
+	}
+
+	var file rulePackFile
+	if err := v.Unmarshal(&file, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "toml"
+		dc.ErrorUnused = true
+	}); err != nil {
+		return nil, fmt.Errorf("invalid rule pack: %w", err)
+	}
+
+	pack := &RulePack{
+		Name:        file.Pack.Name,
+		Version:     file.Pack.Version,
+		Description: file.Pack.Description,
+		Junk:        file.Junk.Sites,
+		Expand:      file.Expand.Sites,
+		Extraction:  file.Extraction.Sites,
+	}
+	if !rulePackNameRe.MatchString(pack.Name) {
+		return nil, fmt.Errorf("rule pack name %q must be lowercase letters, digits, '.', '_' or '-'", pack.Name)
+	}
+	if _, err := parseVersion(pack.Version); err != nil {
+		return nil, fmt.Errorf("rule pack %s: %w", pack.Name, err)
+	}
+	if len(pack.Junk)+len(pack.Expand)+len(pack.Extraction) == 0 {
+		return nil, fmt.Errorf("rule pack %s has no site rules", pack.Name)
+	}
+	return pack, nil
+}
+
+// CompareVersions orders two dotted numeric versions such as "1.10.2",
+// returning -1, 0 or 1; missing components count as 0 and a leading "v" is
+// ignored
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for len(va) < len(vb) {
+		va = append(va, 0)
+	}
+	for len(vb) < len(va) {
+		vb = append(vb, 0)
+	}
+	return slices.Compare(va, vb), nil
+}
+
+func parseVersion(version string) ([]int, error) {
+	if version == "" {
+		return nil, fmt.Errorf("missing version")
+	}
+	var parts []int
+	for _, field := range strings.Split(strings.TrimPrefix(version, "v"), ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q: want dotted numbers such as 1.2.0", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRulePack = `
+[pack]
+name = "news"
+version = "1.2.0"
+description = "News site cleanup"
+
+[[junk.sites]]
+host = "news.example"
+selectors = [".promo"]
+keep = [".byline"]
+
+[[expand.sites]]
+host = "news.example"
+selectors = [".more"]
+
+[[extraction.sites]]
+host = "paywall.example"
+backend = "jina"
+`
+
+func TestParseRulePack(t *testing.T) {
+	pack, err := ParseRulePack([]byte(testRulePack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Name != "news" || pack.Version != "1.2.0" || pack.Description != "News site cleanup" {
+		t.Errorf("unexpected pack header: %+v", pack)
+	}
+	if len(pack.Junk) != 1 || pack.Junk[0].Keep[0] != ".byline" {
+		t.Errorf("unexpected junk rules: %+v", pack.Junk)
+	}
+	if len(pack.Expand) != 1 || len(pack.Extraction) != 1 || pack.Extraction[0].Backend != "jina" {
+		t.Errorf("unexpected expand or extraction rules: %+v %+v", pack.Expand, pack.Extraction)
+	}
+}
+
+func TestParseRulePack_Rejects(t *testing.T) {
+	tests := []struct {
+		name, pack, want string
+	}{
+		{"other section", "[pack]\nname = \"x\"\nversion = \"1\"\n[network]\ntimeout = 1\n", "found [network]"},
+		{"section setting", "[pack]\nname = \"x\"\nversion = \"1\"\n[junk]\nenabled = false\n", "found junk.enabled"},
+		{"unknown site key", "[pack]\nname = \"x\"\nversion = \"1\"\n[[junk.sites]]\nhost = \"a\"\nremove = [\"b\"]\n", "remove"},
+		{"bad name", "[pack]\nname = \"../x\"\nversion = \"1\"\n[[junk.sites]]\nhost = \"a\"\n", "rule pack name"},
+		{"no version", "[pack]\nname = \"x\"\n[[junk.sites]]\nhost = \"a\"\n", "missing version"},
+		{"bad version", "[pack]\nname = \"x\"\nversion = \"1.x\"\n[[junk.sites]]\nhost = \"a\"\n", "invalid version"},
+		{"no rules", "[pack]\nname = \"x\"\nversion = \"1\"\n", "no site rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRulePack([]byte(tt.pack))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.0", "1.2", 0},
+		{"v1.10.0", "1.9.9", 1},
+		{"0.9", "1.0.0", -1},
+		{"2", "1.99", 1},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if _, err := CompareVersions("1.0-beta", "1.0"); err == nil {
+		t.Error("expected an error for a non-numeric version")
+	}
+}
+
+func TestLoad_LayersInstalledRulePacks(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, RulesDir, "news.toml"), testRulePack)
+	main := filepath.Join(dir, "config.toml")
+	writeConfig(t, main, `
+[[extraction.sites]]
+host = "paywall.example"
+backend = "readability"
+`)
+
+	cfg, err := Load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Junk.Sites) != 1 || cfg.Junk.Sites[0].Host != "news.example" {
+		t.Errorf("expected the pack's junk rules, got %+v", cfg.Junk.Sites)
+	}
+	sites := cfg.Extraction.Sites
+	if len(sites) != 2 || sites[1].Backend != "readability" {
+		t.Errorf("expected the main config's route after the pack's, got %+v", sites)
+	}
+}
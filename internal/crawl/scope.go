@@ -0,0 +1,201 @@
+// Package crawl holds the rules that decide which discovered links a crawl
+// follows
+package crawl
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Subdomain policies: which hosts besides the seed hosts are in scope
+const (
+	SubdomainsNone   = "none"   // only the seed hosts
+	SubdomainsAll    = "all"    // the seed hosts and their subdomains
+	SubdomainsDomain = "domain" // every host under a seed's registrable domain
+)
+
+// ScopeRules configures a Scope. Hosts always come from the seed URLs; the
+// other rules narrow what is followed on them.
+type ScopeRules struct {
+	Subdomains      string   // none (default), all or domain
+	PathPrefixes    []string // follow only paths below one of these (empty = all)
+	ExcludePrefixes []string // never follow paths below these
+	Include         []string // URL patterns; one must match (empty = all)
+	Exclude         []string // URL patterns never followed
+	MaxPagesPerHost int      // 0 = unlimited
+	FollowQuery     bool     // follow URLs with a query string
+}
+
+// Scope decides whether a crawl follows a URL. Not safe for concurrent use.
+type Scope struct {
+	rules   ScopeRules
+	hosts   map[string]bool // seed hosts, without www.
+	domains map[string]bool // registrable domains of the seed hosts
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	pages   map[string]int // admitted URLs per host
+}
+
+// NewScope compiles the rules for a crawl starting at seeds, reporting
+// unknown subdomain policies and invalid patterns
+func NewScope(seeds []string, rules ScopeRules) (*Scope, error) {
+	switch rules.Subdomains {
+	case "":
+		rules.Subdomains = SubdomainsNone
+	case SubdomainsNone, SubdomainsAll, SubdomainsDomain:
+	default:
+		return nil, fmt.Errorf("unknown subdomain policy: %s (available: none, all, domain)", rules.Subdomains)
+	}
+	if rules.MaxPagesPerHost < 0 {
+		return nil, fmt.Errorf("max pages per host must not be negative")
+	}
+
+	s := &Scope{
+		rules:   rules,
+		hosts:   make(map[string]bool),
+		domains: make(map[string]bool),
+		pages:   make(map[string]int),
+	}
+	for _, seed := range seeds {
+		u, err := url.Parse(seed)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid seed URL: %s", seed)
+		}
+		host := normalizeHost(u.Hostname())
+		s.hosts[host] = true
+		s.domains[registrableDomain(host)] = true
+	}
+
+	var err error
+	if s.include, err = compilePatterns(rules.Include); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = compilePatterns(rules.Exclude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crawl pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Allow reports whether rawURL is in scope, and the rule that excludes it
+// when it is not. The page limit counts URLs passed to Admit.
+func (s *Scope) Allow(rawURL string) (bool, string) {
+	if ok, reason := s.Match(rawURL); !ok {
+		return false, reason
+	}
+	u, _ := url.Parse(rawURL)
+	host := normalizeHost(u.Hostname())
+	if limit := s.rules.MaxPagesPerHost; limit > 0 && s.pages[host] >= limit {
+		return false, fmt.Sprintf("page limit of %d reached for %s", limit, host)
+	}
+	return true, ""
+}
+
+// Match is Allow without the page limit: it reports whether the rules let
+// rawURL in at all
+func (s *Scope) Match(rawURL string) (bool, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, "invalid URL"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, "not an http(s) URL"
+	}
+	host := normalizeHost(u.Hostname())
+	if !s.hostInScope(host) {
+		return false, "host out of scope: " + host
+	}
+	if u.RawQuery != "" && !s.rules.FollowQuery {
+		return false, "has a query string"
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if len(s.rules.PathPrefixes) > 0 && !hasAnyPrefix(path, s.rules.PathPrefixes) {
+		return false, "path outside path_prefixes"
+	}
+	if hasAnyPrefix(path, s.rules.ExcludePrefixes) {
+		return false, "path in exclude_prefixes"
+	}
+	if len(s.include) > 0 && !matchesAny(rawURL, s.include) {
+		return false, "matches no include pattern"
+	}
+	if matchesAny(rawURL, s.exclude) {
+		return false, "matches an exclude pattern"
+	}
+	return true, ""
+}
+
+// Admit checks rawURL and, when it is in scope, counts it against its
+// host's page limit
+func (s *Scope) Admit(rawURL string) (bool, string) {
+	ok, reason := s.Allow(rawURL)
+	if ok {
+		u, _ := url.Parse(rawURL)
+		s.pages[normalizeHost(u.Hostname())]++
+	}
+	return ok, reason
+}
+
+func (s *Scope) hostInScope(host string) bool {
+	if s.hosts[host] {
+		return true
+	}
+	switch s.rules.Subdomains {
+	case SubdomainsAll:
+		for seed := range s.hosts {
+			if strings.HasSuffix(host, "."+seed) {
+				return true
+			}
+		}
+	case SubdomainsDomain:
+		return s.domains[registrableDomain(host)]
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+func registrableDomain(host string) string {
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
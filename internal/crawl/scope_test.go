@@ -0,0 +1,93 @@
+package crawl
+
+import "testing"
+
+func TestScope_SubdomainPolicies(t *testing.T) {
+	seeds := []string{"https://www.example.com/docs/"}
+	cases := []struct {
+		policy string
+		url    string
+		want   bool
+	}{
+		{SubdomainsNone, "https://example.com/a", true},
+		{SubdomainsNone, "https://blog.example.com/a", false},
+		{SubdomainsAll, "https://blog.example.com/a", true},
+		{SubdomainsAll, "https://other.com/a", false},
+		{SubdomainsDomain, "https://blog.example.com/a", true},
+		{SubdomainsDomain, "https://example.org/a", false},
+	}
+	for _, c := range cases {
+		s, err := NewScope(seeds, ScopeRules{Subdomains: c.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, reason := s.Allow(c.url); got != c.want {
+			t.Errorf("%s: Allow(%s) = %v (%s), want %v", c.policy, c.url, got, reason, c.want)
+		}
+	}
+}
+
+func TestScope_PathsPatternsAndQueries(t *testing.T) {
+	s, err := NewScope([]string{"https://example.com/"}, ScopeRules{
+		PathPrefixes:    []string{"/docs/"},
+		ExcludePrefixes: []string{"/docs/archive/"},
+		Exclude:         []string{`\.pdf$`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		"https://example.com/docs/intro":        true,
+		"https://example.com/blog/post":         false,
+		"https://example.com/docs/archive/old":  false,
+		"https://example.com/docs/manual.pdf":   false,
+		"https://example.com/docs/intro?page=2": false,
+		"mailto:team@example.com":               false,
+	}
+	for u, want := range cases {
+		if got, reason := s.Allow(u); got != want {
+			t.Errorf("Allow(%s) = %v (%s), want %v", u, got, reason, want)
+		}
+	}
+
+	s, _ = NewScope([]string{"https://example.com/"}, ScopeRules{FollowQuery: true, Include: []string{`/docs/`}})
+	if ok, reason := s.Allow("https://example.com/docs/intro?page=2"); !ok {
+		t.Errorf("expected query URL with follow_query, got %s", reason)
+	}
+	if ok, _ := s.Allow("https://example.com/blog/"); ok {
+		t.Error("expected URL matching no include pattern to be out of scope")
+	}
+}
+
+func TestScope_MaxPagesPerHost(t *testing.T) {
+	s, err := NewScope([]string{"https://example.com/"}, ScopeRules{MaxPagesPerHost: 2, Subdomains: SubdomainsAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"https://example.com/a", "https://www.example.com/b"} {
+		if ok, reason := s.Admit(u); !ok {
+			t.Fatalf("Admit(%s) refused: %s", u, reason)
+		}
+	}
+	if ok, _ := s.Admit("https://example.com/c"); ok {
+		t.Error("expected third page on the host to be refused")
+	}
+	if ok, reason := s.Match("https://example.com/c"); !ok {
+		t.Errorf("expected Match to ignore the page limit, got %s", reason)
+	}
+	if ok, reason := s.Admit("https://blog.example.com/a"); !ok {
+		t.Errorf("expected the limit to be per host, got %s", reason)
+	}
+}
+
+func TestNewScope_Errors(t *testing.T) {
+	if _, err := NewScope(nil, ScopeRules{Subdomains: "sideways"}); err == nil {
+		t.Error("expected error for unknown subdomain policy")
+	}
+	if _, err := NewScope(nil, ScopeRules{Exclude: []string{"("}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := NewScope([]string{"not a url"}, ScopeRules{}); err == nil {
+		t.Error("expected error for invalid seed")
+	}
+}
@@ -0,0 +1,249 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// Chapter is one article in the book
+type Chapter struct {
+	Title  string
+	URL    string
+	Author string
+	HTML   string // article body HTML; converted to XHTML when the book is written
+}
+
+// Book is an EPUB 3 document assembled from extracted articles
+type Book struct {
+	Title      string
+	Author     string
+	Language   string
+	Identifier string // defaults to a hash of the chapter URLs
+	Date       time.Time
+	Chapters   []Chapter
+
+	images []image
+}
+
+type image struct {
+	href      string
+	mediaType string
+	data      []byte
+}
+
+// New creates an empty book
+func New(title string) *Book {
+	return &Book{
+		Title:    title,
+		Language: "en",
+		Date:     time.Now().UTC(),
+	}
+}
+
+// AddChapter appends an article to the book
+func (b *Book) AddChapter(ch Chapter) {
+	b.Chapters = append(b.Chapters, ch)
+}
+
+// AddImage embeds image data and returns the href chapters should reference.
+// Identical images are stored once.
+func (b *Book) AddImage(data []byte, mediaType string) string {
+	sum := sha256.Sum256(data)
+	name := "images/" + hex.EncodeToString(sum[:8]) + ImageExtension(mediaType)
+	for _, img := range b.images {
+		if img.href == name {
+			return name
+		}
+	}
+	b.images = append(b.images, image{href: name, mediaType: mediaType, data: data})
+	return name
+}
+
+// ImageExtension returns the file extension for an image media type
+func ImageExtension(mediaType string) string {
+	switch mediaType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	default:
+		return ".jpg"
+	}
+}
+
+// Write serializes the book as an EPUB container
+func (b *Book) Write(w io.Writer) error {
+	if len(b.Chapters) == 0 {
+		return fmt.Errorf("epub: book has no chapters")
+	}
+	if b.Identifier == "" {
+		b.Identifier = b.defaultIdentifier()
+	}
+
+	zw := zip.NewWriter(w)
+
+	// The mimetype entry must come first and be stored uncompressed
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return fmt.Errorf("epub: %w", err)
+	}
+	if _, err := io.WriteString(mt, "application/epub+zip"); err != nil {
+		return fmt.Errorf("epub: %w", err)
+	}
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"META-INF/container.xml", []byte(containerXML)},
+		{"OEBPS/content.opf", []byte(b.packageDocument())},
+		{"OEBPS/nav.xhtml", []byte(b.navDocument())},
+	}
+
+	for i, ch := range b.Chapters {
+		doc, err := b.chapterDocument(ch)
+		if err != nil {
+			return fmt.Errorf("epub: chapter %d (%s): %w", i+1, ch.URL, err)
+		}
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{"OEBPS/" + chapterFile(i), []byte(doc)})
+	}
+	for _, img := range b.images {
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{"OEBPS/" + img.href, img.data})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("epub: %w", err)
+		}
+		if _, err := fw.Write(f.content); err != nil {
+			return fmt.Errorf("epub: %w", err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func (b *Book) defaultIdentifier() string {
+	h := sha256.New()
+	for _, ch := range b.Chapters {
+		io.WriteString(h, ch.URL)
+	}
+	return "urn:scrpr:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func chapterFile(i int) string {
+	return fmt.Sprintf("chapter-%03d.xhtml", i+1)
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (b *Book) packageDocument() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&sb, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", html.EscapeString(b.Identifier))
+	fmt.Fprintf(&sb, "    <dc:title>%s</dc:title>\n", html.EscapeString(b.Title))
+	fmt.Fprintf(&sb, "    <dc:language>%s</dc:language>\n", html.EscapeString(b.Language))
+	if b.Author != "" {
+		fmt.Fprintf(&sb, "    <dc:creator>%s</dc:creator>\n", html.EscapeString(b.Author))
+	}
+	fmt.Fprintf(&sb, "    <dc:date>%s</dc:date>\n", b.Date.Format("2006-01-02"))
+	fmt.Fprintf(&sb, "    <meta property=\"dcterms:modified\">%s</meta>\n", b.Date.Format("2006-01-02T15:04:05Z"))
+	for _, ch := range b.Chapters {
+		if ch.URL != "" {
+			fmt.Fprintf(&sb, "    <dc:source>%s</dc:source>\n", html.EscapeString(ch.URL))
+		}
+	}
+	sb.WriteString("  </metadata>\n  <manifest>\n")
+	sb.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	for i := range b.Chapters {
+		fmt.Fprintf(&sb, "    <item id=\"ch%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterFile(i))
+	}
+	for i, img := range b.images {
+		fmt.Fprintf(&sb, "    <item id=\"img%d\" href=\"%s\" media-type=\"%s\"/>\n", i+1, img.href, img.mediaType)
+	}
+	sb.WriteString("  </manifest>\n  <spine>\n")
+	for i := range b.Chapters {
+		fmt.Fprintf(&sb, "    <itemref idref=\"ch%d\"/>\n", i+1)
+	}
+	sb.WriteString("  </spine>\n</package>\n")
+	return sb.String()
+}
+
+func (b *Book) navDocument() string {
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader(b.Title, b.Language))
+	sb.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>\n<ol>\n")
+	for i, ch := range b.Chapters {
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n", chapterFile(i), html.EscapeString(chapterTitle(ch, i)))
+	}
+	sb.WriteString("</ol>\n</nav>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+func (b *Book) chapterDocument(ch Chapter) (string, error) {
+	body, err := ToXHTML(ch.HTML)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	title := chapterTitle(ch, 0)
+	sb.WriteString(xhtmlHeader(title, b.Language))
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(title))
+	if ch.Author != "" {
+		fmt.Fprintf(&sb, "<p class=\"byline\">%s</p>\n", html.EscapeString(ch.Author))
+	}
+	if ch.URL != "" {
+		fmt.Fprintf(&sb, "<p class=\"source\"><a href=\"%s\">%s</a></p>\n", html.EscapeString(ch.URL), html.EscapeString(ch.URL))
+	}
+	sb.WriteString(body)
+	sb.WriteString("\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+func chapterTitle(ch Chapter, i int) string {
+	if ch.Title != "" {
+		return ch.Title
+	}
+	if ch.URL != "" {
+		return ch.URL
+	}
+	return fmt.Sprintf("Chapter %d", i+1)
+}
+
+func xhtmlHeader(title, lang string) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+`)
+	fmt.Fprintf(&buf, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" xml:lang=\"%s\" lang=\"%s\">\n", html.EscapeString(lang), html.EscapeString(lang))
+	fmt.Fprintf(&buf, "<head>\n<meta charset=\"UTF-8\"/>\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+	return buf.String()
+}
@@ -0,0 +1,68 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestToXHTML_WellFormed(t *testing.T) {
+	got, err := ToXHTML(`<p>One<br>two &amp; <img src="a.png" alt="x"></p><script>evil()</script><p onclick="x()">three`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "evil") || strings.Contains(got, "onclick") {
+		t.Errorf("expected scripts and handlers removed: %s", got)
+	}
+
+	dec := xml.NewDecoder(strings.NewReader("<div>" + got + "</div>"))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("not well-formed XHTML: %v\n%s", err, got)
+		}
+	}
+}
+
+func TestBookWrite(t *testing.T) {
+	book := New("Reading List")
+	book.AddChapter(Chapter{Title: "First", URL: "https://example.com/1", HTML: "<p>Hello</p>"})
+	book.AddChapter(Chapter{Title: "Second", URL: "https://example.com/2", HTML: "<p>World</p>"})
+	href := book.AddImage([]byte("fake png"), "image/png")
+	if again := book.AddImage([]byte("fake png"), "image/png"); again != href {
+		t.Errorf("expected duplicate image to reuse %s, got %s", href, again)
+	}
+
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+		t.Errorf("mimetype must be the first, uncompressed entry")
+	}
+
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/chapter-001.xhtml", "OEBPS/chapter-002.xhtml", "OEBPS/" + href} {
+		if !names[want] {
+			t.Errorf("missing %s in archive", want)
+		}
+	}
+}
+
+func TestBookWrite_Empty(t *testing.T) {
+	if err := New("empty").Write(io.Discard); err == nil {
+		t.Error("expected error for book without chapters")
+	}
+}
@@ -0,0 +1,99 @@
+package epub
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// voidElements are written as self-closing tags in XHTML
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// droppedElements are not allowed (or not useful) in EPUB content documents
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true,
+	"form": true, "input": true, "button": true, "object": true, "embed": true,
+}
+
+// ToXHTML converts an HTML fragment into well-formed XHTML suitable for an
+// EPUB content document. Scripts, forms and embeds are dropped.
+func ToXHTML(fragment string) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeXHTML(&sb, n)
+	}
+	return sb.String(), nil
+}
+
+func writeXHTML(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(escapeXML(n.Data))
+	case html.ElementNode:
+		if droppedElements[n.Data] {
+			return
+		}
+		sb.WriteByte('<')
+		sb.WriteString(n.Data)
+		for _, attr := range n.Attr {
+			if attr.Namespace != "" || !validAttrName(attr.Key) || strings.HasPrefix(attr.Key, "on") {
+				continue
+			}
+			sb.WriteByte(' ')
+			sb.WriteString(attr.Key)
+			sb.WriteString(`="`)
+			sb.WriteString(escapeXML(attr.Val))
+			sb.WriteByte('"')
+		}
+		if voidElements[n.Data] {
+			sb.WriteString("/>")
+			return
+		}
+		sb.WriteByte('>')
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTML(sb, c)
+		}
+		sb.WriteString("</")
+		sb.WriteString(n.Data)
+		sb.WriteByte('>')
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTML(sb, c)
+		}
+	}
+	// Comments and doctypes are dropped
+}
+
+func validAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
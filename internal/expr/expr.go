@@ -0,0 +1,329 @@
+// Package expr is a small boolean expression language for filtering
+// results, e.g. `length > 500 && lang == "en" && !paywalled`.
+//
+// Values are numbers, strings and booleans. Operators, loosest first:
+// ||, &&, the comparisons == != < <= > >= and =~ (regular expression
+// match), then prefix !. Parentheses group. Expressions are type checked
+// against the variables when compiled, so evaluating one cannot fail.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of a variable or subexpression
+type Type int
+
+const (
+	Bool Type = iota
+	Number
+	String
+)
+
+func (t Type) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// Expr is a compiled expression
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src and checks it against vars, the names and types of the
+// variables it may use; the whole expression must be a bool
+func Compile(src string, vars map[string]Type) (*Expr, error) {
+	p := &parser{lex: lexer{src: src}, vars: vars}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	switch p.tok.kind {
+	case tokEOF:
+	case tokError:
+		return nil, p.errorf("%s", p.tok.text)
+	default:
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	if root.typ() != Bool {
+		return nil, fmt.Errorf("expression is a %s, not a condition", root.typ())
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval evaluates the expression. env holds the variable values: float64 or
+// int for numbers, string and bool; missing ones are the zero value.
+func (e *Expr) Eval(env map[string]any) bool {
+	return e.root.eval(env).(bool)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type node interface {
+	typ() Type
+	eval(env map[string]any) any
+}
+
+type literal struct {
+	t Type
+	v any
+}
+
+func (n literal) typ() Type               { return n.t }
+func (n literal) eval(map[string]any) any { return n.v }
+
+type variable struct {
+	t    Type
+	name string
+}
+
+func (n variable) typ() Type { return n.t }
+
+func (n variable) eval(env map[string]any) any {
+	switch v := env[n.name].(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case nil:
+		return zero(n.t)
+	default:
+		return v
+	}
+}
+
+func zero(t Type) any {
+	switch t {
+	case Bool:
+		return false
+	case Number:
+		return 0.0
+	default:
+		return ""
+	}
+}
+
+type not struct{ x node }
+
+func (n not) typ() Type                   { return Bool }
+func (n not) eval(env map[string]any) any { return !n.x.eval(env).(bool) }
+
+type logical struct {
+	and  bool
+	l, r node
+}
+
+func (n logical) typ() Type { return Bool }
+
+func (n logical) eval(env map[string]any) any {
+	l := n.l.eval(env).(bool)
+	if n.and {
+		return l && n.r.eval(env).(bool)
+	}
+	return l || n.r.eval(env).(bool)
+}
+
+type compare struct {
+	op   string
+	l, r node
+}
+
+func (n compare) typ() Type { return Bool }
+
+func (n compare) eval(env map[string]any) any {
+	l, r := n.l.eval(env), n.r.eval(env)
+	var c int
+	switch l := l.(type) {
+	case float64:
+		switch r := r.(float64); {
+		case l < r:
+			c = -1
+		case l > r:
+			c = 1
+		}
+	case string:
+		c = strings.Compare(l, r.(string))
+	case bool:
+		if l != r.(bool) {
+			c = 1
+		}
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default: // >=
+		return c >= 0
+	}
+}
+
+type match struct {
+	x  node
+	re *regexp.Regexp
+}
+
+func (n match) typ() Type                   { return Bool }
+func (n match) eval(env map[string]any) any { return n.re.MatchString(n.x.eval(env).(string)) }
+
+type parser struct {
+	lex  lexer
+	tok  token
+	vars map[string]Type
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical(false)
+}
+
+// parseLogical parses a chain of || (and = false) or && operands
+func (p *parser) parseLogical(and bool) (node, error) {
+	op, operand := "||", func() (node, error) { return p.parseLogical(true) }
+	if and {
+		op, operand = "&&", p.parseUnary
+	}
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == op {
+		pos := p.tok.pos
+		p.next()
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != Bool || r.typ() != Bool {
+			return nil, fmt.Errorf("column %d: %s needs conditions on both sides", pos+1, op)
+		}
+		l = logical{and: and, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		pos := p.tok.pos
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != Bool {
+			return nil, fmt.Errorf("column %d: ! needs a condition, not a %s", pos+1, x.typ())
+		}
+		return not{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return l, nil
+	}
+	op, pos := p.tok.text, p.tok.pos
+	switch op {
+	case "=~":
+		p.next()
+		if p.tok.kind != tokString {
+			return nil, p.errorf("=~ needs a quoted regular expression")
+		}
+		re, err := regexp.Compile(p.tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid regular expression: %v", err)
+		}
+		p.next()
+		if l.typ() != String {
+			return nil, fmt.Errorf("column %d: =~ needs a string, not a %s", pos+1, l.typ())
+		}
+		return match{x: l, re: re}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		r, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != r.typ() {
+			return nil, fmt.Errorf("column %d: cannot compare a %s with a %s", pos+1, l.typ(), r.typ())
+		}
+		if l.typ() == Bool && op != "==" && op != "!=" {
+			return nil, fmt.Errorf("column %d: conditions only compare with == and !=", pos+1)
+		}
+		return compare{op: op, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: invalid number %q", tok.pos+1, tok.text)
+		}
+		return literal{t: Number, v: v}, nil
+	case tokString:
+		p.next()
+		return literal{t: String, v: tok.text}, nil
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true", "false":
+			return literal{t: Bool, v: tok.text == "true"}, nil
+		}
+		t, ok := p.vars[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("column %d: unknown name %q", tok.pos+1, tok.text)
+		}
+		return variable{t: t, name: tok.text}, nil
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokOp || p.tok.text != ")" {
+				return nil, p.errorf("expected )")
+			}
+			p.next()
+			return x, nil
+		}
+	case tokError:
+		return nil, fmt.Errorf("column %d: %s", tok.pos+1, tok.text)
+	}
+	return nil, p.errorf("expected a value, got %s", tok)
+}
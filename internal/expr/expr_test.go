@@ -0,0 +1,74 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+var testVars = map[string]Type{
+	"length":    Number,
+	"lang":      String,
+	"title":     String,
+	"paywalled": Bool,
+}
+
+func TestEval(t *testing.T) {
+	env := map[string]any{"length": 1200, "lang": "en", "title": "Go 1.25 released", "paywalled": false}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`length > 500 && lang == "en" && !paywalled`, true},
+		{`length > 500 && paywalled`, false},
+		{`length < 500 || lang == 'en'`, true},
+		{`!(length >= 1200)`, false},
+		{`length <= 1200 && length != 1000`, true},
+		{`title =~ "(?i)go \d+\.\d+"`, true},
+		{`title =~ "^Rust"`, false},
+		{`lang != "de" && paywalled == false`, true},
+		{`true || paywalled && false`, true}, // && binds tighter
+		{`"a\"b" == 'a"b'`, true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src, testVars)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.src, err)
+		}
+		if got := e.Eval(env); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvalMissingVariables(t *testing.T) {
+	e, err := Compile(`length == 0 && lang == "" && !paywalled`, testVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Eval(nil) {
+		t.Error("missing variables should be zero values")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`length > "500"`, "cannot compare a number with a string"},
+		{`lenght > 500`, `unknown name "lenght"`},
+		{`length`, "expression is a number"},
+		{`!lang`, "! needs a condition"},
+		{`length > 5 &&`, "expected a value"},
+		{`(length > 5`, "expected )"},
+		{`lang == "en`, "unterminated string"},
+		{`title =~ "("`, "invalid regular expression"},
+		{`length =~ "1"`, "=~ needs a string"},
+		{`paywalled < true`, "only compare with == and !="},
+		{`length > 5 lang`, "unexpected"},
+		{`length # 5`, "unexpected character"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src, testVars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
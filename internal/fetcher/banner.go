@@ -0,0 +1,207 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/chromedp/chromedp"
+)
+
+// BannerAction is the choice clicked on a cookie banner
+type BannerAction string
+
+const (
+	BannerAccept BannerAction = "accept"
+	BannerReject BannerAction = "reject"
+)
+
+// bannerPoll is how often the page is searched for a banner while the
+// consent manager may still be loading
+const bannerPoll = 250 * time.Millisecond
+
+// bannerGrace is how long a page that shows no banner is watched for one
+// before giving up; consent managers load after the page, but rarely later
+// than this
+const bannerGrace = 2 * time.Second
+
+// bannerSettle is the pause after a click for the banner to close
+const bannerSettle = 500 * time.Millisecond
+
+// bannerCMP is a consent management platform whose banner buttons are
+// known. Reject is empty when its first layer has no reject button.
+type bannerCMP struct {
+	Name   string `json:"name"`
+	Accept string `json:"accept"`
+	Reject string `json:"reject"`
+}
+
+// bannerCMPs are the consent managers recognized by their buttons. Banners
+// of others are dismissed by the labels of their buttons, see bannerScript.
+var bannerCMPs = []bannerCMP{
+	{"onetrust", "#onetrust-accept-btn-handler", "#onetrust-reject-all-handler"},
+	{"cookiebot", "#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll, #CybotCookiebotDialogBodyButtonAccept", "#CybotCookiebotDialogBodyButtonDecline"},
+	{"didomi", "#didomi-notice-agree-button", "#didomi-notice-disagree-button, .didomi-continue-without-agreeing"},
+	{"quantcast", ".qc-cmp2-summary-buttons button[mode='primary']", ".qc-cmp2-summary-buttons button[mode='secondary']"},
+	{"usercentrics", "[data-testid='uc-accept-all-button']", "[data-testid='uc-deny-all-button']"},
+	{"trustarc", "#truste-consent-button", "#truste-consent-required"},
+	{"osano", ".osano-cm-accept-all", ".osano-cm-denyAll"},
+	{"cookieyes", ".cky-btn-accept", ".cky-btn-reject"},
+	{"complianz", ".cmplz-accept", ".cmplz-deny"},
+	{"iubenda", ".iubenda-cs-accept-btn", ".iubenda-cs-reject-btn"},
+	{"klaro", ".klaro .cm-btn-accept-all, .klaro .cm-btn-success", ".klaro .cn-decline"},
+	{"borlabs", "#BorlabsCookieBox a[data-cookie-accept-all], #BorlabsCookieBox ._brlbs-btn-accept-all", "#BorlabsCookieBox a[data-cookie-refuse]"},
+	{"cookieconsent", ".cc-window .cc-allow, .cc-window .cc-dismiss", ".cc-window .cc-deny"},
+}
+
+// BannerRules configures cookie banner dismissal in JS mode
+type BannerRules struct {
+	action    BannerAction
+	selectors []string
+}
+
+// NewBannerRules validates the user's banner buttons, tried before the
+// built-in ones, and the action ("" = accept)
+func NewBannerRules(selectors []string, action BannerAction) (*BannerRules, error) {
+	switch action {
+	case "":
+		action = BannerAccept
+	case BannerAccept, BannerReject:
+	default:
+		return nil, fmt.Errorf("invalid banner action %q (accept or reject)", action)
+	}
+	for _, sel := range selectors {
+		if _, err := cascadia.Compile(sel); err != nil {
+			return nil, fmt.Errorf("invalid banner selector %q: %w", sel, err)
+		}
+	}
+	return &BannerRules{action: action, selectors: selectors}, nil
+}
+
+// bannerScript finds the button that dismisses the cookie banner with the
+// configured action and marks it with data-scrpr-banner: a configured
+// selector, a known consent manager's button, or a button labeled accept
+// (or reject) inside a visible cookie notice. Usercentrics-style banners
+// live in a shadow root; their button is clicked here, as CDP queries do not
+// reach it. Returns {name, shadow}, or null when no banner is showing.
+const bannerScript = `((cfg) => {
+	const roots = [document];
+	for (const el of document.querySelectorAll("body > *")) {
+		if (el.shadowRoot) roots.push(el.shadowRoot);
+	}
+	const visible = (el) => {
+		const r = el.getBoundingClientRect();
+		const s = getComputedStyle(el);
+		return r.width > 0 && r.height > 0 && s.visibility !== "hidden" && s.display !== "none";
+	};
+	const find = (sel) => {
+		for (const root of roots) {
+			let els = [];
+			try { els = root.querySelectorAll(sel); } catch (e) {}
+			for (const el of els) if (visible(el)) return el;
+		}
+		return null;
+	};
+	const mark = (el, name) => {
+		const shadow = el.getRootNode() !== document;
+		if (shadow) el.click();
+		else el.setAttribute("data-scrpr-banner", "");
+		return {name: name, shadow: shadow};
+	};
+
+	for (const sel of cfg.selectors) {
+		const el = find(sel);
+		if (el) return mark(el, "custom");
+	}
+	for (const cmp of cfg.cmps) {
+		const sel = cfg.reject ? cmp.reject : cmp.accept;
+		const el = sel && find(sel);
+		if (el) return mark(el, cmp.name);
+	}
+
+	const acceptRe = /^(accept( all| cookies)?|allow( all| cookies)?|agree|i agree|got it|ok|okay|alle akzeptieren|akzeptieren|alle cookies akzeptieren|zustimmen|einverstanden|tout accepter|accepter|j'accepte|accetta( tutto| tutti)?|aceptar( todo| todas)?|akkoord|accepteren|godkänn alla)\b/i;
+	const rejectRe = /^(reject( all| cookies)?|decline( all)?|deny|refuse( all)?|(only|use) (necessary|essential)|necessary only|essential only|alle ablehnen|ablehnen|nur (notwendige|erforderliche)|tout refuser|refuser|continuer sans accepter|rifiuta( tutto)?|rechazar( todo)?|weigeren)\b/i;
+	const labelRe = cfg.reject ? rejectRe : acceptRe;
+	const noticeRe = /cookie|consent|privacy|gdpr|datenschutz|tracking/i;
+	const notices = "[id*='cookie' i], [class*='cookie' i], [id*='consent' i], [class*='consent' i], [id*='gdpr' i], [class*='gdpr' i], [role='dialog'], [role='alertdialog'], [aria-modal='true']";
+	for (const root of roots) {
+		for (const notice of root.querySelectorAll(notices)) {
+			if (!visible(notice) || !noticeRe.test(notice.textContent)) continue;
+			for (const el of notice.querySelectorAll("button, [role='button'], a, input[type='button'], input[type='submit']")) {
+				const label = (el.value || el.textContent || "").trim();
+				if (label.length > 40 || !labelRe.test(label) || !visible(el)) continue;
+				const href = el.getAttribute("href");
+				if (href && !href.startsWith("#") && !href.startsWith("javascript:")) continue;
+				return mark(el, "generic");
+			}
+		}
+	}
+	return null;
+})(%s)`
+
+// bannerHit is what bannerScript found
+type bannerHit struct {
+	Name   string `json:"name"`
+	Shadow bool   `json:"shadow"`
+}
+
+// dismissBanner looks for a cookie banner for up to bannerGrace and clicks
+// its button with a real (CDP) mouse click; timeout bounds the whole step.
+// *dismissed is set to the consent manager of the dismissed banner, "" when
+// there was none. Failing to dismiss one is not an error: the page is
+// extracted with the banner.
+func dismissBanner(rules *BannerRules, timeout time.Duration, dismissed *string) chromedp.Action {
+	return chromedp.ActionFunc(func(parent context.Context) error {
+		ctx := parent
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(parent, timeout)
+			defer cancel()
+		}
+		cfg, err := json.Marshal(struct {
+			Selectors []string    `json:"selectors"`
+			CMPs      []bannerCMP `json:"cmps"`
+			Reject    bool        `json:"reject"`
+		}{rules.selectors, bannerCMPs, rules.action == BannerReject})
+		if err != nil {
+			return err
+		}
+		script := fmt.Sprintf(bannerScript, cfg)
+
+		start := time.Now()
+		for ctx.Err() == nil {
+			var hit *bannerHit
+			if err := chromedp.Evaluate(script, &hit).Do(ctx); err != nil {
+				break
+			}
+			if hit != nil {
+				if !hit.Shadow {
+					clickBanner(ctx)
+				}
+				*dismissed = hit.Name
+				_ = chromedp.Sleep(bannerSettle).Do(ctx)
+				break
+			}
+			if time.Since(start) >= bannerGrace {
+				break
+			}
+			_ = chromedp.Sleep(bannerPoll).Do(ctx)
+		}
+		// Running out of the step's own time is not the fetch's failure
+		return parent.Err()
+	})
+}
+
+// clickBanner clicks the button bannerScript marked; a button the mouse
+// cannot reach, under an overlay or off screen, is clicked from script
+func clickBanner(ctx context.Context) {
+	const marked = "[data-scrpr-banner]"
+	clickCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := chromedp.Click(marked, chromedp.ByQuery, chromedp.NodeVisible).Do(clickCtx); err != nil {
+		_ = chromedp.Evaluate(`document.querySelector("`+marked+`")?.click()`, nil).Do(ctx)
+	}
+	_ = chromedp.Evaluate(`document.querySelector("`+marked+`")?.removeAttribute("data-scrpr-banner")`, nil).Do(ctx)
+}
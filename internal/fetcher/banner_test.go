@@ -0,0 +1,50 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+)
+
+func TestNewBannerRules(t *testing.T) {
+	rules, err := NewBannerRules(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.action != BannerAccept {
+		t.Errorf("default action = %q, want accept", rules.action)
+	}
+
+	rules, err = NewBannerRules([]string{"#cmp .agree", "button[data-consent='yes']"}, BannerReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.action != BannerReject || len(rules.selectors) != 2 {
+		t.Errorf("rules = %+v", rules)
+	}
+
+	if _, err := NewBannerRules(nil, "ignore"); err == nil {
+		t.Error("unknown action should be refused")
+	}
+	if _, err := NewBannerRules([]string{"button[["}, BannerAccept); err == nil {
+		t.Error("invalid selector should be refused")
+	}
+}
+
+func TestBannerCMPs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmp := range bannerCMPs {
+		if seen[cmp.Name] {
+			t.Errorf("%s listed twice", cmp.Name)
+		}
+		seen[cmp.Name] = true
+		if cmp.Accept == "" {
+			t.Errorf("%s has no accept button", cmp.Name)
+		}
+		for _, sel := range []string{cmp.Accept, cmp.Reject} {
+			if _, err := cascadia.ParseGroup(sel); sel != "" && err != nil {
+				t.Errorf("%s: invalid selector %q: %v", cmp.Name, sel, err)
+			}
+		}
+	}
+}
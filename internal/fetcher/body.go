@@ -0,0 +1,66 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResponseTooLargeError reports a response body past the download limit
+type ResponseTooLargeError struct {
+	Limit int64 // bytes
+	Size  int64 // declared Content-Length (0 = unknown, body was cut off)
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	if e.Size > 0 {
+		return fmt.Sprintf("response too large: %d bytes exceeds limit of %s", e.Size, byteSize(e.Limit))
+	}
+	return fmt.Sprintf("response too large: download aborted past limit of %s", byteSize(e.Limit))
+}
+
+// byteSize formats n in whole MB when it is a multiple of one
+func byteSize(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// responseLimit resolves MaxResponseSize: 0 is the default, negative is
+// unlimited (returned as 0)
+func responseLimit(opts FetchOptions) int64 {
+	switch {
+	case opts.MaxResponseSize == 0:
+		return defaultMaxResponseSize
+	case opts.MaxResponseSize < 0:
+		return 0
+	}
+	return opts.MaxResponseSize
+}
+
+// readBody streams a response body into memory, stopping as soon as it
+// grows past limit bytes (0 = unlimited). A Content-Length over the limit
+// fails before anything is read.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, &ResponseTooLargeError{Limit: limit, Size: resp.ContentLength}
+	}
+
+	var buf bytes.Buffer
+	if resp.ContentLength > 0 {
+		buf.Grow(int(resp.ContentLength))
+	}
+	r := io.Reader(resp.Body)
+	if limit > 0 {
+		r = io.LimitReader(resp.Body, limit+1)
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if limit > 0 && int64(buf.Len()) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamingServer serves size bytes without a Content-Length, flushing so
+// the body arrives in chunks
+func streamingServer(size int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		chunk := strings.Repeat("x", 64<<10)
+		for sent := 0; sent < size; sent += len(chunk) {
+			w.Write([]byte(chunk[:min(len(chunk), size-sent)]))
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func TestFetchStatic_StreamingBodyOverLimit(t *testing.T) {
+	server := streamingServer(2 << 20)
+	defer server.Close()
+
+	_, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{
+		MaxResponseSize: 1 << 20,
+		Retry:           RetryConfig{MaxRetries: 2, RetryOnNetwork: true},
+	})
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected ResponseTooLargeError, got %v", err)
+	}
+	if tooLarge.Limit != 1<<20 || tooLarge.Size != 0 {
+		t.Errorf("unexpected error fields: %+v", tooLarge)
+	}
+}
+
+func TestFetchStatic_UnlimitedBody(t *testing.T) {
+	server := streamingServer(6 << 20)
+	defer server.Close()
+
+	result, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{MaxResponseSize: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.HTML) != 6<<20 {
+		t.Errorf("expected the whole body, got %d bytes", len(result.HTML))
+	}
+}
+
+func TestContentFetcher_ReadsWholeBody(t *testing.T) {
+	server := streamingServer(3 << 20)
+	defer server.Close()
+
+	cf := NewContentFetcher()
+	result, err := cf.Fetch(context.Background(), server.URL, FetchOptions{Mode: FetchModeStatic})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.HTML) != 3<<20 {
+		t.Errorf("expected %d bytes, got %d", 3<<20, len(result.HTML))
+	}
+
+	_, err = cf.Fetch(context.Background(), server.URL, FetchOptions{Mode: FetchModeStatic, MaxResponseSize: 1 << 20})
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected ResponseTooLargeError, got %v", err)
+	}
+}
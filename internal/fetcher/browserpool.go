@@ -0,0 +1,192 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrPoolClosed is returned by Acquire once the pool was closed
+var ErrPoolClosed = errors.New("browser pool closed")
+
+// BrowserPoolOptions sizes a BrowserPool
+type BrowserPoolOptions struct {
+	Browsers       int // Chrome instances kept running (0 = 1)
+	Tabs           int // pages rendered at once per instance (0 = 1)
+	MaxNavigations int // restart an instance after this many pages (0 = never)
+	// Flags of the Chrome instances (nil = chromedp's headless defaults)
+	AllocatorOptions []chromedp.ExecAllocatorOption
+}
+
+// BrowserPool keeps Chrome instances running for the whole run, so a batch
+// of JS fetches pays for starting Chrome once per instance instead of once
+// per URL. Every page is rendered in a new tab of its own browser context,
+// which Chrome disposes with the tab: cookies, storage and cache of one page
+// are never seen by the next. Safe for concurrent use.
+type BrowserPool struct {
+	opts  BrowserPoolOptions
+	slots chan struct{} // one per tab that may be open
+
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+	closed   bool
+
+	// Replaced in tests, which run without Chrome
+	launch  func() (context.Context, context.CancelFunc, error)
+	openTab func(browser context.Context) (context.Context, context.CancelFunc)
+}
+
+// pooledBrowser is one running Chrome instance
+type pooledBrowser struct {
+	ctx     context.Context // chromedp context of the instance
+	stop    context.CancelFunc
+	active  int  // tabs open
+	uses    int  // pages rendered
+	retired bool // takes no more tabs; stopped once the last one closes
+}
+
+// NewBrowserPool returns a pool; instances start on first use
+func NewBrowserPool(opts BrowserPoolOptions) *BrowserPool {
+	opts.Browsers = max(opts.Browsers, 1)
+	opts.Tabs = max(opts.Tabs, 1)
+	p := &BrowserPool{
+		opts:  opts,
+		slots: make(chan struct{}, opts.Browsers*opts.Tabs),
+	}
+	p.launch = p.launchChrome
+	p.openTab = func(browser context.Context) (context.Context, context.CancelFunc) {
+		return chromedp.NewContext(browser, chromedp.WithNewBrowserContext())
+	}
+	return p
+}
+
+// launchChrome starts an instance, keeping its first tab open as the
+// instance lives as long as that tab's context
+func (p *BrowserPool) launchChrome() (context.Context, context.CancelFunc, error) {
+	allocOpts := p.opts.AllocatorOptions
+	if allocOpts == nil {
+		allocOpts = chromedp.DefaultExecAllocatorOptions[:]
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	stop := func() {
+		cancel()
+		cancelAlloc()
+	}
+	if err := chromedp.Run(ctx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("failed to start Chrome: %w", err)
+	}
+	return ctx, stop, nil
+}
+
+// Acquire waits for a free tab and opens it in an isolated browser context.
+// The tab is closed, and ctx's cancellation closes it too, when release is
+// called; every Acquire must be paired with one release.
+func (p *BrowserPool) Acquire(ctx context.Context) (tab context.Context, release func(), err error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	b, err := p.browser()
+	if err != nil {
+		<-p.slots
+		return nil, nil, err
+	}
+	tab, closeTab := p.openTab(b.ctx)
+	stopAfter := context.AfterFunc(ctx, closeTab)
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			stopAfter()
+			closeTab()
+			p.done(b)
+			<-p.slots
+		})
+	}
+	return tab, release, nil
+}
+
+// browser picks the running instance with the fewest open tabs, starting
+// another while fewer than Browsers run and all of them are busy
+func (p *BrowserPool) browser() (*pooledBrowser, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	var best *pooledBrowser
+	running := 0
+	for _, b := range p.browsers {
+		if b.retired {
+			continue
+		}
+		// An instance that crashed or was killed is replaced
+		if b.ctx.Err() != nil {
+			b.retired = true
+			p.stopIdle(b)
+			continue
+		}
+		running++
+		if b.active < p.opts.Tabs && (best == nil || b.active < best.active) {
+			best = b
+		}
+	}
+	if best == nil || (best.active > 0 && running < p.opts.Browsers) {
+		ctx, stop, err := p.launch()
+		if err != nil {
+			if best != nil {
+				best.active++
+				return best, nil
+			}
+			return nil, err
+		}
+		best = &pooledBrowser{ctx: ctx, stop: stop}
+		p.browsers = append(p.browsers, best)
+	}
+	best.active++
+	return best, nil
+}
+
+// done returns a tab of b, retiring b once it rendered MaxNavigations pages
+func (p *BrowserPool) done(b *pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.active--
+	b.uses++
+	if p.opts.MaxNavigations > 0 && b.uses >= p.opts.MaxNavigations {
+		b.retired = true
+	}
+	if b.retired || p.closed {
+		p.stopIdle(b)
+	}
+}
+
+// stopIdle stops a retired instance without open tabs; p.mu must be held
+func (p *BrowserPool) stopIdle(b *pooledBrowser) {
+	if b.active > 0 {
+		return
+	}
+	b.stop()
+	for i, other := range p.browsers {
+		if other == b {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			break
+		}
+	}
+}
+
+// Close stops the instances; tabs still open are closed with them
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, b := range p.browsers {
+		b.stop()
+	}
+	p.browsers = nil
+}
@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakePool is a pool whose instances are plain contexts, counting launches
+// and stops
+func fakePool(opts BrowserPoolOptions) (p *BrowserPool, launched, stopped *int) {
+	launched, stopped = new(int), new(int)
+	p = NewBrowserPool(opts)
+	p.launch = func() (context.Context, context.CancelFunc, error) {
+		*launched++
+		ctx, cancel := context.WithCancel(context.Background())
+		return ctx, func() {
+			if ctx.Err() == nil {
+				*stopped++
+			}
+			cancel()
+		}, nil
+	}
+	p.openTab = func(browser context.Context) (context.Context, context.CancelFunc) {
+		return context.WithCancel(browser)
+	}
+	return p, launched, stopped
+}
+
+func TestBrowserPool_ReusesInstances(t *testing.T) {
+	p, launched, _ := fakePool(BrowserPoolOptions{Browsers: 2, Tabs: 2})
+	defer p.Close()
+
+	for range 5 {
+		_, release, err := p.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if *launched != 1 {
+		t.Errorf("expected sequential fetches to share one instance, got %d launches", *launched)
+	}
+
+	// Busy instances get a second one, then share tabs
+	var releases []func()
+	for range 4 {
+		_, release, err := p.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	if *launched != 2 {
+		t.Errorf("expected 2 instances for 4 tabs, got %d", *launched)
+	}
+	for _, b := range p.browsers {
+		if b.active != 2 {
+			t.Errorf("expected tabs spread evenly, got %d on an instance", b.active)
+		}
+	}
+
+	// The pool is full until a tab is released
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := p.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a full pool to wait, got %v", err)
+	}
+	releases[0]()
+	releases[0]() // releasing twice is harmless
+	if _, release, err := p.Acquire(context.Background()); err != nil {
+		t.Errorf("expected a released tab to be reused, got %v", err)
+	} else {
+		release()
+	}
+	for _, release := range releases[1:] {
+		release()
+	}
+}
+
+func TestBrowserPool_RestartsAfterMaxNavigations(t *testing.T) {
+	p, launched, stopped := fakePool(BrowserPoolOptions{Tabs: 2, MaxNavigations: 2})
+	defer p.Close()
+
+	for range 4 {
+		_, release, err := p.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if *launched != 2 || *stopped != 2 {
+		t.Errorf("expected 2 launches and 2 stops, got %d and %d", *launched, *stopped)
+	}
+}
+
+func TestBrowserPool_ReplacesCrashedInstance(t *testing.T) {
+	p, launched, _ := fakePool(BrowserPoolOptions{})
+	defer p.Close()
+
+	_, release, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	p.browsers[0].stop() // Chrome went away
+
+	tab, release, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if *launched != 2 || tab.Err() != nil {
+		t.Errorf("expected a fresh instance, got %d launches, tab err %v", *launched, tab.Err())
+	}
+}
+
+func TestBrowserPool_Close(t *testing.T) {
+	p, _, stopped := fakePool(BrowserPoolOptions{})
+
+	tab, release, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if *stopped != 1 || tab.Err() == nil {
+		t.Errorf("expected Close to stop the instance and its tabs")
+	}
+	release()
+	if _, _, err := p.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+)
+
+// consentHosts are the interstitial hosts EU visitors get redirected to
+// before the article, by provider
+var consentHosts = map[string]string{
+	"consent.yahoo.com":     "yahoo",
+	"guce.yahoo.com":        "yahoo",
+	"guce.aol.com":          "aol",
+	"consent.google.com":    "google",
+	"consent.youtube.com":   "google",
+	"myprivacy.dpgmedia.nl": "dpg media",
+	"myprivacy.dpgmedia.be": "dpg media",
+	"consent.tumblr.com":    "tumblr",
+}
+
+// maxConsentWallText bounds the text of a page taken for a consent wall; a
+// real article that merely has a consent form is much longer
+const maxConsentWallText = 2000
+
+var consentTextRe = regexp.MustCompile(`(?i)\b(cookies?|consent|privacy settings|personal data|your privacy|datenschutz|einwilligung)\b`)
+
+// consentSettle is how long the accept flow waits for the redirect back to
+// the article
+const consentSettle = 3 * time.Second
+
+// ConsentWallError reports a consent interstitial served in place of the page
+type ConsentWallError struct {
+	Provider string
+}
+
+func (e *ConsentWallError) Error() string {
+	return fmt.Sprintf("consent wall (%s) served instead of the page", e.Provider)
+}
+
+// DetectConsentWall reports the provider of a full-page consent interstitial,
+// or "" when the page is not one. finalURL is the URL after redirects.
+func DetectConsentWall(finalURL, html string) string {
+	if u, err := url.Parse(finalURL); err == nil {
+		if provider, ok := consentHosts[strings.ToLower(u.Hostname())]; ok {
+			return provider
+		}
+	}
+
+	if !strings.Contains(strings.ToLower(html), "<form") {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	form := doc.Find("form[action*='consent' i], form[action*='cookie' i], form[action*='privacy' i]")
+	if form.Length() == 0 {
+		return ""
+	}
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	text := strings.Join(strings.Fields(body.Text()), " ")
+	if len(text) > maxConsentWallText || !consentTextRe.MatchString(text) {
+		return ""
+	}
+	if u, err := url.Parse(finalURL); err == nil && u.Hostname() != "" {
+		return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	return "consent form"
+}
+
+// acceptConsentScript clicks the accept button of a consent interstitial.
+// Returns whether it found one.
+const acceptConsentScript = `(() => {
+	const labelRe = /^(accept( all)?|agree|i agree|allow all|alle akzeptieren|akzeptieren|zustimmen|tout accepter|accepter|accetta tutto|aceptar todo|akkoord|accepteren|godkänn alla)\b/i;
+	const known = document.querySelector("button[name='agree'], button[value='agree'], #L2AGLb, button.accept-all, button[data-testid='accept-all']");
+	if (known) {
+		known.click();
+		return true;
+	}
+	for (const el of document.querySelectorAll("button, input[type='submit'], [role='button'], a")) {
+		const label = (el.value || el.textContent || "").trim();
+		if (label.length < 40 && labelRe.test(label)) {
+			el.click();
+			return true;
+		}
+	}
+	return false;
+})()`
+
+// acceptConsent follows the accept flow when the tab landed on a consent
+// wall, waiting for the redirect back. *provider is set to the wall's
+// provider, "" when there was none.
+func acceptConsent(provider *string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var location, html string
+		if err := chromedp.Location(&location).Do(ctx); err != nil {
+			return err
+		}
+		if err := chromedp.OuterHTML("html", &html).Do(ctx); err != nil {
+			return err
+		}
+		if *provider = DetectConsentWall(location, html); *provider == "" {
+			return nil
+		}
+
+		var clicked bool
+		if err := chromedp.Evaluate(acceptConsentScript, &clicked).Do(ctx); err != nil {
+			return fmt.Errorf("failed to accept consent: %w", err)
+		}
+		if !clicked {
+			return nil
+		}
+		if err := chromedp.Sleep(consentSettle).Do(ctx); err != nil {
+			return err
+		}
+		return chromedp.WaitReady("body").Do(ctx)
+	})
+}
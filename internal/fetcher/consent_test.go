@@ -0,0 +1,44 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetectConsentWall(t *testing.T) {
+	wall := `<html><body><h1>We value your privacy</h1>
+<p>We and our partners use cookies to store and access personal data on your device.</p>
+<form action="/v2/collectConsent" method="post"><button name="agree" value="agree">Accept all</button><button name="reject">Reject all</button></form>
+</body></html>`
+	article := `<html><body><article><h1>Story</h1><p>` + strings.Repeat("Plenty of article text here. ", 100) + `</p></article>
+<form action="/cookie-settings"><button>Accept cookies</button></form></body></html>`
+
+	tests := []struct {
+		name, url, html, want string
+	}{
+		{"known host", "https://consent.yahoo.com/v2/collectConsent?sessionId=1", "<html></html>", "yahoo"},
+		{"consent form page", "https://www.example.com/consent", wall, "example.com"},
+		{"article with consent form", "https://example.com/story", article, ""},
+		{"plain page", "https://example.com/", "<html><body><p>Hello</p></body></html>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectConsentWall(tt.url, tt.html); got != tt.want {
+				t.Errorf("DetectConsentWall() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsentWallError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &ConsentWallError{Provider: "yahoo"})
+	var wall *ConsentWallError
+	if !errors.As(err, &wall) || wall.Provider != "yahoo" {
+		t.Fatalf("expected a ConsentWallError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "consent wall (yahoo)") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
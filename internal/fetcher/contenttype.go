@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is how much of the body is inspected, as in http.DetectContentType
+const sniffLen = 512
+
+// UnsupportedContentError reports a response that is not a document text
+// can be extracted from, such as a video, archive or executable. Its body is
+// not downloaded.
+type UnsupportedContentError struct {
+	ContentType string
+	Size        int64 // declared Content-Length (-1 = unknown)
+}
+
+func (e *UnsupportedContentError) Error() string {
+	if e.Size >= 0 {
+		return fmt.Sprintf("not an HTML document: %s, %s", e.ContentType, byteSize(e.Size))
+	}
+	return fmt.Sprintf("not an HTML document: %s", e.ContentType)
+}
+
+// IsImageType reports whether a Content-Type is an image, which fetchers
+// return without a body
+func IsImageType(contentType string) bool {
+	return strings.HasPrefix(mediaType(contentType), "image/")
+}
+
+// checkContentType decides from the Content-Type header and the first bytes
+// of the body whether a response is worth downloading. It returns the type
+// to report: the header, or the sniffed type when the header is missing,
+// generic, or contradicted by a binary signature. Non-text responses other
+// than images fail with UnsupportedContentError.
+func checkContentType(resp *http.Response) (string, error) {
+	// A read error here shows up again when the body is read
+	br := bufio.NewReaderSize(resp.Body, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, resp.Body}
+
+	contentType := resp.Header.Get("Content-Type")
+	declared := mediaType(contentType)
+	sniffed := http.DetectContentType(head)
+	switch {
+	case declared == "" || declared == "application/octet-stream" || declared == "binary/octet-stream":
+		contentType = sniffed
+	case textualType(declared) && !textualType(sniffed) && mediaType(sniffed) != "application/octet-stream":
+		// A file served as text/html by a misconfigured server
+		contentType = sniffed
+	}
+
+	if textualType(mediaType(contentType)) || IsImageType(contentType) {
+		return contentType, nil
+	}
+	return contentType, &UnsupportedContentError{ContentType: mediaType(contentType), Size: resp.ContentLength}
+}
+
+// mediaType returns the lowercased type of a Content-Type without parameters
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// textualType reports whether a media type is text that extraction handles
+func textualType(t string) bool {
+	if strings.HasPrefix(t, "text/") || strings.HasSuffix(t, "+xml") || strings.HasSuffix(t, "+json") {
+		return true
+	}
+	switch t {
+	case "application/xhtml+xml", "application/xml", "application/json", "application/javascript":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchStatic_ContentTypeCheck(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 64)
+	zip := "PK\x03\x04" + strings.Repeat("\x00", 64)
+	tests := []struct {
+		name, header, body string
+		wantType           string // "" = UnsupportedContentError
+		wantHTML           bool
+	}{
+		{"html", "text/html; charset=utf-8", "<html><body><p>hi</p></body></html>", "text/html; charset=utf-8", true},
+		{"sniffed html", "", "<!DOCTYPE html><html><body><p>hi</p></body></html>", "text/html; charset=utf-8", true},
+		{"plain text", "text/plain", "just text", "text/plain", true},
+		{"image", "image/png", png, "image/png", false},
+		{"image as octet stream", "application/octet-stream", png, "image/png", false},
+		{"zip served as html", "text/html", zip, "", false},
+		{"video", "video/mp4", "\x00\x00\x00\x18ftypmp42", "", false},
+		{"unknown binary", "application/octet-stream", "\x00\x01\x02\x03", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Content-Type", tt.header)
+				} else {
+					w.Header()["Content-Type"] = nil
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			result, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{Format: "text"})
+			if tt.wantType == "" {
+				var unsupported *UnsupportedContentError
+				if !errors.As(err, &unsupported) {
+					t.Fatalf("expected UnsupportedContentError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", result.ContentType, tt.wantType)
+			}
+			if got := result.HTML != ""; got != tt.wantHTML {
+				t.Errorf("body read = %v, want %v", got, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestUnsupportedContentError(t *testing.T) {
+	err := &UnsupportedContentError{ContentType: "video/mp4", Size: 20 << 20}
+	if got, want := err.Error(), "not an HTML document: video/mp4, 20 MB"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	err.Size = -1
+	if got, want := err.Error(), "not an HTML document: video/mp4"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// applicableCookies keeps the cookies a browser would attach to a request for
+// u: unexpired, path-matching (RFC 6265 5.1.4) and, for Secure cookies, only
+// over https
+func applicableCookies(cookies []*http.Cookie, u *url.URL, now time.Time) []*http.Cookie {
+	var kept []*http.Cookie
+	for _, cookie := range cookies {
+		if cookie == nil {
+			continue
+		}
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			continue
+		}
+		if cookie.MaxAge < 0 {
+			continue
+		}
+		if cookie.Secure && u.Scheme != "https" {
+			continue
+		}
+		if !pathMatches(cookie.Path, u.EscapedPath()) {
+			continue
+		}
+		kept = append(kept, cookie)
+	}
+	return kept
+}
+
+// pathMatches implements RFC 6265 path-match; an empty cookie path matches
+// everything
+func pathMatches(cookiePath, requestPath string) bool {
+	if cookiePath == "" || cookiePath == "/" {
+		return true
+	}
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// setCookies puts the cookies into Chrome's cookie store before the page is
+// loaded, so the first request, and the ones its scripts make, carry them
+func setCookies(cookies []*http.Cookie, pageURL string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		u, err := url.Parse(pageURL)
+		if err != nil {
+			return err
+		}
+		params := browserCookies(cookies, u, time.Now())
+		if len(params) == 0 {
+			return nil
+		}
+		if err := network.SetCookies(params).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set cookies: %w", err)
+		}
+		return nil
+	})
+}
+
+// browserCookies converts cookies for Chrome. Unlike applicableCookies it
+// keeps every path, as the page's scripts may request other paths; expired
+// cookies are dropped, and Secure ones on plain HTTP, which Chrome refuses.
+// Cookies without a domain are host-only cookies of u.
+func browserCookies(cookies []*http.Cookie, u *url.URL, now time.Time) []*network.CookieParam {
+	var params []*network.CookieParam
+	for _, cookie := range cookies {
+		if cookie == nil || cookie.MaxAge < 0 {
+			continue
+		}
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			continue
+		}
+		if cookie.Secure && u.Scheme != "https" {
+			continue
+		}
+		p := &network.CookieParam{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HttpOnly,
+		}
+		if p.Domain == "" {
+			p.URL = u.Scheme + "://" + u.Host + "/"
+		}
+		if p.Path == "" {
+			p.Path = "/"
+		}
+		switch cookie.SameSite {
+		case http.SameSiteStrictMode:
+			p.SameSite = network.CookieSameSiteStrict
+		case http.SameSiteLaxMode:
+			p.SameSite = network.CookieSameSiteLax
+		case http.SameSiteNoneMode:
+			p.SameSite = network.CookieSameSiteNone
+		}
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if !expires.IsZero() {
+			t := cdp.TimeSinceEpoch(expires)
+			p.Expires = &t
+		}
+		params = append(params, p)
+	}
+	return params
+}
@@ -0,0 +1,119 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestApplicableCookies(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cookies := []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "expired", Value: "1", Expires: now.Add(-time.Hour)},
+		{Name: "future", Value: "1", Expires: now.Add(time.Hour)},
+		{Name: "secure", Value: "1", Secure: true},
+		{Name: "docs", Value: "1", Path: "/docs"},
+		{Name: "docs-slash", Value: "1", Path: "/docs/"},
+	}
+
+	names := func(rawURL string) map[string]bool {
+		u, _ := url.Parse(rawURL)
+		got := make(map[string]bool)
+		for _, c := range applicableCookies(cookies, u, now) {
+			got[c.Name] = true
+		}
+		return got
+	}
+
+	got := names("https://example.com/docs/intro")
+	for _, want := range []string{"session", "future", "secure", "docs", "docs-slash"} {
+		if !got[want] {
+			t.Errorf("expected %s on https /docs/intro", want)
+		}
+	}
+	if got["expired"] {
+		t.Error("expired cookie must not be sent")
+	}
+
+	got = names("http://example.com/docsearch")
+	if got["secure"] {
+		t.Error("secure cookie must not be sent over http")
+	}
+	if got["docs"] || got["docs-slash"] {
+		t.Error("/docs cookies must not match /docsearch")
+	}
+
+	got = names("https://example.com/docs")
+	if !got["docs"] || got["docs-slash"] {
+		t.Errorf("unexpected path matching for /docs: %v", got)
+	}
+}
+
+func TestFetchStatic_FiltersCookies(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Cookie")
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer server.Close()
+
+	opts := FetchOptions{Cookies: []*http.Cookie{
+		{Name: "keep", Value: "1"},
+		{Name: "old", Value: "1", Expires: time.Now().Add(-time.Minute)},
+		{Name: "tls", Value: "1", Secure: true},
+	}}
+	if _, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if header != "keep=1" {
+		t.Errorf("expected only keep=1, got %q", header)
+	}
+}
+
+func TestBrowserCookies(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cookies := []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "expired", Value: "1", Expires: now.Add(-time.Hour)},
+		{Name: "deleted", Value: "1", MaxAge: -1},
+		{Name: "secure", Value: "1", Secure: true},
+		{Name: "api", Value: "1", Domain: ".example.com", Path: "/api", HttpOnly: true, SameSite: http.SameSiteLaxMode},
+		{Name: "maxage", Value: "1", MaxAge: 60},
+	}
+
+	u, _ := url.Parse("http://www.example.com/docs/page")
+	params := browserCookies(cookies, u, now)
+	got := make(map[string]*network.CookieParam)
+	for _, p := range params {
+		got[p.Name] = p
+	}
+	for _, name := range []string{"expired", "deleted", "secure"} {
+		if got[name] != nil {
+			t.Errorf("%s should be dropped", name)
+		}
+	}
+
+	session := got["session"]
+	if session == nil || session.URL != "http://www.example.com/" || session.Domain != "" || session.Path != "/" {
+		t.Errorf("host-only cookie = %+v, want URL of the host and path /", session)
+	}
+	api := got["api"]
+	if api == nil || api.URL != "" || api.Domain != ".example.com" || api.Path != "/api" || !api.HTTPOnly || api.SameSite != network.CookieSameSiteLax {
+		t.Errorf("domain cookie = %+v", api)
+	}
+	if p := got["maxage"]; p == nil || p.Expires == nil || !p.Expires.Time().Equal(now.Add(time.Minute)) {
+		t.Errorf("Max-Age cookie = %+v, want expiry in a minute", p)
+	}
+
+	u, _ = url.Parse("https://www.example.com/")
+	if len(browserCookies(cookies, u, now)) != 4 {
+		t.Error("Secure cookie should be kept over https")
+	}
+}
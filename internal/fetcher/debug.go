@@ -0,0 +1,122 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// DebugOptions run Chrome so a page's rendering can be watched, to find out
+// why it fails or which banner hides the content
+type DebugOptions struct {
+	Headful  bool          // show the browser window
+	DevTools bool          // open DevTools with each tab; implies Headful
+	SlowMo   time.Duration // pause before each step of a page (0 = none)
+	Console  io.Writer     // console messages and uncaught errors of pages (nil = dropped)
+}
+
+// AllocatorOptions returns the Chrome flags: chromedp's defaults, without
+// headless mode when the window is shown
+func (d DebugOptions) AllocatorOptions() []chromedp.ExecAllocatorOption {
+	if !d.Headful && !d.DevTools {
+		return nil
+	}
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	opts = append(opts, chromedp.Flag("headless", false), chromedp.Flag("hide-scrollbars", false))
+	if d.DevTools {
+		opts = append(opts, chromedp.Flag("auto-open-devtools-for-tabs", true))
+	}
+	return opts
+}
+
+// SetDebug runs Chrome for debugging; with a browser pool, give the pool
+// d.AllocatorOptions() too
+func (cf *ContentFetcher) SetDebug(d DebugOptions) {
+	cf.debug = d
+	if d.Console != nil {
+		cf.debug.Console = &lineWriter{w: d.Console}
+	}
+}
+
+// slowMo pauses before each action, so a shown window can be followed
+func slowMo(tasks []chromedp.Action, pause time.Duration) []chromedp.Action {
+	slowed := make([]chromedp.Action, 0, 2*len(tasks))
+	for _, task := range tasks {
+		slowed = append(slowed, chromedp.Sleep(pause), task)
+	}
+	return slowed
+}
+
+// logConsole writes the page's console messages and uncaught errors to w,
+// one line each, prefixed with the page URL
+func logConsole(w io.Writer, pageURL string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		chromedp.ListenTarget(ctx, func(ev any) {
+			switch e := ev.(type) {
+			case *runtime.EventConsoleAPICalled:
+				fmt.Fprintf(w, "[console.%s] %s: %s\n", e.Type, pageURL, consoleArgs(e.Args))
+			case *runtime.EventExceptionThrown:
+				fmt.Fprintf(w, "[page error] %s: %s\n", pageURL, exceptionText(e.ExceptionDetails))
+			}
+		})
+		return nil
+	})
+}
+
+// consoleArgs joins the arguments of a console call as the console shows
+// them: strings unquoted, objects by their description
+func consoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		var s string
+		switch {
+		case len(arg.Value) > 0 && json.Unmarshal(arg.Value, &s) == nil:
+		case len(arg.Value) > 0:
+			s = string(arg.Value)
+		case arg.UnserializableValue != "":
+			s = string(arg.UnserializableValue)
+		case arg.Description != "":
+			s = arg.Description
+		default:
+			s = string(arg.Type)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// exceptionText returns an uncaught error's message and where it was thrown
+func exceptionText(d *runtime.ExceptionDetails) string {
+	if d == nil {
+		return "unknown error"
+	}
+	text := d.Text
+	if d.Exception != nil && d.Exception.Description != "" {
+		// The description starts with the message and carries the stack
+		text = d.Exception.Description
+	}
+	text, _, _ = strings.Cut(text, "\n")
+	if d.URL != "" {
+		text += fmt.Sprintf(" (%s:%d:%d)", d.URL, d.LineNumber+1, d.ColumnNumber+1)
+	}
+	return text
+}
+
+// lineWriter keeps the lines of tabs rendering at once from interleaving
+type lineWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
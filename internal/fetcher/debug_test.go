@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+func TestConsoleArgs(t *testing.T) {
+	args := []*runtime.RemoteObject{
+		{Type: runtime.TypeString, Value: []byte(`"loaded"`)},
+		{Type: runtime.TypeNumber, Value: []byte(`42`)},
+		{Type: runtime.TypeNumber, UnserializableValue: "NaN"},
+		{Type: runtime.TypeObject, ClassName: "HTMLDivElement", Description: "div#root"},
+		{Type: runtime.TypeUndefined},
+	}
+	if got, want := consoleArgs(args), "loaded 42 NaN div#root undefined"; got != want {
+		t.Errorf("consoleArgs = %q, want %q", got, want)
+	}
+}
+
+func TestExceptionText(t *testing.T) {
+	d := &runtime.ExceptionDetails{
+		Text:         "Uncaught",
+		URL:          "https://example.com/app.js",
+		LineNumber:   9,
+		ColumnNumber: 4,
+		Exception: &runtime.RemoteObject{
+			Description: "TypeError: x is undefined\n    at render (app.js:10:5)",
+		},
+	}
+	if got, want := exceptionText(d), "TypeError: x is undefined (https://example.com/app.js:10:5)"; got != want {
+		t.Errorf("exceptionText = %q, want %q", got, want)
+	}
+	if got := exceptionText(&runtime.ExceptionDetails{Text: "Uncaught SyntaxError"}); got != "Uncaught SyntaxError" {
+		t.Errorf("exceptionText without exception object = %q", got)
+	}
+}
+
+func TestDebugAllocatorOptions(t *testing.T) {
+	if opts := (DebugOptions{SlowMo: time.Second}).AllocatorOptions(); opts != nil {
+		t.Error("headless debugging should keep the default flags")
+	}
+	if n := len((DebugOptions{Headful: true}).AllocatorOptions()); n != len(chromedp.DefaultExecAllocatorOptions)+2 {
+		t.Errorf("headful options = %d, want the defaults plus headless and scrollbars", n)
+	}
+	if n := len((DebugOptions{DevTools: true}).AllocatorOptions()); n != len(chromedp.DefaultExecAllocatorOptions)+3 {
+		t.Errorf("devtools options = %d, want headful plus DevTools", n)
+	}
+}
+
+func TestSlowMo(t *testing.T) {
+	tasks := []chromedp.Action{chromedp.Sleep(0), chromedp.Sleep(0)}
+	if n := len(slowMo(tasks, time.Millisecond)); n != 4 {
+		t.Errorf("slowMo returned %d actions, want a pause before each of 2", n)
+	}
+}
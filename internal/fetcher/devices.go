@@ -0,0 +1,181 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultMobileDevice is used by --mobile without a device name
+const DefaultMobileDevice = "iphone"
+
+// Device describes an emulated mobile device: the user agent for static
+// fetches and the screen metrics applied in JS mode
+type Device struct {
+	Name              string
+	UserAgent         string
+	Width             int64
+	Height            int64
+	DeviceScaleFactor float64
+	Touch             bool
+}
+
+var devices = map[string]Device{
+	"iphone": {
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 18_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Mobile/15E148 Safari/604.1",
+		Width:             393,
+		Height:            852,
+		DeviceScaleFactor: 3,
+		Touch:             true,
+	},
+	"iphone-se": {
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 18_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Mobile/15E148 Safari/604.1",
+		Width:             375,
+		Height:            667,
+		DeviceScaleFactor: 2,
+		Touch:             true,
+	},
+	"pixel": {
+		UserAgent:         "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Mobile Safari/537.36",
+		Width:             412,
+		Height:            915,
+		DeviceScaleFactor: 2.625,
+		Touch:             true,
+	},
+	"galaxy": {
+		UserAgent:         "Mozilla/5.0 (Linux; Android 14; SM-S921B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Mobile Safari/537.36",
+		Width:             360,
+		Height:            780,
+		DeviceScaleFactor: 3,
+		Touch:             true,
+	},
+	"ipad": {
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 18_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Mobile/15E148 Safari/604.1",
+		Width:             820,
+		Height:            1180,
+		DeviceScaleFactor: 2,
+		Touch:             true,
+	},
+}
+
+// LookupDevice returns the named device preset
+func LookupDevice(name string) (*Device, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultMobileDevice
+	}
+	d, ok := devices[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown device: %s (available: %s)", name, strings.Join(DeviceNames(), ", "))
+	}
+	d.Name = name
+	return &d, nil
+}
+
+// DeviceNames lists the device presets
+func DeviceNames() []string {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Viewport is the window size of a rendered page in CSS pixels
+type Viewport struct {
+	Width  int64
+	Height int64
+}
+
+// ParseViewport reads a WIDTHxHEIGHT size such as 1280x800
+func ParseViewport(s string) (*Viewport, error) {
+	w, h, found := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	width, errW := strconv.ParseInt(w, 10, 64)
+	height, errH := strconv.ParseInt(h, 10, 64)
+	if !found || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid viewport %q (want WIDTHxHEIGHT, e.g. 390x844)", s)
+	}
+	return &Viewport{Width: width, Height: height}, nil
+}
+
+func (v Viewport) String() string {
+	return fmt.Sprintf("%dx%d", v.Width, v.Height)
+}
+
+// WithViewport returns a copy of the device with the viewport as its screen
+func (d *Device) WithViewport(v *Viewport) *Device {
+	sized := *d
+	sized.Width, sized.Height = v.Width, v.Height
+	return &sized
+}
+
+// emulateViewport sizes the tab's window as a desktop browser's
+func emulateViewport(v *Viewport) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := emulation.SetDeviceMetricsOverride(v.Width, v.Height, 1, false).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set viewport: %w", err)
+		}
+		return nil
+	})
+}
+
+// emulateDevice applies the device's viewport, pixel ratio, touch support and
+// user agent to the tab
+func emulateDevice(d *Device) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := emulation.SetDeviceMetricsOverride(d.Width, d.Height, d.DeviceScaleFactor, true).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set device metrics: %w", err)
+		}
+		if err := emulation.SetTouchEmulationEnabled(d.Touch).Do(ctx); err != nil {
+			return fmt.Errorf("failed to enable touch emulation: %w", err)
+		}
+		if err := emulation.SetUserAgentOverride(d.UserAgent).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set user agent: %w", err)
+		}
+		return nil
+	})
+}
+
+// MobileAlternate returns the mobile variant a desktop page announces via
+// <link rel="alternate" media="..."> (e.g. m.example.com), or "" when it names
+// none. Mobile variants tend to carry far less chrome around the article.
+func MobileAlternate(html, pageURL string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	alternate := ""
+	doc.Find(`link[rel="alternate"][media][href]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		media := strings.ToLower(s.AttrOr("media", ""))
+		if !strings.Contains(media, "max-width") && !strings.Contains(media, "handheld") {
+			return true
+		}
+		ref, err := url.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil {
+			return true
+		}
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			return true
+		}
+		if resolved.String() != base.String() {
+			alternate = resolved.String()
+			return false
+		}
+		return true
+	})
+	return alternate
+}
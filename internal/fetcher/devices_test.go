@@ -0,0 +1,77 @@
+package fetcher
+
+import "testing"
+
+func TestLookupDevice(t *testing.T) {
+	d, err := LookupDevice("")
+	if err != nil || d.Name != DefaultMobileDevice {
+		t.Fatalf("expected default device, got %v, %v", d, err)
+	}
+	d, err = LookupDevice("Pixel")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clientHintMobile(d.UserAgent) != "?1" || clientHintPlatform(d.UserAgent) != "Android" {
+		t.Errorf("pixel UA should send mobile Android client hints: %q", d.UserAgent)
+	}
+	if _, err := LookupDevice("nokia"); err == nil {
+		t.Error("expected error for unknown device")
+	}
+}
+
+func TestMobileAlternate(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{
+			name: "relative alternate",
+			html: `<html><head><link rel="alternate" media="only screen and (max-width: 640px)" href="/m/post"></head></html>`,
+			want: "https://example.com/m/post",
+		},
+		{
+			name: "absolute m-dot",
+			html: `<html><head><link rel="alternate" media="handheld" href="https://m.example.com/post"></head></html>`,
+			want: "https://m.example.com/post",
+		},
+		{
+			name: "feed alternate ignored",
+			html: `<html><head><link rel="alternate" type="application/rss+xml" href="/feed"></head></html>`,
+			want: "",
+		},
+		{
+			name: "self reference ignored",
+			html: `<html><head><link rel="alternate" media="(max-width: 640px)" href="https://example.com/post"></head></html>`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MobileAlternate(tt.html, "https://example.com/post"); got != tt.want {
+				t.Errorf("MobileAlternate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseViewport(t *testing.T) {
+	v, err := ParseViewport(" 390X844 ")
+	if err != nil || *v != (Viewport{Width: 390, Height: 844}) {
+		t.Fatalf("ParseViewport = %v, %v; want 390x844", v, err)
+	}
+	for _, in := range []string{"", "390", "390x", "x844", "0x844", "-390x844", "390x844x2", "wide"} {
+		if _, err := ParseViewport(in); err == nil {
+			t.Errorf("ParseViewport(%q): expected an error", in)
+		}
+	}
+}
+
+func TestDeviceWithViewport(t *testing.T) {
+	d, _ := LookupDevice("pixel")
+	sized := d.WithViewport(&Viewport{Width: 390, Height: 844})
+	if sized.Width != 390 || sized.Height != 844 || sized.UserAgent != d.UserAgent || sized.DeviceScaleFactor != d.DeviceScaleFactor {
+		t.Errorf("WithViewport = %+v, want pixel with a 390x844 screen", sized)
+	}
+	if d.Width != 412 {
+		t.Error("WithViewport changed the preset")
+	}
+}
@@ -0,0 +1,143 @@
+package fetcher
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultAcceptEncoding is advertised unless the config sets another list
+const DefaultAcceptEncoding = "gzip, deflate, br"
+
+// setAcceptEncoding advertises the given encodings. Empty leaves the header
+// to net/http, which asks for gzip only and decodes it itself.
+func setAcceptEncoding(h http.Header, encodings string) {
+	if encodings != "" {
+		h.Set("Accept-Encoding", encodings)
+	}
+}
+
+// decodeBody replaces a compressed response body with its decoded stream.
+// net/http only decodes gzip, and only when it asked for it; some CDNs
+// answer with brotli regardless, which would otherwise reach extraction as
+// binary garbage. Stacked encodings ("deflate, br") are undone in reverse.
+func decodeBody(resp *http.Response) error {
+	header := resp.Header.Get("Content-Encoding")
+	if header == "" {
+		return nil
+	}
+	encodings := strings.Split(header, ",")
+
+	body := resp.Body
+	var r io.Reader = body
+	var closers []io.Closer
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		var closer io.Closer
+		switch enc := strings.ToLower(strings.TrimSpace(encodings[i])); enc {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			var zr *gzip.Reader
+			zr, err = gzip.NewReader(r)
+			r, closer = zr, zr
+		case "deflate":
+			r, closer, err = deflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(r)
+			if err == nil {
+				r, closer = zr, zstdCloser{zr}
+			}
+		default:
+			err = fmt.Errorf("unsupported Content-Encoding %q", enc)
+		}
+		if err != nil {
+			body.Close()
+			return fmt.Errorf("failed to decode response body: %w", err)
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+
+	resp.Body = &decodedBody{Reader: r, closers: append(closers, body)}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// utf8Body returns a page as UTF-8, decoding the charset its Content-Type,
+// byte order mark or <meta charset> declares. Extraction reads pages as
+// UTF-8, so a Latin-1 or Shift JIS page would otherwise come out garbled. A
+// body that is valid UTF-8 is kept when only the page's markup or a guess
+// says otherwise, as such declarations are often stale.
+func utf8Body(body []byte, contentType string) string {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" || (!certain && utf8.Valid(body)) {
+		return string(body)
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body)
+	}
+	return string(decoded)
+}
+
+// deflateReader decodes HTTP deflate, which is meant to be zlib-wrapped but
+// is sent as raw deflate by some servers
+func deflateReader(r io.Reader) (io.Reader, io.Closer, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	// A zlib header names deflate (CM 8) and is a multiple of 31
+	if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr, nil
+	}
+	fr := flate.NewReader(br)
+	return fr, fr, nil
+}
+
+// zstdCloser adapts zstd.Decoder, whose Close returns nothing
+type zstdCloser struct{ d *zstd.Decoder }
+
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
+
+// decodedBody closes the decoders and then the network body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var first error
+	for _, c := range b.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
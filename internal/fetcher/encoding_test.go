@@ -0,0 +1,163 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const encodedPage = "<html><head><title>Encoded</title></head><body><p>Compressed content</p></body></html>"
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestFetchStatic_DecodesContentEncodings(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		body   func(t *testing.T) []byte
+	}{
+		{"brotli", "br", func(t *testing.T) []byte { return compress(t, "br", []byte(encodedPage)) }},
+		{"zlib deflate", "deflate", func(t *testing.T) []byte { return compress(t, "deflate", []byte(encodedPage)) }},
+		{"raw deflate", "deflate", func(t *testing.T) []byte { return compress(t, "raw-deflate", []byte(encodedPage)) }},
+		{"gzip", "gzip", func(t *testing.T) []byte { return compress(t, "gzip", []byte(encodedPage)) }},
+		{"zstd", "zstd", func(t *testing.T) []byte { return compress(t, "zstd", []byte(encodedPage)) }},
+		{"stacked", "gzip, br", func(t *testing.T) []byte {
+			return compress(t, "br", compress(t, "gzip", []byte(encodedPage)))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accept string
+			body := tt.body(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Encoding", tt.header)
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			result, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{AcceptEncoding: DefaultAcceptEncoding})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.HTML != encodedPage {
+				t.Errorf("expected the decoded page, got %q", result.HTML)
+			}
+			if accept != DefaultAcceptEncoding {
+				t.Errorf("expected Accept-Encoding %q, got %q", DefaultAcceptEncoding, accept)
+			}
+		})
+	}
+}
+
+func TestFetchStatic_DecodesUnrequestedBrotli(t *testing.T) {
+	body := compress(t, "br", []byte(encodedPage))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	for _, fetch := range []func() (*FetchResult, error){
+		func() (*FetchResult, error) {
+			return NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{})
+		},
+		func() (*FetchResult, error) {
+			return NewContentFetcher().Fetch(context.Background(), server.URL, FetchOptions{Mode: FetchModeStatic})
+		},
+	} {
+		result, err := fetch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.HTML != encodedPage {
+			t.Errorf("expected the decoded page, got %q", result.HTML)
+		}
+	}
+}
+
+func TestFetchStatic_UnsupportedEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "compress")
+		w.Write([]byte("\x1f\x9d..."))
+	}))
+	defer server.Close()
+
+	_, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{})
+	if err == nil || !strings.Contains(err.Error(), `unsupported Content-Encoding "compress"`) {
+		t.Errorf("expected an unsupported encoding error, got %v", err)
+	}
+}
+
+func TestFetchStatic_DecodesCharset(t *testing.T) {
+	latin1 := "<html><head>%s<title>Gr\xfc\xdfe</title></head><body><p>Sch\xf6ne Gr\xfc\xdfe</p></body></html>"
+	tests := []struct {
+		name        string
+		contentType string
+		meta        string
+		body        string
+		want        string
+	}{
+		{"header", "text/html; charset=iso-8859-1", "", latin1, "Schöne Grüße"},
+		{"meta", "text/html", `<meta charset="windows-1252">`, latin1, "Schöne Grüße"},
+		{"undeclared utf-8", "text/html", "", "<html><head>%s<title>Grüße</title></head><body><p>Schöne Grüße</p></body></html>", "Schöne Grüße"},
+		{"stale meta", "text/html", `<meta charset="iso-8859-1">`, "<html><head>%s<title>Grüße</title></head><body><p>Schöne Grüße</p></body></html>", "Schöne Grüße"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			fmt.Fprintf(w, tt.body, tt.meta)
+		}))
+		result, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{})
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !strings.Contains(result.HTML, tt.want) || result.Title != "Grüße" {
+			t.Errorf("%s: expected the page as UTF-8, got title %q in %q", tt.name, result.Title, result.HTML)
+		}
+	}
+}
+
+func TestExtractBodyContent_InvalidUTF8(t *testing.T) {
+	// strings.ToLower turns each invalid byte into three
+	html := "<html><head><title>\xfc\xfc\xfc</title></head><BODY><p>\xfc\xfc\xfc body</p></BODY></html>"
+	cf := NewContentFetcher()
+	if got := cf.extractBodyContent(html); got != "<p>\xfc\xfc\xfc body</p>" {
+		t.Errorf("extractBodyContent = %q", got)
+	}
+	if got := cf.extractTitle(html); got != "\xfc\xfc\xfc" {
+		t.Errorf("extractTitle = %q", got)
+	}
+}
@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// PageScript is JavaScript run in a rendered page before it is read, to
+// click what the built-in steps miss, remove overlays or write data the
+// page only holds in scripts into the DOM
+type PageScript struct {
+	Name   string // file name, for errors
+	Source string
+}
+
+// LoadPageScripts reads the script files, keeping their order
+func LoadPageScripts(files []string) ([]PageScript, error) {
+	var scripts []PageScript
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page script: %w", err)
+		}
+		scripts = append(scripts, PageScript{Name: filepath.Base(file), Source: string(src)})
+	}
+	return scripts, nil
+}
+
+// pageScriptWrapper runs a script as the body of an async function, so it
+// may await and return early; the page is read once its promise settles
+const pageScriptWrapper = "(async () => {\n%s\n})()"
+
+// evalScripts runs the scripts in order. A script that throws fails the
+// page, as its changes are then unknown.
+func evalScripts(scripts []PageScript) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, s := range scripts {
+			// The result is not needed; a reference spares serializing it
+			var result *runtime.RemoteObject
+			err := chromedp.Evaluate(fmt.Sprintf(pageScriptWrapper, s.Source), &result,
+				func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+					return p.WithAwaitPromise(true)
+				}).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("page script %s: %w", s.Name, err)
+			}
+		}
+		return nil
+	})
+}
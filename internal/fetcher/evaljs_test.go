@@ -0,0 +1,34 @@
+package fetcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPageScripts(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"overlay.js": `document.querySelector(".paywall")?.remove()`,
+		"more.js":    `document.querySelectorAll(".more").forEach(b => b.click())`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scripts, err := LoadPageScripts([]string{filepath.Join(dir, "overlay.js"), filepath.Join(dir, "more.js")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 2 || scripts[0].Name != "overlay.js" || scripts[1].Name != "more.js" {
+		t.Fatalf("scripts out of order: %+v", scripts)
+	}
+	if scripts[0].Source != `document.querySelector(".paywall")?.remove()` {
+		t.Errorf("unexpected source %q", scripts[0].Source)
+	}
+
+	if _, err := LoadPageScripts([]string{filepath.Join(dir, "missing.js")}); err == nil {
+		t.Error("expected an error for a missing script")
+	}
+}
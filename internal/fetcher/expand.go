@@ -0,0 +1,174 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/chromedp/chromedp"
+)
+
+// expandRounds bounds the click passes; content revealed by one click may
+// hold further collapsed sections
+const expandRounds = 3
+
+// expandSettle is the pause after a click pass for revealed content to load
+const expandSettle = 500 * time.Millisecond
+
+// ExpandSelectors are the built-in read-more, show-full-text and accordion
+// controls clicked before extraction. Buttons are also matched by their
+// label, see expandScript.
+var ExpandSelectors = []string{
+	"button[aria-expanded='false']",
+	"[role='button'][aria-expanded='false']",
+	"button.read-more",
+	"button.show-more",
+	".read-more-button",
+	".show-more-button",
+	".read-more-toggle",
+	"[data-testid*='read-more']",
+	"[data-testid*='show-more']",
+	".accordion-button.collapsed",
+	".accordion-header[aria-expanded='false']",
+	".faq-question[aria-expanded='false']",
+	".truncate-toggle",
+	".expand-button",
+}
+
+// ExpandSite overrides the expand pass for a host and its subdomains
+type ExpandSite struct {
+	Host      string
+	Disabled  bool     // no clicking on this site
+	Selectors []string // added to the global selectors
+}
+
+// ExpandRules configures the click pass that opens collapsed content in JS
+// mode
+type ExpandRules struct {
+	selectors []string
+	sites     map[string]*ExpandSite // by lowercased host
+}
+
+// NewExpandRules validates the extra selectors and site rules. The built-in
+// ExpandSelectors always apply.
+func NewExpandRules(selectors []string, sites []ExpandSite) (*ExpandRules, error) {
+	if err := checkSelectors(selectors); err != nil {
+		return nil, err
+	}
+	r := &ExpandRules{
+		selectors: slices.Concat(ExpandSelectors, selectors),
+		sites:     make(map[string]*ExpandSite),
+	}
+	for _, site := range sites {
+		host := strings.TrimPrefix(strings.ToLower(site.Host), "www.")
+		if host == "" {
+			return nil, fmt.Errorf("expand site rule without host")
+		}
+		if err := checkSelectors(site.Selectors); err != nil {
+			return nil, fmt.Errorf("expand rules for %s: %w", site.Host, err)
+		}
+		r.sites[host] = &site
+	}
+	return r, nil
+}
+
+func checkSelectors(selectors []string) error {
+	for _, sel := range selectors {
+		if _, err := cascadia.Compile(sel); err != nil {
+			return fmt.Errorf("invalid expand selector %q: %w", sel, err)
+		}
+	}
+	return nil
+}
+
+// SelectorsFor returns the selectors clicked on host, nil when the site
+// disables the pass
+func (r *ExpandRules) SelectorsFor(host string) []string {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for host != "" {
+		if site, ok := r.sites[host]; ok {
+			if site.Disabled {
+				return nil
+			}
+			return slices.Concat(r.selectors, site.Selectors)
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return r.selectors
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// expandScript opens <details> and clicks the matching controls once each. It
+// leaves navigation, header and footer controls alone, and anything that
+// would navigate away or submit a form. Returns the number of elements
+// opened.
+const expandScript = `((selectors) => {
+	const labelRe = /^(read|show|see|view|load) (more|all|full|the (full|rest|whole))\b|^(expand|continue reading|mehr (lesen|anzeigen)|weiterlesen|lire la suite)\b/i;
+	const candidates = [];
+	for (const sel of selectors) {
+		try { candidates.push(...document.querySelectorAll(sel)); } catch (e) {}
+	}
+	for (const el of document.querySelectorAll("button, [role='button']")) {
+		const label = el.textContent.trim();
+		if (label.length < 60 && labelRe.test(label)) candidates.push(el);
+	}
+	let opened = 0;
+	for (const d of document.querySelectorAll("details:not([open])")) {
+		if (d.closest("nav, header, footer, [role='navigation']")) continue;
+		d.open = true;
+		opened++;
+	}
+	for (const el of new Set(candidates)) {
+		if (el.dataset.scrprExpanded || !el.isConnected) continue;
+		if (el.closest("nav, header, footer, [role='navigation'], [role='dialog']")) continue;
+		const link = el.closest("a[href]");
+		const href = link ? link.getAttribute("href") : "";
+		if (href && !href.startsWith("#") && !href.startsWith("javascript:")) continue;
+		if (el.type === "submit" && el.form) continue;
+		el.dataset.scrprExpanded = "1";
+		el.click();
+		opened++;
+	}
+	return opened;
+})(%s)`
+
+// expandCollapsed clicks the read-more and accordion controls, repeating
+// while a pass still finds something to open
+func expandCollapsed(selectors []string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		list, err := json.Marshal(selectors)
+		if err != nil {
+			return err
+		}
+		script := fmt.Sprintf(expandScript, list)
+		for range expandRounds {
+			var opened int
+			if err := chromedp.Evaluate(script, &opened).Do(ctx); err != nil {
+				return fmt.Errorf("failed to expand collapsed content: %w", err)
+			}
+			if opened == 0 {
+				return nil
+			}
+			if err := chromedp.Sleep(expandSettle).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
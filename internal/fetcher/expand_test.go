@@ -0,0 +1,37 @@
+package fetcher
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandRules(t *testing.T) {
+	rules, err := NewExpandRules([]string{".story-expand"}, []ExpandSite{
+		{Host: "www.example.com", Selectors: []string{".faq-toggle"}},
+		{Host: "news.example.org", Disabled: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	global := rules.SelectorsFor("other.net")
+	if !slices.Contains(global, ".story-expand") || !slices.Contains(global, ExpandSelectors[0]) {
+		t.Errorf("global selectors should hold built-in and extra selectors: %v", global)
+	}
+	if slices.Contains(global, ".faq-toggle") {
+		t.Errorf("site selector leaked into global rules: %v", global)
+	}
+	if site := rules.SelectorsFor("blog.example.com"); !slices.Contains(site, ".faq-toggle") || !slices.Contains(site, ".story-expand") {
+		t.Errorf("subdomain should use the example.com rules: %v", site)
+	}
+	if site := rules.SelectorsFor("news.example.org"); site != nil {
+		t.Errorf("disabled site should click nothing, got %v", site)
+	}
+
+	if _, err := NewExpandRules([]string{"button[unclosed"}, nil); err == nil {
+		t.Error("expected error for invalid selector")
+	}
+	if _, err := NewExpandRules(nil, []ExpandSite{{Selectors: []string{".x"}}}); err == nil {
+		t.Error("expected error for site rule without host")
+	}
+}
@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Address families for SetAddressFamily
+const (
+	FamilyAny  = "any"
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// addressFamily restricts every dial of the process; set it before the
+// first fetch
+var addressFamily = FamilyAny
+
+// SetAddressFamily makes static fetches and warmup connect over IPv4 or
+// IPv6 only, for hosts whose records for the other family are broken.
+// "" is the same as FamilyAny.
+func SetAddressFamily(family string) error {
+	switch family = strings.ToLower(strings.TrimSpace(family)); family {
+	case "", FamilyAny:
+		addressFamily = FamilyAny
+	case FamilyIPv4, FamilyIPv6:
+		addressFamily = family
+	default:
+		return fmt.Errorf("invalid address family %q (available: any, ipv4, ipv6)", family)
+	}
+	return nil
+}
+
+// familyNetwork narrows a dial network such as "tcp" to the address family
+func familyNetwork(network string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch addressFamily {
+	case FamilyIPv4:
+		return "tcp4"
+	case FamilyIPv6:
+		return "tcp6"
+	}
+	return network
+}
+
+// lookupHost resolves host to the addresses of the address family
+func lookupHost(ctx context.Context, host string) ([]string, error) {
+	var network string
+	switch addressFamily {
+	case FamilyIPv4:
+		network = "ip4"
+	case FamilyIPv6:
+		network = "ip6"
+	default:
+		return net.DefaultResolver.LookupHost(ctx, host)
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	return addrs, nil
+}
@@ -0,0 +1,44 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetAddressFamily(t *testing.T) {
+	defer SetAddressFamily(FamilyAny)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><p>ok</p></body></html>"))
+	}))
+	defer server.Close()
+
+	// The test server listens on 127.0.0.1 only. A fresh transport keeps
+	// pooled connections from an earlier dial out of the picture.
+	fetch := func() error {
+		client := &http.Client{Transport: newTransport(DefaultTimeouts())}
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := SetAddressFamily("IPv4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(); err != nil {
+		t.Errorf("ipv4: %v", err)
+	}
+	SetAddressFamily(FamilyIPv6)
+	if err := fetch(); err == nil {
+		t.Error("ipv6: dial to an IPv4 address succeeded")
+	}
+	if err := SetAddressFamily("ipv5"); err == nil {
+		t.Error("expected an error for an unknown family")
+	}
+}
@@ -2,11 +2,17 @@ package fetcher
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
@@ -20,6 +26,9 @@ const (
 
 const defaultMaxResponseSize = 5 << 20 // 5MB
 
+// DefaultAcceptLanguage is sent when no Accept-Language is configured
+const DefaultAcceptLanguage = "en-US,en;q=0.9"
+
 // RetryConfig controls retry behavior for the fetcher
 type RetryConfig struct {
 	MaxRetries     int           // maximum retry attempts (default 3)
@@ -39,18 +48,123 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
+// Timeouts bounds each stage of a fetch independently so that a slow stage
+// cannot consume the budget of the others
+type Timeouts struct {
+	Connect        time.Duration // TCP dial (default 10s)
+	TLSHandshake   time.Duration // TLS handshake once connected (default 10s)
+	ResponseHeader time.Duration // wait for response headers after the request is sent (default 15s)
+	Total          time.Duration // whole static fetch including body read (default 30s)
+	Render         time.Duration // JavaScript rendering in Chrome (default 15s)
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Connect:        10 * time.Second,
+		TLSHandshake:   10 * time.Second,
+		ResponseHeader: 15 * time.Second,
+		Total:          30 * time.Second,
+		Render:         15 * time.Second,
+	}
+}
+
+// Connection pool limits. Batches hit the same host from several workers at
+// once, so keep more idle connections per host than net/http's default of 2.
+const (
+	maxIdleConns        = 128
+	maxIdleConnsPerHost = 16
+	idleConnTimeout     = 90 * time.Second
+)
+
+// transports holds one transport per timeout setting, so every fetcher in a
+// process shares its connection pool
+var transports = struct {
+	sync.Mutex
+	byTimeouts map[[3]time.Duration]*http.Transport
+}{byTimeouts: make(map[[3]time.Duration]*http.Transport)}
+
+// sharedTransport returns the pooled transport for the connect and response
+// header timeouts, creating it on first use
+func sharedTransport(t Timeouts) *http.Transport {
+	t = t.withDefaults()
+	key := [3]time.Duration{t.Connect, t.TLSHandshake, t.ResponseHeader}
+
+	transports.Lock()
+	defer transports.Unlock()
+	if transport, ok := transports.byTimeouts[key]; ok {
+		return transport
+	}
+	transport := newTransport(t)
+	transports.byTimeouts[key] = transport
+	return transport
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	defaults := DefaultTimeouts()
+	if t.Connect <= 0 {
+		t.Connect = defaults.Connect
+	}
+	if t.TLSHandshake <= 0 {
+		t.TLSHandshake = defaults.TLSHandshake
+	}
+	if t.ResponseHeader <= 0 {
+		t.ResponseHeader = defaults.ResponseHeader
+	}
+	return t
+}
+
+// newTransport builds an HTTP transport honoring the connect and response
+// header timeouts, with keep-alive pooling and HTTP/2. Zero values fall back
+// to the defaults.
+func newTransport(t Timeouts) *http.Transport {
+	t = t.withDefaults()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = cachedDialContext(&net.Dialer{
+		Timeout:   t.Connect,
+		KeepAlive: 30 * time.Second,
+	})
+	transport.TLSHandshakeTimeout = t.TLSHandshake
+	transport.ResponseHeaderTimeout = t.ResponseHeader
+	transport.TLSClientConfig = &tls.Config{ClientSessionCache: sessionCache}
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+	return transport
+}
+
 type FetchOptions struct {
 	Mode            FetchMode
-	Timeout         time.Duration
+	Timeout         time.Duration // total fetch deadline
+	RenderTimeout   time.Duration // JS rendering deadline (0 = use Timeout)
 	UserAgent       string
 	BrowserAgent    string
+	Device          *Device   // mobile device to emulate; its UA applies unless UserAgent is set
+	Viewport        *Viewport // window size in JS mode; with Device, the device's screen size (nil = default)
 	Cookies         []*http.Cookie
-	SkipBanners     bool
-	BannerTimeout   time.Duration
-	WaitForSelector string
-	MaxResponseSize int64  // 0 = default 5MB, -1 = unlimited
-	Format          string // "text" | "markdown" | "html"
+	AcceptLanguage  string        // Accept-Language header; also drives the JS locale (default en-US)
+	AcceptEncoding  string        // Accept-Encoding header (empty = gzip, decoded by net/http)
+	Referer         string        // Referer URL, or "auto" for the target's homepage (empty = none)
+	Timezone        string        // IANA timezone emulated in JS mode (empty = system)
+	SkipBanners     bool          // dismiss cookie banners in JS mode
+	BannerTimeout   time.Duration // bound of the dismissal step (0 = none)
+	Banners         *BannerRules  // buttons and action for SkipBanners (nil = accept with built-ins)
+	WaitForSelector string        // CSS selector that must be visible before the page is read in JS mode
+	WaitForText     string        // text the page must show before it is read in JS mode
+	WaitUntil       WaitCondition // when the page counts as loaded in JS mode ("" = load)
+	NetworkIdle     time.Duration // in JS mode, read once no request was in flight this long instead of once <body> is ready (0 = off)
+	PrintMedia      bool          // emulate @media print in JS mode and drop what it hides
+	Stealth         bool          // hide the marks of headless Chrome from the page in JS mode
+	Scroll          *ScrollConfig // scroll down for lazy content in JS mode (nil = off)
+	Scripts         []PageScript  // run in the page before it is read in JS mode
+	MaxResponseSize int64         // body limit in bytes: 0 = default 5MB, -1 = unlimited
+	Format          string        // "text" | "markdown" | "html"
 	Retry           RetryConfig
+	Expand          *ExpandRules // read-more/accordion click pass in JS mode (nil = off)
+	Method          string       // HTTP method of static fetches (empty = GET)
+	Body            []byte       // request payload (nil = none)
+	ContentType     string       // Content-Type of Body (empty = form encoded)
 }
 
 type FetchResult struct {
@@ -60,24 +174,84 @@ type FetchResult struct {
 	UsedJS      bool
 	Metadata    map[string]string
 	ContentType string // MIME type of the response
+	Status      int    // HTTP status of a static fetch
+	FinalURL    string // URL the page was served from after redirects ("" = unknown)
+	Redirects   []Redirect
+	ConsentWall string   // provider of the consent interstitial served instead of the page ("" = none)
+	Banner      string   // consent manager whose cookie banner was dismissed in JS mode ("" = none)
+	RobotsTag   []string // X-Robots-Tag header values of a static fetch
+	UserAgent   string   // User-Agent sent with a static fetch
+	Proxy       string   // proxy the request went through ("" = direct)
+	Attempts    int      // requests made, retries included
+}
+
+// proxyFor names the proxy the environment (HTTP_PROXY, HTTPS_PROXY,
+// NO_PROXY) routes req through, without credentials; "" = direct
+func proxyFor(req *http.Request) string {
+	u, err := http.ProxyFromEnvironment(req)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
 }
 
 type ContentFetcher struct {
 	client          *http.Client
 	userAgentSelect *UserAgentSelector
+	browsers        *BrowserPool // nil = a new Chrome per JS fetch
+	debug           DebugOptions
 }
 
 func NewContentFetcher() *ContentFetcher {
+	timeouts := DefaultTimeouts()
 	return &ContentFetcher{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:       timeouts.Total,
+			Transport:     sharedTransport(timeouts),
+			CheckRedirect: redirectPolicy(true, DefaultMaxRedirects),
 		},
 		userAgentSelect: NewUserAgentSelector(),
 	}
 }
 
+// SetRedirects configures whether static fetches follow HTTP redirects and
+// how many before failing (0 = DefaultMaxRedirects)
+func (cf *ContentFetcher) SetRedirects(follow bool, max int) {
+	cf.client.CheckRedirect = redirectPolicy(follow, max)
+}
+
+// SetTimeouts configures the per-stage timeouts used for static fetches
+func (cf *ContentFetcher) SetTimeouts(t Timeouts) {
+	cf.client.Transport = sharedTransport(t)
+	if t.Total > 0 {
+		cf.client.Timeout = t.Total
+	}
+}
+
+// SetUserAgentPools replaces the built-in user agent pools
+func (cf *ContentFetcher) SetUserAgentPools(pools map[UserAgentType][]string) {
+	cf.userAgentSelect.SetPools(pools)
+}
+
+// SetUserAgentSelector shares a selector with other fetchers of the run
+func (cf *ContentFetcher) SetUserAgentSelector(uas *UserAgentSelector) {
+	cf.userAgentSelect = uas
+}
+
+// SetStickyUserAgent pins one user agent per host for the fetcher's lifetime
+func (cf *ContentFetcher) SetStickyUserAgent(sticky bool) {
+	cf.userAgentSelect.SetSticky(sticky)
+}
+
+// SetBrowserPool renders JS fetches in tabs of the pool's Chrome instances
+// instead of starting Chrome for each; the caller closes the pool
+func (cf *ContentFetcher) SetBrowserPool(pool *BrowserPool) {
+	cf.browsers = pool
+}
+
 func (cf *ContentFetcher) Fetch(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
-	if opts.Mode == FetchModeStatic {
+	// The browser only navigates with GET, so other requests stay static
+	if opts.Mode == FetchModeStatic || !IsPlainGet(opts) {
 		return cf.fetchStatic(ctx, url, opts)
 	}
 
@@ -91,41 +265,53 @@ func (cf *ContentFetcher) Fetch(ctx context.Context, url string, opts FetchOptio
 		return nil, err
 	}
 
-	if cf.needsJSRendering(result.HTML) {
+	// A consent wall is accepted in the browser, which then returns to the page
+	if cf.NeedsRendering(result) {
 		return cf.fetchWithJS(ctx, url, opts)
 	}
 
 	return result, nil
 }
 
+// Render fetches url in Chrome regardless of opts.Mode, for callers that
+// made the static fetch themselves
+func (cf *ContentFetcher) Render(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
+	return cf.fetchWithJS(ctx, url, opts)
+}
+
+// NeedsRendering reports whether a static result is better rendered in
+// Chrome: a document that looks built by scripts, or a consent wall the
+// browser can accept
+func (cf *ContentFetcher) NeedsRendering(result *FetchResult) bool {
+	if IsImageType(result.ContentType) {
+		return false
+	}
+	return result.ConsentWall != "" || cf.needsJSRendering(result.HTML)
+}
+
 func (cf *ContentFetcher) fetchStatic(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := newRequest(ctx, url, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
 	// Set user agent (custom takes precedence, then browser agent, then random)
 	userAgent := opts.UserAgent
+	if userAgent == "" && opts.Device != nil {
+		userAgent = opts.Device.UserAgent
+	}
 	if userAgent == "" {
 		// Use browser agent selector if no custom user agent specified
-		userAgent = cf.userAgentSelect.GetUserAgent(opts.BrowserAgent)
+		userAgent = cf.userAgentSelect.UserAgentForHost(opts.BrowserAgent, req.URL.Hostname())
 	}
-	req.Header.Set("User-Agent", userAgent)
-
-	// Add headers that make the request look more like a real browser
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	// Don't set Accept-Encoding - let Go's http client handle compression automatically
+	// Add headers that make the request look like a navigation in that browser
+	setBrowserHeaders(req.Header, userAgent, url, resolveReferer(opts.Referer, url))
+	req.Header.Set("Accept-Language", acceptLanguage(opts.AcceptLanguage))
+	setAcceptEncoding(req.Header, opts.AcceptEncoding)
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("Sec-Fetch-Dest", "document")
-	req.Header.Set("Sec-Fetch-Mode", "navigate")
-	req.Header.Set("Sec-Fetch-Site", "none")
-	req.Header.Set("Sec-Fetch-User", "?1")
-	req.Header.Set("Cache-Control", "max-age=0")
-
-	// Add cookies
-	for _, cookie := range opts.Cookies {
+
+	// Add cookies that apply to this URL
+	for _, cookie := range applicableCookies(opts.Cookies, req.URL, time.Now()) {
 		req.AddCookie(cookie)
 	}
 
@@ -136,137 +322,253 @@ func (cf *ContentFetcher) fetchStatic(ctx context.Context, url string, opts Fetc
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+		return nil, newStatusError(resp)
 	}
 
-	// Read response body
-	buf := make([]byte, 1024*1024) // 1MB buffer
-	n, err := resp.Body.Read(buf)
-	if err != nil && err.Error() != "EOF" {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if err := decodeBody(resp); err != nil {
+		return nil, err
+	}
+	contentType, err := checkContentType(resp)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if !IsImageType(contentType) {
+		if body, err = readBody(resp, responseLimit(opts)); err != nil {
+			return nil, err
+		}
 	}
 
-	html := string(buf[:n])
+	html := utf8Body(body, contentType)
 
 	return &FetchResult{
-		HTML:     html,
-		Title:    cf.extractTitle(html),
-		URL:      url,
-		UsedJS:   false,
-		Metadata: cf.extractMetadata(html),
+		HTML:        html,
+		Title:       cf.extractTitle(html),
+		URL:         url,
+		UsedJS:      false,
+		Metadata:    cf.extractMetadata(html),
+		ContentType: contentType,
+		FinalURL:    resp.Request.URL.String(),
+		Redirects:   redirectChain(resp),
+		ConsentWall: DetectConsentWall(resp.Request.URL.String(), html),
+		RobotsTag:   resp.Header.Values("X-Robots-Tag"),
+		Status:      resp.StatusCode,
+		UserAgent:   userAgent,
+		Proxy:       proxyFor(req),
+		Attempts:    1,
 	}, nil
 }
 
 func (cf *ContentFetcher) fetchWithJS(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
-	// Create Chrome context
-	chromeCtx, cancel := chromedp.NewContext(ctx)
-	defer cancel()
-
-	// Set timeout
-	if opts.Timeout > 0 {
-		chromeCtx, cancel = context.WithTimeout(chromeCtx, opts.Timeout)
-		defer cancel()
+	// Create Chrome context, in a tab of the pool when there is one
+	var chromeCtx context.Context
+	var cancel context.CancelFunc
+	if cf.browsers != nil {
+		tab, release, err := cf.browsers.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a browser tab: %w", err)
+		}
+		chromeCtx, cancel = tab, release
+	} else if allocOpts := cf.debug.AllocatorOptions(); allocOpts != nil {
+		allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocOpts...)
+		defer cancelAlloc()
+		chromeCtx, cancel = chromedp.NewContext(allocCtx)
+	} else {
+		chromeCtx, cancel = chromedp.NewContext(ctx)
 	}
+	defer cancel()
 
-	var html, title string
+	var html, title, location string
 	var err error
 
-	tasks := []chromedp.Action{
-		chromedp.Navigate(url),
+	var tasks []chromedp.Action
+	if cf.debug.Console != nil {
+		tasks = append(tasks, logConsole(cf.debug.Console, url))
+	}
+	tasks = append(tasks, cf.emulateLocale(opts))
+	switch {
+	case opts.Device != nil && opts.Viewport != nil:
+		tasks = append(tasks, emulateDevice(opts.Device.WithViewport(opts.Viewport)))
+	case opts.Device != nil:
+		tasks = append(tasks, emulateDevice(opts.Device))
+	case opts.Viewport != nil:
+		tasks = append(tasks, emulateViewport(opts.Viewport))
+	}
+	if opts.Stealth {
+		tasks = append(tasks, applyStealth(opts))
+	}
+	if opts.PrintMedia {
+		tasks = append(tasks, emulatePrintMedia())
 	}
-
-	// Add cookies if provided
 	if len(opts.Cookies) > 0 {
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-			for _, _ = range opts.Cookies {
-				// TODO: Implement cookie setting with proper cdproto API
-				// For now, skip cookie setting as the API requires cdproto conversion
-			}
-			return nil
-		}))
-		// Navigate again after setting cookies
-		tasks = append(tasks, chromedp.Navigate(url))
+		tasks = append(tasks, setCookies(opts.Cookies, url))
 	}
+	// Requests are followed from before the navigation starts them
+	var idle *idleTracker
+	if opts.NetworkIdle > 0 && opts.WaitForSelector == "" {
+		idle = newIdleTracker()
+		tasks = append(tasks, idle.listen())
+	}
+	tasks = append(tasks, navigate(url, resolveReferer(opts.Referer, url), opts.WaitUntil))
 
-	// Dismiss cookie banners if enabled
+	var banner string
 	if opts.SkipBanners {
-		tasks = append(tasks, cf.dismissCookieBanners(opts.BannerTimeout)...)
+		rules := opts.Banners
+		if rules == nil {
+			rules = &BannerRules{action: BannerAccept}
+		}
+		tasks = append(tasks, dismissBanner(rules, opts.BannerTimeout, &banner))
 	}
 
 	// Wait for specific selector if provided
 	if opts.WaitForSelector != "" {
 		tasks = append(tasks, chromedp.WaitVisible(opts.WaitForSelector))
+	} else if idle != nil {
+		tasks = append(tasks, idle.wait(opts.NetworkIdle))
 	} else {
 		// Default wait for document ready
 		tasks = append(tasks, chromedp.WaitReady("body"))
 	}
+	if opts.WaitForText != "" {
+		tasks = append(tasks, waitForText(opts.WaitForText))
+	}
+	var consentProvider string
+	tasks = append(tasks, acceptConsent(&consentProvider))
+	var scrolled ScrollStats
+	if opts.Scroll != nil {
+		tasks = append(tasks, scrollPage(opts.Scroll, &scrolled))
+	}
+	if opts.Expand != nil {
+		if selectors := opts.Expand.SelectorsFor(hostOf(url)); selectors != nil {
+			tasks = append(tasks, expandCollapsed(selectors))
+		}
+	}
+	if opts.PrintMedia {
+		tasks = append(tasks, dropPrintHidden())
+	}
+	if len(opts.Scripts) > 0 {
+		tasks = append(tasks, evalScripts(opts.Scripts))
+	}
 
 	// Extract content
 	tasks = append(tasks,
 		chromedp.OuterHTML("html", &html),
 		chromedp.Title(&title),
+		chromedp.Location(&location),
 	)
 
+	// Rendering gets its own budget so a slow render doesn't eat the fetch timeout
+	renderTimeout := opts.RenderTimeout
+	if renderTimeout <= 0 {
+		renderTimeout = opts.Timeout
+	}
+	if renderTimeout > 0 && opts.Scroll != nil {
+		renderTimeout += opts.Scroll.Timeout
+	}
+	if cf.debug.SlowMo > 0 {
+		if renderTimeout > 0 {
+			renderTimeout += time.Duration(len(tasks)) * cf.debug.SlowMo
+		}
+		tasks = slowMo(tasks, cf.debug.SlowMo)
+	}
+	if renderTimeout > 0 {
+		chromeCtx, cancel = context.WithTimeout(chromeCtx, renderTimeout)
+		defer cancel()
+	}
+
 	if err = chromedp.Run(chromeCtx, tasks...); err != nil {
 		return nil, fmt.Errorf("failed to run Chrome tasks: %w", err)
 	}
 
-	return &FetchResult{
-		HTML:     html,
-		Title:    title,
-		URL:      url,
-		UsedJS:   true,
-		Metadata: cf.extractMetadata(html),
-	}, nil
-}
-
-func (cf *ContentFetcher) dismissCookieBanners(timeout time.Duration) []chromedp.Action {
-	bannerSelectors := []string{
-		`[id*="cookie"]`,
-		`[class*="cookie"]`,
-		`[id*="consent"]`,
-		`[class*="consent"]`,
-		`[id*="gdpr"]`,
-		`[class*="gdpr"]`,
-		`.cookie-banner`,
-		`.consent-banner`,
-		`#cookieConsent`,
-		`#cookie-notice`,
-		`[role="dialog"]`,
-		`.modal`,
-	}
-
-	acceptSelectors := []string{
-		`button[id*="accept"]`,
-		`button[class*="accept"]`,
-		`.cookie-accept`,
-		`[data-action="accept"]`,
-		`button:contains("Accept")`,
-		`button:contains("OK")`,
-		`button:contains("Agree")`,
-		`button:contains("Allow")`,
+	result := &FetchResult{
+		HTML:        html,
+		Title:       title,
+		URL:         url,
+		UsedJS:      true,
+		Metadata:    cf.extractMetadata(html),
+		ContentType: "text/html",
+		FinalURL:    location,
+		ConsentWall: DetectConsentWall(location, html),
+		Banner:      banner,
+		Attempts:    1,
+	}
+	if banner != "" {
+		result.Metadata["cookie_banner"] = banner
+		result.Metadata["cookie_banner_action"] = "accepted"
+		if opts.Banners != nil && opts.Banners.action == BannerReject {
+			result.Metadata["cookie_banner_action"] = "rejected"
+		}
 	}
+	if scrolled.Rounds > 0 {
+		result.Metadata["scroll_rounds"] = strconv.Itoa(scrolled.Rounds)
+		result.Metadata["scroll_height"] = strconv.Itoa(scrolled.Height)
+	}
+	if consentProvider != "" {
+		result.Metadata["consent_wall"] = consentProvider
+		result.Metadata["consent_action"] = "accepted"
+		if result.ConsentWall != "" {
+			result.Metadata["consent_action"] = "unresolved"
+		}
+	}
+	return result, nil
+}
 
-	var tasks []chromedp.Action
-
-	// Wait a bit for banners to appear
-	tasks = append(tasks, chromedp.Sleep(1*time.Second))
+// emulateLocale makes Chrome present the same language as the static fetcher:
+// the Accept-Language header, navigator.language/Intl locale and, when
+// configured, the timezone
+func (cf *ContentFetcher) emulateLocale(opts FetchOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		header := acceptLanguage(opts.AcceptLanguage)
+		if err := network.SetExtraHTTPHeaders(network.Headers{"Accept-Language": header}).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set Accept-Language: %w", err)
+		}
+		if locale := LocaleFromAcceptLanguage(header); locale != "" {
+			if err := emulation.SetLocaleOverride().WithLocale(locale).Do(ctx); err != nil {
+				return fmt.Errorf("failed to set locale %s: %w", locale, err)
+			}
+		}
+		if opts.Timezone != "" {
+			if err := emulation.SetTimezoneOverride(opts.Timezone).Do(ctx); err != nil {
+				return fmt.Errorf("failed to set timezone %s: %w", opts.Timezone, err)
+			}
+		}
+		return nil
+	})
+}
 
-	// Try to find and dismiss banners
-	for _, selector := range bannerSelectors {
-		_ = selector // used in close selectors below when chromedp API is fixed
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-			// TODO: Fix chromedp API usage
-			// Check if banner exists - temporarily disabled
-			return nil
-		}))
+// acceptLanguage returns the configured header or the default
+func acceptLanguage(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return DefaultAcceptLanguage
 	}
+	return header
+}
 
-	// NOTE: The following banner dismissal logic is disabled pending chromedp API fixes.
-	// When re-enabled, it should iterate bannerSelectors and try accept/close buttons.
-	_ = acceptSelectors
-
-	return tasks
+// LocaleFromAcceptLanguage returns the highest-priority language tag of an
+// Accept-Language header, e.g. "de-DE" for "de-DE,de;q=0.9,en;q=0.5"
+func LocaleFromAcceptLanguage(header string) string {
+	best := ""
+	bestQ := -1.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if v, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
 }
 
 func (cf *ContentFetcher) needsJSRendering(html string) bool {
@@ -301,7 +603,7 @@ func (cf *ContentFetcher) needsJSRendering(html string) bool {
 }
 
 func (cf *ContentFetcher) extractTitle(html string) string {
-	lowerHTML := strings.ToLower(html)
+	lowerHTML := lowerASCII(html)
 	titleStart := strings.Index(lowerHTML, "<title")
 	if titleStart == -1 {
 		return ""
@@ -313,7 +615,7 @@ func (cf *ContentFetcher) extractTitle(html string) string {
 	}
 	start += titleStart + 1
 
-	end := strings.Index(strings.ToLower(html[start:]), "</title>")
+	end := strings.Index(lowerASCII(html[start:]), "</title>")
 	if end == -1 {
 		return ""
 	}
@@ -321,8 +623,21 @@ func (cf *ContentFetcher) extractTitle(html string) string {
 	return strings.TrimSpace(html[start : start+end])
 }
 
+// lowerASCII lowercases the ASCII letters of s only, so that indexes into the
+// result are indexes into s; strings.ToLower changes the length of invalid
+// UTF-8 and of some letters
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
 func (cf *ContentFetcher) extractBodyContent(html string) string {
-	lowerHTML := strings.ToLower(html)
+	lowerHTML := lowerASCII(html)
 	bodyStart := strings.Index(lowerHTML, "<body")
 	if bodyStart == -1 {
 		return html
@@ -383,7 +698,7 @@ func (cf *ContentFetcher) findMetaContent(html, property string) string {
 		fmt.Sprintf(`property='%s'`, property),
 	}
 
-	lowerHTML := strings.ToLower(html)
+	lowerHTML := lowerASCII(html)
 
 	for _, pattern := range patterns {
 		if idx := strings.Index(lowerHTML, pattern); idx != -1 {
@@ -0,0 +1,75 @@
+package fetcher
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsFileURL reports whether rawURL names a local file (file://)
+func IsFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "file://")
+}
+
+// FetchFile reads a document from a file:// URL with the checks of a
+// download: the type comes from the extension or the first bytes, and
+// MaxResponseSize applies.
+func FetchFile(rawURL string, opts FetchOptions) (*FetchResult, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "file") {
+		return nil, fmt.Errorf("not a file URL: %s", rawURL)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("file URL on another host: %s", rawURL)
+	}
+	path := filepath.FromSlash(u.Path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	// Shaped as a response so files pass the same type and size checks
+	resp := &http.Response{
+		Header:        http.Header{},
+		Body:          f,
+		ContentLength: info.Size(),
+		Request:       &http.Request{URL: u},
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		resp.Header.Set("Content-Type", t)
+	}
+	contentType, err := checkContentType(resp)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if !IsImageType(contentType) {
+		if body, err = readBody(resp, responseLimit(opts)); err != nil {
+			return nil, err
+		}
+	}
+
+	html := utf8Body(body, contentType)
+	sf := &SimpleFetcher{}
+	return &FetchResult{
+		HTML:        html,
+		Title:       sf.extractTitle(html),
+		URL:         rawURL,
+		Metadata:    sf.extractMetadata(html),
+		ContentType: contentType,
+		FinalURL:    rawURL,
+	}, nil
+}
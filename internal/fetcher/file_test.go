@@ -0,0 +1,50 @@
+package fetcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchFile(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	os.WriteFile(page, []byte("<html><head><title>Local</title></head><body><p>Hello</p></body></html>"), 0644)
+	noExt := filepath.Join(dir, "saved")
+	os.WriteFile(noExt, []byte("<!DOCTYPE html><html><body>x</body></html>"), 0644)
+	binary := filepath.Join(dir, "archive.html")
+	os.WriteFile(binary, []byte("PK\x03\x04 zipped"), 0644)
+
+	result, err := FetchFile("file://"+filepath.ToSlash(page), FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Local" || result.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("got title %q, type %q", result.Title, result.ContentType)
+	}
+
+	if result, err := FetchFile("file://"+filepath.ToSlash(noExt), FetchOptions{}); err != nil || result.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("sniffed type: %v, %v", result, err)
+	}
+
+	// The extension says HTML, the bytes say zip
+	var unsupported *UnsupportedContentError
+	if _, err := FetchFile("file://"+filepath.ToSlash(binary), FetchOptions{}); !errors.As(err, &unsupported) {
+		t.Errorf("binary file: got %v, want UnsupportedContentError", err)
+	}
+
+	var tooLarge *ResponseTooLargeError
+	if _, err := FetchFile("file://"+filepath.ToSlash(page), FetchOptions{MaxResponseSize: 10}); !errors.As(err, &tooLarge) {
+		t.Errorf("size limit: got %v", err)
+	}
+	if _, err := FetchFile("file://"+filepath.ToSlash(dir), FetchOptions{}); err == nil {
+		t.Error("expected an error for a directory")
+	}
+	if _, err := FetchFile("file://"+filepath.ToSlash(filepath.Join(dir, "missing.html")), FetchOptions{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got %v", err)
+	}
+	if _, err := FetchFile("file://example.com/etc/passwd", FetchOptions{}); err == nil {
+		t.Error("expected an error for a remote file URL")
+	}
+}
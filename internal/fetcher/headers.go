@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	chromeVersionRe = regexp.MustCompile(`Chrome/(\d+)`)
+	edgeVersionRe   = regexp.MustCompile(`Edg/(\d+)`)
+)
+
+// browserFamily infers which browser a user agent string claims to be so the
+// remaining headers can match it. Strings that name no known browser (custom
+// tools, bots) return "".
+func browserFamily(ua string) UserAgentType {
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		return UserAgentEdge
+	case strings.Contains(ua, "Firefox/"):
+		return UserAgentFirefox
+	case strings.Contains(ua, "CriOS/"):
+		// Chrome on iOS is WebKit underneath and sends Safari's headers
+		return UserAgentSafari
+	case strings.Contains(ua, "Chrome/"):
+		return UserAgentChrome
+	case strings.Contains(ua, "Safari/") && strings.Contains(ua, "Version/"):
+		return UserAgentSafari
+	}
+	return ""
+}
+
+// browserAccept returns the navigation Accept header each browser family sends
+func browserAccept(family UserAgentType) string {
+	switch family {
+	case UserAgentFirefox:
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	case UserAgentSafari:
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	default:
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"
+	}
+}
+
+// setBrowserHeaders sets the User-Agent together with the headers a top-level
+// navigation in that browser carries: Chromium adds UA client hints, Firefox
+// sends fetch metadata without them and Safari omits Sec-Fetch-User. Unknown
+// agents get neither, as a plain HTTP client would.
+func setBrowserHeaders(h http.Header, ua, target, referer string) {
+	family := browserFamily(ua)
+
+	h.Set("User-Agent", ua)
+	h.Set("Accept", browserAccept(family))
+	if referer != "" {
+		h.Set("Referer", referer)
+	}
+	if family == "" {
+		return
+	}
+
+	h.Set("Upgrade-Insecure-Requests", "1")
+	if family == UserAgentChrome || family == UserAgentEdge {
+		h.Set("Sec-CH-UA", clientHintBrands(ua, family))
+		h.Set("Sec-CH-UA-Mobile", clientHintMobile(ua))
+		h.Set("Sec-CH-UA-Platform", fmt.Sprintf("%q", clientHintPlatform(ua)))
+	}
+	h.Set("Sec-Fetch-Dest", "document")
+	h.Set("Sec-Fetch-Mode", "navigate")
+	h.Set("Sec-Fetch-Site", secFetchSite(referer, target))
+	if family != UserAgentSafari {
+		h.Set("Sec-Fetch-User", "?1")
+	}
+}
+
+// clientHintBrands builds the Sec-CH-UA brand list for a Chromium user agent
+func clientHintBrands(ua string, family UserAgentType) string {
+	version := "120"
+	if m := chromeVersionRe.FindStringSubmatch(ua); m != nil {
+		version = m[1]
+	}
+	brand := fmt.Sprintf(`"Google Chrome";v="%s"`, version)
+	if family == UserAgentEdge {
+		edgeVersion := version
+		if m := edgeVersionRe.FindStringSubmatch(ua); m != nil {
+			edgeVersion = m[1]
+		}
+		brand = fmt.Sprintf(`"Microsoft Edge";v="%s"`, edgeVersion)
+	}
+	return fmt.Sprintf(`"Not_A Brand";v="8", "Chromium";v="%s", %s`, version, brand)
+}
+
+func clientHintMobile(ua string) string {
+	if strings.Contains(ua, "Mobile") {
+		return "?1"
+	}
+	return "?0"
+}
+
+// clientHintPlatform maps the user agent's OS token to a Sec-CH-UA-Platform value
+func clientHintPlatform(ua string) string {
+	switch {
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "Android"):
+		return "Android"
+	case strings.Contains(ua, "CrOS"):
+		return "Chrome OS"
+	case strings.Contains(ua, "Macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,84 @@
+package fetcher
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBrowserFamily(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want UserAgentType
+	}{
+		{userAgents[UserAgentChrome][0], UserAgentChrome},
+		{userAgents[UserAgentFirefox][0], UserAgentFirefox},
+		{userAgents[UserAgentSafari][0], UserAgentSafari},
+		{userAgents[UserAgentEdge][0], UserAgentEdge},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1", UserAgentSafari},
+		{"scrpr/1.0", ""},
+	}
+	for _, tt := range tests {
+		if got := browserFamily(tt.ua); got != tt.want {
+			t.Errorf("browserFamily(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestSetBrowserHeaders(t *testing.T) {
+	const target = "https://example.com/post"
+
+	t.Run("chrome", func(t *testing.T) {
+		h := http.Header{}
+		setBrowserHeaders(h, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", target, "")
+		if got := h.Get("Sec-CH-UA"); got != `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"` {
+			t.Errorf("unexpected Sec-CH-UA %q", got)
+		}
+		if got := h.Get("Sec-CH-UA-Platform"); got != `"Windows"` {
+			t.Errorf("unexpected Sec-CH-UA-Platform %q", got)
+		}
+		if h.Get("Sec-CH-UA-Mobile") != "?0" || h.Get("Sec-Fetch-User") != "?1" || h.Get("Sec-Fetch-Site") != "none" {
+			t.Errorf("unexpected fetch metadata %v", h)
+		}
+	})
+
+	t.Run("edge", func(t *testing.T) {
+		h := http.Header{}
+		setBrowserHeaders(h, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 Edg/119.0.0.0", target, "")
+		if got := h.Get("Sec-CH-UA"); got != `"Not_A Brand";v="8", "Chromium";v="119", "Microsoft Edge";v="119"` {
+			t.Errorf("unexpected Sec-CH-UA %q", got)
+		}
+	})
+
+	t.Run("firefox", func(t *testing.T) {
+		h := http.Header{}
+		setBrowserHeaders(h, userAgents[UserAgentFirefox][0], target, "https://example.com/")
+		if h.Get("Sec-CH-UA") != "" || h.Get("Sec-CH-UA-Platform") != "" {
+			t.Errorf("Firefox must not send client hints: %v", h)
+		}
+		if h.Get("Sec-Fetch-Site") != "same-origin" || h.Get("Sec-Fetch-User") != "?1" {
+			t.Errorf("unexpected fetch metadata %v", h)
+		}
+		if h.Get("Accept") != browserAccept(UserAgentFirefox) {
+			t.Errorf("unexpected Accept %q", h.Get("Accept"))
+		}
+	})
+
+	t.Run("safari", func(t *testing.T) {
+		h := http.Header{}
+		setBrowserHeaders(h, userAgents[UserAgentSafari][0], target, "")
+		if h.Get("Sec-CH-UA") != "" || h.Get("Sec-Fetch-User") != "" {
+			t.Errorf("unexpected Safari headers %v", h)
+		}
+		if h.Get("Sec-Fetch-Mode") != "navigate" {
+			t.Errorf("expected Sec-Fetch-Mode navigate, got %q", h.Get("Sec-Fetch-Mode"))
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		h := http.Header{}
+		setBrowserHeaders(h, "scrpr/1.0", target, "")
+		if h.Get("Sec-Fetch-Mode") != "" || h.Get("Sec-CH-UA") != "" {
+			t.Errorf("custom agents should not send browser-only headers: %v", h)
+		}
+	})
+}
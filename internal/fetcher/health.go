@@ -0,0 +1,348 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// healthWeight is the weight of the newest request in the moving
+	// averages, so a host recovers after a few good requests
+	healthWeight = 0.3
+	// slowLatency is the average latency from which a host counts as
+	// struggling
+	slowLatency = 3 * time.Second
+	// failingRate is the average error rate from which a host counts as
+	// struggling
+	failingRate = 0.2
+	// stateHalfLife is how fast a saved error rate fades between runs, so a
+	// run five minutes after a burst of 429s still backs off and one an
+	// hour later starts fresh
+	stateHalfLife = 10 * time.Minute
+	// stateHorizon is how long saved stats without a pending pause are kept
+	stateHorizon = time.Hour
+)
+
+// HostHealth tracks the latency and error rate of each host during a run.
+// Struggling hosts get a growing delay between requests and, with
+// SetHostLimit, fewer requests at once; healthy hosts get neither, so one
+// slow or failing site does not hold back the rest of a batch. Safe for
+// concurrent use.
+type HostHealth struct {
+	maxDelay  time.Duration
+	hostLimit int // requests a healthy host may have in flight (0 = no cap)
+
+	mu    sync.Mutex
+	slots *sync.Cond // signalled when a host frees or gains a slot
+	hosts map[string]*hostStats
+}
+
+type hostStats struct {
+	latency    time.Duration // moving average
+	errRate    float64       // moving average of failures, 0-1
+	last       time.Time     // end of the last request
+	pauseUntil time.Time     // from a Retry-After header
+	limit      int           // requests allowed in flight (0 = hostLimit)
+	active     int           // requests in flight
+}
+
+// NewHostHealth creates a tracker whose delays never exceed maxDelay
+func NewHostHealth(maxDelay time.Duration) *HostHealth {
+	h := &HostHealth{maxDelay: maxDelay, hosts: make(map[string]*hostStats)}
+	h.slots = sync.NewCond(&h.mu)
+	return h
+}
+
+// SetHostLimit caps the requests one host has in flight at once at n (0 =
+// no cap). Each failure halves a host's share and a slow request takes one
+// away; each fast success gives one back, up to n.
+func (h *HostHealth) SetHostLimit(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hostLimit = max(n, 0)
+	for _, st := range h.hosts {
+		st.limit = min(st.limit, h.hostLimit)
+	}
+	h.slots.Broadcast()
+}
+
+// stats returns the stats of host, adding it as a healthy host; h.mu must
+// be held
+func (h *HostHealth) stats(host string) *hostStats {
+	st, ok := h.hosts[host]
+	if !ok {
+		st = &hostStats{}
+		h.hosts[host] = st
+	}
+	if st.limit == 0 {
+		st.limit = h.hostLimit
+	}
+	return st
+}
+
+// Limit returns how many requests rawURL's host may have in flight at once
+// (0 = no cap)
+func (h *HostHealth) Limit(rawURL string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hostLimit == 0 {
+		return 0
+	}
+	st, ok := h.hosts[strings.ToLower(hostOf(rawURL))]
+	if !ok || st.limit == 0 {
+		return h.hostLimit
+	}
+	return st.limit
+}
+
+// Acquire waits until rawURL's host may take one more request and returns
+// the function that gives the slot back
+func (h *HostHealth) Acquire(rawURL string) (release func()) {
+	host := strings.ToLower(hostOf(rawURL))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if host == "" || h.hostLimit == 0 {
+		return func() {}
+	}
+	st := h.stats(host)
+	for h.hostLimit > 0 && st.active >= st.limit {
+		h.slots.Wait()
+	}
+	st.active++
+	return sync.OnceFunc(func() {
+		h.mu.Lock()
+		st.active--
+		h.mu.Unlock()
+		h.slots.Broadcast()
+	})
+}
+
+// Observe records a finished request to rawURL. failed marks network
+// errors, timeouts and overload responses (429, 5xx), not pages that merely
+// failed to extract; the latency of failed requests, which includes their
+// retries, is not counted.
+func (h *HostHealth) Observe(rawURL string, latency time.Duration, failed bool) {
+	host := strings.ToLower(hostOf(rawURL))
+	if host == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// A new host starts out healthy, so one failure is not an outage
+	st := h.stats(host)
+	st.last = time.Now()
+	if failed {
+		st.errRate = (1-healthWeight)*st.errRate + healthWeight
+		if h.hostLimit > 0 {
+			st.limit = max(st.limit/2, 1)
+		}
+		return
+	}
+	st.errRate = (1 - healthWeight) * st.errRate
+	if st.latency == 0 {
+		st.latency = latency
+	} else {
+		st.latency = time.Duration((1-healthWeight)*float64(st.latency) + healthWeight*float64(latency))
+	}
+	if h.hostLimit == 0 {
+		return
+	}
+	if latency >= slowLatency {
+		st.limit = max(st.limit-1, 1)
+	} else if st.limit < h.hostLimit {
+		st.limit++
+		h.slots.Broadcast()
+	}
+}
+
+// MaxDelay is the longest adaptive delay; only a Retry-After pause waits
+// longer
+func (h *HostHealth) MaxDelay() time.Duration {
+	return h.maxDelay
+}
+
+// Pause holds off requests to rawURL's host for d, as asked by a
+// Retry-After header; a longer pause already in place is kept
+func (h *HostHealth) Pause(rawURL string, d time.Duration) {
+	host := strings.ToLower(hostOf(rawURL))
+	if host == "" || d <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.hosts[host]
+	if !ok {
+		st = h.stats(host)
+		st.last = time.Now()
+	}
+	if until := time.Now().Add(d); until.After(st.pauseUntil) {
+		st.pauseUntil = until
+	}
+}
+
+// Delay returns the pause a host needs between requests: none while it is
+// healthy, then its average latency (halving the load a slow host sees) plus
+// a share of maxDelay that grows with the square of its error rate, so
+// isolated failures cost little and a host that keeps failing nears maxDelay
+func (h *HostHealth) Delay(rawURL string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.hosts[strings.ToLower(hostOf(rawURL))]
+	if !ok {
+		return 0
+	}
+	return h.delay(st)
+}
+
+func (h *HostHealth) delay(st *hostStats) time.Duration {
+	var d time.Duration
+	if st.latency >= slowLatency {
+		d += st.latency
+	}
+	if st.errRate >= failingRate {
+		d += time.Duration(st.errRate * st.errRate * float64(h.maxDelay))
+	}
+	return min(d, h.maxDelay)
+}
+
+// Ready returns when the next request to a host may start
+func (h *HostHealth) Ready(rawURL string) time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.hosts[strings.ToLower(hostOf(rawURL))]
+	if !ok {
+		return time.Time{}
+	}
+	ready := st.last.Add(h.delay(st))
+	if st.pauseUntil.After(ready) {
+		return st.pauseUntil
+	}
+	return ready
+}
+
+// hostState is how a host's stats are saved between runs
+type hostState struct {
+	Latency    time.Duration `json:"latency"`
+	ErrRate    float64       `json:"err_rate"`
+	Last       time.Time     `json:"last"`
+	PauseUntil time.Time     `json:"pause_until,omitzero"`
+}
+
+// LoadState picks up the hosts saved by earlier runs, so a run started
+// shortly after one that was rate limited keeps backing off. Error rates
+// fade with the time since the host's last request; a missing file is not
+// an error.
+func (h *HostHealth) LoadState(path string) error {
+	saved, err := readHostStates(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for host, state := range saved {
+		if _, ok := h.hosts[host]; ok {
+			continue
+		}
+		fade := math.Pow(0.5, float64(now.Sub(state.Last))/float64(stateHalfLife))
+		st := &hostStats{
+			latency:    state.Latency,
+			errRate:    state.ErrRate * fade,
+			last:       state.Last,
+			pauseUntil: state.PauseUntil,
+		}
+		// A host that was still failing starts over one request at a time
+		if st.errRate >= failingRate && h.hostLimit > 0 {
+			st.limit = 1
+		}
+		h.hosts[host] = st
+	}
+	return nil
+}
+
+// SaveState writes the hosts that are still worth remembering to path,
+// merged with what other runs saved there in the meantime. Until a host
+// struggles there is nothing to remember, so runs of healthy hosts create
+// no file.
+func (h *HostHealth) SaveState(path string) error {
+	saved, err := readHostStates(path)
+	if err != nil {
+		saved = nil // a corrupt file is replaced
+	} else if saved == nil && !h.struggled() {
+		return nil
+	}
+	if saved == nil {
+		saved = make(map[string]hostState)
+	}
+	h.mu.Lock()
+	for host, st := range h.hosts {
+		if prev, ok := saved[host]; !ok || !prev.Last.After(st.last) {
+			saved[host] = hostState{Latency: st.latency, ErrRate: st.errRate, Last: st.last, PauseUntil: st.pauseUntil}
+		}
+	}
+	h.mu.Unlock()
+
+	now := time.Now()
+	for host, state := range saved {
+		if now.Sub(state.Last) > stateHorizon && !state.PauseUntil.After(now) {
+			delete(saved, host)
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save host state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save host state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save host state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save host state: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// struggled reports whether a host failed, was slow or asked for a pause
+// during the run
+func (h *HostHealth) struggled() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, st := range h.hosts {
+		if st.errRate > 0 || st.latency >= slowLatency || !st.pauseUntil.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func readHostStates(path string) (map[string]hostState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host state: %w", err)
+	}
+	var states map[string]hostState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("invalid host state %s: %w", path, err)
+	}
+	return states, nil
+}
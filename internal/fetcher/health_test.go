@@ -0,0 +1,220 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHostHealth_HealthyHostsGetNoDelay(t *testing.T) {
+	h := NewHostHealth(30 * time.Second)
+	for range 5 {
+		h.Observe("https://fast.example/a", 200*time.Millisecond, false)
+	}
+	if d := h.Delay("https://fast.example/b"); d != 0 {
+		t.Errorf("expected no delay for a healthy host, got %v", d)
+	}
+	if d := h.Delay("https://unseen.example/"); d != 0 {
+		t.Errorf("expected no delay for an unseen host, got %v", d)
+	}
+	if !h.Ready("https://unseen.example/").IsZero() {
+		t.Error("expected an unseen host to be ready at once")
+	}
+}
+
+func TestHostHealth_FailingHostSlowsDownAndRecovers(t *testing.T) {
+	h := NewHostHealth(10 * time.Second)
+	for range 3 {
+		h.Observe("https://flaky.example/a", 100*time.Millisecond, true)
+	}
+	h.Observe("https://fast.example/a", 100*time.Millisecond, false)
+
+	failing := h.Delay("https://FLAKY.example/b")
+	if failing <= 0 || failing > 10*time.Second {
+		t.Fatalf("expected a delay within the cap for a failing host, got %v", failing)
+	}
+	if d := h.Delay("https://fast.example/b"); d != 0 {
+		t.Errorf("expected the healthy host to keep full speed, got %v", d)
+	}
+	if !h.Ready("https://flaky.example/").After(time.Now()) {
+		t.Error("expected the failing host to be paused")
+	}
+
+	for range 10 {
+		h.Observe("https://flaky.example/a", 100*time.Millisecond, false)
+	}
+	if d := h.Delay("https://flaky.example/b"); d != 0 {
+		t.Errorf("expected the host to recover after successes, got %v", d)
+	}
+}
+
+func TestHostHealth_SlowHostWaitsItsLatency(t *testing.T) {
+	h := NewHostHealth(30 * time.Second)
+	h.Observe("https://slow.example/a", 5*time.Second, false)
+	if d := h.Delay("https://slow.example/b"); d != 5*time.Second {
+		t.Errorf("expected a slow host to wait its latency, got %v", d)
+	}
+
+	h = NewHostHealth(2 * time.Second)
+	h.Observe("https://slow.example/a", 5*time.Second, false)
+	h.Observe("https://slow.example/a", 5*time.Second, true)
+	if d := h.Delay("https://slow.example/b"); d != 2*time.Second {
+		t.Errorf("expected the delay to be capped, got %v", d)
+	}
+}
+
+func TestHostHealth_StatePersistsPausesAndErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+
+	h := NewHostHealth(10 * time.Second)
+	for range 3 {
+		h.Observe("https://limited.example/a", 100*time.Millisecond, true)
+	}
+	h.Pause("https://limited.example/a", time.Hour)
+	h.Observe("https://fast.example/a", 100*time.Millisecond, false)
+	if err := h.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	next := NewHostHealth(10 * time.Second)
+	if err := next.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Until(next.Ready("https://limited.example/b")); wait < 59*time.Minute {
+		t.Errorf("expected the Retry-After pause to carry over, got %v", wait)
+	}
+	if d := next.Delay("https://limited.example/b"); d <= 0 {
+		t.Error("expected the error rate to carry over")
+	}
+	if d := next.Delay("https://fast.example/b"); d != 0 {
+		t.Errorf("expected the healthy host to stay fast, got %v", d)
+	}
+}
+
+func TestHostHealth_HealthyRunSavesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "hosts.json")
+	h := NewHostHealth(10 * time.Second)
+	h.Observe("https://fast.example/a", 100*time.Millisecond, false)
+	if err := h.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("expected no state written for healthy hosts, got %v", err)
+	}
+}
+
+func TestHostHealth_StateFadesAndMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	writeState := func(states map[string]hostState) {
+		data, _ := json.Marshal(states)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeState(map[string]hostState{
+		"stale.example":  {ErrRate: 0.9, Last: old},
+		"other.example":  {ErrRate: 0.9, Last: recent},
+		"paused.example": {Last: old, PauseUntil: time.Now().Add(time.Hour)},
+	})
+
+	h := NewHostHealth(10 * time.Second)
+	if err := h.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if d := h.Delay("https://stale.example/"); d != 0 {
+		t.Errorf("expected an old error rate to have faded, got %v", d)
+	}
+	if d := h.Delay("https://other.example/"); d == 0 {
+		t.Error("expected a recent error rate to still count")
+	}
+
+	// Another run saved meanwhile; its hosts survive our save
+	writeState(map[string]hostState{"parallel.example": {ErrRate: 0.5, Last: recent}})
+	h.Observe("https://mine.example/", 100*time.Millisecond, false)
+	if err := h.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := readHostStates(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"parallel.example", "mine.example", "paused.example", "other.example"} {
+		if _, ok := saved[host]; !ok {
+			t.Errorf("expected %s to be saved", host)
+		}
+	}
+	if _, ok := saved["stale.example"]; ok {
+		t.Error("expected the stale host to be dropped")
+	}
+}
+
+func TestHostHealth_HostLimitShrinksAndGrows(t *testing.T) {
+	h := NewHostHealth(10 * time.Second)
+	if n := h.Limit("https://flaky.example/"); n != 0 {
+		t.Fatalf("expected no cap without SetHostLimit, got %d", n)
+	}
+	h.SetHostLimit(4)
+
+	tests := []struct {
+		latency time.Duration
+		failed  bool
+		want    int
+	}{
+		{100 * time.Millisecond, false, 4}, // healthy hosts keep the full share
+		{100 * time.Millisecond, true, 2},
+		{100 * time.Millisecond, true, 1},
+		{100 * time.Millisecond, true, 1}, // never below one
+		{100 * time.Millisecond, false, 2},
+		{5 * time.Second, false, 1}, // slow requests take one away
+		{100 * time.Millisecond, false, 2},
+		{100 * time.Millisecond, false, 3},
+		{100 * time.Millisecond, false, 4},
+		{100 * time.Millisecond, false, 4},
+	}
+	for i, tt := range tests {
+		h.Observe("https://flaky.example/a", tt.latency, tt.failed)
+		if n := h.Limit("https://FLAKY.example/b"); n != tt.want {
+			t.Errorf("after request %d: limit %d, want %d", i+1, n, tt.want)
+		}
+	}
+	if n := h.Limit("https://fast.example/"); n != 4 {
+		t.Errorf("expected an unseen host to get the full share, got %d", n)
+	}
+}
+
+func TestHostHealth_AcquireWaitsForAFreeSlot(t *testing.T) {
+	h := NewHostHealth(10 * time.Second)
+	h.SetHostLimit(2)
+	h.Observe("https://flaky.example/a", 100*time.Millisecond, true)
+
+	release := h.Acquire("https://flaky.example/a")
+	other := h.Acquire("https://fast.example/a") // another host is not held up
+	defer other()
+
+	acquired := make(chan func())
+	go func() { acquired <- h.Acquire("https://flaky.example/b") }()
+	select {
+	case <-acquired:
+		t.Fatal("a second request got through to a host limited to one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	release() // giving a slot back twice frees it once
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("the waiting request did not get the freed slot")
+	}
+
+	// A fast success gives the host its second slot back
+	h.Observe("https://flaky.example/a", 100*time.Millisecond, false)
+	first, second := h.Acquire("https://flaky.example/a"), h.Acquire("https://flaky.example/b")
+	first()
+	second()
+}
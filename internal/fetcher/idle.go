@@ -0,0 +1,90 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// idlePoll is how often the tab's network is checked for quiet
+const idlePoll = 50 * time.Millisecond
+
+// idleTracker follows the requests of a tab from before its navigation, so
+// the page can be read once none has been in flight for a while: many
+// single-page apps have a ready <body> long before their content arrives
+type idleTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time // when a request last started or ended
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{inflight: make(map[network.RequestID]bool), last: time.Now()}
+}
+
+// listen starts following the tab's requests; it must run before navigate
+func (t *idleTracker) listen() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		chromedp.ListenTarget(ctx, func(ev any) {
+			switch e := ev.(type) {
+			case *network.EventRequestWillBeSent:
+				t.started(e.RequestID, time.Now())
+			case *network.EventLoadingFinished:
+				t.ended(e.RequestID, time.Now())
+			case *network.EventLoadingFailed:
+				t.ended(e.RequestID, time.Now())
+			}
+		})
+		return nil
+	})
+}
+
+// started and ended record a request; a redirect is sent again under the
+// same ID and ends once
+func (t *idleTracker) started(id network.RequestID, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight[id] = true
+	t.last = now
+}
+
+func (t *idleTracker) ended(id network.RequestID, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inflight[id] {
+		delete(t.inflight, id)
+		t.last = now
+	}
+}
+
+// quiet returns how long no request has been in flight as of now (0 while
+// one is)
+func (t *idleTracker) quiet(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.inflight) > 0 {
+		return 0
+	}
+	return now.Sub(t.last)
+}
+
+// wait returns once no request has been in flight for idle. A page that
+// keeps polling never gets there and fails at the render timeout.
+func (t *idleTracker) wait(idle time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(idlePoll)
+		defer ticker.Stop()
+		for t.quiet(time.Now()) < idle {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for %v of network idle: %w", idle, ctx.Err())
+			}
+		}
+		return nil
+	})
+}
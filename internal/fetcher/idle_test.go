@@ -0,0 +1,38 @@
+package fetcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleTracker(t *testing.T) {
+	start := time.Now()
+	tr := newIdleTracker()
+	tr.started("1", start)
+	tr.started("2", start.Add(100*time.Millisecond))
+	if q := tr.quiet(start.Add(time.Second)); q != 0 {
+		t.Errorf("quiet with requests in flight = %v, want 0", q)
+	}
+	tr.ended("1", start.Add(200*time.Millisecond))
+	tr.ended("2", start.Add(300*time.Millisecond))
+	if q := tr.quiet(start.Add(time.Second)); q != 700*time.Millisecond {
+		t.Errorf("quiet = %v, want 700ms since the last request ended", q)
+	}
+	// An event for a request the tracker never saw start does not reset it
+	tr.ended("3", start.Add(900*time.Millisecond))
+	if q := tr.quiet(start.Add(time.Second)); q != 700*time.Millisecond {
+		t.Errorf("quiet after an unknown request ended = %v, want 700ms", q)
+	}
+}
+
+func TestIdleTracker_Redirect(t *testing.T) {
+	start := time.Now()
+	tr := newIdleTracker()
+	// A redirect is sent again under the same ID and finishes once
+	tr.started("1", start)
+	tr.started("1", start.Add(50*time.Millisecond))
+	tr.ended("1", start.Add(100*time.Millisecond))
+	if q := tr.quiet(start.Add(600 * time.Millisecond)); q != 500*time.Millisecond {
+		t.Errorf("quiet = %v, want 500ms", q)
+	}
+}
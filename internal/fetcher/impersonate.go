@@ -0,0 +1,255 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/http2"
+)
+
+// utlsSessionCache resumes impersonated TLS sessions, like sessionCache does
+// for crypto/tls
+var utlsSessionCache = utls.NewLRUClientSessionCache(256)
+
+// clientHello is the TLS ClientHello presented for a browser family. Edge is
+// Chromium and hello like Chrome; custom pools do too, as the most common
+// browser.
+func clientHello(family UserAgentType) utls.ClientHelloID {
+	switch family {
+	case UserAgentFirefox:
+		return utls.HelloFirefox_Auto
+	case UserAgentSafari:
+		return utls.HelloSafari_Auto
+	default:
+		return utls.HelloChrome_Auto
+	}
+}
+
+// impersonatingTransport makes HTTPS connections with the TLS ClientHello of
+// the browser named by each request's User-Agent, instead of Go's own, which
+// some bot protections block whatever the headers say. The browser's ALPN
+// decides between HTTP/2 and HTTP/1.1 per host, as in the browser. Plain
+// HTTP and proxied requests go through the regular transport. Only the
+// handshake is impersonated: header order and HTTP/2 SETTINGS stay Go's.
+type impersonatingTransport struct {
+	timeouts Timeouts
+	fallback http.RoundTripper
+	rootCAs  *x509.CertPool // nil = system roots
+
+	mu       sync.Mutex
+	families map[string]*helloTransport // by ClientHello client name
+}
+
+func newImpersonatingTransport(t Timeouts) *impersonatingTransport {
+	return &impersonatingTransport{
+		timeouts: t.withDefaults(),
+		fallback: sharedTransport(t),
+		families: make(map[string]*helloTransport),
+	}
+}
+
+func (t *impersonatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.fallback.RoundTrip(req)
+	}
+	if proxy, err := http.ProxyFromEnvironment(req); err != nil || proxy != nil {
+		return t.fallback.RoundTrip(req)
+	}
+	return t.forFamily(browserFamily(req.Header.Get("User-Agent"))).roundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of every family
+func (t *impersonatingTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, h := range t.families {
+		h.h1.CloseIdleConnections()
+		h.h2.CloseIdleConnections()
+		h.closePending()
+	}
+}
+
+// forFamily returns the connections of one ClientHello; they are kept apart
+// so a pooled connection never carries another browser's requests
+func (t *impersonatingTransport) forFamily(family UserAgentType) *helloTransport {
+	hello := clientHello(family)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if h, ok := t.families[hello.Client]; ok {
+		return h
+	}
+	h := newHelloTransport(hello, t.timeouts, t.rootCAs)
+	t.families[hello.Client] = h
+	return h
+}
+
+// helloTransport pools HTTP/1.1 and HTTP/2 connections made with one
+// ClientHello
+type helloTransport struct {
+	hello     utls.ClientHelloID
+	rootCAs   *x509.CertPool
+	handshake time.Duration // TLS handshake timeout
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	h1        *http.Transport
+	h2        *http2.Transport
+
+	mu      sync.Mutex
+	protos  map[string]string      // host:port -> protocol its server chose
+	probes  map[string]*protoProbe // host:port -> handshake learning its protocol
+	pending map[string][]net.Conn  // handshaken connections a transport is about to dial
+}
+
+// protoProbe is the first handshake with a server; requests to it in the
+// meantime wait for its protocol instead of handshaking too
+type protoProbe struct {
+	done  chan struct{}
+	proto string
+	err   error
+}
+
+func newHelloTransport(hello utls.ClientHelloID, t Timeouts, rootCAs *x509.CertPool) *helloTransport {
+	h := &helloTransport{
+		hello:     hello,
+		rootCAs:   rootCAs,
+		handshake: t.TLSHandshake,
+		dial: cachedDialContext(&net.Dialer{
+			Timeout:   t.Connect,
+			KeepAlive: 30 * time.Second,
+		}),
+		protos:  make(map[string]string),
+		probes:  make(map[string]*protoProbe),
+		pending: make(map[string][]net.Conn),
+	}
+	h.h1 = &http.Transport{
+		DialTLSContext:        h.dialForTransport,
+		ResponseHeaderTimeout: t.ResponseHeader,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	h.h2 = &http2.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return h.dialForTransport(ctx, network, addr)
+		},
+		ReadIdleTimeout: 30 * time.Second,
+	}
+	return h
+}
+
+func (h *helloTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	proto, err := h.protocol(req.Context(), canonicalAddr(req.URL.Hostname(), req.URL.Port()))
+	if err != nil {
+		return nil, err
+	}
+	if proto == http2.NextProtoTLS {
+		return h.h2.RoundTrip(req)
+	}
+	return h.h1.RoundTrip(req)
+}
+
+// protocol returns the protocol the server at addr chose. The first
+// handshake tells; its connection is handed to the transport for that
+// protocol, and requests racing it wait rather than leave connections of
+// their own behind. When it fails, the next waiter tries.
+func (h *helloTransport) protocol(ctx context.Context, addr string) (string, error) {
+	for {
+		h.mu.Lock()
+		if proto, ok := h.protos[addr]; ok {
+			h.mu.Unlock()
+			return proto, nil
+		}
+		probe, probing := h.probes[addr]
+		if !probing {
+			probe = &protoProbe{done: make(chan struct{})}
+			h.probes[addr] = probe
+		}
+		h.mu.Unlock()
+
+		if !probing {
+			conn, err := h.dialTLS(ctx, addr)
+			h.mu.Lock()
+			delete(h.probes, addr)
+			if err == nil {
+				probe.proto = conn.ConnectionState().NegotiatedProtocol
+				h.protos[addr] = probe.proto
+				h.pending[addr] = append(h.pending[addr], conn)
+			}
+			h.mu.Unlock()
+			probe.err = err
+			close(probe.done)
+			return probe.proto, err
+		}
+
+		select {
+		case <-probe.done:
+			if probe.err == nil {
+				return probe.proto, nil
+			}
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// dialForTransport hands out a connection made by roundTrip, or dials one
+func (h *helloTransport) dialForTransport(ctx context.Context, _, addr string) (net.Conn, error) {
+	h.mu.Lock()
+	if conns := h.pending[addr]; len(conns) > 0 {
+		h.pending[addr] = conns[1:]
+		h.mu.Unlock()
+		return conns[0], nil
+	}
+	h.mu.Unlock()
+	return h.dialTLS(ctx, addr)
+}
+
+// closePending closes the connections no transport has taken, such as
+// those of requests cancelled before their transport dialed
+func (h *helloTransport) closePending() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for addr, conns := range h.pending {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		delete(h.pending, addr)
+	}
+}
+
+func (h *helloTransport) dialTLS(ctx context.Context, addr string) (*utls.UConn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := h.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := utls.UClient(raw, &utls.Config{
+		ServerName:         host,
+		RootCAs:            h.rootCAs,
+		ClientSessionCache: utlsSessionCache,
+	}, h.hello)
+
+	hsCtx, cancel := context.WithTimeout(ctx, h.handshake)
+	defer cancel()
+	if err := conn.HandshakeContext(hsCtx); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// canonicalAddr is host:port with the default HTTPS port filled in
+func canonicalAddr(host, port string) string {
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,149 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// hasGREASE reports whether a ClientHello offered a GREASE cipher suite,
+// which Chrome does and Go and Firefox do not
+func hasGREASE(suites []uint16) bool {
+	for _, s := range suites {
+		if s&0x0f0f == 0x0a0a {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImpersonatingTransport(t *testing.T) {
+	var mu sync.Mutex
+	var grease bool
+	var proto string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proto = r.Proto
+		mu.Unlock()
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			grease = hasGREASE(hello.CipherSuites)
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	transport := newImpersonatingTransport(Timeouts{})
+	transport.rootCAs = roots
+
+	sf := NewSimpleFetcher()
+	sf.client.Transport = transport
+	tests := []struct {
+		agent   string
+		grease  bool
+		httpVer string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36", true, "HTTP/2.0"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:140.0) Gecko/20100101 Firefox/140.0", false, "HTTP/2.0"},
+	}
+	for _, tt := range tests {
+		if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{UserAgent: tt.agent}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		mu.Lock()
+		if grease != tt.grease || proto != tt.httpVer {
+			t.Errorf("%s: GREASE %v over %s, want %v over %s", browserFamily(tt.agent), grease, proto, tt.grease, tt.httpVer)
+		}
+		mu.Unlock()
+	}
+	if len(transport.families) != 2 {
+		t.Errorf("expected separate connections per browser, got %d", len(transport.families))
+	}
+}
+
+func TestImpersonatingTransportHTTP1(t *testing.T) {
+	var proto string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	transport := newImpersonatingTransport(Timeouts{})
+	transport.rootCAs = roots
+	sf := NewSimpleFetcher()
+	sf.client.Transport = transport
+
+	for i := 0; i < 2; i++ {
+		if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{}); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if proto != "HTTP/1.1" {
+			t.Errorf("request %d: expected HTTP/1.1 for a server without h2, got %s", i, proto)
+		}
+	}
+}
+
+func TestImpersonatingTransportConcurrentFirstRequests(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	transport := newImpersonatingTransport(Timeouts{})
+	transport.rootCAs = roots
+	sf := NewSimpleFetcher()
+	sf.client.Transport = transport
+
+	const requests = 8
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{})
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// HTTP/2 may dial a few connections of its own until the server's
+	// settings arrive; those are pooled. A handshake of a racing request
+	// would be left over.
+	for _, h := range transport.families {
+		for addr, pending := range h.pending {
+			if len(pending) > 0 {
+				t.Errorf("connections to %s left pending: %d", addr, len(pending))
+			}
+		}
+	}
+}
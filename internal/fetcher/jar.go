@@ -0,0 +1,159 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieJar keeps the cookies responses set and sends them back to the same
+// sites, like a browser session: a consent or session cookie picked up on
+// one URL applies to the next URL of the site. It wraps net/http/cookiejar,
+// which cannot list its cookies, and records what it was given so the jar
+// can be saved between runs. Safe for concurrent use.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu    sync.Mutex
+	saved map[string]savedCookie // by domain, path and name
+}
+
+// savedCookie is a cookie as stored by SaveCookies, with the URL whose
+// response set it so loading repeats the same domain checks
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"` // empty for host-only cookies
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires,omitzero"` // zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+func NewCookieJar() *CookieJar {
+	// Options with a public suffix list never make New fail
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &CookieJar{jar: jar, saved: make(map[string]savedCookie)}
+}
+
+// SetCookies stores the cookies of a response from u (http.CookieJar)
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		sc := savedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.MaxAge > 0 {
+			sc.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		scope := sc.Domain
+		if scope == "" {
+			scope = strings.ToLower(u.Hostname())
+		}
+		key := scope + ";" + sc.Path + ";" + sc.Name
+		if c.MaxAge < 0 || (!sc.Expires.IsZero() && !sc.Expires.After(now)) {
+			delete(j.saved, key) // the server deleted the cookie
+			continue
+		}
+		j.saved[key] = sc
+	}
+}
+
+// Cookies returns the cookies to send with a request to u (http.CookieJar)
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// LoadCookies adds the unexpired cookies saved at path; a missing file is
+// an empty jar
+func (j *CookieJar) LoadCookies(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cookie jar: %w", err)
+	}
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid cookie jar %s: %w", path, err)
+	}
+	for _, sc := range saved {
+		u, err := url.Parse(sc.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     sc.Name,
+			Value:    sc.Value,
+			Domain:   sc.Domain,
+			Path:     sc.Path,
+			Expires:  sc.Expires,
+			Secure:   sc.Secure,
+			HttpOnly: sc.HttpOnly,
+		}})
+	}
+	return nil
+}
+
+// SaveCookies writes the unexpired cookies, session cookies included, to
+// path. The file is private to the user: cookies can log in as them.
+func (j *CookieJar) SaveCookies(path string) error {
+	now := time.Now()
+	j.mu.Lock()
+	saved := make([]savedCookie, 0, len(j.saved))
+	for _, sc := range j.saved {
+		if sc.Expires.IsZero() || sc.Expires.After(now) {
+			saved = append(saved, sc)
+		}
+	}
+	j.mu.Unlock()
+	// Stable order keeps the file diffable
+	slices.SortFunc(saved, func(a, b savedCookie) int {
+		return strings.Compare(a.URL+a.Path+a.Name, b.URL+b.Path+b.Name)
+	})
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save cookie jar: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save cookie jar: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save cookie jar: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save cookie jar: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,73 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestCookieJarReplaysCookies(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/consent" {
+			http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+		}
+		if c, err := r.Cookie("consent"); err == nil {
+			got = c.Value
+		}
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer server.Close()
+
+	jar := NewCookieJar()
+	sf := NewSimpleFetcher()
+	sf.SetCookieJar(jar)
+	for _, path := range []string{"/consent", "/article"} {
+		if _, err := sf.FetchStatic(context.Background(), server.URL+path, FetchOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got != "yes" {
+		t.Fatalf("expected the consent cookie on the next request, got %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.SaveCookies(path); err != nil {
+		t.Fatalf("SaveCookies: %v", err)
+	}
+	loaded := NewCookieJar()
+	if err := loaded.LoadCookies(path); err != nil {
+		t.Fatalf("LoadCookies: %v", err)
+	}
+	u, _ := url.Parse(server.URL + "/other")
+	if cookies := loaded.Cookies(u); len(cookies) != 2 {
+		t.Errorf("expected both cookies after loading, got %v", cookies)
+	}
+}
+
+func TestCookieJarForgetsDeletedCookies(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "abc"}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", MaxAge: -1}})
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.SaveCookies(path); err != nil {
+		t.Fatalf("SaveCookies: %v", err)
+	}
+	loaded := NewCookieJar()
+	if err := loaded.LoadCookies(path); err != nil {
+		t.Fatalf("LoadCookies: %v", err)
+	}
+	if cookies := loaded.Cookies(u); len(cookies) != 0 {
+		t.Errorf("expected deleted cookie to be gone, got %v", cookies)
+	}
+	if err := loaded.LoadCookies(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("missing jar should load as empty, got %v", err)
+	}
+}
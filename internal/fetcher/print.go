@@ -0,0 +1,42 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// emulatePrintMedia makes the tab match @media print rules (and
+// matchMedia("print") in scripts) from the first stylesheet on
+func emulatePrintMedia() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := emulation.SetEmulatedMedia().WithMedia("print").Do(ctx); err != nil {
+			return fmt.Errorf("failed to emulate print media: %w", err)
+		}
+		return nil
+	})
+}
+
+// dropHiddenScript removes the body elements the print stylesheet hides, so
+// the navigation, share bars and ads it collapses do not reach the extractor
+const dropHiddenScript = `(() => {
+	let removed = 0;
+	for (const el of Array.from(document.body.querySelectorAll("*"))) {
+		if (!el.isConnected || ["SCRIPT", "STYLE", "TEMPLATE", "NOSCRIPT"].includes(el.tagName)) {
+			continue;
+		}
+		if (getComputedStyle(el).display === "none") {
+			el.remove();
+			removed++;
+		}
+	}
+	return removed;
+})()`
+
+// dropPrintHidden removes the elements hidden under print media
+func dropPrintHidden() chromedp.Action {
+	var removed int
+	return chromedp.Evaluate(dropHiddenScript, &removed)
+}
@@ -0,0 +1,54 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// DefaultMaxRedirects is net/http's own limit
+const DefaultMaxRedirects = 10
+
+// Redirect is one hop of a followed redirect chain
+type Redirect struct {
+	URL    string `json:"url"`    // URL that answered with the redirect
+	Status int    `json:"status"` // 301, 302, 303, 307 or 308
+}
+
+// TooManyRedirectsError reports a redirect chain longer than the limit;
+// fetches that hit it are not retried
+type TooManyRedirectsError struct {
+	Max int
+}
+
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("stopped after %d redirects (see max_redirects)", e.Max)
+}
+
+// redirectPolicy is a CheckRedirect that follows at most max redirects
+// (0 = DefaultMaxRedirects), or none when follow is off, in which case the
+// redirect response itself is returned
+func redirectPolicy(follow bool, max int) func(*http.Request, []*http.Request) error {
+	if max <= 0 {
+		max = DefaultMaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > max {
+			return &TooManyRedirectsError{Max: max}
+		}
+		return nil
+	}
+}
+
+// redirectChain lists the redirects that led to resp, first hop first
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		chain = append(chain, Redirect{URL: r.Request.URL.String(), Status: r.StatusCode})
+	}
+	slices.Reverse(chain)
+	return chain
+}
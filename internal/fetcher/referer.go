@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// RefererAuto makes the fetcher present the target site's homepage as the
+// referer, like a visitor who navigated there from the front page
+const RefererAuto = "auto"
+
+// resolveReferer turns the configured referer (a URL, "auto" or empty) into
+// the Referer header value for targetURL
+func resolveReferer(referer, targetURL string) string {
+	referer = strings.TrimSpace(referer)
+	if referer != RefererAuto {
+		return referer
+	}
+
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	home := u.Scheme + "://" + u.Host + "/"
+	if strings.TrimSuffix(targetURL, "/")+"/" == home {
+		// Requesting the homepage itself: there is nothing to come from
+		return ""
+	}
+	return home
+}
+
+// secFetchSite computes the Sec-Fetch-Site value browsers send for a
+// navigation from referer to target: none, same-origin, same-site or cross-site
+func secFetchSite(referer, target string) string {
+	if referer == "" {
+		return "none"
+	}
+
+	from, err := url.Parse(referer)
+	if err != nil || from.Host == "" {
+		return "none"
+	}
+	to, err := url.Parse(target)
+	if err != nil || to.Host == "" {
+		return "cross-site"
+	}
+
+	if strings.EqualFold(from.Scheme, to.Scheme) && strings.EqualFold(from.Host, to.Host) {
+		return "same-origin"
+	}
+	if !strings.EqualFold(from.Scheme, to.Scheme) {
+		return "cross-site"
+	}
+	if registrableDomain(from.Hostname()) == registrableDomain(to.Hostname()) {
+		return "same-site"
+	}
+	return "cross-site"
+}
+
+// registrableDomain returns eTLD+1 for host, or the host itself when it has
+// none (IP addresses, localhost)
+func registrableDomain(host string) string {
+	host = strings.ToLower(host)
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,76 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveReferer(t *testing.T) {
+	tests := []struct {
+		referer, target, want string
+	}{
+		{"", "https://example.com/a", ""},
+		{"https://news.ycombinator.com/", "https://example.com/a", "https://news.ycombinator.com/"},
+		{"auto", "https://example.com/blog/post?id=1", "https://example.com/"},
+		{"auto", "https://example.com/", ""},
+		{"auto", "https://example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := resolveReferer(tt.referer, tt.target); got != tt.want {
+			t.Errorf("resolveReferer(%q, %q) = %q, want %q", tt.referer, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSecFetchSite(t *testing.T) {
+	tests := []struct {
+		referer, target, want string
+	}{
+		{"", "https://example.com/", "none"},
+		{"https://example.com/", "https://example.com/a", "same-origin"},
+		{"https://www.example.com/", "https://blog.example.com/a", "same-site"},
+		{"https://a.co.uk/", "https://b.co.uk/", "cross-site"},
+		{"http://example.com/", "https://example.com/", "cross-site"},
+		{"https://google.com/", "https://example.com/", "cross-site"},
+	}
+	for _, tt := range tests {
+		if got := secFetchSite(tt.referer, tt.target); got != tt.want {
+			t.Errorf("secFetchSite(%q, %q) = %q, want %q", tt.referer, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestFetchStatic_RefererHeaders(t *testing.T) {
+	var referer, site string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		referer = r.Header.Get("Referer")
+		site = r.Header.Get("Sec-Fetch-Site")
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	if _, err := sf.FetchStatic(context.Background(), server.URL+"/post", FetchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if referer != "" || site != "none" {
+		t.Errorf("expected no referer and Sec-Fetch-Site none, got %q / %q", referer, site)
+	}
+
+	if _, err := sf.FetchStatic(context.Background(), server.URL+"/post", FetchOptions{Referer: RefererAuto}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if referer != server.URL+"/" || site != "same-origin" {
+		t.Errorf("expected homepage referer and same-origin, got %q / %q", referer, site)
+	}
+
+	if _, err := sf.FetchStatic(context.Background(), server.URL+"/post", FetchOptions{Referer: "https://www.google.com/"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if site != "cross-site" {
+		t.Errorf("expected cross-site, got %q", site)
+	}
+}
@@ -0,0 +1,49 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultFormContentType is sent with a request body when no content type
+// is given, as for an HTML form
+const DefaultFormContentType = "application/x-www-form-urlencoded"
+
+// requestMethod is the method of opts, GET when unset
+func requestMethod(opts FetchOptions) string {
+	if opts.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(opts.Method)
+}
+
+// IsPlainGet reports whether opts fetch with a bodiless GET, the only kind
+// of request that rendering, caches and API backends can repeat
+func IsPlainGet(opts FetchOptions) bool {
+	return requestMethod(opts) == http.MethodGet && opts.Body == nil
+}
+
+// newRequest builds the request of a static fetch with the method and body
+// of opts. A fresh body reader per call lets retries send the body again.
+func newRequest(ctx context.Context, url string, opts FetchOptions) (*http.Request, error) {
+	var body io.Reader
+	if opts.Body != nil {
+		body = bytes.NewReader(opts.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, requestMethod(opts), url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if opts.Body != nil {
+		contentType := opts.ContentType
+		if contentType == "" {
+			contentType = DefaultFormContentType
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
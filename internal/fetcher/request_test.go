@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchStatic_RequestBody(t *testing.T) {
+	var method, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		fmt.Fprint(w, `<html><head><title>Results</title></head><body>ok</body></html>`)
+	}))
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	opts := FetchOptions{Method: "post", Body: []byte(`{"q":"go"}`), ContentType: "application/json"}
+	result, err := sf.FetchStatic(context.Background(), server.URL+"/search", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodPost || contentType != "application/json" || body != `{"q":"go"}` {
+		t.Errorf("got %s %q with body %q", method, contentType, body)
+	}
+	if result.Title != "Results" {
+		t.Errorf("expected the response to be read, got title %q", result.Title)
+	}
+
+	if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{Body: []byte("q=go")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodGet || contentType != DefaultFormContentType || body != "q=go" {
+		t.Errorf("expected a form encoded GET body, got %s %q with body %q", method, contentType, body)
+	}
+}
+
+func TestIsPlainGet(t *testing.T) {
+	tests := []struct {
+		opts FetchOptions
+		want bool
+	}{
+		{FetchOptions{}, true},
+		{FetchOptions{Method: "get"}, true},
+		{FetchOptions{Method: "POST"}, false},
+		{FetchOptions{Body: []byte{}}, false},
+	}
+	for _, tt := range tests {
+		if got := IsPlainGet(tt.opts); got != tt.want {
+			t.Errorf("IsPlainGet(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
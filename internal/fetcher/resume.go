@@ -0,0 +1,113 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// resumableBody is a response body that, when the connection drops
+// mid-body, asks for the rest with a Range request instead of failing, so a
+// large page is not downloaded again from the start. Each resume uses up one
+// retry. It reads the body as sent, before Content-Encoding is decoded,
+// since ranges count those bytes.
+type resumableBody struct {
+	ctx       context.Context
+	client    *http.Client
+	req       *http.Request // request of the current response, after redirects
+	validator string        // ETag or Last-Modified the rest must match (If-Range)
+	body      io.ReadCloser
+	read      int64 // bytes read so far
+	left      int   // resumes still allowed
+	resumes   int   // resumes made
+	err       error // failure that could not be resumed
+}
+
+// newResumableBody wraps resp.Body when a resume could work: the server
+// takes byte ranges and names the version of the page, so the rest cannot
+// come from a different one. Otherwise the body is left alone and nil is
+// returned.
+func newResumableBody(ctx context.Context, client *http.Client, resp *http.Response, retries int) *resumableBody {
+	if retries <= 0 || resp.StatusCode != http.StatusOK || resp.Request.Method != http.MethodGet {
+		return nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(resp.Header.Get("Accept-Ranges")), "bytes") {
+		return nil
+	}
+	validator := resp.Header.Get("ETag")
+	if strings.HasPrefix(validator, "W/") {
+		validator = "" // If-Range takes strong validators only
+	}
+	if validator == "" {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		return nil
+	}
+	b := &resumableBody{
+		ctx:       ctx,
+		client:    client,
+		req:       resp.Request,
+		validator: validator,
+		body:      resp.Body,
+		left:      retries,
+	}
+	resp.Body = b
+	return b
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+	if b.left == 0 || b.ctx.Err() != nil || b.resume() != nil {
+		b.err = err // the original failure is what went wrong
+		return n, err
+	}
+	return n, nil
+}
+
+// resumed is the number of resumes made; nil-safe for bodies not wrapped
+func (b *resumableBody) resumed() int {
+	if b == nil {
+		return 0
+	}
+	return b.resumes
+}
+
+// resume replaces the broken body with the rest of the page
+func (b *resumableBody) resume() error {
+	b.left--
+	b.body.Close()
+
+	req := b.req.Clone(b.ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.read))
+	req.Header.Set("If-Range", b.validator)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		b.body = http.NoBody
+		return err
+	}
+	// 200 means the page changed or ranges are off after all; anything but
+	// the exact rest would corrupt the page
+	if resp.StatusCode != http.StatusPartialContent ||
+		!strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.read)) {
+		resp.Body.Close()
+		b.body = http.NoBody
+		return fmt.Errorf("resume refused: %s", resp.Status)
+	}
+	b.body = resp.Body
+	b.resumes++
+	return nil
+}
+
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,96 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cutOffServer serves page, dropping the connection halfway through every
+// full download; ranges are served whole when ranges is set
+func cutOffServer(t *testing.T, page []byte, etag string, ranges bool) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		if ranges {
+			w.Header().Set("Accept-Ranges", "bytes")
+		}
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("Range") != "" && ranges {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(page))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.Write(page[:len(page)/2])
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	return server, &requests
+}
+
+func TestFetchStaticResumesCutOffBody(t *testing.T) {
+	page := []byte("<html><head><title>Big</title></head><body><p>" + strings.Repeat("lorem ipsum ", 20000) + "</p></body></html>")
+	server, requests := cutOffServer(t, page, `"v1"`, true)
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	sf.SetJitter(false)
+	opts := FetchOptions{Retry: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, RetryOnNetwork: true}}
+	result, err := sf.FetchStatic(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("FetchStatic: %v", err)
+	}
+	if result.HTML != string(page) {
+		t.Errorf("got %d bytes, want the %d byte page", len(result.HTML), len(page))
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("made %d requests, want the download and one range request", n)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", result.Attempts)
+	}
+}
+
+func TestFetchStaticRestartsWithoutRanges(t *testing.T) {
+	page := []byte("<html><body>" + strings.Repeat("x", 100000) + "</body></html>")
+	server, requests := cutOffServer(t, page, `"v1"`, false)
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	sf.SetJitter(false)
+	opts := FetchOptions{Retry: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, RetryOnNetwork: true}}
+	if _, err := sf.FetchStatic(context.Background(), server.URL, opts); err == nil {
+		t.Fatal("expected every cut-off download to fail")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("made %d requests, want 3 full downloads", n)
+	}
+}
+
+func TestResumableBodyNeedsStrongValidator(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Accept-Ranges": {"bytes"}, "Etag": {`W/"v1"`}},
+		Request:    httptest.NewRequest(http.MethodGet, "http://example.com/", nil),
+		Body:       http.NoBody,
+	}
+	if newResumableBody(context.Background(), http.DefaultClient, resp, 3) != nil {
+		t.Error("resumed with only a weak ETag")
+	}
+	resp.Header.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+	if newResumableBody(context.Background(), http.DefaultClient, resp, 3) == nil {
+		t.Error("did not resume with Last-Modified")
+	}
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// robotsAgent is the crawler name robots directives may address scrpr by,
+// as in <meta name="scrpr"> or "X-Robots-Tag: scrpr: noarchive"
+const robotsAgent = "scrpr"
+
+// Robots holds the robots directives of a page that apply to scrpr, from
+// its robots meta tags and X-Robots-Tag headers
+type Robots struct {
+	NoIndex   bool
+	NoArchive bool
+	NoFollow  bool
+	NoSnippet bool
+}
+
+// Forbids reports whether the page asks not to be kept: noindex or
+// noarchive
+func (r Robots) Forbids() bool {
+	return r.NoIndex || r.NoArchive
+}
+
+// String lists the directives set, e.g. "noindex, nofollow"; "" when none
+func (r Robots) String() string {
+	var set []string
+	for _, d := range []struct {
+		name string
+		on   bool
+	}{{"noindex", r.NoIndex}, {"noarchive", r.NoArchive}, {"nofollow", r.NoFollow}, {"nosnippet", r.NoSnippet}} {
+		if d.on {
+			set = append(set, d.name)
+		}
+	}
+	return strings.Join(set, ", ")
+}
+
+// ParseRobots reads the robots directives of an HTML page and its
+// X-Robots-Tag header values. Directives for robots in general and for
+// scrpr apply; those addressed to other crawlers, such as
+// <meta name="googlebot"> or "X-Robots-Tag: bingbot: noindex", do not.
+func ParseRobots(html string, headers []string) Robots {
+	var r Robots
+	for _, header := range headers {
+		r.parseHeader(header)
+	}
+	lower := strings.ToLower(html)
+	if !strings.Contains(lower, "robots") && !strings.Contains(lower, robotsAgent) {
+		return r
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return r
+	}
+	doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+		switch strings.ToLower(strings.TrimSpace(s.AttrOr("name", ""))) {
+		case "robots", robotsAgent:
+			r.parse(s.AttrOr("content", ""))
+		}
+	})
+	return r
+}
+
+// parseHeader reads one X-Robots-Tag value. A value may address a crawler
+// by a leading "name:", which holds until the next name.
+func (r *Robots) parseHeader(value string) {
+	applies := true
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if name, rest, ok := strings.Cut(part, ":"); ok && !strings.ContainsAny(name, " \t") && !isRobotsDirective(name) {
+			applies = strings.EqualFold(name, robotsAgent)
+			part = strings.TrimSpace(rest)
+		}
+		if applies {
+			r.parse(part)
+		}
+	}
+}
+
+// isRobotsDirective reports whether name is a directive that takes a
+// value after a colon, such as "unavailable_after: 2026-01-01"
+func isRobotsDirective(name string) bool {
+	switch strings.ToLower(name) {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+// parse applies a comma-separated list of directives
+func (r *Robots) parse(content string) {
+	for _, d := range strings.Split(content, ",") {
+		switch strings.ToLower(strings.TrimSpace(d)) {
+		case "noindex":
+			r.NoIndex = true
+		case "noarchive", "nocache":
+			r.NoArchive = true
+		case "nofollow":
+			r.NoFollow = true
+		case "nosnippet":
+			r.NoSnippet = true
+		case "none":
+			r.NoIndex = true
+			r.NoFollow = true
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package fetcher
+
+import "testing"
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		headers []string
+		want    string
+	}{
+		{"none", `<html><head><title>x</title></head></html>`, nil, ""},
+		{"meta", `<meta name="robots" content="noindex, NoFollow">`, nil, "noindex, nofollow"},
+		{"meta for scrpr", `<meta name="SCRPR" content="noarchive">`, nil, "noarchive"},
+		{"meta for another crawler", `<meta name="googlebot" content="noindex">`, nil, ""},
+		{"none directive", `<meta name="robots" content="none">`, nil, "noindex, nofollow"},
+		{"header", "", []string{"noarchive, nosnippet"}, "noarchive, nosnippet"},
+		{"header for another crawler", "", []string{"googlebot: noindex, nofollow"}, ""},
+		{"header switching crawlers", "", []string{"googlebot: noindex, scrpr: noarchive"}, "noarchive"},
+		{"header with dated directive", "", []string{"unavailable_after: 2026-01-01, nocache"}, "noarchive"},
+		{"meta and header", `<meta name="robots" content="nofollow">`, []string{"noindex"}, "noindex, nofollow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRobots(tt.html, tt.headers).String(); got != tt.want {
+				t.Errorf("ParseRobots() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobots_Forbids(t *testing.T) {
+	if (Robots{NoFollow: true, NoSnippet: true}).Forbids() {
+		t.Error("expected nofollow and nosnippet to allow keeping the page")
+	}
+	if !(Robots{NoArchive: true}).Forbids() || !(Robots{NoIndex: true}).Forbids() {
+		t.Error("expected noindex and noarchive to forbid keeping the page")
+	}
+}
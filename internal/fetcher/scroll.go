@@ -0,0 +1,99 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// scrollSettle is the pause after a scroll for lazy content to load
+const scrollSettle = 500 * time.Millisecond
+
+// scrollStableRounds is how many scrolls in a row must leave the page's
+// height unchanged for it to count as fully loaded
+const scrollStableRounds = 2
+
+// ScrollConfig scrolls a rendered page to its bottom before it is read, so
+// infinite-scroll lists and lazy comment threads load
+type ScrollConfig struct {
+	Rounds      int           // scrolls to the bottom (0 = until stable only)
+	UntilStable bool          // stop once the page stops growing; with Rounds, at most that many
+	MaxHeight   int           // stop once the page is this tall, in CSS pixels (0 = unlimited)
+	Timeout     time.Duration // bound of the scrolling, on top of the render timeout (0 = none)
+}
+
+// ScrollStats tells how far a page was scrolled
+type ScrollStats struct {
+	Rounds int // scrolls waited on for content to load
+	Height int // page height after the last, in CSS pixels
+}
+
+// scrollScript jumps to the bottom of the page and returns its height
+const scrollScript = `(() => {
+	const el = document.scrollingElement || document.documentElement;
+	window.scrollTo(0, el.scrollHeight);
+	return el.scrollHeight;
+})()`
+
+// scrollPage scrolls the page as opts say, recording what it did in stats
+func scrollPage(opts *ScrollConfig, stats *ScrollStats) chromedp.Action {
+	return chromedp.ActionFunc(func(parent context.Context) error {
+		ctx := parent
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(parent, opts.Timeout)
+			defer cancel()
+		}
+		err := scrollLoop(ctx, opts, stats, func(ctx context.Context) (int, error) {
+			var height int
+			err := chromedp.Evaluate(scrollScript, &height).Do(ctx)
+			return height, err
+		}, func(ctx context.Context) error {
+			return chromedp.Sleep(scrollSettle).Do(ctx)
+		})
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		// Running out of the step's own time keeps what has loaded
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to scroll: %w", err)
+		}
+		return nil
+	})
+}
+
+// scrollLoop scrolls with scroll, which returns the page height, and waits
+// with settle after each, until the rounds are made, the page stops growing
+// or reaches the max height
+func scrollLoop(ctx context.Context, opts *ScrollConfig, stats *ScrollStats, scroll func(context.Context) (int, error), settle func(context.Context) error) error {
+	if opts.Rounds == 0 && !opts.UntilStable {
+		return nil
+	}
+	unchanged := 0
+	for opts.Rounds == 0 || stats.Rounds < opts.Rounds {
+		height, err := scroll(ctx)
+		if err != nil {
+			return err
+		}
+		// The height a scroll sees includes what the one before loaded
+		if stats.Rounds > 0 && height == stats.Height {
+			unchanged++
+		} else {
+			unchanged = 0
+		}
+		stats.Height = height
+		switch {
+		case opts.UntilStable && unchanged >= scrollStableRounds:
+			return nil
+		case opts.MaxHeight > 0 && height >= opts.MaxHeight:
+			return nil
+		}
+		stats.Rounds++
+		if err := settle(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakePage grows by the given heights, one per scroll, then stays put
+func fakePage(heights ...int) func(context.Context) (int, error) {
+	i := 0
+	return func(context.Context) (int, error) {
+		h := heights[min(i, len(heights)-1)]
+		i++
+		return h, nil
+	}
+}
+
+func noSettle(context.Context) error { return nil }
+
+func TestScrollLoop(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ScrollConfig
+		heights []int
+		rounds  int
+		height  int
+	}{
+		{"off", ScrollConfig{}, []int{1000}, 0, 0},
+		{"fixed rounds", ScrollConfig{Rounds: 3}, []int{1000, 2000, 3000, 4000, 5000}, 3, 3000},
+		{"until stable", ScrollConfig{UntilStable: true}, []int{1000, 2000, 3000}, 4, 3000},
+		{"stable page", ScrollConfig{UntilStable: true}, []int{1000}, 2, 1000},
+		{"rounds cap until stable", ScrollConfig{Rounds: 2, UntilStable: true}, []int{1000, 2000, 3000}, 2, 2000},
+		{"max height", ScrollConfig{UntilStable: true, MaxHeight: 2500}, []int{1000, 2000, 3000, 4000}, 2, 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats ScrollStats
+			if err := scrollLoop(context.Background(), &tt.opts, &stats, fakePage(tt.heights...), noSettle); err != nil {
+				t.Fatal(err)
+			}
+			if stats.Rounds != tt.rounds || stats.Height != tt.height {
+				t.Errorf("got %d rounds, height %d; want %d, %d", stats.Rounds, stats.Height, tt.rounds, tt.height)
+			}
+		})
+	}
+}
+
+func TestScrollLoopError(t *testing.T) {
+	failed := errors.New("target closed")
+	var stats ScrollStats
+	err := scrollLoop(context.Background(), &ScrollConfig{Rounds: 5}, &stats, func(context.Context) (int, error) {
+		return 0, failed
+	}, noSettle)
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v, want the scroll's error", err)
+	}
+}
@@ -1,9 +1,10 @@
 package fetcher
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -13,26 +14,85 @@ import (
 type SimpleFetcher struct {
 	client          *http.Client
 	userAgentSelect *UserAgentSelector
+	followRedirects bool
+	maxRedirects    int
+	noJitter        bool
+	timeouts        Timeouts
+	impersonate     bool
 }
 
 func NewSimpleFetcher() *SimpleFetcher {
+	timeouts := DefaultTimeouts()
 	return &SimpleFetcher{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:       timeouts.Total,
+			Transport:     sharedTransport(timeouts),
+			CheckRedirect: redirectPolicy(true, DefaultMaxRedirects),
 		},
 		userAgentSelect: NewUserAgentSelector(),
+		followRedirects: true,
+		maxRedirects:    DefaultMaxRedirects,
 	}
 }
 
+// SetTimeouts configures the connect, response header and total timeouts
+func (sf *SimpleFetcher) SetTimeouts(t Timeouts) {
+	sf.timeouts = t
+	sf.client.Transport = sf.transport()
+	if t.Total > 0 {
+		sf.client.Timeout = t.Total
+	}
+}
+
+// SetImpersonate makes HTTPS requests present the TLS ClientHello of the
+// browser in their User-Agent instead of Go's, for sites that block Go's
+// TLS fingerprint
+func (sf *SimpleFetcher) SetImpersonate(enabled bool) {
+	sf.impersonate = enabled
+	sf.client.Transport = sf.transport()
+}
+
+func (sf *SimpleFetcher) transport() http.RoundTripper {
+	if sf.impersonate {
+		return newImpersonatingTransport(sf.timeouts)
+	}
+	return sharedTransport(sf.timeouts)
+}
+
+// SetUserAgentPools replaces the built-in user agent pools
+func (sf *SimpleFetcher) SetUserAgentPools(pools map[UserAgentType][]string) {
+	sf.userAgentSelect.SetPools(pools)
+}
+
+// SetUserAgentSelector shares a selector between fetchers, so per-host
+// sticky agents hold across every fetcher in a run
+func (sf *SimpleFetcher) SetUserAgentSelector(uas *UserAgentSelector) {
+	sf.userAgentSelect = uas
+}
+
+// SetCookieJar keeps cookies set by responses and sends them with later
+// requests to the same site (nil = no jar)
+func (sf *SimpleFetcher) SetCookieJar(jar http.CookieJar) {
+	sf.client.Jar = jar
+}
+
+// SetJitter enables the random ±25% spread of retry backoff (the default).
+// Without it retries wait exactly the exponential delay.
+func (sf *SimpleFetcher) SetJitter(enabled bool) {
+	sf.noJitter = !enabled
+}
+
 // SetFollowRedirects configures whether the fetcher follows HTTP redirects
 func (sf *SimpleFetcher) SetFollowRedirects(follow bool) {
-	if !follow {
-		sf.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
-	} else {
-		sf.client.CheckRedirect = nil
-	}
+	sf.followRedirects = follow
+	sf.client.CheckRedirect = redirectPolicy(sf.followRedirects, sf.maxRedirects)
+}
+
+// SetMaxRedirects sets how many redirects are followed before the fetch
+// fails (0 = DefaultMaxRedirects)
+func (sf *SimpleFetcher) SetMaxRedirects(max int) {
+	sf.maxRedirects = max
+	sf.client.CheckRedirect = redirectPolicy(sf.followRedirects, sf.maxRedirects)
 }
 
 func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
@@ -41,16 +101,14 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 		retryConfig = DefaultRetryConfig()
 	}
 
-	maxSize := opts.MaxResponseSize
-	if maxSize == 0 {
-		maxSize = defaultMaxResponseSize
-	}
+	maxSize := responseLimit(opts)
 
 	var lastErr error
 
+	var retryAfter time.Duration // asked for by the last response
 	for attempt := 0; attempt <= retryConfig.MaxRetries; attempt++ {
 		if attempt > 0 {
-			delay := sf.backoffDelay(attempt, retryConfig.BaseDelay, retryConfig.MaxDelay)
+			delay := max(sf.backoffDelay(attempt, retryConfig.BaseDelay, retryConfig.MaxDelay), retryAfter)
 			select {
 			case <-ctx.Done():
 				return nil, fmt.Errorf("fetch cancelled: %w", ctx.Err())
@@ -66,7 +124,8 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 		resp, err := sf.client.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to fetch URL: %w", err)
-			if retryConfig.RetryOnNetwork && attempt < retryConfig.MaxRetries {
+			var tooMany *TooManyRedirectsError
+			if retryConfig.RetryOnNetwork && attempt < retryConfig.MaxRetries && !errors.As(err, &tooMany) {
 				continue
 			}
 			return nil, lastErr
@@ -75,8 +134,11 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 		// Handle retryable status codes
 		if sf.shouldRetryStatus(resp.StatusCode, retryConfig.RetryStatuses) {
 			resp.Body.Close()
-			lastErr = fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
-			if attempt < retryConfig.MaxRetries {
+			statusErr := newStatusError(resp)
+			lastErr = statusErr
+			// A pause longer than any retry delay is left to the caller
+			retryAfter = statusErr.RetryAfter
+			if attempt < retryConfig.MaxRetries && retryAfter <= cmp.Or(retryConfig.MaxDelay, 30*time.Second) {
 				continue
 			}
 			return nil, lastErr
@@ -95,38 +157,44 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 
 		if resp.StatusCode >= 400 {
 			resp.Body.Close()
-			return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+			return nil, newStatusError(resp)
 		}
 
-		// Check Content-Length before reading body
-		if resp.ContentLength > maxSize && maxSize > 0 {
+		// A body cut off mid-download continues where it stopped when the
+		// server allows, using up a retry per resume
+		var resumable *resumableBody
+		if retryConfig.RetryOnNetwork {
+			resumable = newResumableBody(ctx, sf.client, resp, retryConfig.MaxRetries-attempt)
+		}
+		if err := decodeBody(resp); err != nil {
+			return nil, err
+		}
+		contentType, err := checkContentType(resp)
+		if err != nil {
 			resp.Body.Close()
-			return nil, fmt.Errorf("response too large: %d bytes exceeds limit of %d bytes", resp.ContentLength, maxSize)
+			return nil, err
 		}
-
-		// Read body with size limit
+		// Images are reported by type only; their bytes are of no use
 		var body []byte
 		var readErr error
-		if maxSize > 0 {
-			body, readErr = io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
-		} else {
-			body, readErr = io.ReadAll(resp.Body)
+		if !IsImageType(contentType) {
+			body, readErr = readBody(resp, maxSize)
 		}
 		resp.Body.Close()
+		attempt += resumable.resumed()
 		if readErr != nil {
-			lastErr = fmt.Errorf("failed to read response body: %w", readErr)
+			var tooLarge *ResponseTooLargeError
+			if errors.As(readErr, &tooLarge) {
+				return nil, readErr
+			}
+			lastErr = readErr
 			if retryConfig.RetryOnNetwork && attempt < retryConfig.MaxRetries {
 				continue
 			}
 			return nil, lastErr
 		}
 
-		if maxSize > 0 && int64(len(body)) > maxSize {
-			return nil, fmt.Errorf("response too large: exceeds limit of %d bytes", maxSize)
-		}
-
-		contentType := resp.Header.Get("Content-Type")
-		html := string(body)
+		html := utf8Body(body, contentType)
 
 		return &FetchResult{
 			HTML:        html,
@@ -135,6 +203,14 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 			UsedJS:      false,
 			Metadata:    sf.extractMetadata(html),
 			ContentType: contentType,
+			FinalURL:    resp.Request.URL.String(),
+			Redirects:   redirectChain(resp),
+			ConsentWall: DetectConsentWall(resp.Request.URL.String(), html),
+			RobotsTag:   resp.Header.Values("X-Robots-Tag"),
+			Status:      resp.StatusCode,
+			UserAgent:   req.Header.Get("User-Agent"),
+			Proxy:       proxyFor(req),
+			Attempts:    attempt + 1,
 		}, nil
 	}
 
@@ -145,44 +221,40 @@ func (sf *SimpleFetcher) FetchStatic(ctx context.Context, url string, opts Fetch
 }
 
 func (sf *SimpleFetcher) buildRequest(ctx context.Context, url string, opts FetchOptions, attempt int) (*http.Request, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := newRequest(ctx, url, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
 	// Determine user agent
 	var userAgent string
 	if opts.UserAgent != "" {
 		userAgent = opts.UserAgent
+	} else if opts.Device != nil {
+		userAgent = opts.Device.UserAgent
 	} else if attempt > 0 && opts.Retry.MaxRetries > 0 {
 		// On retry, try a different random UA or honest UA for Cloudflare
-		userAgent = sf.userAgentSelect.GetUserAgent(opts.BrowserAgent)
+		userAgent = sf.userAgentSelect.RepinHost(opts.BrowserAgent, req.URL.Hostname())
 	} else {
-		userAgent = sf.userAgentSelect.GetUserAgent(opts.BrowserAgent)
+		userAgent = sf.userAgentSelect.UserAgentForHost(opts.BrowserAgent, req.URL.Hostname())
 	}
-	req.Header.Set("User-Agent", userAgent)
+	setBrowserHeaders(req.Header, userAgent, url, resolveReferer(opts.Referer, url))
 
 	// Format-aware Accept header
-	req.Header.Set("Accept", sf.acceptHeader(opts.Format))
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	// Don't set Accept-Encoding - let Go's http client handle compression automatically
+	req.Header.Set("Accept", sf.acceptHeader(opts.Format, browserFamily(userAgent)))
+	req.Header.Set("Accept-Language", acceptLanguage(opts.AcceptLanguage))
+	setAcceptEncoding(req.Header, opts.AcceptEncoding)
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("Sec-Fetch-Dest", "document")
-	req.Header.Set("Sec-Fetch-Mode", "navigate")
-	req.Header.Set("Sec-Fetch-Site", "none")
-	req.Header.Set("Sec-Fetch-User", "?1")
-	req.Header.Set("Cache-Control", "max-age=0")
-
-	// Add cookies
-	for _, cookie := range opts.Cookies {
+
+	// Add cookies that apply to this URL
+	for _, cookie := range applicableCookies(opts.Cookies, req.URL, time.Now()) {
 		req.AddCookie(cookie)
 	}
 
 	return req, nil
 }
 
-func (sf *SimpleFetcher) acceptHeader(format string) string {
+func (sf *SimpleFetcher) acceptHeader(format string, family UserAgentType) string {
 	switch format {
 	case "markdown":
 		return "text/markdown;q=1.0, text/x-markdown;q=0.9, text/plain;q=0.8, text/html;q=0.7, */*;q=0.1"
@@ -191,7 +263,7 @@ func (sf *SimpleFetcher) acceptHeader(format string) string {
 	case "html":
 		return "text/html;q=1.0, application/xhtml+xml;q=0.9, text/plain;q=0.8, text/markdown;q=0.7, */*;q=0.1"
 	default:
-		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"
+		return browserAccept(family)
 	}
 }
 
@@ -213,6 +285,9 @@ func (sf *SimpleFetcher) backoffDelay(attempt int, baseDelay, maxDelay time.Dura
 	}
 	// Exponential backoff: baseDelay * 2^attempt
 	delay := baseDelay * time.Duration(1<<attempt)
+	if sf.noJitter {
+		return min(delay, maxDelay)
+	}
 	// Add jitter: ±25%
 	jitter := time.Duration(float64(delay) * (0.75 + 0.5*rand.Float64()))
 	if jitter > maxDelay {
@@ -222,7 +297,7 @@ func (sf *SimpleFetcher) backoffDelay(attempt int, baseDelay, maxDelay time.Dura
 }
 
 func (sf *SimpleFetcher) extractTitle(html string) string {
-	lowerHTML := strings.ToLower(html)
+	lowerHTML := lowerASCII(html)
 	titleStart := strings.Index(lowerHTML, "<title")
 	if titleStart == -1 {
 		return ""
@@ -234,7 +309,7 @@ func (sf *SimpleFetcher) extractTitle(html string) string {
 	}
 	start += titleStart + 1
 
-	end := strings.Index(strings.ToLower(html[start:]), "</title>")
+	end := strings.Index(lowerASCII(html[start:]), "</title>")
 	if end == -1 {
 		return ""
 	}
@@ -283,7 +358,7 @@ func (sf *SimpleFetcher) findMetaContent(html, property string) string {
 		fmt.Sprintf(`property='%s'`, property),
 	}
 
-	lowerHTML := strings.ToLower(html)
+	lowerHTML := lowerASCII(html)
 
 	for _, pattern := range patterns {
 		if idx := strings.Index(lowerHTML, pattern); idx != -1 {
@@ -2,10 +2,13 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -37,7 +40,7 @@ func TestAcceptHeader(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.format, func(t *testing.T) {
-			got := sf.acceptHeader(tt.format)
+			got := sf.acceptHeader(tt.format, UserAgentChrome)
 			if got != tt.expected {
 				t.Errorf("acceptHeader(%q) = %q, want %q", tt.format, got, tt.expected)
 			}
@@ -74,6 +77,32 @@ func TestFetchStatic_Success(t *testing.T) {
 	}
 }
 
+func TestFetchStatic_SharedConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>Hello</p></body></html>`)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	// Separate fetchers with the same timeouts draw from one pool
+	for i := 0; i < 3; i++ {
+		sf := NewSimpleFetcher()
+		sf.SetTimeouts(Timeouts{Connect: 7 * time.Second})
+		if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected one pooled connection, got %d", n)
+	}
+}
+
 func TestFetchStatic_MarkdownAcceptHeader(t *testing.T) {
 	var acceptHeader string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -260,6 +289,19 @@ func TestBackoffDelay(t *testing.T) {
 	}
 }
 
+func TestBackoffDelayWithoutJitter(t *testing.T) {
+	sf := NewSimpleFetcher()
+	sf.SetJitter(false)
+	for i := 0; i < 5; i++ {
+		if d := sf.backoffDelay(1, 1*time.Second, 10*time.Second); d != 2*time.Second {
+			t.Fatalf("expected exactly 2s without jitter, got %v", d)
+		}
+	}
+	if d := sf.backoffDelay(10, 1*time.Second, 5*time.Second); d != 5*time.Second {
+		t.Errorf("expected the 5s cap, got %v", d)
+	}
+}
+
 func TestShouldRetryStatus(t *testing.T) {
 	sf := NewSimpleFetcher()
 	statuses := []int{429, 502, 503}
@@ -274,3 +316,181 @@ func TestShouldRetryStatus(t *testing.T) {
 		t.Error("expected 404 to not be retryable")
 	}
 }
+
+func TestFetchStatic_ResponseHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, `<html><body>too slow</body></html>`)
+	}))
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	sf.SetTimeouts(Timeouts{ResponseHeader: 50 * time.Millisecond})
+
+	_, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{
+		Format: "text",
+		Retry:  RetryConfig{MaxRetries: 1, BaseDelay: 10 * time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("expected response header timeout error")
+	}
+}
+
+func TestFetchStatic_TLSHandshakeTimeout(t *testing.T) {
+	// Accepts connections but never answers the ClientHello
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	sf := NewSimpleFetcher()
+	sf.SetTimeouts(Timeouts{TLSHandshake: 50 * time.Millisecond, Total: 5 * time.Second})
+
+	start := time.Now()
+	_, err = sf.FetchStatic(context.Background(), "https://"+ln.Addr().String()+"/", FetchOptions{
+		Retry: RetryConfig{MaxRetries: 1, BaseDelay: 10 * time.Millisecond},
+	})
+	if err == nil || !strings.Contains(err.Error(), "handshake timeout") {
+		t.Fatalf("expected TLS handshake timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %v, want the handshake timeout rather than the total deadline", elapsed)
+	}
+}
+
+func TestFetchStatic_AcceptLanguage(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Accept-Language")
+		fmt.Fprint(w, `<html><body>hallo</body></html>`)
+	}))
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != DefaultAcceptLanguage {
+		t.Errorf("expected default Accept-Language, got %q", got)
+	}
+
+	if _, err := sf.FetchStatic(context.Background(), server.URL, FetchOptions{AcceptLanguage: "de-DE,de;q=0.9"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "de-DE,de;q=0.9" {
+		t.Errorf("expected configured Accept-Language, got %q", got)
+	}
+}
+
+func TestLocaleFromAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en-US,en;q=0.9":        "en-US",
+		"de;q=0.5, fr-FR;q=0.8": "fr-FR",
+		"*;q=1, ja-JP":          "ja-JP",
+		"":                      "",
+	}
+	for header, want := range tests {
+		if got := LocaleFromAcceptLanguage(header); got != want {
+			t.Errorf("LocaleFromAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestFetchStatic_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, err := NewSimpleFetcher().FetchStatic(context.Background(), server.URL, FetchOptions{
+		Format: "text",
+		Retry:  RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second, RetryStatuses: []int{429}},
+	})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a StatusError, got %v", err)
+	}
+	if statusErr.Code != 429 || statusErr.RetryAfter != 10*time.Minute {
+		t.Errorf("got %+v, want 429 with a 10m Retry-After", statusErr)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("expected a pause past the retry budget not to be waited out")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-5":                            0,
+		"Fri, 02 Jan 2026 15:05:00 GMT": 5 * time.Minute,
+		"Fri, 02 Jan 2026 14:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestFetchStatic_RedirectChain(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, server.URL+"/final", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			fmt.Fprint(w, "<html><body>final</body></html>")
+		}
+	}))
+	defer server.Close()
+
+	sf := NewSimpleFetcher()
+	result, err := sf.FetchStatic(context.Background(), server.URL+"/old", FetchOptions{Format: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FinalURL != server.URL+"/final" {
+		t.Errorf("FinalURL = %q", result.FinalURL)
+	}
+	want := []Redirect{{server.URL + "/old", 301}, {server.URL + "/moved", 302}}
+	if fmt.Sprint(result.Redirects) != fmt.Sprint(want) {
+		t.Errorf("Redirects = %v, want %v", result.Redirects, want)
+	}
+
+	sf.SetMaxRedirects(1)
+	if _, err := sf.FetchStatic(context.Background(), server.URL+"/old", FetchOptions{Format: "text"}); err == nil || !strings.Contains(err.Error(), "stopped after 1 redirects") {
+		t.Errorf("expected the redirect limit to fail the fetch, got %v", err)
+	}
+	sf.SetMaxRedirects(2)
+	if _, err := sf.FetchStatic(context.Background(), server.URL+"/old", FetchOptions{Format: "text"}); err != nil {
+		t.Errorf("expected two redirects to be within the limit, got %v", err)
+	}
+
+	sf.SetFollowRedirects(false)
+	result, err = sf.FetchStatic(context.Background(), server.URL+"/old", FetchOptions{Format: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FinalURL != server.URL+"/old" || len(result.Redirects) != 0 {
+		t.Errorf("expected no redirect to be followed, got %q %v", result.FinalURL, result.Redirects)
+	}
+}
@@ -0,0 +1,44 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError reports an HTTP error response
+type StatusError struct {
+	Code       int
+	Status     string        // e.g. "429 Too Many Requests"
+	RetryAfter time.Duration // pause the server asked for on 429 and 503 (0 = none)
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP error: %d %s", e.Code, e.Status)
+}
+
+func newStatusError(resp *http.Response) *StatusError {
+	err := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header, either delay seconds or an
+// HTTP date; anything else, or a date in the past, is 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// stealthScript removes what sets headless Chrome apart from a desktop one
+// in the checks sites run before serving content: the webdriver flag, an
+// empty plugin list, missing window.chrome, a notification permission
+// that contradicts Notification.permission and a software WebGL renderer.
+// %s is the navigator.languages array.
+const stealthScript = `(() => {
+  const define = (obj, prop, value) =>
+    Object.defineProperty(obj, prop, { get: () => value, configurable: true });
+
+  define(Navigator.prototype, "webdriver", false);
+  define(Navigator.prototype, "languages", Object.freeze(%s));
+
+  if (navigator.plugins.length === 0) {
+    const pdf = { type: "application/pdf", suffixes: "pdf", description: "Portable Document Format" };
+    const plugins = ["PDF Viewer", "Chrome PDF Viewer", "Chromium PDF Viewer", "Microsoft Edge PDF Viewer", "WebKit built-in PDF"]
+      .map((name) => Object.setPrototypeOf(
+        { name, filename: "internal-pdf-viewer", description: "Portable Document Format", length: 1, 0: pdf },
+        Plugin.prototype));
+    const list = Object.assign({
+      length: plugins.length,
+      item: (i) => plugins[i] ?? null,
+      namedItem: (name) => plugins.find((p) => p.name === name) ?? null,
+      refresh: () => {},
+    }, plugins);
+    define(Navigator.prototype, "plugins", Object.setPrototypeOf(list, PluginArray.prototype));
+  }
+
+  if (!window.chrome) {
+    window.chrome = { runtime: {}, app: { isInstalled: false }, csi: () => ({}), loadTimes: () => ({}) };
+  }
+
+  if (window.Permissions && window.Notification) {
+    const query = Permissions.prototype.query;
+    Permissions.prototype.query = function (desc) {
+      if (desc && desc.name === "notifications") {
+        const state = Notification.permission === "default" ? "prompt" : Notification.permission;
+        return Promise.resolve({ state, onchange: null });
+      }
+      return query.call(this, desc);
+    };
+  }
+
+  for (const gl of [window.WebGLRenderingContext, window.WebGL2RenderingContext]) {
+    if (!gl) continue;
+    const getParameter = gl.prototype.getParameter;
+    gl.prototype.getParameter = function (param) {
+      if (param === 37445) return "Intel Inc."; // UNMASKED_VENDOR_WEBGL
+      if (param === 37446) return "Intel Iris OpenGL Engine"; // UNMASKED_RENDERER_WEBGL
+      return getParameter.call(this, param);
+    };
+  }
+})();`
+
+// applyStealth installs stealthScript for every document of the tab and,
+// unless a device or user agent is emulated, drops "Headless" from Chrome's
+// user agent. It must run before navigate.
+func applyStealth(opts FetchOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		header := acceptLanguage(opts.AcceptLanguage)
+		languages, _ := json.Marshal(acceptLanguageTags(header))
+		if _, err := page.AddScriptToEvaluateOnNewDocument(fmt.Sprintf(stealthScript, languages)).Do(ctx); err != nil {
+			return fmt.Errorf("failed to add stealth script: %w", err)
+		}
+		if opts.Device != nil {
+			return nil
+		}
+		userAgent := opts.UserAgent
+		if userAgent == "" {
+			_, _, _, chromeAgent, _, err := browser.GetVersion().Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to read Chrome's user agent: %w", err)
+			}
+			userAgent = strings.Replace(chromeAgent, "HeadlessChrome/", "Chrome/", 1)
+		}
+		if err := emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(header).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set user agent: %w", err)
+		}
+		return nil
+	})
+}
+
+// acceptLanguageTags returns the language tags of an Accept-Language header
+// by falling priority, as navigator.languages lists them
+func acceptLanguageTags(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.TrimSpace(fields[0])
+		if name == "" || name == "*" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		tags = append(tags, tag{name, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
@@ -0,0 +1,32 @@
+package fetcher
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcceptLanguageTags(t *testing.T) {
+	tests := map[string][]string{
+		"en-US,en;q=0.9":                     {"en-US", "en"},
+		"en;q=0.5, de-DE, de;q=0.8, *;q=0.1": {"de-DE", "de", "en"},
+		"fr, fr":                             {"fr"},
+		"":                                   {},
+	}
+	for header, want := range tests {
+		if got := acceptLanguageTags(header); !reflect.DeepEqual(got, want) {
+			t.Errorf("acceptLanguageTags(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestStealthScript(t *testing.T) {
+	script := fmt.Sprintf(stealthScript, `["de-DE","de"]`)
+	if strings.Contains(script, "%!") {
+		t.Fatal("stealthScript has a stray format verb")
+	}
+	if !strings.Contains(script, `Object.freeze(["de-DE","de"])`) {
+		t.Error("navigator.languages not filled in")
+	}
+}
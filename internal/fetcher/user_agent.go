@@ -1,8 +1,14 @@
 package fetcher
 
 import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,49 +22,188 @@ const (
 	UserAgentEdge    UserAgentType = "edge"
 )
 
-var userAgents = map[UserAgentType][]string{
-	UserAgentChrome: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
-	},
-	UserAgentFirefox: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.1; rv:121.0) Gecko/20100101 Firefox/121.0",
-		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.0; rv:120.0) Gecko/20100101 Firefox/120.0",
-	},
-	UserAgentSafari: {
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_1_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_1_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
-		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
-		"Mozilla/5.0 (iPad; CPU OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
-	},
-	UserAgentEdge: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 Edg/119.0.0.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
-	},
+// UserAgentDatasetFile is the name of the refreshed dataset in the data directory
+const UserAgentDatasetFile = "useragents.json"
+
+//go:embed useragents.json
+var embeddedUserAgents []byte
+
+// userAgents is the built-in pool, shipped with the binary
+var userAgents = mustParseEmbeddedUserAgents()
+
+// UserAgentDataset is the pool format shared by the embedded copy and the
+// file written by `scrpr ua update`. Agents is keyed by browser family.
+type UserAgentDataset struct {
+	Updated string              `json:"updated"`
+	Agents  map[string][]string `json:"agents"`
+}
+
+// ParseUserAgentDataset decodes and validates a dataset
+func ParseUserAgentDataset(data []byte) (*UserAgentDataset, error) {
+	var ds UserAgentDataset
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("invalid user agent dataset: %w", err)
+	}
+
+	total := 0
+	for family, agents := range ds.Agents {
+		for _, ua := range agents {
+			if !strings.HasPrefix(ua, "Mozilla/5.0 ") {
+				return nil, fmt.Errorf("invalid user agent in %s pool: %q", family, ua)
+			}
+		}
+		total += len(agents)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("user agent dataset contains no agents")
+	}
+	return &ds, nil
+}
+
+// LoadUserAgentDataset reads a dataset file
+func LoadUserAgentDataset(path string) (*UserAgentDataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUserAgentDataset(data)
+}
+
+// UserAgentPools returns the dataset at path, or the built-in pool when the
+// file does not exist, with custom pools layered on top. A custom pool named
+// after a browser family replaces it; other names become selectable via
+// --browser-agent. An unreadable dataset falls back to the built-in pool and
+// is reported as an error.
+func UserAgentPools(path string, custom map[string][]string) (map[UserAgentType][]string, error) {
+	pools := make(map[UserAgentType][]string, len(userAgents)+len(custom))
+	for family, agents := range userAgents {
+		pools[family] = agents
+	}
+
+	var loadErr error
+	if path != "" {
+		ds, err := LoadUserAgentDataset(path)
+		switch {
+		case err == nil:
+			pools = ds.pools()
+		case !errors.Is(err, os.ErrNotExist):
+			loadErr = fmt.Errorf("using built-in user agents: %w", err)
+		}
+	}
+
+	for name, agents := range custom {
+		if len(agents) > 0 {
+			pools[UserAgentType(strings.ToLower(name))] = agents
+		}
+	}
+	return pools, loadErr
+}
+
+func (ds *UserAgentDataset) pools() map[UserAgentType][]string {
+	pools := make(map[UserAgentType][]string, len(ds.Agents))
+	for family, agents := range ds.Agents {
+		if len(agents) > 0 {
+			pools[UserAgentType(strings.ToLower(family))] = agents
+		}
+	}
+	return pools
 }
 
+func mustParseEmbeddedUserAgents() map[UserAgentType][]string {
+	ds, err := ParseUserAgentDataset(embeddedUserAgents)
+	if err != nil {
+		panic(err)
+	}
+	return ds.pools()
+}
+
+// UserAgentSelector picks user agents from the pools. It is safe for
+// concurrent use; a run shares one so picks come from a single source.
 type UserAgentSelector struct {
-	rng *rand.Rand
+	// mu guards every field, rng included: rand.Rand is not safe for
+	// concurrent use
+	mu    sync.Mutex
+	rng   *rand.Rand
+	pools map[UserAgentType][]string
+
+	// sticky pins the first agent picked for a host so every request to it
+	// presents the same browser
+	sticky bool
+	pinned map[string]string
 }
 
 func NewUserAgentSelector() *UserAgentSelector {
+	return NewSeededUserAgentSelector(time.Now().UnixNano())
+}
+
+// NewSeededUserAgentSelector returns a selector whose picks repeat for the
+// same seed and the same sequence of calls, for reproducible runs and tests
+func NewSeededUserAgentSelector(seed int64) *UserAgentSelector {
 	return &UserAgentSelector{
-		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:   rand.New(rand.NewSource(seed)),
+		pools: userAgents,
+	}
+}
+
+// SetSticky enables per-host user agent pinning for the selector's lifetime
+func (uas *UserAgentSelector) SetSticky(sticky bool) {
+	uas.mu.Lock()
+	defer uas.mu.Unlock()
+	uas.sticky = sticky
+	if sticky && uas.pinned == nil {
+		uas.pinned = make(map[string]string)
+	}
+}
+
+// UserAgentForHost returns the agent pinned to host, picking and pinning one
+// on first use. Without sticky selection it behaves like GetUserAgent.
+func (uas *UserAgentSelector) UserAgentForHost(uaType, host string) string {
+	uas.mu.Lock()
+	defer uas.mu.Unlock()
+	if !uas.sticky || host == "" {
+		return uas.pick(uaType)
+	}
+
+	key := strings.ToLower(host) + "|" + strings.ToLower(strings.TrimSpace(uaType))
+	if ua, ok := uas.pinned[key]; ok {
+		return ua
+	}
+	ua := uas.pick(uaType)
+	uas.pinned[key] = ua
+	return ua
+}
+
+// RepinHost discards the agent pinned to host and pins a fresh one, for
+// retries after the previous agent was challenged
+func (uas *UserAgentSelector) RepinHost(uaType, host string) string {
+	uas.mu.Lock()
+	if uas.sticky {
+		delete(uas.pinned, strings.ToLower(host)+"|"+strings.ToLower(strings.TrimSpace(uaType)))
+	}
+	uas.mu.Unlock()
+	return uas.UserAgentForHost(uaType, host)
+}
+
+// SetPools replaces the built-in pools, e.g. with the result of UserAgentPools
+func (uas *UserAgentSelector) SetPools(pools map[UserAgentType][]string) {
+	uas.mu.Lock()
+	defer uas.mu.Unlock()
+	if len(pools) > 0 {
+		uas.pools = pools
 	}
 }
 
 // GetUserAgent returns a user agent string based on the specified type
 // If uaType is "auto" or empty, it randomly selects from all available user agents
-// If a specific browser type is specified, it randomly selects from that browser's user agents
+// If a specific browser type or custom pool is specified, it randomly selects from that pool
 func (uas *UserAgentSelector) GetUserAgent(uaType string) string {
+	uas.mu.Lock()
+	defer uas.mu.Unlock()
+	return uas.pick(uaType)
+}
+
+// pick is GetUserAgent for callers holding mu
+func (uas *UserAgentSelector) pick(uaType string) string {
 	// Normalize the input
 	uaType = strings.ToLower(strings.TrimSpace(uaType))
 
@@ -73,21 +218,25 @@ func (uas *UserAgentSelector) GetUserAgent(uaType string) string {
 	case UserAgentChrome, UserAgentFirefox, UserAgentSafari, UserAgentEdge:
 		return uas.getRandomFromType(UserAgentType(uaType))
 	default:
+		if _, ok := uas.pools[UserAgentType(uaType)]; ok {
+			return uas.getRandomFromType(UserAgentType(uaType))
+		}
 		// If it's a custom string, return it as-is
 		return uaType
 	}
 }
 
-// getRandomFromAll selects a random user agent from all available types
+// getRandomFromAll selects a random user agent from all browser families.
+// Custom pools are only used when asked for by name.
 func (uas *UserAgentSelector) getRandomFromAll() string {
 	allUAs := []string{}
-	for _, uas := range userAgents {
-		allUAs = append(allUAs, uas...)
+	for _, family := range []UserAgentType{UserAgentChrome, UserAgentFirefox, UserAgentSafari, UserAgentEdge} {
+		allUAs = append(allUAs, uas.pools[family]...)
 	}
 
 	if len(allUAs) == 0 {
 		// Fallback to a default Chrome user agent
-		return "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+		return "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36"
 	}
 
 	return allUAs[uas.rng.Intn(len(allUAs))]
@@ -95,7 +244,7 @@ func (uas *UserAgentSelector) getRandomFromAll() string {
 
 // getRandomFromType selects a random user agent from a specific browser type
 func (uas *UserAgentSelector) getRandomFromType(uaType UserAgentType) string {
-	agents, ok := userAgents[uaType]
+	agents, ok := uas.pools[uaType]
 	if !ok || len(agents) == 0 {
 		// Fallback to auto if type not found
 		return uas.getRandomFromAll()
@@ -0,0 +1,160 @@
+package fetcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEmbeddedUserAgents(t *testing.T) {
+	for _, family := range []UserAgentType{UserAgentChrome, UserAgentFirefox, UserAgentSafari, UserAgentEdge} {
+		agents := userAgents[family]
+		if len(agents) == 0 {
+			t.Fatalf("no built-in %s agents", family)
+		}
+		for _, ua := range agents {
+			if browserFamily(ua) != family {
+				t.Errorf("%s pool contains %q, detected as %q", family, ua, browserFamily(ua))
+			}
+		}
+	}
+}
+
+func TestParseUserAgentDataset(t *testing.T) {
+	if _, err := ParseUserAgentDataset([]byte(`{"agents":{}}`)); err == nil {
+		t.Error("expected error for empty dataset")
+	}
+	if _, err := ParseUserAgentDataset([]byte(`{"agents":{"chrome":["<html>"]}}`)); err == nil {
+		t.Error("expected error for non-UA entry")
+	}
+	if _, err := ParseUserAgentDataset([]byte(`not json`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+	ds, err := ParseUserAgentDataset([]byte(`{"updated":"2025-01-01","agents":{"Firefox":["Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0"]}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ds.pools()[UserAgentFirefox]) != 1 {
+		t.Errorf("expected family keys to be normalized, got %v", ds.pools())
+	}
+}
+
+func TestUserAgentPools(t *testing.T) {
+	dir := t.TempDir()
+	custom := map[string][]string{"Mobile": {"Mozilla/5.0 (iPhone; CPU iPhone OS 18_5 like Mac OS X) Mobile"}}
+
+	pools, err := UserAgentPools(filepath.Join(dir, "missing.json"), custom)
+	if err != nil {
+		t.Fatalf("missing dataset should not be an error: %v", err)
+	}
+	if len(pools[UserAgentChrome]) != len(userAgents[UserAgentChrome]) {
+		t.Error("expected built-in chrome pool")
+	}
+	if len(pools["mobile"]) != 1 {
+		t.Error("expected custom pool keyed by lowercased name")
+	}
+
+	path := filepath.Join(dir, UserAgentDatasetFile)
+	os.WriteFile(path, []byte(`{"agents":{"chrome":["Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/999.0.0.0 Safari/537.36"]}}`), 0644)
+	pools, err = UserAgentPools(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pools[UserAgentChrome]) != 1 || !strings.Contains(pools[UserAgentChrome][0], "Chrome/999") {
+		t.Errorf("expected refreshed dataset to replace built-in pool, got %v", pools[UserAgentChrome])
+	}
+
+	os.WriteFile(path, []byte(`{broken`), 0644)
+	pools, err = UserAgentPools(path, nil)
+	if err == nil {
+		t.Error("expected error for corrupt dataset")
+	}
+	if len(pools[UserAgentFirefox]) == 0 {
+		t.Error("expected built-in fallback for corrupt dataset")
+	}
+}
+
+func TestGetUserAgentCustomPool(t *testing.T) {
+	uas := NewUserAgentSelector()
+	uas.SetPools(map[UserAgentType][]string{
+		UserAgentChrome: {"Mozilla/5.0 chrome-only"},
+		"bots":          {"Mozilla/5.0 (compatible; Examplebot/1.0)"},
+	})
+
+	if got := uas.GetUserAgent("bots"); got != "Mozilla/5.0 (compatible; Examplebot/1.0)" {
+		t.Errorf("expected custom pool agent, got %q", got)
+	}
+	if got := uas.GetUserAgent("auto"); got != "Mozilla/5.0 chrome-only" {
+		t.Errorf("auto should only draw from browser families, got %q", got)
+	}
+}
+
+func TestUserAgentForHostSticky(t *testing.T) {
+	uas := NewUserAgentSelector()
+	uas.SetSticky(true)
+
+	first := uas.UserAgentForHost("auto", "example.com")
+	for i := 0; i < 20; i++ {
+		if got := uas.UserAgentForHost("auto", "EXAMPLE.com"); got != first {
+			t.Fatalf("expected pinned agent %q, got %q", first, got)
+		}
+	}
+	if got := uas.UserAgentForHost("firefox", "example.com"); browserFamily(got) != UserAgentFirefox {
+		t.Errorf("explicit family should get its own pin, got %q", got)
+	}
+
+	uas.SetPools(map[UserAgentType][]string{UserAgentChrome: {"Mozilla/5.0 repinned"}})
+	if got := uas.RepinHost("auto", "example.com"); got != "Mozilla/5.0 repinned" {
+		t.Errorf("expected fresh agent after repin, got %q", got)
+	}
+	if got := uas.UserAgentForHost("auto", "example.com"); got != "Mozilla/5.0 repinned" {
+		t.Errorf("expected repinned agent to stick, got %q", got)
+	}
+}
+
+func TestUserAgentForHostNotSticky(t *testing.T) {
+	uas := NewUserAgentSelector()
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		seen[uas.UserAgentForHost("auto", "example.com")] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected varying agents without sticky selection")
+	}
+}
+
+func TestSeededUserAgentSelector(t *testing.T) {
+	a, b := NewSeededUserAgentSelector(42), NewSeededUserAgentSelector(42)
+	for i := 0; i < 20; i++ {
+		if x, y := a.GetUserAgent("auto"), b.GetUserAgent("auto"); x != y {
+			t.Fatalf("pick %d differs for the same seed: %q vs %q", i, x, y)
+		}
+	}
+}
+
+func TestUserAgentSelectorConcurrent(t *testing.T) {
+	uas := NewUserAgentSelector()
+	uas.SetSticky(true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				host := fmt.Sprintf("host%d.example", j%5)
+				if uas.GetUserAgent("auto") == "" || uas.UserAgentForHost("auto", host) == "" {
+					t.Error("empty user agent")
+					return
+				}
+				if j%25 == 0 {
+					uas.RepinHost("auto", host)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
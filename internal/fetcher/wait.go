@@ -0,0 +1,148 @@
+package fetcher
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// WaitCondition is when a page counts as loaded in JS mode
+type WaitCondition string
+
+const (
+	WaitLoad             WaitCondition = "load"             // the load event: images and scripts are in
+	WaitDOMContentLoaded WaitCondition = "domcontentloaded" // the document is parsed
+	WaitNetworkIdle      WaitCondition = "network-idle"     // no requests for half a second after load
+)
+
+// lifecycleEvents are Chrome's names of the lifecycle events
+var lifecycleEvents = map[WaitCondition]string{
+	WaitLoad:             "load",
+	WaitDOMContentLoaded: "DOMContentLoaded",
+	WaitNetworkIdle:      "networkIdle",
+}
+
+// ParseWaitCondition reads a --wait value ("" = load)
+func ParseWaitCondition(s string) (WaitCondition, error) {
+	cond := WaitCondition(strings.ToLower(strings.TrimSpace(s)))
+	switch cond {
+	case "":
+		return WaitLoad, nil
+	case "networkidle", "network_idle":
+		return WaitNetworkIdle, nil
+	}
+	if _, ok := lifecycleEvents[cond]; !ok {
+		return "", fmt.Errorf("invalid wait condition %q (available: load, domcontentloaded, network-idle)", s)
+	}
+	return cond, nil
+}
+
+// WaitStrategy is when a rendered page is read: once the main frame reached
+// Until and, when Idle is set, no request was in flight for Idle
+type WaitStrategy struct {
+	Until WaitCondition
+	Idle  time.Duration
+}
+
+// ParseWaitStrategy reads a --wait value: a condition, or
+// "network-idle:<duration>" to wait for the load event and then for the
+// network to be quiet that long, for apps that keep fetching after it
+func ParseWaitStrategy(s string) (WaitStrategy, error) {
+	name, idle, found := strings.Cut(strings.TrimSpace(s), ":")
+	if !found {
+		cond, err := ParseWaitCondition(s)
+		return WaitStrategy{Until: cond}, err
+	}
+	if cond, err := ParseWaitCondition(name); err != nil || cond != WaitNetworkIdle {
+		return WaitStrategy{}, fmt.Errorf("invalid wait condition %q (only network-idle takes a duration)", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(idle))
+	if err != nil || d <= 0 {
+		return WaitStrategy{}, fmt.Errorf("invalid network idle time in %q (e.g. network-idle:800ms)", s)
+	}
+	return WaitStrategy{Until: WaitLoad, Idle: d}, nil
+}
+
+// String returns the strategy as ParseWaitStrategy reads it
+func (w WaitStrategy) String() string {
+	if w.Idle > 0 {
+		return fmt.Sprintf("%s:%v", WaitNetworkIdle, w.Idle)
+	}
+	return string(cmp.Or(w.Until, WaitLoad))
+}
+
+// waitTextPoll is how often the page is checked for the WaitForText text
+const waitTextPoll = 100 * time.Millisecond
+
+// navigate loads url in the tab, presenting referer like a followed link,
+// and returns once the main frame reached cond ("" = load)
+func navigate(url, referer string, cond WaitCondition) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		cond = cmp.Or(cond, WaitLoad)
+		event := lifecycleEvents[cond]
+		if err := page.SetLifecycleEventsEnabled(true).Do(ctx); err != nil {
+			return err
+		}
+
+		// Events may arrive before Navigate returns the loader to wait for
+		var mu sync.Mutex
+		reached := make(map[cdp.LoaderID]bool)
+		wake := make(chan struct{}, 1)
+		listenCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		chromedp.ListenTarget(listenCtx, func(ev any) {
+			if e, ok := ev.(*page.EventLifecycleEvent); ok && e.Name == event {
+				mu.Lock()
+				reached[e.LoaderID] = true
+				mu.Unlock()
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		})
+
+		nav := page.Navigate(url)
+		if referer != "" {
+			nav = nav.WithReferrer(referer)
+		}
+		_, loaderID, errorText, _, err := nav.Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return fmt.Errorf("page load error %s", errorText)
+		}
+		if loaderID == "" {
+			return nil // same-document navigation, e.g. to an anchor
+		}
+		for {
+			mu.Lock()
+			done := reached[loaderID]
+			mu.Unlock()
+			if done {
+				return nil
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for %s: %w", cond, ctx.Err())
+			}
+		}
+	})
+}
+
+// waitForText returns once the page's visible text contains text
+func waitForText(text string) chromedp.Action {
+	quoted, _ := json.Marshal(text)
+	return chromedp.Poll(fmt.Sprintf(`document.body && document.body.innerText.includes(%s)`, quoted), nil,
+		chromedp.WithPollingInterval(waitTextPoll))
+}
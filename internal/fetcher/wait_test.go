@@ -0,0 +1,54 @@
+package fetcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	for in, want := range map[string]WaitCondition{
+		"":                 WaitLoad,
+		"load":             WaitLoad,
+		"DOMContentLoaded": WaitDOMContentLoaded,
+		"network-idle":     WaitNetworkIdle,
+		"networkidle":      WaitNetworkIdle,
+	} {
+		if got, err := ParseWaitCondition(in); err != nil || got != want {
+			t.Errorf("ParseWaitCondition(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseWaitCondition("idle"); err == nil {
+		t.Error("expected an unknown condition to fail")
+	}
+}
+
+func TestParseWaitStrategy(t *testing.T) {
+	for in, want := range map[string]WaitStrategy{
+		"":                   {Until: WaitLoad},
+		"domcontentloaded":   {Until: WaitDOMContentLoaded},
+		"network-idle":       {Until: WaitNetworkIdle},
+		"network-idle:800ms": {Until: WaitLoad, Idle: 800 * time.Millisecond},
+		" networkidle : 2s ": {Until: WaitLoad, Idle: 2 * time.Second},
+	} {
+		if got, err := ParseWaitStrategy(in); err != nil || got != want {
+			t.Errorf("ParseWaitStrategy(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"load:1s", "network-idle:", "network-idle:soon", "network-idle:-1s", "idle:1s"} {
+		if _, err := ParseWaitStrategy(in); err == nil {
+			t.Errorf("ParseWaitStrategy(%q): expected an error", in)
+		}
+	}
+}
+
+func TestWaitStrategyString(t *testing.T) {
+	for _, s := range []string{"load", "domcontentloaded", "network-idle", "network-idle:1.5s"} {
+		w, err := ParseWaitStrategy(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.String(); got != s {
+			t.Errorf("String() = %q, want %q", got, s)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sessionCache is shared by every transport so TLS sessions primed during
+// warmup are resumed by the fetchers created later in the run
+var sessionCache = tls.NewLRUClientSessionCache(256)
+
+// resolvedHosts caches addresses looked up during warmup. Dials to a cached
+// host skip the resolver; hosts that were never prefetched resolve normally.
+var resolvedHosts = &hostCache{addrs: make(map[string][]string)}
+
+type hostCache struct {
+	mu    sync.RWMutex
+	addrs map[string][]string
+}
+
+func (hc *hostCache) get(host string) ([]string, bool) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+	addrs, ok := hc.addrs[host]
+	return addrs, ok
+}
+
+func (hc *hostCache) set(host string, addrs []string) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.addrs[host] = addrs
+}
+
+// cachedDialContext dials prefetched hosts by address, falling back to the
+// regular resolver when no cached address accepts the connection. The cached
+// addresses share one connect timeout, so a host that moved costs at most
+// that before the resolver is asked.
+func cachedDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		network = familyNetwork(network)
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			if addrs, ok := resolvedHosts.get(host); ok {
+				if conn := dialCached(ctx, dialer, network, addrs, port); conn != nil {
+					return conn, nil
+				}
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// dialCached tries the addresses in turn within one connect timeout and
+// returns nil when none connects
+func dialCached(ctx context.Context, dialer *net.Dialer, network string, addrs []string, port string) net.Conn {
+	if dialer.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+	}
+	for _, ip := range addrs {
+		if conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// WarmupOptions controls batch warmup
+type WarmupOptions struct {
+	Concurrency int           // parallel lookups (default 16)
+	TLSHosts    int           // prime TLS sessions for the N hosts with the most URLs
+	Timeout     time.Duration // per-host budget for lookup and TLS priming (default 5s)
+}
+
+// HostStatus is the warmup outcome for a single host
+type HostStatus struct {
+	Host      string
+	URLs      int      // number of batch URLs on this host
+	Addrs     []string // resolved addresses
+	TLSPrimed bool
+	Err       error // resolution failure; the host is most likely dead
+}
+
+// Warmup resolves every unique host in urls concurrently and primes TLS
+// sessions for the busiest HTTPS hosts. Results are sorted by URL count,
+// busiest first.
+func Warmup(ctx context.Context, urls []string, opts WarmupOptions) []HostStatus {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 16
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	counts := make(map[string]int)
+	httpsHosts := make(map[string]bool)
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		counts[u.Hostname()]++
+		if u.Scheme == "https" {
+			httpsHosts[u.Hostname()] = true
+		}
+	}
+
+	statuses := make([]HostStatus, 0, len(counts))
+	for host, n := range counts {
+		statuses = append(statuses, HostStatus{Host: host, URLs: n})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].URLs != statuses[j].URLs {
+			return statuses[i].URLs > statuses[j].URLs
+		}
+		return statuses[i].Host < statuses[j].Host
+	})
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for i := range statuses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(st *HostStatus, rank int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			hostCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+
+			addrs, err := lookupHost(hostCtx, st.Host)
+			if err != nil {
+				st.Err = err
+				return
+			}
+			st.Addrs = addrs
+			resolvedHosts.set(st.Host, addrs)
+
+			if rank < opts.TLSHosts && httpsHosts[st.Host] {
+				st.TLSPrimed = primeTLS(hostCtx, st.Host)
+			}
+		}(&statuses[i], i)
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+// primeTLS completes a cheap HEAD request so the session ticket lands in the
+// shared cache and later fetches can resume instead of doing a full handshake.
+// The connection stays in the shared pool for fetchers using default
+// timeouts.
+func primeTLS(ctx context.Context, host string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+host+"/", nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := (&http.Client{
+		Transport: sharedTransport(DefaultTimeouts()),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}).Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWarmup_CountsAndResolvesHosts(t *testing.T) {
+	urls := []string{
+		"http://127.0.0.1:1/a",
+		"http://127.0.0.1:1/b",
+		"http://localhost:1/c",
+		"not a url",
+	}
+
+	statuses := Warmup(context.Background(), urls, WarmupOptions{Timeout: 2 * time.Second})
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(statuses))
+	}
+
+	if statuses[0].Host != "127.0.0.1" || statuses[0].URLs != 2 {
+		t.Errorf("expected busiest host 127.0.0.1 with 2 URLs, got %+v", statuses[0])
+	}
+	if statuses[0].Err != nil || len(statuses[0].Addrs) == 0 {
+		t.Errorf("expected 127.0.0.1 to resolve, got %+v", statuses[0])
+	}
+
+	if addrs, ok := resolvedHosts.get("127.0.0.1"); !ok || len(addrs) == 0 {
+		t.Error("expected resolved addresses to be cached")
+	}
+}
+
+func TestCachedDialContext_CachedAddressesShareTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// The cached addresses of 127.0.0.1 hang until the dial gives up
+	previous, cached := resolvedHosts.get("127.0.0.1")
+	resolvedHosts.set("127.0.0.1", []string{"127.0.0.2", "127.0.0.3", "127.0.0.4"})
+	t.Cleanup(func() {
+		if cached {
+			resolvedHosts.set("127.0.0.1", previous)
+		} else {
+			resolvedHosts.mu.Lock()
+			delete(resolvedHosts.addrs, "127.0.0.1")
+			resolvedHosts.mu.Unlock()
+		}
+	})
+	const timeout = 300 * time.Millisecond
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			if !strings.HasPrefix(address, "127.0.0.1:") {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	conn, err := cachedDialContext(dialer)(context.Background(), "tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 2*timeout {
+		t.Errorf("dial took %v; the cached addresses should share one %v timeout", elapsed, timeout)
+	}
+}
@@ -0,0 +1,109 @@
+// Package ocr reads the text of images with an external OCR command,
+// tesseract by default, for pages whose content is a picture: scanned
+// letters, infographic announcements.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // registered for DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCommand runs tesseract on the image and prints the text. {file} is
+// replaced by the path of the image; a command without it gets the path
+// appended.
+const DefaultCommand = "tesseract {file} stdout"
+
+// DefaultTimeout bounds one OCR run; tesseract takes seconds on a large scan
+const DefaultTimeout = 60 * time.Second
+
+// Images smaller than minSide on either side, or than minArea overall, are
+// icons and buttons rather than text
+const (
+	minSide = 200
+	minArea = 300 * 300
+)
+
+// Engine runs an OCR command. Safe for concurrent use.
+type Engine struct {
+	command []string
+	timeout time.Duration
+}
+
+// New parses command, split on whitespace; timeout 0 is DefaultTimeout
+func New(command string, timeout time.Duration) (*Engine, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty OCR command")
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Engine{command: fields, timeout: timeout}, nil
+}
+
+// Program is the executable the engine runs
+func (e *Engine) Program() string {
+	return e.command[0]
+}
+
+// Large reports whether data is a PNG, JPEG or GIF big enough to carry text
+// worth reading
+func Large(data []byte) bool {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	return cfg.Width >= minSide && cfg.Height >= minSide && cfg.Width*cfg.Height >= minArea
+}
+
+// Recognize returns the text the command reads from the image in data
+func (e *Engine) Recognize(ctx context.Context, data []byte) (string, error) {
+	f, err := os.CreateTemp("", "scrpr-ocr-*")
+	if err != nil {
+		return "", fmt.Errorf("ocr: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", fmt.Errorf("ocr: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("ocr: %w", err)
+	}
+
+	args := make([]string, 0, len(e.command))
+	replaced := false
+	for _, arg := range e.command[1:] {
+		if strings.Contains(arg, "{file}") {
+			arg = strings.ReplaceAll(arg, "{file}", f.Name())
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, f.Name())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.command[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("ocr: %s: %w: %s", e.command[0], err, msg)
+		}
+		return "", fmt.Errorf("ocr: %s: %w", e.command[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
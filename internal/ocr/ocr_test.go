@@ -0,0 +1,68 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"scan", pngOf(t, 800, 1100), true},
+		{"icon", pngOf(t, 64, 64), false},
+		{"banner strip", pngOf(t, 1200, 90), false},
+		{"not an image", []byte("<svg/>"), false},
+	}
+	for _, tt := range tests {
+		if got := Large(tt.data); got != tt.want {
+			t.Errorf("%s: Large = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecognize(t *testing.T) {
+	if _, err := exec.LookPath("wc"); err != nil {
+		t.Skip("no wc")
+	}
+	if _, err := New("  ", 0); err == nil {
+		t.Error("empty command accepted")
+	}
+
+	// wc stands in for tesseract and prints the size of the image; the path
+	// goes where {file} is, or last
+	for _, command := range []string{"wc -c {file}", "wc -c"} {
+		e, err := New(command, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := e.Recognize(context.Background(), []byte("hello"))
+		if err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+		if !strings.HasPrefix(text, "5 ") {
+			t.Errorf("%s: Recognize = %q, want the byte count of the image", command, text)
+		}
+	}
+
+	e, _ := New("false", 0)
+	if _, err := e.Recognize(context.Background(), []byte("x")); err == nil {
+		t.Error("failing command reported no error")
+	}
+}
@@ -0,0 +1,139 @@
+// Package ordered writes the outputs of a batch in input order, however
+// they complete, so line N of stdout still belongs to URL N
+package ordered
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer holds back outputs that complete ahead of an earlier one and
+// writes each as soon as everything before it is out. Held outputs beyond
+// the memory limit go to a temporary file. Safe for concurrent use.
+type Writer struct {
+	mu        sync.Mutex
+	w         io.Writer
+	unordered bool
+	memLimit  int64
+
+	next    int            // sequence number written next
+	pending map[int]*chunk // completed outputs waiting for next
+	held    int64          // bytes of pending held in memory
+	spill   *os.File       // overflow of pending, created on first use
+	spillAt int64          // end of the data written to spill
+	err     error          // first write error; later calls return it
+}
+
+// chunk is a pending output, in memory or at [off, off+size) of the spill
+// file
+type chunk struct {
+	data    []byte
+	off     int64
+	size    int64
+	spilled bool
+}
+
+// NewWriter writes to w from sequence number 0 on, holding at most memLimit
+// bytes in memory (0 = no limit). An unordered Writer writes every output
+// as it arrives.
+func NewWriter(w io.Writer, memLimit int64, unordered bool) *Writer {
+	return &Writer{w: w, memLimit: memLimit, unordered: unordered, pending: make(map[int]*chunk)}
+}
+
+// Put hands over the output of sequence number seq; nil data marks an
+// input that produced nothing, which releases the outputs after it
+func (o *Writer) Put(seq int, data []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return o.err
+	}
+	if o.unordered {
+		o.write(data)
+		return o.err
+	}
+	if seq < o.next || o.pending[seq] != nil {
+		return fmt.Errorf("output %d handed over twice", seq)
+	}
+
+	if seq == o.next {
+		o.write(data)
+		o.next++
+	} else {
+		o.hold(seq, data)
+	}
+	for o.err == nil {
+		c := o.pending[o.next]
+		if c == nil {
+			break
+		}
+		delete(o.pending, o.next)
+		o.release(c)
+		o.next++
+	}
+	return o.err
+}
+
+// Close writes what is still held, in order, skipping sequence numbers
+// that never arrived (a batch cut short), and removes the spill file
+func (o *Writer) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for len(o.pending) > 0 && o.err == nil {
+		if c := o.pending[o.next]; c != nil {
+			delete(o.pending, o.next)
+			o.release(c)
+		}
+		o.next++
+	}
+	if o.spill != nil {
+		o.spill.Close()
+		os.Remove(o.spill.Name())
+		o.spill = nil
+	}
+	return o.err
+}
+
+func (o *Writer) write(data []byte) {
+	if len(data) == 0 || o.err != nil {
+		return
+	}
+	_, o.err = o.w.Write(data)
+}
+
+// hold keeps an early output until its turn
+func (o *Writer) hold(seq int, data []byte) {
+	size := int64(len(data))
+	if o.memLimit <= 0 || o.held+size <= o.memLimit {
+		o.pending[seq] = &chunk{data: data, size: size}
+		o.held += size
+		return
+	}
+	if o.spill == nil {
+		o.spill, o.err = os.CreateTemp("", "scrpr-ordered-*")
+		if o.err != nil {
+			o.err = fmt.Errorf("failed to buffer output: %w", o.err)
+			return
+		}
+	}
+	if _, err := o.spill.WriteAt(data, o.spillAt); err != nil {
+		o.err = fmt.Errorf("failed to buffer output: %w", err)
+		return
+	}
+	o.pending[seq] = &chunk{off: o.spillAt, size: size, spilled: true}
+	o.spillAt += size
+}
+
+// release writes a held output
+func (o *Writer) release(c *chunk) {
+	if !c.spilled {
+		o.held -= c.size
+		o.write(c.data)
+		return
+	}
+	if _, err := io.Copy(o.w, io.NewSectionReader(o.spill, c.off, c.size)); err != nil {
+		o.err = err
+	}
+}
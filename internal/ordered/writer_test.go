@@ -0,0 +1,81 @@
+package ordered
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriter_Order(t *testing.T) {
+	cases := []struct {
+		name     string
+		memLimit int64
+	}{
+		{"in memory", 0},
+		{"spilled", 4},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		w := NewWriter(&out, c.memLimit, false)
+		put := func(seq int, s string) {
+			var data []byte
+			if s != "" {
+				data = []byte(s)
+			}
+			if err := w.Put(seq, data); err != nil {
+				t.Fatalf("%s: put %d: %v", c.name, seq, err)
+			}
+		}
+
+		put(2, "two\n")
+		put(1, "one\n")
+		if out.Len() != 0 {
+			t.Errorf("%s: wrote %q before output 0", c.name, out.String())
+		}
+		put(0, "zero\n")
+		if got := out.String(); got != "zero\none\ntwo\n" {
+			t.Errorf("%s: got %q", c.name, got)
+		}
+
+		// An input without output releases the ones after it
+		put(4, "four\n")
+		put(3, "")
+		if got := out.String(); !strings.HasSuffix(got, "two\nfour\n") {
+			t.Errorf("%s: got %q", c.name, got)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriter_CloseSkipsGaps(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, 0, false)
+	w.Put(3, []byte("c"))
+	w.Put(1, []byte("a"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "ac" {
+		t.Errorf("got %q, want %q", out.String(), "ac")
+	}
+}
+
+func TestWriter_Unordered(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, 0, true)
+	w.Put(1, []byte("b"))
+	w.Put(0, []byte("a"))
+	if out.String() != "ba" {
+		t.Errorf("got %q, want completion order", out.String())
+	}
+}
+
+func TestWriter_Twice(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, 0, false)
+	w.Put(0, []byte("a"))
+	if err := w.Put(0, []byte("a")); err == nil {
+		t.Error("expected an error for a repeated sequence number")
+	}
+}
@@ -0,0 +1,169 @@
+// Package paths locates scrpr's config, cache and data directories the way
+// each platform expects them: the XDG base directories on Linux and the
+// BSDs, ~/Library on macOS, and %APPDATA% and %LOCALAPPDATA% on Windows.
+//
+// XDG_CONFIG_HOME, XDG_CACHE_HOME and XDG_DATA_HOME override the platform's
+// choice everywhere. Directories created by earlier versions, which used the
+// XDG layout on every platform, keep being used while the platform's own is
+// missing, so upgrading does not strand a config or a cache.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const app = "scrpr"
+
+// Kind is one of the directories scrpr keeps files in
+type Kind string
+
+const (
+	Config Kind = "config" // config.toml and rule packs
+	Cache  Kind = "cache"  // response and API caches, host health
+	Data   Kind = "data"   // user agent dataset, sitemap and watch state
+)
+
+// Dir returns scrpr's directory of the kind. It is not created; see Ensure.
+func Dir(kind Kind) (string, error) {
+	return system.dir(kind)
+}
+
+// Source says where the directory of the kind comes from: the XDG variable
+// that set it, "platform default" or "legacy location"
+func Source(kind Kind) string {
+	_, source, _ := system.resolve(kind)
+	return source
+}
+
+// ExpandHome replaces a leading ~/ (or ~\ on Windows) with the home directory
+func ExpandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok && runtime.GOOS == "windows" {
+		rest, ok = strings.CutPrefix(path, `~\`)
+	}
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// Ensure creates dir when it is missing. A dir that is a symlink to a
+// missing directory, as a dotfiles checkout leaves before it is synced,
+// gets the directory it points to created instead of being replaced.
+func Ensure(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if _, statErr := os.Stat(dir); errors.Is(statErr, os.ErrNotExist) {
+			target, err := os.Readlink(dir)
+			if err != nil {
+				return err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(dir), target)
+			}
+			dir = target
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return nil
+}
+
+// platform resolves the directories for an OS; the fields are swapped out
+// in tests
+type platform struct {
+	goos   string
+	getenv func(string) string
+	home   func() (string, error)
+	exists func(string) bool
+}
+
+var system = platform{
+	goos:   runtime.GOOS,
+	getenv: os.Getenv,
+	home:   os.UserHomeDir,
+	exists: func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	},
+}
+
+// xdgVars are the variables overriding each kind's base directory
+var xdgVars = map[Kind]string{
+	Config: "XDG_CONFIG_HOME",
+	Cache:  "XDG_CACHE_HOME",
+	Data:   "XDG_DATA_HOME",
+}
+
+func (p platform) dir(kind Kind) (string, error) {
+	dir, _, err := p.resolve(kind)
+	return dir, err
+}
+
+// resolve returns the directory of the kind and where it comes from
+func (p platform) resolve(kind Kind) (string, string, error) {
+	if base := p.getenv(xdgVars[kind]); base != "" {
+		return filepath.Join(base, app), "$" + xdgVars[kind], nil
+	}
+	home, err := p.home()
+	if err != nil {
+		return "", "", fmt.Errorf("error finding home directory: %w", err)
+	}
+	legacy := p.legacy(kind, home)
+	native := p.native(kind, home)
+	if native != legacy && !p.exists(native) && p.exists(legacy) {
+		return legacy, "legacy location", nil
+	}
+	return native, "platform default", nil
+}
+
+// legacy is where versions before the platform layout kept the directory:
+// the XDG defaults, on every OS
+func (p platform) legacy(kind Kind, home string) string {
+	switch kind {
+	case Cache:
+		return filepath.Join(home, ".cache", app)
+	case Data:
+		return filepath.Join(home, ".local", "share", app)
+	}
+	return filepath.Join(home, ".config", app)
+}
+
+// native is the platform's own place for the directory
+func (p platform) native(kind Kind, home string) string {
+	switch p.goos {
+	case "darwin", "ios":
+		if kind == Cache {
+			return filepath.Join(home, "Library", "Caches", app)
+		}
+		return filepath.Join(home, "Library", "Application Support", app)
+	case "windows":
+		// Config roams with the profile; data and caches stay on the machine
+		local := p.getenv("LOCALAPPDATA")
+		if local == "" {
+			local = filepath.Join(home, "AppData", "Local")
+		}
+		switch kind {
+		case Cache:
+			return filepath.Join(local, app, "cache")
+		case Data:
+			return filepath.Join(local, app)
+		}
+		roaming := p.getenv("APPDATA")
+		if roaming == "" {
+			roaming = filepath.Join(home, "AppData", "Roaming")
+		}
+		return filepath.Join(roaming, app)
+	}
+	return p.legacy(kind, home)
+}
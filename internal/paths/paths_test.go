@@ -0,0 +1,87 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakePlatform is an OS with the given environment whose existing paths are
+// those listed
+func fakePlatform(goos string, env map[string]string, existing ...string) platform {
+	return platform{
+		goos:   goos,
+		getenv: func(k string) string { return env[k] },
+		home:   func() (string, error) { return "/home/u", nil },
+		exists: func(path string) bool {
+			for _, e := range existing {
+				if e == path {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	j := filepath.Join
+	winEnv := map[string]string{"APPDATA": j("C:", "Users", "u", "AppData", "Roaming"), "LOCALAPPDATA": j("C:", "Users", "u", "AppData", "Local")}
+	tests := []struct {
+		name   string
+		p      platform
+		kind   Kind
+		want   string
+		source string
+	}{
+		{"linux config", fakePlatform("linux", nil), Config, j("/home/u", ".config", "scrpr"), "platform default"},
+		{"linux cache", fakePlatform("linux", nil), Cache, j("/home/u", ".cache", "scrpr"), "platform default"},
+		{"linux data", fakePlatform("linux", nil), Data, j("/home/u", ".local", "share", "scrpr"), "platform default"},
+		{"xdg wins", fakePlatform("darwin", map[string]string{"XDG_CACHE_HOME": "/tmp/c"}), Cache, j("/tmp/c", "scrpr"), "$XDG_CACHE_HOME"},
+		{"macos config", fakePlatform("darwin", nil), Config, j("/home/u", "Library", "Application Support", "scrpr"), "platform default"},
+		{"macos cache", fakePlatform("darwin", nil), Cache, j("/home/u", "Library", "Caches", "scrpr"), "platform default"},
+		{"macos legacy", fakePlatform("darwin", nil, j("/home/u", ".config", "scrpr")), Config, j("/home/u", ".config", "scrpr"), "legacy location"},
+		{"macos both", fakePlatform("darwin", nil, j("/home/u", ".config", "scrpr"), j("/home/u", "Library", "Application Support", "scrpr")), Config, j("/home/u", "Library", "Application Support", "scrpr"), "platform default"},
+		{"windows config", fakePlatform("windows", winEnv), Config, j(winEnv["APPDATA"], "scrpr"), "platform default"},
+		{"windows data", fakePlatform("windows", winEnv), Data, j(winEnv["LOCALAPPDATA"], "scrpr"), "platform default"},
+		{"windows cache", fakePlatform("windows", winEnv), Cache, j(winEnv["LOCALAPPDATA"], "scrpr", "cache"), "platform default"},
+		{"windows no env", fakePlatform("windows", nil), Config, j("/home/u", "AppData", "Roaming", "scrpr"), "platform default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := tt.p.resolve(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || source != tt.source {
+				t.Errorf("got %s (%s), want %s (%s)", got, source, tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestEnsureBrokenSymlink(t *testing.T) {
+	root := t.TempDir()
+	link := filepath.Join(root, "scrpr")
+	if err := os.Symlink(filepath.Join("dotfiles", "scrpr"), link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	if err := Ensure(link); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected the symlink to stay, got %v, %v", fi, err)
+	}
+	if fi, err := os.Stat(filepath.Join(root, "dotfiles", "scrpr")); err != nil || !fi.IsDir() {
+		t.Errorf("expected the link target to be created: %v", err)
+	}
+}
+
+func TestEnsureExisting(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	for range 2 {
+		if err := Ensure(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
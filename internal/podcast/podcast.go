@@ -0,0 +1,215 @@
+// Package podcast finds the transcript of a podcast episode page. Episode
+// pages are mostly show notes and marketing copy; the words of the episode
+// are in the transcript its feed links with <podcast:transcript>.
+package podcast
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Feed is the part of an RSS feed needed to find transcripts
+type Feed struct {
+	Items []Item `xml:"channel>item"`
+}
+
+// Item is one episode of a feed
+type Item struct {
+	Title string `xml:"title"`
+	// Links holds the <link> texts; atom:link elements, which share the
+	// local name, come out empty
+	Links       []string     `xml:"link"`
+	GUID        string       `xml:"guid"`
+	Enclosure   Enclosure    `xml:"enclosure"`
+	Transcripts []Transcript `xml:"transcript"`
+}
+
+// Enclosure is the audio file of an episode
+type Enclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Transcript is a <podcast:transcript> tag
+type Transcript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+	Rel      string `xml:"rel,attr"` // "captions" for timed captions
+}
+
+// transcriptTypes are the transcript formats read, most readable first
+var transcriptTypes = []string{
+	"text/html",
+	"application/json",
+	"text/vtt",
+	"application/x-subrip",
+	"application/srt",
+	"text/plain",
+}
+
+// FeedLinks returns the RSS feeds an HTML page announces with
+// <link rel="alternate">, resolved against pageURL
+func FeedLinks(html, pageURL string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	base, _ := url.Parse(pageURL)
+	var feeds []string
+	doc.Find(`link[rel~="alternate"]`).Each(func(_ int, s *goquery.Selection) {
+		typ, _ := s.Attr("type")
+		href, _ := s.Attr("href")
+		if !strings.EqualFold(strings.TrimSpace(typ), "application/rss+xml") || strings.TrimSpace(href) == "" {
+			return
+		}
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if !slices.Contains(feeds, ref.String()) {
+			feeds = append(feeds, ref.String())
+		}
+	})
+	return feeds
+}
+
+// AudioLinks returns the audio files an HTML page plays or announces:
+// <audio> sources and og:audio, resolved against pageURL. A page without
+// any is not an episode page.
+func AudioLinks(html, pageURL string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	base, _ := url.Parse(pageURL)
+	var links []string
+	add := func(raw string) {
+		ref, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || ref.String() == "" {
+			return
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if !slices.Contains(links, ref.String()) {
+			links = append(links, ref.String())
+		}
+	}
+	doc.Find("audio[src], audio source[src]").Each(func(_ int, s *goquery.Selection) {
+		add(s.AttrOr("src", ""))
+	})
+	doc.Find(`meta[property="og:audio"], meta[property="og:audio:url"], meta[property="og:audio:secure_url"]`).Each(func(_ int, s *goquery.Selection) {
+		add(s.AttrOr("content", ""))
+	})
+	return links
+}
+
+// ParseFeed reads an RSS feed
+func ParseFeed(data []byte) (*Feed, error) {
+	var rss struct {
+		XMLName xml.Name
+		Feed
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	// Feeds declaring a legacy charset are nearly always ASCII in the parts
+	// read here; reading them as is beats failing
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&rss); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+	if rss.XMLName.Local != "rss" {
+		return nil, fmt.Errorf("not an RSS feed: <%s>", rss.XMLName.Local)
+	}
+	return &rss.Feed, nil
+}
+
+// Episode returns the item of the page: the one linking to pageURL, or
+// else the one whose audio file is among audio, the audio URLs of the page.
+// Nil when the page is not an episode of the feed.
+func (f *Feed) Episode(pageURL string, audio []string) *Item {
+	page := normalizeURL(pageURL)
+	for i, item := range f.Items {
+		for _, link := range append(slices.Clone(item.Links), item.GUID) {
+			if link = strings.TrimSpace(link); link != "" && normalizeURL(link) == page {
+				return &f.Items[i]
+			}
+		}
+	}
+	for i, item := range f.Items {
+		file := audioFile(item.Enclosure.URL)
+		if file == "" {
+			continue
+		}
+		for _, a := range audio {
+			if audioFile(a) == file {
+				return &f.Items[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Transcript returns the transcript of the item in the most readable format
+// that can be read, or nil
+func (item *Item) Transcript() *Transcript {
+	var best *Transcript
+	rank := len(transcriptTypes)
+	for i, t := range item.Transcripts {
+		if strings.TrimSpace(t.URL) == "" {
+			continue
+		}
+		r := slices.Index(transcriptTypes, mediaType(t.Type))
+		if r < 0 {
+			r = len(transcriptTypes) - 1 // unknown types are sniffed
+		}
+		if best == nil || r < rank {
+			best, rank = &item.Transcripts[i], r
+		}
+	}
+	return best
+}
+
+// normalizeURL drops what differs between links to the same page: scheme,
+// www., trailing slash, query and fragment
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimSuffix(u.Path, "/")
+}
+
+// audioFile is the file name of an audio URL. Feeds often wrap the file in
+// tracking redirects (dts.podtrac.com/redirect.mp3/host/file.mp3), so only
+// the name is compared.
+func audioFile(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || path.Ext(name) == "" {
+		return ""
+	}
+	return name
+}
+
+// mediaType is a Content-Type without parameters, lower case
+func mediaType(typ string) string {
+	typ, _, _ = strings.Cut(typ, ";")
+	return strings.ToLower(strings.TrimSpace(typ))
+}
@@ -0,0 +1,149 @@
+package podcast
+
+import (
+	"reflect"
+	"testing"
+)
+
+const feedXML = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:podcast="https://podcastindex.org/namespace/1.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Show</title>
+  <atom:link href="https://example.com/feed.xml" rel="self"/>
+  <item>
+    <title>Episode 2</title>
+    <link>https://www.example.com/episodes/2/</link>
+    <enclosure url="https://dts.podtrac.com/redirect.mp3/cdn.example.com/ep2.mp3" type="audio/mpeg"/>
+    <podcast:transcript url="https://example.com/ep2.txt" type="text/plain"/>
+    <podcast:transcript url="https://example.com/ep2.vtt" type="text/vtt" rel="captions"/>
+  </item>
+  <item>
+    <title>Episode 1</title>
+    <guid>https://example.com/episodes/1</guid>
+    <enclosure url="https://cdn.example.com/ep1.mp3?source=rss" type="audio/mpeg"/>
+  </item>
+</channel>
+</rss>`
+
+func TestFeedLinks(t *testing.T) {
+	html := `<html><head>
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+<link rel="alternate" type="application/rss+xml" href="https://example.com/feed.xml">
+<link rel="stylesheet" href="/s.css">
+</head></html>`
+	got := FeedLinks(html, "https://example.com/episodes/2")
+	want := []string{"https://example.com/feed.xml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FeedLinks = %v, want %v", got, want)
+	}
+}
+
+func TestAudioLinks(t *testing.T) {
+	html := `<html><head><meta property="og:audio" content="https://cdn.example.com/ep2.mp3"></head>
+<body><audio controls><source src="/media/ep2.mp3" type="audio/mpeg"></audio>
+<audio src="https://cdn.example.com/ep2.mp3"></audio></body></html>`
+	got := AudioLinks(html, "https://example.com/episodes/2")
+	want := []string{"https://example.com/media/ep2.mp3", "https://cdn.example.com/ep2.mp3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AudioLinks = %v, want %v", got, want)
+	}
+}
+
+func TestEpisode(t *testing.T) {
+	feed, err := ParseFeed([]byte(feedXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		page  string
+		audio []string
+		want  string
+	}{
+		{"http://example.com/episodes/2", nil, "Episode 2"},
+		{"https://example.com/episodes/1/", nil, "Episode 1"},
+		{"https://example.com/listen?id=2", []string{"https://cdn.example.com/ep2.mp3"}, "Episode 2"},
+		{"https://example.com/listen", []string{"https://cdn.example.com/ep1.mp3"}, "Episode 1"},
+		{"https://example.com/about", []string{"https://cdn.example.com/other.mp3"}, ""},
+	}
+	for _, tt := range tests {
+		item := feed.Episode(tt.page, tt.audio)
+		got := ""
+		if item != nil {
+			got = item.Title
+		}
+		if got != tt.want {
+			t.Errorf("Episode(%s, %v) = %q, want %q", tt.page, tt.audio, got, tt.want)
+		}
+	}
+
+	if tr := feed.Items[0].Transcript(); tr == nil || tr.URL != "https://example.com/ep2.vtt" {
+		t.Errorf("Transcript = %+v, want the VTT one", tr)
+	}
+	if tr := feed.Items[1].Transcript(); tr != nil {
+		t.Errorf("Transcript of an item without one = %+v", tr)
+	}
+}
+
+func TestParseFeed_NotRSS(t *testing.T) {
+	if _, err := ParseFeed([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`)); err == nil {
+		t.Error("Atom feed should be refused")
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		typ  string
+		want string
+	}{
+		{
+			"vtt with voices",
+			"WEBVTT\n\nNOTE intro\n\n00:00.000 --> 00:02.000\n<v Alice>Hello and\nwelcome.</v>\n\n00:02.000 --> 00:04.000\n<v Alice>Today &amp; tomorrow.\n\n00:04.000 --> 00:06.000\n<v.loud Bob>Thanks!\n",
+			"text/vtt",
+			"Alice: Hello and welcome. Today & tomorrow.\n\nBob: Thanks!",
+		},
+		{
+			"srt",
+			"1\r\n00:00:00,000 --> 00:00:02,000\r\nFirst line\r\n\r\n2\r\n00:00:02,000 --> 00:00:04,000\r\n<i>second</i> line\r\n",
+			"application/x-subrip",
+			"First line second line",
+		},
+		{
+			"json",
+			`{"version":"1.0.0","segments":[{"speaker":"Alice","startTime":0,"endTime":1,"body":"Hi"},{"speaker":"Alice","body":"there."},{"speaker":"Bob","body":"Hello."}]}`,
+			"application/json",
+			"Alice: Hi there.\n\nBob: Hello.",
+		},
+		{
+			"html",
+			`<html><body><script>x()</script><p>Alice: Hi.</p><p>Bob:
+ Hello.</p></body></html>`,
+			"text/html; charset=utf-8",
+			"Alice: Hi.\n\nBob: Hello.",
+		},
+		{
+			"plain lines",
+			"Alice: Hi.\nBob: Hello.\n",
+			"text/plain",
+			"Alice: Hi.\n\nBob: Hello.",
+		},
+		{
+			"sniffed vtt",
+			"WEBVTT\n\n00:00.000 --> 00:01.000\nHi.\n",
+			"application/octet-stream",
+			"Hi.",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Text([]byte(tt.data), tt.typ)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Text = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package podcast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxParagraph is the length past which a speaker's run of cues is broken
+// at the next sentence end, so captions without speakers do not come out as
+// a single paragraph
+const maxParagraph = 600
+
+// cue is a caption or segment of a timed transcript
+type cue struct {
+	speaker string
+	text    string
+}
+
+var (
+	voiceRe = regexp.MustCompile(`^<v(?:\.[\w.-]+)?\s+([^>]+)>`)
+	tagRe   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Text turns a transcript into paragraphs separated by blank lines. typ is
+// the type the feed or server gave; when it is missing or unknown the
+// format is guessed from the data. Timed formats lose their timestamps, and
+// consecutive cues of one speaker are joined, led by "Speaker:".
+func Text(data []byte, typ string) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch t := mediaType(typ); {
+	case t == "text/vtt", t == "application/x-subrip", t == "application/srt":
+		return joinCues(parseCues(string(data))), nil
+	case t == "application/json":
+		return jsonText(data)
+	case t == "text/html":
+		return htmlText(data)
+	case t == "text/plain":
+		return plainText(string(data)), nil
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("WEBVTT")), bytes.Contains(trimmed, []byte("-->")):
+		return joinCues(parseCues(string(data))), nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return jsonText(data)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return htmlText(data)
+	}
+	return plainText(string(data)), nil
+}
+
+// parseCues reads WebVTT and SRT: the text lines after each timing line.
+// WebVTT voice tags name the speaker.
+func parseCues(src string) []cue {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var cues []cue
+	for _, block := range strings.Split(src, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue // header, NOTE, STYLE and REGION blocks
+		}
+		text := strings.Join(lines[timing+1:], " ")
+		var speaker string
+		if m := voiceRe.FindStringSubmatch(text); m != nil {
+			speaker = strings.TrimSpace(m[1])
+		}
+		text = strings.Join(strings.Fields(tagRe.ReplaceAllString(text, "")), " ")
+		if text != "" {
+			cues = append(cues, cue{speaker: speaker, text: unescapeCue(text)})
+		}
+	}
+	return cues
+}
+
+// unescapeCue decodes the character references WebVTT allows in cue text
+func unescapeCue(text string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "", "&rlm;", "").Replace(text)
+}
+
+// jsonText reads the JSON transcript format of the podcast namespace
+func jsonText(data []byte) (string, error) {
+	var doc struct {
+		Segments []struct {
+			Speaker string `json:"speaker"`
+			Body    string `json:"body"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("invalid JSON transcript: %w", err)
+	}
+	cues := make([]cue, 0, len(doc.Segments))
+	for _, s := range doc.Segments {
+		if text := strings.Join(strings.Fields(s.Body), " "); text != "" {
+			cues = append(cues, cue{speaker: strings.TrimSpace(s.Speaker), text: text})
+		}
+	}
+	return joinCues(cues), nil
+}
+
+// joinCues lays out cues as paragraphs, a new one for each change of
+// speaker
+func joinCues(cues []cue) string {
+	var paras []string
+	var cur strings.Builder
+	speaker := ""
+	flush := func() {
+		if cur.Len() > 0 {
+			paras = append(paras, cur.String())
+			cur.Reset()
+		}
+	}
+	for i, c := range cues {
+		if i > 0 && (c.speaker != speaker || (cur.Len() > maxParagraph && sentenceEnd(cues[i-1].text))) {
+			flush()
+		}
+		if cur.Len() == 0 {
+			if c.speaker != "" {
+				cur.WriteString(c.speaker + ": ")
+			}
+		} else {
+			cur.WriteByte(' ')
+		}
+		cur.WriteString(c.text)
+		speaker = c.speaker
+	}
+	flush()
+	return strings.Join(paras, "\n\n")
+}
+
+func sentenceEnd(text string) bool {
+	text = strings.TrimRight(text, `"')]”’`)
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!")
+}
+
+// htmlText reads an HTML transcript: its paragraphs, or the lines of its
+// body when it has none
+func htmlText(data []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid HTML transcript: %w", err)
+	}
+	doc.Find("script, style, noscript").Remove()
+	var paras []string
+	doc.Find("p").Each(func(_ int, s *goquery.Selection) {
+		if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+			paras = append(paras, text)
+		}
+	})
+	if len(paras) == 0 {
+		return plainText(doc.Find("body").Text()), nil
+	}
+	return strings.Join(paras, "\n\n"), nil
+}
+
+// plainText tidies a plain transcript: paragraphs are kept, lines inside
+// them joined. Without blank lines every line is a paragraph, as
+// transcripts then give one line per speaker turn.
+func plainText(src string) string {
+	src = strings.TrimSpace(strings.ReplaceAll(src, "\r\n", "\n"))
+	sep := "\n\n"
+	if !strings.Contains(src, sep) {
+		sep = "\n"
+	}
+	var paras []string
+	for _, block := range strings.Split(src, sep) {
+		if text := strings.Join(strings.Fields(block), " "); text != "" {
+			paras = append(paras, text)
+		}
+	}
+	return strings.Join(paras, "\n\n")
+}
@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ANSI escape sequences used by RenderANSI
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiCyan      = "\x1b[36m"
+	ansiMagenta   = "\x1b[35m"
+	ansiYellow    = "\x1b[33m"
+)
+
+var (
+	ansiHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ansiListRe    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	ansiOrderedRe = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	ansiRuleRe    = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	ansiImageRe   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+	ansiLinkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]*)[^)]*\)`)
+	ansiBoldRe    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	ansiItalicRe  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+)
+
+// RenderANSI styles markdown for a terminal: headings, emphasis, links, code,
+// quotes and lists. The markdown syntax for styled spans is dropped; links
+// keep their URL, dimmed, after the text.
+func RenderANSI(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	inFrontMatter := len(lines) > 0 && strings.TrimSpace(lines[0]) == "---"
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		// YAML front matter is shown dimmed, as-is
+		if inFrontMatter {
+			out = append(out, ansiDim+line+ansiReset)
+			if i > 0 && trimmed == "---" {
+				inFrontMatter = false
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, "  "+ansiYellow+line+ansiReset)
+			continue
+		}
+
+		switch {
+		case ansiHeadingRe.MatchString(line):
+			m := ansiHeadingRe.FindStringSubmatch(line)
+			style := ansiBold + ansiCyan
+			if len(m[1]) == 1 {
+				style = ansiBold + ansiUnderline + ansiMagenta
+			}
+			out = append(out, style+stripInline(m[2])+ansiReset)
+		case ansiRuleRe.MatchString(line):
+			out = append(out, ansiDim+strings.Repeat("─", 40)+ansiReset)
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimLeft(trimmed, "> "))
+			out = append(out, ansiDim+"│ "+ansiReset+ansiItalic+renderInline(quote)+ansiReset)
+		case ansiListRe.MatchString(line):
+			m := ansiListRe.FindStringSubmatch(line)
+			out = append(out, m[1]+"  • "+renderInline(m[2]))
+		case ansiOrderedRe.MatchString(line):
+			m := ansiOrderedRe.FindStringSubmatch(line)
+			out = append(out, m[1]+"  "+m[2]+" "+renderInline(m[3]))
+		default:
+			out = append(out, renderInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderInline styles the spans of one line, leaving code spans untouched
+func renderInline(line string) string {
+	parts := strings.Split(line, "`")
+	if len(parts)%2 == 0 {
+		// Unbalanced backtick: treat the line as plain text
+		parts = []string{line}
+	}
+	for i, part := range parts {
+		if i%2 == 1 {
+			parts[i] = ansiCyan + part + ansiReset
+			continue
+		}
+		part = ansiImageRe.ReplaceAllStringFunc(part, func(img string) string {
+			if alt := ansiImageRe.FindStringSubmatch(img)[1]; alt != "" {
+				return ansiDim + "[image: " + alt + "]" + ansiReset
+			}
+			return ansiDim + "[image]" + ansiReset
+		})
+		part = ansiLinkRe.ReplaceAllString(part, ansiUnderline+"$1"+ansiReset+" "+ansiDim+"($2)"+ansiReset)
+		part = ansiBoldRe.ReplaceAllString(part, ansiBold+"$1$2"+ansiReset)
+		part = ansiItalicRe.ReplaceAllString(part, ansiItalic+"$1"+ansiReset)
+		parts[i] = part
+	}
+	return strings.Join(parts, "")
+}
+
+// stripInline removes inline markdown from text that is styled as a whole
+func stripInline(text string) string {
+	text = ansiImageRe.ReplaceAllString(text, "$1")
+	text = ansiLinkRe.ReplaceAllString(text, "$1")
+	text = ansiBoldRe.ReplaceAllString(text, "$1$2")
+	text = ansiItalicRe.ReplaceAllString(text, "$1")
+	return strings.ReplaceAll(text, "`", "")
+}
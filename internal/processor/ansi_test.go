@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderANSI(t *testing.T) {
+	md := "# Title\n\nSome **bold** and *italic* with `a*b*c` and [a link](https://example.com).\n\n" +
+		"- item one\n> quoted\n\n```\ncode **stays**\n```\n\n![alt](img.png)"
+	got := RenderANSI(md)
+
+	for _, want := range []string{
+		ansiBold + ansiUnderline + ansiMagenta + "Title" + ansiReset,
+		ansiBold + "bold" + ansiReset,
+		ansiItalic + "italic" + ansiReset,
+		ansiCyan + "a*b*c" + ansiReset,
+		ansiUnderline + "a link" + ansiReset + " " + ansiDim + "(https://example.com)" + ansiReset,
+		"  • item one",
+		"│ ",
+		"code **stays**",
+		"[image: alt]",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%q", want, got)
+		}
+	}
+	if strings.Contains(got, "```") || strings.Contains(got, "# Title") {
+		t.Errorf("markdown syntax left in output:\n%q", got)
+	}
+}
+
+func TestRenderANSIFrontMatter(t *testing.T) {
+	got := RenderANSI("---\ntitle: \"x\"\n---\n\n# Heading")
+	lines := strings.Split(got, "\n")
+	if lines[0] != ansiDim+"---"+ansiReset || lines[2] != ansiDim+"---"+ansiReset {
+		t.Errorf("front matter should be dimmed as-is, got %q", lines[:3])
+	}
+	if !strings.Contains(got, "Heading"+ansiReset) {
+		t.Errorf("heading after front matter not styled: %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/strikethrough"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// MarkdownFlavor selects the markdown dialect of ToMarkdown and friends
+type MarkdownFlavor string
+
+const (
+	// FlavorGFM emits GitHub tables, ~~strikethrough~~, task lists and [^n]
+	// footnotes
+	FlavorGFM MarkdownFlavor = "gfm"
+	// FlavorCommonMark sticks to the CommonMark spec: tables and
+	// strikethrough stay inline HTML, footnotes stay plain links
+	FlavorCommonMark MarkdownFlavor = "commonmark"
+	// FlavorObsidian is GFM plus ==highlights== and [[#Heading]] wiki-links
+	// for links within the article
+	FlavorObsidian MarkdownFlavor = "obsidian"
+)
+
+// MarkdownFlavors lists the accepted flavor names
+var MarkdownFlavors = []MarkdownFlavor{FlavorGFM, FlavorCommonMark, FlavorObsidian}
+
+// ParseMarkdownFlavor validates a flavor name
+func ParseMarkdownFlavor(name string) (MarkdownFlavor, error) {
+	for _, f := range MarkdownFlavors {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown markdown flavor: %s (available: gfm, commonmark, obsidian)", name)
+}
+
+// SetMarkdownFlavor selects the dialect of markdown output
+func (cp *ContentProcessor) SetMarkdownFlavor(flavor MarkdownFlavor) {
+	cp.flavor = flavor
+}
+
+func (cp *ContentProcessor) markdownFlavor() MarkdownFlavor {
+	if cp.flavor == "" {
+		return FlavorGFM
+	}
+	return cp.flavor
+}
+
+// fnrefTag marks a footnote reference between extraction and rendering
+const fnrefTag = "scrpr-fnref"
+
+// convertMarkdown converts article HTML in the selected flavor
+func (cp *ContentProcessor) convertMarkdown(htmlContent string) (string, error) {
+	root, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", err
+	}
+	doc := goquery.NewDocumentFromNode(root)
+	flavor := cp.markdownFlavor()
+
+	var notes []footnote
+	if flavor != FlavorCommonMark {
+		notes = extractFootnotes(doc)
+	}
+	if flavor != FlavorCommonMark {
+		alignTableCells(doc)
+	}
+	var headings map[string]string
+	if flavor == FlavorObsidian {
+		headings = headingTexts(doc)
+	}
+
+	conv := newMarkdownConverter(flavor, headings)
+	out, err := conv.ConvertNode(root)
+	if err != nil {
+		return "", err
+	}
+	if len(notes) == 0 {
+		return string(out), nil
+	}
+
+	var b strings.Builder
+	b.Write(bytes.TrimSpace(out))
+	b.WriteString("\n\n")
+	for _, note := range notes {
+		text, err := conv.ConvertNode(note.body)
+		if err != nil {
+			return "", err
+		}
+		// Continuation lines are indented so they stay part of the note
+		body := strings.ReplaceAll(strings.TrimSpace(string(text)), "\n", "\n    ")
+		fmt.Fprintf(&b, "[^%s]: %s\n", note.label, body)
+	}
+	return b.String(), nil
+}
+
+func newMarkdownConverter(flavor MarkdownFlavor, headings map[string]string) *converter.Converter {
+	plugins := []converter.Plugin{base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()}
+	if flavor != FlavorCommonMark {
+		plugins = append(plugins, newTablePlugin(), strikethrough.NewStrikethroughPlugin())
+	}
+	conv := converter.NewConverter(converter.WithPlugins(plugins...))
+
+	switch flavor {
+	case FlavorCommonMark:
+		conv.Register.RendererFor("table", converter.TagTypeBlock, base.RenderAsHTML, converter.PriorityEarly)
+		for _, tag := range []string{"del", "s", "strike"} {
+			conv.Register.RendererFor(tag, converter.TagTypeInline, base.RenderAsHTML, converter.PriorityEarly)
+		}
+		conv.Register.RendererFor("input", converter.TagTypeInline, renderCheckbox(`\[x\]`, `\[ \]`), converter.PriorityEarly)
+	case FlavorObsidian:
+		conv.Register.RendererFor("mark", converter.TagTypeInline, renderHighlight, converter.PriorityEarly)
+		conv.Register.RendererFor("a", converter.TagTypeInline, renderWikiLink(headings), converter.PriorityEarly)
+		fallthrough
+	default:
+		conv.Register.RendererFor("table", converter.TagTypeBlock, renderComplexTable, converter.PriorityEarly)
+		conv.Register.RendererFor("br", converter.TagTypeInline, renderCellBreak, converter.PriorityEarly)
+		conv.Register.RendererFor("input", converter.TagTypeInline, renderCheckbox("[x]", "[ ]"), converter.PriorityEarly)
+		conv.Register.RendererFor(fnrefTag, converter.TagTypeInline, renderFootnoteRef, converter.PriorityEarly)
+	}
+	return conv
+}
+
+// renderCheckbox writes task list markers for checkbox inputs
+func renderCheckbox(checked, unchecked string) converter.HandleRenderFunc {
+	return func(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+		s := goquery.NewDocumentFromNode(n).Selection
+		if !strings.EqualFold(s.AttrOr("type", ""), "checkbox") {
+			return converter.RenderSuccess // other inputs carry no content
+		}
+		if _, ok := s.Attr("checked"); ok {
+			w.WriteString(checked)
+		} else {
+			w.WriteString(unchecked)
+		}
+		// The label usually follows with its own leading space
+		if next := n.NextSibling; next == nil || next.Type != html.TextNode || !strings.HasPrefix(next.Data, " ") {
+			w.WriteString(" ")
+		}
+		return converter.RenderSuccess
+	}
+}
+
+func renderHighlight(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	var buf bytes.Buffer
+	ctx.RenderChildNodes(ctx, &buf, n)
+	if content := bytes.TrimSpace(buf.Bytes()); len(content) > 0 {
+		w.WriteString("==")
+		w.Write(content)
+		w.WriteString("==")
+	}
+	return converter.RenderSuccess
+}
+
+// renderWikiLink writes links to headings of the article as [[#Heading|text]]
+func renderWikiLink(headings map[string]string) converter.HandleRenderFunc {
+	return func(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+		s := goquery.NewDocumentFromNode(n).Selection
+		id, ok := strings.CutPrefix(s.AttrOr("href", ""), "#")
+		heading := headings[id]
+		if !ok || heading == "" {
+			return converter.RenderTryNext
+		}
+		text := wikiLinkText(s.Text())
+		if text == "" || text == heading {
+			fmt.Fprintf(w, "[[#%s]]", heading)
+		} else {
+			fmt.Fprintf(w, "[[#%s|%s]]", heading, text)
+		}
+		return converter.RenderSuccess
+	}
+}
+
+func renderFootnoteRef(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	label := goquery.NewDocumentFromNode(n).AttrOr("data-label", "")
+	fmt.Fprintf(w, "[^%s]", label)
+	return converter.RenderSuccess
+}
+
+// wikiLinkText removes the characters Obsidian does not allow in links
+func wikiLinkText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimSpace(strings.NewReplacer("[", "", "]", "", "|", "", "#", "", "^", "").Replace(s))
+}
+
+// headingTexts maps the ids of headings (or of anchors inside them) to the
+// heading text
+func headingTexts(doc *goquery.Document) map[string]string {
+	headings := make(map[string]string)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		text := wikiLinkText(s.Text())
+		if text == "" {
+			return
+		}
+		s.Find("[id], [name]").AddSelection(s).Each(func(i int, el *goquery.Selection) {
+			for _, attr := range []string{"id", "name"} {
+				if id := el.AttrOr(attr, ""); id != "" {
+					headings[id] = text
+				}
+			}
+		})
+	})
+	return headings
+}
+
+type footnote struct {
+	label string
+	body  *html.Node
+}
+
+var footnoteLabelRe = regexp.MustCompile(`^\[?\(?([\p{L}\p{N}_-]+)\)?\]?$`)
+
+// extractFootnotes replaces footnote references with fnrefTag markers and
+// detaches the notes they point to, in reference order. A reference is an
+// in-page link that sits in <sup> or is marked as a note reference and
+// targets a list item.
+func extractFootnotes(doc *goquery.Document) []footnote {
+	var notes []footnote
+	var lists []*goquery.Selection
+	labels := make(map[string]string) // note id -> label
+
+	doc.Find("a[href^='#']").Each(func(i int, ref *goquery.Selection) {
+		isRef := ref.Parent().Is("sup") || ref.HasClass("footnote-ref") || ref.AttrOr("role", "") == "doc-noteref"
+		if !isRef {
+			return
+		}
+		id := strings.TrimPrefix(ref.AttrOr("href", ""), "#")
+		note := doc.Find("li[id]").FilterFunction(func(i int, li *goquery.Selection) bool {
+			return li.AttrOr("id", "") == id
+		}).First()
+		if id == "" || note.Length() == 0 {
+			return
+		}
+
+		label, seen := labels[id]
+		if !seen {
+			label = strconv.Itoa(len(notes) + 1)
+			if m := footnoteLabelRe.FindStringSubmatch(strings.TrimSpace(ref.Text())); m != nil {
+				label = m[1]
+			}
+			labels[id] = label
+			lists = append(lists, note.Parent())
+			notes = append(notes, footnote{label: label, body: detachNote(note)})
+		}
+
+		marker := &html.Node{Type: html.ElementNode, Data: fnrefTag, Attr: []html.Attribute{{Key: "data-label", Val: label}}}
+		target := ref
+		if parent := ref.Parent(); parent.Is("sup") && strings.TrimSpace(parent.Text()) == strings.TrimSpace(ref.Text()) {
+			target = parent
+		}
+		target.ReplaceWithNodes(marker)
+	})
+
+	// Drop note lists, and footnote sections around them, that are now empty
+	for _, list := range lists {
+		if list.Children().Length() > 0 {
+			continue
+		}
+		section := list.Parent()
+		list.Remove()
+		isNotes := section.HasClass("footnotes") || section.AttrOr("role", "") == "doc-endnotes"
+		if isNotes && strings.TrimSpace(section.Text()) == "" {
+			section.Remove()
+		}
+	}
+	return notes
+}
+
+// detachNote removes a note's list item and returns its content, without
+// the links back to the reference
+func detachNote(note *goquery.Selection) *html.Node {
+	note.Find("a[href^='#']").Each(func(i int, a *goquery.Selection) {
+		text := strings.TrimSpace(a.Text())
+		if a.HasClass("footnote-backref") || a.AttrOr("role", "") == "doc-backlink" || text == "↩" || text == "↩︎" || text == "^" || text == "↑" {
+			a.Remove()
+		}
+	})
+	body := &html.Node{Type: html.ElementNode, Data: "div"}
+	for _, n := range note.Nodes {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			n.RemoveChild(c)
+			body.AppendChild(c)
+			c = next
+		}
+	}
+	note.Remove()
+	return body
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+const flavorHTML = `<h2 id="setup">Setup</h2>
+<p>Read <a href="#setup">the setup</a> first.<sup><a href="#fn1" id="ref1">1</a></sup> This is <del>old</del> and <mark>important</mark>.</p>
+<ul><li><input type="checkbox" checked> done</li><li><input type="checkbox"> todo</li></ul>
+<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>
+<section class="footnotes"><ol><li id="fn1"><p>The note. <a href="#ref1" class="footnote-backref">↩</a></p></li></ol></section>`
+
+func flavorMarkdown(t *testing.T, flavor MarkdownFlavor) string {
+	t.Helper()
+	cp := NewContentProcessor()
+	cp.SetMarkdownFlavor(flavor)
+	md, err := cp.convertMarkdown(flavorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestMarkdownFlavors(t *testing.T) {
+	tests := []struct {
+		flavor  MarkdownFlavor
+		want    []string
+		notWant []string
+	}{
+		{
+			flavor: FlavorGFM,
+			want: []string{
+				"[the setup](#setup)", "first.[^1]", "~~old~~", "- [x] done", "- [ ] todo",
+				"| A | B |", "[^1]: The note.",
+			},
+			notWant: []string{"↩", "==important=="},
+		},
+		{
+			flavor:  FlavorCommonMark,
+			want:    []string{"<del>old</del>", `\[x\] done`, "<table>", "[1](#fn1)"},
+			notWant: []string{"~~", "[^1]", "| A |"},
+		},
+		{
+			flavor:  FlavorObsidian,
+			want:    []string{"[[#Setup|the setup]]", "==important==", "~~old~~", "[^1]: The note.", "- [x] done"},
+			notWant: []string{"(#setup)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.flavor), func(t *testing.T) {
+			md := flavorMarkdown(t, tt.flavor)
+			for _, s := range tt.want {
+				if !strings.Contains(md, s) {
+					t.Errorf("missing %q in:\n%s", s, md)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(md, s) {
+					t.Errorf("unexpected %q in:\n%s", s, md)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMarkdownFlavor(t *testing.T) {
+	if f, err := ParseMarkdownFlavor("Obsidian"); err != nil || f != FlavorObsidian {
+		t.Errorf("got %q, %v", f, err)
+	}
+	if _, err := ParseMarkdownFlavor("mdx"); err == nil {
+		t.Error("expected an error for an unknown flavor")
+	}
+}
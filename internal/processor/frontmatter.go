@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FrontMatter holds the fields of a markdown YAML front matter block
+type FrontMatter struct {
+	Title  string
+	URL    string
+	Author string
+	Date   string
+	Tags   []string
+}
+
+// NewFrontMatter collects front matter fields from processed content. Tags
+// come from the page's keywords meta tag.
+func NewFrontMatter(content *ProcessedContent, pageURL string) FrontMatter {
+	fm := FrontMatter{
+		Title:  content.Title,
+		URL:    pageURL,
+		Author: content.Author,
+		Date:   content.Metadata["date"],
+	}
+	if fm.Title == "" {
+		fm.Title = content.Metadata["title"]
+	}
+	if fm.Author == "" {
+		fm.Author = content.Metadata["author"]
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(content.Metadata["keywords"], ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			fm.Tags = append(fm.Tags, tag)
+		}
+	}
+	return fm
+}
+
+// String renders the block, delimiters included. Empty fields are omitted;
+// values are double-quoted so titles with colons or quotes stay valid YAML.
+func (fm FrontMatter) String() string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, field := range []struct{ key, value string }{
+		{"title", fm.Title},
+		{"url", fm.URL},
+		{"author", fm.Author},
+		{"date", fm.Date},
+	} {
+		if field.value != "" {
+			b.WriteString(field.key + ": " + yamlString(field.value) + "\n")
+		}
+	}
+	if len(fm.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range fm.Tags {
+			b.WriteString("  - " + yamlString(tag) + "\n")
+		}
+	}
+	b.WriteString("---\n")
+	return b.String()
+}
+
+// yamlString quotes s as a YAML double-quoted scalar. Go's escape sequences
+// are a subset of YAML's.
+func yamlString(s string) string {
+	return strconv.Quote(strings.TrimSpace(s))
+}
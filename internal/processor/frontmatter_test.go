@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrontMatterString(t *testing.T) {
+	fm := NewFrontMatter(&ProcessedContent{
+		Title: `Go: "Generics" explained`,
+		Metadata: map[string]string{
+			"author":   "Jane Doe",
+			"date":     "2024-03-01T10:00:00Z",
+			"keywords": "go, generics,Go, ",
+		},
+	}, "https://example.com/post")
+
+	want := `---
+title: "Go: \"Generics\" explained"
+url: "https://example.com/post"
+author: "Jane Doe"
+date: "2024-03-01T10:00:00Z"
+tags:
+  - "go"
+  - "generics"
+---
+`
+	if got := fm.String(); got != want {
+		t.Errorf("unexpected front matter:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestToMarkdownWithFrontMatter(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>Test Article</title>
+<meta name="author" content="Jane Doe"></head>
+<body><article><h1>Test Article</h1>
+<p>This is the first paragraph of body content that should appear.</p>
+<p>Here is a second paragraph with more information about the topic.</p>
+<p>And a third paragraph to make sure readability picks it up as real content and not boilerplate noise here.</p>
+</article></body></html>`
+
+	cp := NewContentProcessor()
+	p, err := cp.ProcessFromReader(strings.NewReader(html), "http://example.com/", ProcessOptions{
+		IncludeMetadata: true,
+		MetadataFields:  []string{"title", "author", "date", "keywords"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := cp.ToMarkdownWithFrontMatter(p, "http://example.com/", true)
+	if !strings.HasPrefix(md, "---\ntitle: \"Test Article\"\n") {
+		t.Errorf("expected front matter first:\n%s", md)
+	}
+	if strings.Contains(md, "**Author:**") || strings.Contains(md, "# Test Article\n\n") {
+		t.Errorf("front matter output should not repeat title/author lines:\n%s", md)
+	}
+	if !strings.Contains(md, "first paragraph of body content") {
+		t.Errorf("markdown missing body content:\n%s", md)
+	}
+}
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	nurl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// heroJunk are path words of images that are page chrome, not content
+var heroJunk = map[string]bool{
+	"logo": true, "icon": true, "favicon": true, "avatar": true, "sprite": true,
+	"pixel": true, "spacer": true, "badge": true, "emoji": true, "tracking": true, "ad": true,
+}
+
+// Scores stand in for pixel area. An og:image counts as a 1024x768 lead
+// image, so it only loses to clearly larger in-content images; an image
+// without declared dimensions counts as a modest one
+const (
+	heroSocialScore  = 1024 * 768
+	heroUnknownScore = 300 * 200
+	heroMinSide      = 150
+)
+
+// selectHeroImage picks the one image that best represents the article: the
+// og:image/twitter:image or the largest well-proportioned image in the
+// article body, skipping logos, icons and tracking pixels. The result is
+// resolved against pageURL; empty when nothing qualifies.
+func selectHeroImage(page, article *goquery.Document, pageURL *nurl.URL) string {
+	best, bestScore := "", 0.0
+	consider := func(src string, score float64) {
+		src = strings.TrimSpace(src)
+		if src == "" || strings.HasPrefix(src, "data:") || isJunkImage(src) {
+			return
+		}
+		if score > bestScore {
+			best, bestScore = src, score
+		}
+	}
+
+	for _, sel := range []string{"meta[property='og:image']", "meta[name='og:image']", "meta[name='twitter:image']", "meta[property='twitter:image']"} {
+		if src := page.Find(sel).AttrOr("content", ""); src != "" {
+			consider(src, heroSocialScore)
+			break
+		}
+	}
+
+	if article != nil {
+		article.Find("img").Each(func(i int, s *goquery.Selection) {
+			src := s.AttrOr("src", "")
+			if src == "" || strings.HasPrefix(src, "data:") {
+				src = s.AttrOr("data-src", "")
+			}
+			w, _ := strconv.Atoi(strings.TrimSuffix(s.AttrOr("width", ""), "px"))
+			h, _ := strconv.Atoi(strings.TrimSuffix(s.AttrOr("height", ""), "px"))
+
+			score := float64(heroUnknownScore)
+			if w > 0 && h > 0 {
+				if w < heroMinSide || h < heroMinSide {
+					return
+				}
+				score = float64(w*h) * aspectFactor(float64(w)/float64(h))
+			}
+			// The first image is usually the lead image
+			if i == 0 {
+				score *= 1.2
+			}
+			consider(src, score)
+		})
+	}
+
+	if best == "" || pageURL == nil {
+		return best
+	}
+	if ref, err := nurl.Parse(best); err == nil {
+		return pageURL.ResolveReference(ref).String()
+	}
+	return best
+}
+
+// aspectFactor favours landscape images that crop well into previews
+func aspectFactor(ratio float64) float64 {
+	switch {
+	case ratio >= 1.0 && ratio <= 2.5:
+		return 1
+	case ratio < 0.5 || ratio > 4:
+		return 0.2
+	default:
+		return 0.6
+	}
+}
+
+// isJunkImage matches whole words of the image path, so "logo-small.png"
+// is junk but "silicon.jpg" is not
+func isJunkImage(src string) bool {
+	lower := strings.ToLower(src)
+	if u, err := nurl.Parse(lower); err == nil {
+		lower = u.Path
+	}
+	if strings.HasSuffix(lower, ".svg") || strings.HasSuffix(lower, ".ico") {
+		return true
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	for _, word := range words {
+		if heroJunk[strings.TrimSuffix(word, "s")] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	nurl "net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func heroFor(t *testing.T, head, body string) string {
+	t.Helper()
+	page, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + head + "</head><body>" + body + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageURL, _ := nurl.Parse("https://example.com/posts/one")
+	return selectHeroImage(page, page, pageURL)
+}
+
+func TestSelectHeroImage(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		body string
+		want string
+	}{
+		{
+			name: "og image",
+			head: `<meta property="og:image" content="/img/lead.jpg">`,
+			body: `<img src="small.jpg" width="300" height="200">`,
+			want: "https://example.com/img/lead.jpg",
+		},
+		{
+			name: "largest in-content image",
+			body: `<img src="a.jpg" width="400" height="300"><img src="b.jpg" width="1200" height="800">`,
+			want: "https://example.com/posts/b.jpg",
+		},
+		{
+			name: "tall image loses to landscape",
+			body: `<img src="tall.jpg" width="400" height="2000"><img src="wide.jpg" width="800" height="450">`,
+			want: "https://example.com/posts/wide.jpg",
+		},
+		{
+			name: "junk skipped",
+			head: `<meta property="og:image" content="/static/site-logo.png">`,
+			body: `<img src="/pixel.gif" width="1" height="1"><img src="/photos/silicon.jpg">`,
+			want: "https://example.com/photos/silicon.jpg",
+		},
+		{
+			name: "nothing qualifies",
+			body: `<img src="/icons/share.svg"><img src="data:image/png;base64,AAAA">`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heroFor(t, tt.head, tt.body); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package processor
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+//go:embed junkrules.json
+var embeddedJunkRules []byte
+
+// junkGroups is the built-in ruleset, shipped with the binary
+var junkGroups = mustParseJunkRules(embeddedJunkRules)
+
+// maxJunkTextLen bounds the blocks text patterns may remove, so a pattern
+// can only take out a short block, never a section of the article
+const maxJunkTextLen = 200
+
+// junkTextCandidates are the elements text patterns are matched against,
+// outermost first
+const junkTextCandidates = "aside, section, form, div, p, li, h2, h3, h4, h5, h6"
+
+type junkRuleFile struct {
+	Updated string                   `json:"updated"`
+	Groups  map[string]junkRuleGroup `json:"groups"`
+}
+
+type junkRuleGroup struct {
+	Selectors []string `json:"selectors"`
+	Text      []string `json:"text"`
+}
+
+func mustParseJunkRules(data []byte) map[string]junkRuleGroup {
+	var rules junkRuleFile
+	if err := json.Unmarshal(data, &rules); err != nil {
+		panic(fmt.Sprintf("invalid embedded junk rules: %v", err))
+	}
+	return rules.Groups
+}
+
+// JunkGroups lists the built-in rule groups
+func JunkGroups() []string {
+	groups := make([]string, 0, len(junkGroups))
+	for name := range junkGroups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// JunkOptions configures a JunkFilter. Groups selects built-in rule groups
+// (empty = all); Selectors and Text add rules of your own.
+type JunkOptions struct {
+	Groups    []string
+	Selectors []string
+	Text      []string
+	Sites     []JunkSite
+}
+
+// JunkSite overrides the rules for a host and its subdomains
+type JunkSite struct {
+	Host      string
+	Disabled  bool     // no junk filtering on this site
+	Groups    []string // replaces the global groups when set
+	Selectors []string // added to the global rules
+	Text      []string
+	Keep      []string // selectors that are never removed
+}
+
+// JunkFilter removes newsletter signups, cookie notices, share bars and
+// related-post widgets from a page before readability sees it
+type JunkFilter struct {
+	global *junkRules
+	sites  map[string]*junkRules // by lowercased host; nil = disabled
+}
+
+type junkRules struct {
+	selectors []goquery.Matcher
+	text      []*regexp.Regexp
+	keep      []goquery.Matcher
+}
+
+// NewJunkFilter compiles the rules, reporting unknown groups, invalid
+// selectors and invalid patterns
+func NewJunkFilter(opts JunkOptions) (*JunkFilter, error) {
+	global, err := compileJunkRules(opts.Groups, opts.Selectors, opts.Text, nil)
+	if err != nil {
+		return nil, err
+	}
+	f := &JunkFilter{global: global, sites: make(map[string]*junkRules)}
+
+	for _, site := range opts.Sites {
+		host := strings.TrimPrefix(strings.ToLower(site.Host), "www.")
+		if host == "" {
+			return nil, fmt.Errorf("junk site rule without host")
+		}
+		if site.Disabled {
+			f.sites[host] = nil
+			continue
+		}
+		groups := opts.Groups
+		if site.Groups != nil {
+			groups = site.Groups
+		}
+		rules, err := compileJunkRules(groups, slices.Concat(opts.Selectors, site.Selectors), slices.Concat(opts.Text, site.Text), site.Keep)
+		if err != nil {
+			return nil, fmt.Errorf("junk rules for %s: %w", site.Host, err)
+		}
+		f.sites[host] = rules
+	}
+	return f, nil
+}
+
+func compileJunkRules(groups, selectors, text, keep []string) (*junkRules, error) {
+	if len(groups) == 0 {
+		groups = JunkGroups()
+	}
+	for _, name := range groups {
+		group, ok := junkGroups[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown junk rule group: %s (available: %s)", name, strings.Join(JunkGroups(), ", "))
+		}
+		selectors = append(slices.Clip(selectors), group.Selectors...)
+		text = append(slices.Clip(text), group.Text...)
+	}
+
+	rules := &junkRules{}
+	for _, sel := range selectors {
+		m, err := cascadia.Compile(sel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid junk selector %q: %w", sel, err)
+		}
+		rules.selectors = append(rules.selectors, m)
+	}
+	for _, pattern := range text {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid junk text pattern %q: %w", pattern, err)
+		}
+		rules.text = append(rules.text, re)
+	}
+	for _, sel := range keep {
+		m, err := cascadia.Compile(sel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep selector %q: %w", sel, err)
+		}
+		rules.keep = append(rules.keep, m)
+	}
+	return rules, nil
+}
+
+// rulesFor returns the rules of the most specific site entry matching host
+func (f *JunkFilter) rulesFor(host string) *junkRules {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for host != "" {
+		if rules, ok := f.sites[host]; ok {
+			return rules
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return f.global
+}
+
+// apply removes junk from the page body in place and returns the number of
+// elements removed
+func (f *JunkFilter) apply(doc *goquery.Document, host string) int {
+	rules := f.rulesFor(host)
+	if rules == nil {
+		return 0
+	}
+	body := doc.Find("body")
+	removed := 0
+	remove := func(s *goquery.Selection) {
+		// Skip elements already removed with an ancestor
+		if s.Closest("body").Length() == 0 || rules.kept(s) {
+			return
+		}
+		s.Remove()
+		removed++
+	}
+
+	for _, m := range rules.selectors {
+		body.FindMatcher(m).Each(func(i int, s *goquery.Selection) { remove(s) })
+	}
+
+	if len(rules.text) > 0 {
+		body.Find(junkTextCandidates).Each(func(i int, s *goquery.Selection) {
+			text := strings.Join(strings.Fields(s.Text()), " ")
+			if text == "" || len(text) > maxJunkTextLen || !rules.matchesText(text) {
+				return
+			}
+			// A matching heading takes the list it introduces with it
+			if s.Is("h2, h3, h4, h5, h6") {
+				if next := s.Next(); next.Is("ul, ol") {
+					remove(next)
+				}
+			}
+			remove(s)
+		})
+	}
+	return removed
+}
+
+func (r *junkRules) matchesText(text string) bool {
+	for _, re := range r.text {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// kept reports whether s is, contains or sits inside a keep element
+func (r *junkRules) kept(s *goquery.Selection) bool {
+	for _, m := range r.keep {
+		if s.IsMatcher(m) || s.FindMatcher(m).Length() > 0 || s.ParentsMatcher(m).Length() > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const junkPage = `<html><body><article>
+<h1>Parsing HTML</h1>
+<p>HTML parsing is harder than it looks, and this article walks through why that is.</p>
+<div class="newsletter-signup-box"><p>Get the best articles in your inbox.</p></div>
+<p>Subscribe to our weekly newsletter for more.</p>
+<div class="share-buttons"><a href="#">Tweet</a></div>
+<p>Tokenizers handle malformed markup; we talk about cookies and why the site uses them below.</p>
+<h3>Related posts</h3>
+<ul><li><a href="/a">Another post</a></li></ul>
+<div class="related-posts keep-me"><a href="/b">Kept on example.org</a></div>
+</article></body></html>`
+
+func applyJunk(t *testing.T, opts JunkOptions, host string) string {
+	t.Helper()
+	f, err := NewJunkFilter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(junkPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.apply(doc, host)
+	return doc.Find("body").Text()
+}
+
+func TestJunkFilter(t *testing.T) {
+	text := applyJunk(t, JunkOptions{}, "example.com")
+	for _, gone := range []string{"best articles", "weekly newsletter", "Tweet", "Related posts", "Another post", "Kept on"} {
+		if strings.Contains(text, gone) {
+			t.Errorf("junk %q not removed:\n%s", gone, text)
+		}
+	}
+	for _, kept := range []string{"HTML parsing is harder", "Tokenizers handle malformed markup"} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("content %q removed:\n%s", kept, text)
+		}
+	}
+}
+
+func TestJunkFilterSites(t *testing.T) {
+	opts := JunkOptions{Sites: []JunkSite{
+		{Host: "example.org", Keep: []string{".keep-me"}},
+		{Host: "www.example.net", Disabled: true},
+		{Host: "example.io", Groups: []string{"share"}},
+	}}
+
+	if text := applyJunk(t, opts, "blog.example.org"); !strings.Contains(text, "Kept on example.org") {
+		t.Errorf("keep selector ignored:\n%s", text)
+	}
+	if text := applyJunk(t, opts, "example.net"); !strings.Contains(text, "weekly newsletter") {
+		t.Errorf("disabled site was filtered:\n%s", text)
+	}
+	text := applyJunk(t, opts, "example.io")
+	if strings.Contains(text, "Tweet") || !strings.Contains(text, "weekly newsletter") {
+		t.Errorf("site groups not applied:\n%s", text)
+	}
+}
+
+func TestNewJunkFilterErrors(t *testing.T) {
+	for _, opts := range []JunkOptions{
+		{Groups: []string{"popups"}},
+		{Selectors: []string{"div[class="}},
+		{Text: []string{"(unclosed"}},
+		{Sites: []JunkSite{{Keep: []string{"p"}}}},
+	} {
+		if _, err := NewJunkFilter(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package processor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// languageRules tune the text cleanup for a language: when a line break
+// inside a paragraph ends a sentence, and how broken lines are rejoined
+type languageRules struct {
+	sentenceEnds  string // characters that end a sentence or clause
+	closingQuotes string // may follow a sentence end, as in `."` or `.«`
+	openingQuotes string // may precede the first letter of a sentence
+	capitalNouns  bool   // a capital letter does not start a sentence (German nouns)
+	dropHyphens   bool   // a word hyphenated across a line break loses the hyphen
+	noSpaces      bool   // words are not separated by spaces (Japanese, Chinese)
+}
+
+var defaultLanguageRules = languageRules{
+	sentenceEnds:  ".!?:;",
+	closingQuotes: "\"'”’)",
+	openingQuotes: "\"'“‘(",
+}
+
+var languages = map[string]languageRules{
+	"en": defaultLanguageRules,
+	"de": {
+		sentenceEnds:  ".!?:;",
+		closingQuotes: "\"'“‘»«›‹)",
+		openingQuotes: "\"'„‚»«›‹(",
+		capitalNouns:  true,
+		dropHyphens:   true,
+	},
+	"fr": {
+		sentenceEnds:  ".!?:;…",
+		closingQuotes: "\"'»”’)",
+		openingQuotes: "\"'«“‘(",
+	},
+	"ja": {
+		sentenceEnds:  "。！？!?：:",
+		closingQuotes: "」』）)\"”",
+		openingQuotes: "「『（(\"“",
+		noSpaces:      true,
+	},
+	"zh": {
+		sentenceEnds:  "。！？!?：:；;",
+		closingQuotes: "”’」』）)\"",
+		openingQuotes: "“‘「『（(\"",
+		noSpaces:      true,
+	},
+}
+
+// Languages lists the languages with their own text rules; others use the
+// English ones
+func Languages() []string {
+	return []string{"de", "en", "fr", "ja", "zh"}
+}
+
+// rulesFor returns the rules for a language tag such as "de-AT"
+func rulesFor(lang string) languageRules {
+	if rules, ok := languages[primaryLanguage(lang)]; ok {
+		return rules
+	}
+	return defaultLanguageRules
+}
+
+func primaryLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// languageStopwords are frequent short words that tell the languages with
+// their own rules apart in Latin script
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "that", "with"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une"},
+}
+
+// DetectLanguage returns the primary language of an article: the declared
+// one (<html lang>) when present, else a guess from the script and common
+// words of the text, or "" when the text gives no clear answer
+func DetectLanguage(declared, text string) string {
+	if lang := primaryLanguage(declared); lang != "" {
+		return lang
+	}
+
+	// Kana only occurs in Japanese; Han without it is most likely Chinese
+	var kana, han, letters int
+	for i, r := range text {
+		if i > 4000 {
+			break
+		}
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	switch {
+	case kana > 10:
+		return "ja"
+	case han > 20 && han > letters:
+		return "zh"
+	}
+
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, word := range words[:min(len(words), 1000)] {
+		for lang, stopwords := range languageStopwords {
+			for _, sw := range stopwords {
+				if word == sw {
+					counts[lang]++
+				}
+			}
+		}
+	}
+	best, bestCount, total := "", 0, 0
+	for _, lang := range []string{"en", "de", "fr"} {
+		total += counts[lang]
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	// Require a few hits and a clear majority
+	if bestCount < 3 || bestCount*2 <= total {
+		return ""
+	}
+	return best
+}
+
+// languageOf returns the language content was processed for, detecting it
+// for content that did not go through ProcessFromReader (API backends)
+func languageOf(content *ProcessedContent) string {
+	if content.Language != "" {
+		return content.Language
+	}
+	return DetectLanguage("", content.TextContent)
+}
+
+// shortLine is the length below which a line that is followed by a capital
+// counts as a heading or label in languages that capitalize nouns
+const shortLine = 40
+
+// continues reports whether a line continues the sentence of the previous
+// one, so the break between them only comes from the page's source layout
+func (r languageRules) continues(prev, line string) bool {
+	if r.endsSentence(prev) || r.startsSentence(line) {
+		return false
+	}
+	if r.capitalNouns && utf8.RuneCountInString(prev) < shortLine && !hyphenated(prev) {
+		first, _ := utf8.DecodeRuneInString(line)
+		return !unicode.IsUpper(first)
+	}
+	return true
+}
+
+// endsSentence reports whether a line ends a sentence, looking past closing
+// quotes and brackets
+func (r languageRules) endsSentence(line string) bool {
+	line = strings.TrimRightFunc(line, func(c rune) bool {
+		return strings.ContainsRune(r.closingQuotes, c) || unicode.IsSpace(c)
+	})
+	last, _ := utf8.DecodeLastRuneInString(line)
+	return last != utf8.RuneError && strings.ContainsRune(r.sentenceEnds, last)
+}
+
+// startsSentence reports whether a line starts a new sentence or list item
+func (r languageRules) startsSentence(line string) bool {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "• ") {
+		return true
+	}
+	first, _ := utf8.DecodeRuneInString(strings.TrimLeftFunc(line, func(c rune) bool {
+		return strings.ContainsRune(r.openingQuotes, c)
+	}))
+	switch {
+	case first >= '0' && first <= '9':
+		return true
+	case r.capitalNouns || r.noSpaces:
+		// Capitals start nouns too, and CJK scripts have none
+		return false
+	}
+	return unicode.IsUpper(first)
+}
+
+// join appends a broken line to the previous one, rejoining a word
+// hyphenated across the break
+func (r languageRules) join(prev, line string) string {
+	if hyphenated(prev) {
+		if next, _ := utf8.DecodeRuneInString(line); unicode.IsLetter(next) {
+			if r.dropHyphens && unicode.IsLower(next) {
+				return prev[:len(prev)-1] + line
+			}
+			return prev + line
+		}
+	}
+	if r.noSpaces {
+		return prev + line
+	}
+	return prev + " " + line
+}
+
+// hyphenated reports whether a line ends in a word broken with a hyphen
+func hyphenated(line string) bool {
+	word, ok := strings.CutSuffix(line, "-")
+	before, _ := utf8.DecodeLastRuneInString(word)
+	return ok && unicode.IsLetter(before)
+}
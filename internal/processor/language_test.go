@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanNewlinesLang(t *testing.T) {
+	cp := NewContentProcessor()
+	tests := []struct {
+		name, lang, in, want string
+	}{
+		{
+			name: "english capital starts a sentence",
+			lang: "en",
+			in:   "The committee met on Tuesday and\nMembers voted later.",
+			want: "The committee met on Tuesday and\nMembers voted later.",
+		},
+		{
+			name: "german nouns continue the sentence",
+			lang: "de",
+			in:   "Am Dienstag hat der Ausschuss nach langer Beratung über den\nHaushalt abgestimmt.",
+			want: "Am Dienstag hat der Ausschuss nach langer Beratung über den Haushalt abgestimmt.",
+		},
+		{
+			name: "german short line stays a heading",
+			lang: "de",
+			in:   "Haushalt 2026\nDer Ausschuss hat abgestimmt.",
+			want: "Haushalt 2026\nDer Ausschuss hat abgestimmt.",
+		},
+		{
+			name: "german hyphenation is undone",
+			lang: "de-AT",
+			in:   "Der Verkehrs-\nminister sprach.",
+			want: "Der Verkehrsminister sprach.",
+		},
+		{
+			name: "german compound keeps its hyphen",
+			lang: "de",
+			in:   "Die Nord-\nSüd-Verbindung",
+			want: "Die Nord-Süd-Verbindung",
+		},
+		{
+			name: "english hyphenated word",
+			lang: "",
+			in:   "a well-\nknown fact",
+			want: "a well-known fact",
+		},
+		{
+			name: "french quote after sentence end",
+			lang: "fr",
+			in:   "Il a dit « c'est fini. »\nle lendemain tout a recommencé",
+			want: "Il a dit « c'est fini. »\nle lendemain tout a recommencé",
+		},
+		{
+			name: "japanese joins without space",
+			lang: "ja",
+			in:   "東京で会議が\n開かれました。\n翌日も続いた。",
+			want: "東京で会議が開かれました。\n翌日も続いた。",
+		},
+		{
+			name: "soft hyphens are removed",
+			lang: "en",
+			in:   "extra­ordinary",
+			want: "extraordinary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cp.CleanNewlinesLang(tt.in, tt.lang); got != tt.want {
+				t.Errorf("CleanNewlinesLang(%q, %q) = %q, want %q", tt.in, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		declared, text, want string
+	}{
+		{"de-DE", "The text does not matter when the page declares it.", "de"},
+		{"", "Der Hund und die Katze sind nicht mit dem Auto gefahren, das ist klar.", "de"},
+		{"", "Le chat et la souris sont dans les jardins, une histoire des voisins.", "fr"},
+		{"", "The cat and the dog went to the park with a friend that is kind.", "en"},
+		{"", strings.Repeat("これは日本語の文章です。", 3), "ja"},
+		{"", strings.Repeat("这是一个中文句子。", 4), "zh"},
+		{"", "Short text.", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.declared, tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q, %q) = %q, want %q", tt.declared, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestProcessDetectsLanguage(t *testing.T) {
+	page := `<html lang="de"><head><title>Haushalt</title></head><body><article>
+<p>Am Dienstag hat der Ausschuss nach langer und kontroverser Beratung über den
+Haushalt des kommenden Jahres abgestimmt und ihn mit knapper Mehrheit angenommen.</p>
+<p>Die Opposition kündigte an, gegen den Beschluss vor Gericht zu ziehen, weil
+Fristen nicht eingehalten worden seien und die Unterlagen zu spät kamen.</p>
+</article></body></html>`
+	cp := NewContentProcessor()
+	p, err := cp.ProcessFromReader(strings.NewReader(page), "https://example.de/", ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Language != "de" {
+		t.Errorf("Language = %q, want de", p.Language)
+	}
+	if !strings.Contains(p.TextContent, "über den Haushalt") {
+		t.Errorf("German line break not joined:\n%s", p.TextContent)
+	}
+}
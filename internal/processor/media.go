@@ -0,0 +1,219 @@
+package processor
+
+import (
+	nurl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Media is an audio or video embed found in the article
+type Media struct {
+	Type     string // video or audio
+	URL      string // the page to watch or listen on, not the player
+	Title    string
+	Provider string // youtube, vimeo, spotify, ...; empty for <video> and <audio>
+}
+
+// mediaPlayer maps an embedded player host to its provider and media type
+type mediaPlayer struct {
+	provider string
+	kind     string
+}
+
+var mediaPlayers = map[string]mediaPlayer{
+	"youtube.com":              {"youtube", "video"},
+	"youtube-nocookie.com":     {"youtube", "video"},
+	"player.vimeo.com":         {"vimeo", "video"},
+	"dailymotion.com":          {"dailymotion", "video"},
+	"player.twitch.tv":         {"twitch", "video"},
+	"fast.wistia.net":          {"wistia", "video"},
+	"open.spotify.com":         {"spotify", "audio"},
+	"w.soundcloud.com":         {"soundcloud", "audio"},
+	"embed.podcasts.apple.com": {"apple-podcasts", "audio"},
+	"bandcamp.com":             {"bandcamp", "audio"},
+	"player.simplecast.com":    {"simplecast", "audio"},
+	"anchor.fm":                {"anchor", "audio"},
+	"buzzsprout.com":           {"buzzsprout", "audio"},
+	"megaphone.fm":             {"megaphone", "audio"},
+	"podbean.com":              {"podbean", "audio"},
+	"libsyn.com":               {"libsyn", "audio"},
+	"omny.fm":                  {"omny", "audio"},
+	"art19.com":                {"art19", "audio"},
+	"share.transistor.fm":      {"transistor", "audio"},
+	"iframe.mediadelivery.net": {"bunny", "video"},
+}
+
+// providerNames label media without a title
+var providerNames = map[string]string{
+	"youtube":        "YouTube",
+	"vimeo":          "Vimeo",
+	"dailymotion":    "Dailymotion",
+	"twitch":         "Twitch",
+	"spotify":        "Spotify",
+	"soundcloud":     "SoundCloud",
+	"apple-podcasts": "Apple Podcasts",
+}
+
+// replaceMediaEmbeds swaps every recognised player iframe and <video>/<audio>
+// element below root for a paragraph linking to the media, so the embed
+// survives readability and markdown conversion, which drop players. It
+// returns the embeds in document order.
+func replaceMediaEmbeds(root *html.Node, pageURL *nurl.URL) []Media {
+	var media []Media
+	doc := goquery.NewDocumentFromNode(root)
+	doc.Find("iframe, video, audio").Each(func(i int, s *goquery.Selection) {
+		// Players nested in a recognised element were already replaced
+		if s.ParentsFiltered("video, audio").Length() > 0 {
+			return
+		}
+		m, ok := embedMedia(s, pageURL)
+		if !ok {
+			return
+		}
+		media = append(media, m)
+
+		label := "Video"
+		if m.Type == "audio" {
+			label = "Audio"
+		}
+		link := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: "href", Val: m.URL}}}
+		link.AppendChild(&html.Node{Type: html.TextNode, Data: label + ": " + m.Title})
+		para := &html.Node{Type: html.ElementNode, Data: "p"}
+		para.AppendChild(link)
+		s.ReplaceWithNodes(para)
+	})
+	return media
+}
+
+// embedMedia describes one embed element; false for iframes that are not
+// known players (ads, widgets, forms)
+func embedMedia(s *goquery.Selection, pageURL *nurl.URL) (Media, bool) {
+	title := strings.TrimSpace(s.AttrOr("title", s.AttrOr("aria-label", "")))
+
+	if goquery.NodeName(s) != "iframe" {
+		src := s.AttrOr("src", "")
+		if src == "" {
+			src = s.Find("source[src]").First().AttrOr("src", "")
+		}
+		u := resolveMediaURL(src, pageURL)
+		if u == nil {
+			return Media{}, false
+		}
+		m := Media{Type: goquery.NodeName(s), URL: u.String(), Title: title}
+		if m.Title == "" {
+			m.Title = mediaFileName(u)
+		}
+		return m, true
+	}
+
+	u := resolveMediaURL(s.AttrOr("src", s.AttrOr("data-src", "")), pageURL)
+	if u == nil {
+		return Media{}, false
+	}
+	player, ok := lookupPlayer(u.Hostname())
+	if !ok {
+		return Media{}, false
+	}
+	m := Media{Type: player.kind, URL: canonicalMediaURL(player.provider, u), Title: title, Provider: player.provider}
+	if m.Title == "" {
+		m.Title = providerNames[player.provider]
+		if m.Title == "" {
+			m.Title = u.Hostname()
+		}
+		m.Title += " " + m.Type
+	}
+	return m, true
+}
+
+// lookupPlayer matches host and its parent domains against mediaPlayers
+func lookupPlayer(host string) (mediaPlayer, bool) {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for host != "" {
+		if p, ok := mediaPlayers[host]; ok {
+			return p, true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found || !strings.Contains(parent, ".") {
+			break
+		}
+		host = parent
+	}
+	return mediaPlayer{}, false
+}
+
+// canonicalMediaURL turns a player URL into the page people share
+func canonicalMediaURL(provider string, u *nurl.URL) string {
+	path := strings.Trim(u.Path, "/")
+	switch provider {
+	case "youtube":
+		if id, ok := strings.CutPrefix(path, "embed/"); ok && id != "" && id != "videoseries" {
+			return "https://www.youtube.com/watch?v=" + id
+		}
+		if list := u.Query().Get("list"); list != "" {
+			return "https://www.youtube.com/playlist?list=" + list
+		}
+	case "vimeo":
+		if id, ok := strings.CutPrefix(path, "video/"); ok && id != "" {
+			return "https://vimeo.com/" + id
+		}
+	case "dailymotion":
+		if id, ok := strings.CutPrefix(path, "embed/video/"); ok && id != "" {
+			return "https://www.dailymotion.com/video/" + id
+		}
+	case "spotify":
+		if rest, ok := strings.CutPrefix(path, "embed/"); ok {
+			return "https://open.spotify.com/" + rest
+		}
+	case "soundcloud":
+		if track := u.Query().Get("url"); track != "" {
+			return track
+		}
+	case "apple-podcasts":
+		return "https://podcasts.apple.com" + u.Path
+	}
+	return u.String()
+}
+
+func resolveMediaURL(src string, pageURL *nurl.URL) *nurl.URL {
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "blob:") {
+		return nil
+	}
+	u, err := nurl.Parse(src)
+	if err != nil {
+		return nil
+	}
+	if pageURL != nil {
+		u = pageURL.ResolveReference(u)
+	} else if u.Scheme == "" && strings.HasPrefix(src, "//") {
+		u.Scheme = "https"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	return u
+}
+
+func mediaFileName(u *nurl.URL) string {
+	if i := strings.LastIndex(u.Path, "/"); i >= 0 && i < len(u.Path)-1 {
+		return u.Path[i+1:]
+	}
+	return u.Hostname()
+}
+
+// keepArticleMedia drops embeds that readability left out of the article
+func keepArticleMedia(media []Media, article *goquery.Document) []Media {
+	linked := make(map[string]bool)
+	article.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		linked[s.AttrOr("href", "")] = true
+	})
+	kept := []Media{}
+	for _, m := range media {
+		if linked[m.URL] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMediaEmbeds(t *testing.T) {
+	html := `<html><head><title>Episode notes</title></head><body><article>
+<h1>Episode notes</h1>
+<p>This week we talk about parsers, tokenizers and why HTML is harder than it looks to everyone who tries it.</p>
+<iframe src="https://www.youtube-nocookie.com/embed/xyz789?rel=0" title="Talk recording"></iframe>
+<p>The podcast episode is below, along with a short clip and an unrelated widget that should be left alone.</p>
+<iframe src="https://open.spotify.com/embed/episode/42"></iframe>
+<video controls><source src="/clips/demo.mp4" type="video/mp4"></video>
+<iframe src="https://widgets.example.net/newsletter"></iframe>
+<p>Thanks for listening, and see you next week with another long paragraph to keep readability happy.</p>
+</article></body></html>`
+
+	cp := NewContentProcessor()
+	p, err := cp.ProcessFromReader(strings.NewReader(html), "https://blog.example.com/ep/1", ProcessOptions{CleanHTML: true, RemoveAds: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Media{
+		{Type: "video", URL: "https://www.youtube.com/watch?v=xyz789", Title: "Talk recording", Provider: "youtube"},
+		{Type: "audio", URL: "https://open.spotify.com/episode/42", Title: "Spotify audio", Provider: "spotify"},
+		{Type: "video", URL: "https://blog.example.com/clips/demo.mp4", Title: "demo.mp4"},
+	}
+	if len(p.Media) != len(want) {
+		t.Fatalf("got %d media, want %d: %+v", len(p.Media), len(want), p.Media)
+	}
+	for i := range want {
+		if p.Media[i] != want[i] {
+			t.Errorf("media[%d] = %+v, want %+v", i, p.Media[i], want[i])
+		}
+	}
+
+	md := cp.ToMarkdownBody(p, true)
+	for _, link := range []string{
+		"[Video: Talk recording](https://www.youtube.com/watch?v=xyz789)",
+		"[Audio: Spotify audio](https://open.spotify.com/episode/42)",
+	} {
+		if !strings.Contains(md, link) {
+			t.Errorf("markdown missing %s:\n%s", link, md)
+		}
+	}
+}
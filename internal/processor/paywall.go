@@ -0,0 +1,32 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// notFreeRe finds isAccessibleForFree set to false in JSON-LD, as a boolean
+// or a string, at any depth (hasPart, @graph)
+var notFreeRe = regexp.MustCompile(`"isAccessibleForFree"\s*:\s*"?(?i:false)"?`)
+
+// isPaywalled reports whether the page marks its content as not free to
+// read with schema.org isAccessibleForFree, the markup search engines ask
+// paywalled sites to use, in JSON-LD or microdata
+func isPaywalled(page *goquery.Document) bool {
+	paywalled := false
+	page.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		paywalled = notFreeRe.MatchString(s.Text())
+		return !paywalled
+	})
+	if paywalled {
+		return true
+	}
+	page.Find(`[itemprop="isAccessibleForFree"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		value := s.AttrOr("content", s.Text())
+		paywalled = strings.EqualFold(strings.TrimSpace(value), "false")
+		return !paywalled
+	})
+	return paywalled
+}
@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestIsPaywalled(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"json-ld bool", `<script type="application/ld+json">{"@type":"NewsArticle","isAccessibleForFree": false}</script>`, true},
+		{"json-ld string", `<script type="application/ld+json">{"@graph":[{"@type":"WebPage"},{"isAccessibleForFree":"False","hasPart":{}}]}</script>`, true},
+		{"json-ld free", `<script type="application/ld+json">{"isAccessibleForFree": true}</script>`, false},
+		{"microdata", `<div itemscope><meta itemprop="isAccessibleForFree" content="false"></div>`, true},
+		{"no markup", `<p>Free to read.</p>`, false},
+	}
+	for _, tt := range tests {
+		page, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isPaywalled(page); got != tt.want {
+			t.Errorf("%s: isPaywalled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,20 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	nurl "net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-shiori/go-readability"
-	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
-	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
-	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
-	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
+	"golang.org/x/net/html"
 )
 
 type ProcessOptions struct {
@@ -19,6 +23,10 @@ type ProcessOptions struct {
 	MinContentLength int
 	IncludeMetadata  bool
 	MetadataFields   []string
+	Trace            *Trace      // when set, receives the HTML after each stage
+	Junk             *JunkFilter // strips signup forms, share bars etc. before readability
+	Site             *SiteConfig // ftr-site-config rules for the page's site (nil = none)
+	Language         string      // text cleanup rules to use ("" = detect from the page)
 }
 
 type ProcessedContent struct {
@@ -32,6 +40,9 @@ type ProcessedContent struct {
 	Metadata    map[string]string
 	Images      []string
 	Links       []Link
+	Media       []Media
+	Language    string // primary language the text was cleaned for ("" = unknown)
+	Paywalled   bool   // the page marks its content as not free to read
 }
 
 type Link struct {
@@ -40,27 +51,115 @@ type Link struct {
 }
 
 type ContentProcessor struct {
+	toc    bool // table of contents at the top of markdown bodies
+	flavor MarkdownFlavor
 }
 
 func NewContentProcessor() *ContentProcessor {
-	return &ContentProcessor{}
+	return &ContentProcessor{flavor: FlavorGFM}
 }
 
+// Process extracts the main content from an HTML string.
+//
+// Deprecated: use ProcessFromReader, which parses the document only once.
 func (cp *ContentProcessor) Process(html, url string, opts ProcessOptions) (*ProcessedContent, error) {
-	if len(html) < opts.MinContentLength {
-		return nil, fmt.Errorf("content too short: %d characters (minimum: %d)", len(html), opts.MinContentLength)
+	return cp.ProcessFromReader(strings.NewReader(html), url, opts)
+}
+
+// ProcessFromReader streams the document through the HTML tokenizer once and
+// runs readability, metadata extraction and cleanup on the parsed tree instead
+// of re-parsing intermediate HTML strings at every stage.
+func (cp *ContentProcessor) ProcessFromReader(r io.Reader, pageURL string, opts ProcessOptions) (*ProcessedContent, error) {
+	return cp.process(context.Background(), r, pageURL, opts)
+}
+
+// process is ProcessFromReader stopping once ctx is done: reading the
+// document fails, and the stages after the running one are skipped
+func (cp *ContentProcessor) process(ctx context.Context, r io.Reader, pageURL string, opts ProcessOptions) (*ProcessedContent, error) {
+	site := opts.Site
+	if site != nil && len(site.replace) > 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HTML: %w", err)
+		}
+		r = strings.NewReader(site.rewrite(string(data)))
 	}
 
-	// Use readability to extract main content
-	article, err := readability.FromReader(strings.NewReader(html), nil)
+	counter := &countingReader{ctx: ctx, r: r}
+	root, err := html.Parse(counter)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, processingStopped(ctx)
+		}
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	if counter.n < int64(opts.MinContentLength) {
+		return nil, fmt.Errorf("content too short: %d characters (minimum: %d)", counter.n, opts.MinContentLength)
+	}
+
+	parsedURL, _ := nurl.Parse(pageURL)
+	// Read before site configs and junk rules strip scripts
+	paywalled := isPaywalled(goquery.NewDocumentFromNode(root))
+
+	if site != nil {
+		siteStart := time.Now()
+		site.stripNodes(root)
+		opts.Trace.record("site-config", goquery.NewDocumentFromNode(root), siteStart)
+	}
+
+	if opts.Junk != nil && parsedURL != nil {
+		junkStart := time.Now()
+		page := goquery.NewDocumentFromNode(root)
+		opts.Junk.apply(page, parsedURL.Hostname())
+		opts.Trace.record("junk", page, junkStart)
+	}
+
+	// Players would be dropped by readability or render as nothing in
+	// markdown, so they become links first
+	media := replaceMediaEmbeds(root, parsedURL)
+	if ctx.Err() != nil {
+		return nil, processingStopped(ctx)
+	}
+
+	// Use readability to extract main content. It works on a clone, so root
+	// stays intact for metadata extraction below.
+	stageStart := time.Now()
+	article, err := readability.FromDocument(root, parsedURL)
+	var siteDate string
+	if site != nil {
+		// The site's own rules beat readability's guesses
+		if body := site.bodyNode(root, parsedURL); body != nil {
+			article.Node = body
+			article.Content, _ = goquery.OuterHtml(goquery.NewDocumentFromNode(body).Selection)
+			article.TextContent = goquery.NewDocumentFromNode(body).Text()
+			article.Length = len(article.TextContent)
+			err = nil
+		}
+		if title := site.field(root, site.title); title != "" {
+			article.Title = title
+		}
+		if author := site.field(root, site.author); author != "" {
+			article.Byline = author
+		}
+		siteDate = site.field(root, site.date)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to process with readability: %w", err)
 	}
+	if ctx.Err() != nil {
+		return nil, processingStopped(ctx)
+	}
+
+	lang := primaryLanguage(opts.Language)
+	if lang == "" {
+		lang = DetectLanguage(article.Language, article.TextContent)
+	}
 
 	result := &ProcessedContent{
 		Title:       article.Title,
 		Content:     article.Content,
-		TextContent: cp.CleanNewlines(article.TextContent),
+		TextContent: cp.CleanNewlinesLang(article.TextContent, lang),
 		Author:      article.Byline,
 		Excerpt:     article.Excerpt,
 		Byline:      article.Byline,
@@ -68,41 +167,148 @@ func (cp *ContentProcessor) Process(html, url string, opts ProcessOptions) (*Pro
 		Metadata:    make(map[string]string),
 		Images:      []string{},
 		Links:       []Link{},
+		Media:       []Media{},
+		Language:    lang,
+		Paywalled:   paywalled,
 	}
 
-	// Parse HTML for additional processing
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(article.Content))
-	if err != nil {
+	if article.Node == nil {
 		return result, nil // Return what we have from readability
 	}
+	doc := goquery.NewDocumentFromNode(article.Node)
+	opts.Trace.record("readability", doc, stageStart)
 
 	// Extract images
 	result.Images = cp.extractImages(doc)
 
 	// Extract links
 	result.Links = cp.extractLinks(doc)
+	result.Media = keepArticleMedia(media, doc)
 
 	// Extract additional metadata if requested
 	if opts.IncludeMetadata {
-		originalDoc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-		if err == nil {
-			result.Metadata = cp.extractMetadata(originalDoc, opts.MetadataFields)
+		page := goquery.NewDocumentFromNode(root)
+		result.Metadata = cp.extractMetadata(page, parsedURL, opts.MetadataFields)
+		if siteDate != "" && slices.Contains(opts.MetadataFields, "date") {
+			result.Metadata["date"] = siteDate
 		}
+		if slices.Contains(opts.MetadataFields, "hero_image") {
+			if hero := selectHeroImage(page, doc, parsedURL); hero != "" {
+				result.Metadata["hero_image"] = hero
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, processingStopped(ctx)
 	}
 
 	// Clean HTML if requested
 	if opts.CleanHTML {
-		result.Content = cp.cleanHTML(result.Content)
+		stageStart = time.Now()
+		cp.cleanDocument(doc)
+		opts.Trace.record("clean", doc, stageStart)
 	}
 
 	// Remove ads if requested
 	if opts.RemoveAds {
-		result.Content = cp.removeAds(result.Content)
+		stageStart = time.Now()
+		cp.removeAdsDocument(doc)
+		opts.Trace.record("remove-ads", doc, stageStart)
+	}
+
+	if opts.CleanHTML || opts.RemoveAds {
+		if content, err := goquery.OuterHtml(doc.Selection); err == nil {
+			result.Content = content
+		}
 	}
 
 	return result, nil
 }
 
+// maxStalledWorkers caps the goroutines ProcessContext has given up on but
+// that are still stuck in readability. Once that many are stuck, further
+// documents are refused until one of them returns, so a site serving
+// pathological pages cannot pile up goroutines and memory for the rest of
+// a long run.
+var maxStalledWorkers int64 = 16
+
+// stalledWorkers counts the goroutines ProcessContext gave up on that have
+// not returned yet
+var stalledWorkers atomic.Int64
+
+// ErrProcessingStalled is returned while maxStalledWorkers documents are
+// still stuck in processing
+var ErrProcessingStalled = errors.New("processing refused: too many earlier documents are still stuck in readability")
+
+// ProcessContext runs ProcessFromReader but gives up once ctx is done, so a
+// pathological document cannot stall the pipeline past the processing
+// timeout. The work stops too, at the end of the stage it is in; only
+// readability cannot be interrupted, so a document that stalls it keeps
+// one goroutine busy until readability returns. At most maxStalledWorkers
+// such goroutines are left behind; past that ProcessContext returns
+// ErrProcessingStalled.
+func (cp *ContentProcessor) ProcessContext(ctx context.Context, r io.Reader, url string, opts ProcessOptions) (*ProcessedContent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("processing cancelled: %w", err)
+	}
+	if stalledWorkers.Load() >= maxStalledWorkers {
+		return nil, ErrProcessingStalled
+	}
+
+	type outcome struct {
+		result *ProcessedContent
+		err    error
+	}
+	const (
+		running = iota
+		finished
+		abandoned
+	)
+	var state atomic.Int32
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := cp.process(ctx, r, url, opts)
+		done <- outcome{result, err}
+		if !state.CompareAndSwap(running, finished) {
+			stalledWorkers.Add(-1)
+		}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		stalledWorkers.Add(1)
+		if !state.CompareAndSwap(running, abandoned) {
+			// Finished meanwhile; nothing is left behind
+			stalledWorkers.Add(-1)
+		}
+		return nil, processingStopped(ctx)
+	}
+}
+
+func processingStopped(ctx context.Context) error {
+	return fmt.Errorf("processing timed out: %w", ctx.Err())
+}
+
+// countingReader records how many bytes have passed through it, and fails
+// once ctx is done
+type countingReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func (cp *ContentProcessor) extractImages(doc *goquery.Document) []string {
 	var images []string
 
@@ -142,7 +348,7 @@ func (cp *ContentProcessor) extractLinks(doc *goquery.Document) []Link {
 	return links
 }
 
-func (cp *ContentProcessor) extractMetadata(doc *goquery.Document, fields []string) map[string]string {
+func (cp *ContentProcessor) extractMetadata(doc *goquery.Document, pageURL *nurl.URL, fields []string) map[string]string {
 	metadata := make(map[string]string)
 
 	for _, field := range fields {
@@ -177,12 +383,64 @@ func (cp *ContentProcessor) extractMetadata(doc *goquery.Document, fields []stri
 			if keywords := cp.findMetaContent(doc, []string{"keywords"}); keywords != "" {
 				metadata["keywords"] = keywords
 			}
+		case "site_name":
+			if name := cp.findMetaContent(doc, []string{"og:site_name", "application-name"}); name != "" {
+				metadata["site_name"] = name
+			} else if pageURL != nil && pageURL.Hostname() != "" {
+				metadata["site_name"] = strings.TrimPrefix(pageURL.Hostname(), "www.")
+			}
+		case "favicon":
+			if favicon := findFavicon(doc, pageURL); favicon != "" {
+				metadata["favicon"] = favicon
+			}
 		}
 	}
 
 	return metadata
 }
 
+// findFavicon returns the page's declared icon, preferring rel="icon" over
+// apple-touch-icon, or /favicon.ico when none is declared. The URL is
+// resolved against the page's <base> or pageURL.
+func findFavicon(doc *goquery.Document, pageURL *nurl.URL) string {
+	if pageURL == nil || pageURL.Host == "" {
+		return ""
+	}
+	base := pageURL
+	if href := doc.Find("base[href]").AttrOr("href", ""); href != "" {
+		if ref, err := nurl.Parse(href); err == nil {
+			base = pageURL.ResolveReference(ref)
+		}
+	}
+
+	var icon, touchIcon string
+	doc.Find("link[rel][href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			switch rel {
+			case "icon":
+				icon = s.AttrOr("href", "")
+				return false
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				if touchIcon == "" {
+					touchIcon = s.AttrOr("href", "")
+				}
+			}
+		}
+		return true
+	})
+	if icon == "" {
+		icon = touchIcon
+	}
+	if icon == "" {
+		return base.ResolveReference(&nurl.URL{Path: "/favicon.ico"}).String()
+	}
+	ref, err := nurl.Parse(strings.TrimSpace(icon))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
 func (cp *ContentProcessor) findMetaContent(doc *goquery.Document, properties []string) string {
 	for _, prop := range properties {
 		// Check name attribute
@@ -203,15 +461,21 @@ func (cp *ContentProcessor) cleanHTML(content string) string {
 		return content
 	}
 
+	cp.cleanDocument(doc)
+
+	result, _ := doc.Html()
+	return result
+}
+
+// cleanDocument strips scripts, styles, comments and empty blocks in place
+func (cp *ContentProcessor) cleanDocument(doc *goquery.Document) {
 	// Remove script and style elements
 	doc.Find("script, style, noscript").Remove()
 
 	// Remove comments
-	doc.Find("*").Each(func(i int, s *goquery.Selection) {
-		html, _ := s.Html()
-		cleanedHTML := cp.removeHTMLComments(html)
-		s.SetHtml(cleanedHTML)
-	})
+	for _, root := range doc.Nodes {
+		removeCommentNodes(root)
+	}
 
 	// Remove empty paragraphs and divs
 	doc.Find("p, div").Each(func(i int, s *goquery.Selection) {
@@ -219,9 +483,19 @@ func (cp *ContentProcessor) cleanHTML(content string) string {
 			s.Remove()
 		}
 	})
+}
 
-	result, _ := doc.Html()
-	return result
+// removeCommentNodes detaches every comment node below n
+func removeCommentNodes(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else {
+			removeCommentNodes(c)
+		}
+		c = next
+	}
 }
 
 func (cp *ContentProcessor) removeHTMLComments(html string) string {
@@ -272,6 +546,14 @@ func (cp *ContentProcessor) removeAds(content string) string {
 		return content
 	}
 
+	cp.removeAdsDocument(doc)
+
+	result, _ := doc.Html()
+	return result
+}
+
+// removeAdsDocument removes advertising elements in place
+func (cp *ContentProcessor) removeAdsDocument(doc *goquery.Document) {
 	doc.Find("[id], [class]").Each(func(i int, s *goquery.Selection) {
 		id, _ := s.Attr("id")
 		class, _ := s.Attr("class")
@@ -279,9 +561,6 @@ func (cp *ContentProcessor) removeAds(content string) string {
 			s.Remove()
 		}
 	})
-
-	result, _ := doc.Html()
-	return result
 }
 
 func (cp *ContentProcessor) ToText(content *ProcessedContent, lineWidth int) string {
@@ -298,7 +577,7 @@ func (cp *ContentProcessor) ToText(content *ProcessedContent, lineWidth int) str
 	}
 
 	// Clean newlines before wrapping
-	text = cp.CleanNewlines(text)
+	text = cp.CleanNewlinesLang(text, languageOf(content))
 	return cp.wrapText(text, lineWidth)
 }
 
@@ -318,39 +597,45 @@ func (cp *ContentProcessor) ToMarkdown(content *ProcessedContent, includeMetadat
 		if content.Excerpt != "" {
 			md.WriteString(fmt.Sprintf("**Summary:** %s\n\n", content.Excerpt))
 		}
-		for key, value := range content.Metadata {
+		// Sorted, so the same page always renders the same
+		for _, key := range slices.Sorted(maps.Keys(content.Metadata)) {
 			if key != "title" { // Title already added
-				md.WriteString(fmt.Sprintf("**%s:** %s\n\n", strings.Title(key), value))
+				md.WriteString(fmt.Sprintf("**%s:** %s\n\n", strings.Title(key), content.Metadata[key]))
 			}
 		}
 	}
 
+	md.WriteString(cp.ToMarkdownBody(content, preserveLinks))
+	return md.String()
+}
+
+// ToMarkdownWithFrontMatter renders content as markdown that starts with a
+// YAML front matter block. The title lives in the front matter, so no heading
+// or **Author:** lines are added.
+func (cp *ContentProcessor) ToMarkdownWithFrontMatter(content *ProcessedContent, pageURL string, preserveLinks bool) string {
+	return NewFrontMatter(content, pageURL).String() + "\n" + cp.ToMarkdownBody(content, preserveLinks)
+}
+
+// ToMarkdownBody converts the article HTML to markdown without the title
+// heading or metadata lines, falling back to the plain text content
+func (cp *ContentProcessor) ToMarkdownBody(content *ProcessedContent, preserveLinks bool) string {
+	lang := languageOf(content)
+
 	// If we have text content from readability, use that as fallback
 	if content.TextContent != "" && strings.TrimSpace(content.Content) == "" {
-		md.WriteString(cp.CleanNewlines(content.TextContent))
-		return md.String()
+		return cp.CleanNewlinesLang(content.TextContent, lang)
 	}
 
 	// Convert HTML content to markdown using battle-tested library
 	htmlContent := content.Content
 	if htmlContent == "" {
-		md.WriteString(cp.CleanNewlines(content.TextContent))
-		return md.String()
+		return cp.CleanNewlinesLang(content.TextContent, lang)
 	}
 
-	conv := converter.NewConverter(
-		converter.WithPlugins(
-			base.NewBasePlugin(),
-			commonmark.NewCommonmarkPlugin(),
-			table.NewTablePlugin(),
-		),
-	)
-
-	result, err := conv.ConvertString(htmlContent)
+	result, err := cp.convertMarkdown(htmlContent)
 	if err != nil {
 		// Fallback to text content on conversion failure
-		md.WriteString(cp.CleanNewlines(content.TextContent))
-		return md.String()
+		return cp.CleanNewlinesLang(content.TextContent, lang)
 	}
 
 	// Strip links if not preserving them
@@ -358,8 +643,13 @@ func (cp *ContentProcessor) ToMarkdown(content *ProcessedContent, includeMetadat
 		result = cp.stripMarkdownLinks(result)
 	}
 
-	md.WriteString(cp.CleanNewlines(result))
-	return md.String()
+	return cp.withTOC(cp.CleanNewlinesLang(result, lang))
+}
+
+// ToHTML returns the readability-extracted article HTML, after cleanHTML and
+// removeAds have been applied according to the ProcessOptions
+func (cp *ContentProcessor) ToHTML(content *ProcessedContent) string {
+	return strings.TrimSpace(content.Content)
 }
 
 // stripMarkdownLinks converts [text](url) -> text
@@ -429,23 +719,24 @@ func (cp *ContentProcessor) wrapText(text string, lineWidth int) string {
 	return result.String()
 }
 
-func (cp *ContentProcessor) ProcessFromReader(r io.Reader, url string, opts ProcessOptions) (*ProcessedContent, error) {
-	htmlBytes, err := io.ReadAll(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read HTML: %w", err)
-	}
-
-	return cp.Process(string(htmlBytes), url, opts)
+// CleanNewlines removes unwanted newlines that break up sentences, using
+// the English rules
+func (cp *ContentProcessor) CleanNewlines(text string) string {
+	return cp.CleanNewlinesLang(text, "")
 }
 
-// CleanNewlines removes unwanted newlines that break up sentences
-func (cp *ContentProcessor) CleanNewlines(text string) string {
+// CleanNewlinesLang removes unwanted newlines that break up sentences,
+// following the sentence, quotation and hyphenation rules of lang
+func (cp *ContentProcessor) CleanNewlinesLang(text, lang string) string {
 	// Remove newlines that are in the middle of sentences
 	// This preserves paragraph breaks (double newlines) and intentional line breaks
+	rules := rulesFor(lang)
 
 	// First normalize line endings
 	text = strings.ReplaceAll(text, "\r\n", "\n")
 	text = strings.ReplaceAll(text, "\r", "\n")
+	// Soft hyphens only mark where a word may break
+	text = strings.ReplaceAll(text, "\u00ad", "")
 
 	// Split into paragraphs (preserve double newlines)
 	paragraphs := strings.Split(text, "\n\n")
@@ -462,31 +753,14 @@ func (cp *ContentProcessor) CleanNewlines(text string) string {
 				continue
 			}
 
-			// If this is not the first line and the previous line doesn't end with
-			// sentence-ending punctuation, and this line doesn't start with a capital letter
-			// or bullet point, then join it with the previous line
-			if len(cleanedLines) > 0 {
+			// Join a line to the previous one when it continues its sentence:
+			// the previous line doesn't end with sentence-ending punctuation and
+			// this one doesn't start a new sentence or list item.
+			// Table rows keep their own lines
+			if len(cleanedLines) > 0 && !isTableRow(line) && !isTableRow(cleanedLines[len(cleanedLines)-1]) {
 				prevLine := cleanedLines[len(cleanedLines)-1]
-
-				// Check if previous line ends with sentence-ending punctuation
-				endsWithPunctuation := strings.HasSuffix(prevLine, ".") ||
-					strings.HasSuffix(prevLine, "!") ||
-					strings.HasSuffix(prevLine, "?") ||
-					strings.HasSuffix(prevLine, ":") ||
-					strings.HasSuffix(prevLine, ";")
-
-				// Check if current line starts with capital letter, number, or bullet
-				startsNewSentence := len(line) > 0 &&
-					(line[0] >= 'A' && line[0] <= 'Z' ||
-						line[0] >= '0' && line[0] <= '9' ||
-						strings.HasPrefix(line, "- ") ||
-						strings.HasPrefix(line, "* ") ||
-						strings.HasPrefix(line, "• "))
-
-				// If previous line doesn't end with punctuation and current line doesn't start new sentence,
-				// join them with a space
-				if !endsWithPunctuation && !startsNewSentence {
-					cleanedLines[len(cleanedLines)-1] = prevLine + " " + line
+				if rules.continues(prevLine, line) {
+					cleanedLines[len(cleanedLines)-1] = rules.join(prevLine, line)
 					continue
 				}
 			}
@@ -501,10 +775,17 @@ func (cp *ContentProcessor) CleanNewlines(text string) string {
 
 	result := strings.Join(cleanedParagraphs, "\n\n")
 
-	// Clean up multiple spaces
-	for strings.Contains(result, "  ") {
-		result = strings.ReplaceAll(result, "  ", " ")
+	// Clean up multiple spaces, except for the column padding of tables
+	lines := strings.Split(result, "\n")
+	for i, line := range lines {
+		if isTableRow(line) {
+			continue
+		}
+		for strings.Contains(line, "  ") {
+			line = strings.ReplaceAll(line, "  ", " ")
+		}
+		lines[i] = line
 	}
 
-	return strings.TrimSpace(result)
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
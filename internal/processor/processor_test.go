@@ -1,8 +1,15 @@
 package processor
 
 import (
+	"context"
+	"errors"
+	"io"
+	nurl "net/url"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 // Regression for trx-2d9y: markdown output must include body content, not just
@@ -17,7 +24,7 @@ func TestToMarkdownIncludesBody(t *testing.T) {
 </article></body></html>`
 
 	cp := NewContentProcessor()
-	p, err := cp.Process(html, "http://example.com/", ProcessOptions{
+	p, err := cp.ProcessFromReader(strings.NewReader(html), "http://example.com/", ProcessOptions{
 		RemoveAds:        true,
 		CleanHTML:        true,
 		MinContentLength: 100,
@@ -43,3 +50,200 @@ func TestRemoveAdsKeepsNonAdTokens(t *testing.T) {
 		t.Errorf("did not remove ad element: %q", got)
 	}
 }
+
+func TestProcessContextCancelled(t *testing.T) {
+	cp := NewContentProcessor()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cp.ProcessContext(ctx, strings.NewReader(strings.Repeat("<p>text</p>", 50)), "http://example.com/", ProcessOptions{})
+	if err == nil {
+		t.Fatal("expected error for cancelled context")
+	}
+}
+
+// cancellingReader cancels its context after the first read
+type cancellingReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancellingReader) Read(p []byte) (int, error) {
+	defer c.cancel()
+	return c.r.Read(p[:min(len(p), 64)])
+}
+
+func TestProcessStopsOnceCancelled(t *testing.T) {
+	cp := NewContentProcessor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &cancellingReader{r: strings.NewReader(strings.Repeat("<p>text</p>", 500)), cancel: cancel}
+	_, err := cp.process(ctx, r, "http://example.com/", ProcessOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("process() error = %v, want it to stop with context.Canceled", err)
+	}
+}
+
+// stallingReader blocks its first read until release is closed, as a
+// document that stalls the parser would
+type stallingReader struct {
+	release chan struct{}
+}
+
+func (s *stallingReader) Read(p []byte) (int, error) {
+	<-s.release
+	return 0, io.EOF
+}
+
+func TestProcessContextCapsStalledWorkers(t *testing.T) {
+	defer func(saved int64) { maxStalledWorkers = saved }(maxStalledWorkers)
+	maxStalledWorkers = 2
+	cp := NewContentProcessor()
+	release := make(chan struct{})
+
+	for i := range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := cp.ProcessContext(ctx, &stallingReader{release: release}, "http://example.com/", ProcessOptions{})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("stalled document %d: error = %v, want a timeout", i+1, err)
+		}
+	}
+	if n := stalledWorkers.Load(); n != 2 {
+		t.Fatalf("%d stalled workers, want 2", n)
+	}
+
+	page := strings.Repeat("<p>Some text of a paragraph.</p>", 20)
+	if _, err := cp.ProcessContext(context.Background(), strings.NewReader(page), "http://example.com/", ProcessOptions{}); !errors.Is(err, ErrProcessingStalled) {
+		t.Fatalf("with the cap reached, error = %v, want ErrProcessingStalled", err)
+	}
+
+	// Once the stalled parses return, documents are taken again
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for stalledWorkers.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d stalled workers left after they were released", stalledWorkers.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := cp.ProcessContext(context.Background(), strings.NewReader(page), "http://example.com/", ProcessOptions{}); errors.Is(err, ErrProcessingStalled) {
+		t.Fatalf("still refused after the stalled workers returned")
+	}
+}
+
+func TestCleanHTMLRemovesComments(t *testing.T) {
+	cp := NewContentProcessor()
+	got := cp.cleanHTML(`<div><p>visible<!-- hidden --></p><p></p><script>x()</script></div>`)
+	if strings.Contains(got, "hidden") || strings.Contains(got, "script") {
+		t.Errorf("comments or scripts survived cleaning: %q", got)
+	}
+	if !strings.Contains(got, "visible") {
+		t.Errorf("removed legitimate content: %q", got)
+	}
+}
+
+func TestToHTMLAppliesCleanup(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>Test Article</title></head>
+<body><article><h1>Test Article</h1>
+<p>This is the first paragraph of body content that should appear.</p>
+<!-- tracking pixel -->
+<p>Here is a second paragraph with more information about the topic.</p>
+<p>And a third paragraph to make sure readability picks it up as real content and not boilerplate noise here.</p>
+</article></body></html>`
+
+	cp := NewContentProcessor()
+	p, err := cp.ProcessFromReader(strings.NewReader(html), "http://example.com/", ProcessOptions{
+		RemoveAds:        true,
+		CleanHTML:        true,
+		MinContentLength: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := cp.ToHTML(p)
+	if !strings.Contains(out, "<p>This is the first paragraph") {
+		t.Errorf("expected article paragraphs in HTML output:\n%s", out)
+	}
+	if strings.Contains(out, "tracking pixel") {
+		t.Errorf("expected comments to be removed:\n%s", out)
+	}
+}
+
+func TestProcessTraceStages(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>Traced</title></head>
+<body><article><h1>Traced</h1>
+<p>First paragraph of body content that readability should keep around.</p>
+<p>Second paragraph with more information, long enough to count as content.</p>
+</article></body></html>`
+
+	trace := &Trace{}
+	cp := NewContentProcessor()
+	if _, err := cp.ProcessFromReader(strings.NewReader(html), "http://example.com/", ProcessOptions{
+		RemoveAds:        true,
+		CleanHTML:        true,
+		MinContentLength: 100,
+		Trace:            trace,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, st := range trace.Stages {
+		names = append(names, st.Name)
+	}
+	if got := strings.Join(names, ","); got != "readability,clean,remove-ads" {
+		t.Fatalf("unexpected stages %q", got)
+	}
+	if !strings.Contains(trace.Stages[0].HTML, "First paragraph") {
+		t.Errorf("readability stage missing content: %s", trace.Stages[0].HTML)
+	}
+	for _, st := range trace.Stages[1:] {
+		if !strings.Contains(st.HTML, "Second paragraph") {
+			t.Errorf("%s stage lost content: %s", st.Name, st.HTML)
+		}
+	}
+}
+
+func TestSiteMetadata(t *testing.T) {
+	cp := NewContentProcessor()
+	tests := []struct {
+		name, head, wantSite, wantIcon string
+	}{
+		{
+			name:     "declared",
+			head:     `<meta property="og:site_name" content="Example News"><link rel="apple-touch-icon" href="/touch.png"><link rel="shortcut icon" href="static/fav.png">`,
+			wantSite: "Example News",
+			wantIcon: "https://www.example.com/blog/static/fav.png",
+		},
+		{
+			name:     "base href",
+			head:     `<base href="https://cdn.example.com/assets/"><link rel="icon" href="i.ico">`,
+			wantSite: "example.com",
+			wantIcon: "https://cdn.example.com/assets/i.ico",
+		},
+		{
+			name:     "fallbacks",
+			wantSite: "example.com",
+			wantIcon: "https://www.example.com/favicon.ico",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.head + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pageURL, _ := nurl.Parse("https://www.example.com/blog/post")
+			meta := cp.extractMetadata(doc, pageURL, []string{"site_name", "favicon"})
+			if meta["site_name"] != tt.wantSite {
+				t.Errorf("site_name = %q, want %q", meta["site_name"], tt.wantSite)
+			}
+			if meta["favicon"] != tt.wantIcon {
+				t.Errorf("favicon = %q, want %q", meta["favicon"], tt.wantIcon)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package processor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SiteConfig holds the extraction rules of one site in the FiveFilters
+// ftr-site-config format (https://github.com/fivefilters/ftr-site-config):
+// "directive: value" lines with XPath expressions. The directives used are
+// body, strip, strip_id_or_class, strip_image_src, title, author, date,
+// single_page_link and find_string/replace_string; the rest are ignored.
+type SiteConfig struct {
+	body           []*xpath.Expr // content containers, first match wins
+	strip          []*xpath.Expr
+	stripIDOrClass []string
+	stripImageSrc  []string
+	title          []*xpath.Expr
+	author         []*xpath.Expr
+	date           []*xpath.Expr
+	singlePageLink []*xpath.Expr
+	replace        []stringReplacement // applied to the raw HTML, in order
+}
+
+type stringReplacement struct {
+	find, replace string
+}
+
+// ParseSiteConfig reads an ftr-site-config file. Expressions that do not
+// compile are skipped, as FiveFilters does, so one broken line does not
+// lose the rest of the file.
+func ParseSiteConfig(r io.Reader) (*SiteConfig, error) {
+	sc := &SiteConfig{}
+	var finds, replaces []string
+	compile := func(list *[]*xpath.Expr, value string) {
+		if expr, err := xpath.Compile(value); err == nil {
+			*list = append(*list, expr)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		directive, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		directive, value = strings.TrimSpace(directive), strings.TrimSpace(value)
+
+		// replace_string(find): replacement
+		if find, ok := strings.CutPrefix(directive, "replace_string("); ok && strings.HasSuffix(find, ")") {
+			sc.replace = append(sc.replace, stringReplacement{strings.TrimSuffix(find, ")"), value})
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		switch strings.ToLower(directive) {
+		case "body":
+			compile(&sc.body, value)
+		case "strip":
+			compile(&sc.strip, value)
+		case "title":
+			compile(&sc.title, value)
+		case "author":
+			compile(&sc.author, value)
+		case "date":
+			compile(&sc.date, value)
+		case "single_page_link":
+			compile(&sc.singlePageLink, value)
+		case "strip_id_or_class":
+			sc.stripIDOrClass = append(sc.stripIDOrClass, strings.Trim(value, `"'`))
+		case "strip_image_src":
+			sc.stripImageSrc = append(sc.stripImageSrc, value)
+		case "find_string":
+			finds = append(finds, value)
+		case "replace_string":
+			replaces = append(replaces, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read site config: %w", err)
+	}
+	for i := range min(len(finds), len(replaces)) {
+		sc.replace = append(sc.replace, stringReplacement{finds[i], replaces[i]})
+	}
+	return sc, nil
+}
+
+// SiteConfigs looks up ftr-site-config files in a directory such as a clone
+// of the FiveFilters repository. Files are read on first use and cached.
+// Safe for concurrent use.
+type SiteConfigs struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*SiteConfig // file name -> parsed config (nil = absent)
+}
+
+// NewSiteConfigs looks up site configs in dir
+func NewSiteConfigs(dir string) *SiteConfigs {
+	return &SiteConfigs{dir: dir, files: make(map[string]*SiteConfig)}
+}
+
+// For returns the config for a host, or nil when there is none. Like
+// FiveFilters it tries the host without "www." (example.com.txt), then
+// wildcard files for it and its parent domains (.example.com.txt).
+func (s *SiteConfigs) For(host string) *SiteConfig {
+	if s == nil {
+		return nil
+	}
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	if host == "" {
+		return nil
+	}
+	if sc := s.load(host + ".txt"); sc != nil {
+		return sc
+	}
+	for h := host; strings.Contains(h, "."); {
+		if sc := s.load("." + h + ".txt"); sc != nil {
+			return sc
+		}
+		_, h, _ = strings.Cut(h, ".")
+	}
+	return nil
+}
+
+func (s *SiteConfigs) load(name string) *SiteConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sc, ok := s.files[name]; ok {
+		return sc
+	}
+	var sc *SiteConfig
+	if f, err := os.Open(filepath.Join(s.dir, name)); err == nil {
+		sc, _ = ParseSiteConfig(f)
+		f.Close()
+	}
+	s.files[name] = sc
+	return sc
+}
+
+// rewrite applies the find_string/replace_string pairs to the raw HTML
+func (sc *SiteConfig) rewrite(page string) string {
+	for _, r := range sc.replace {
+		page = strings.ReplaceAll(page, r.find, r.replace)
+	}
+	return page
+}
+
+// stripNodes removes the elements matched by strip, strip_id_or_class and
+// strip_image_src and returns how many were removed
+func (sc *SiteConfig) stripNodes(root *html.Node) int {
+	var doomed []*html.Node
+	for _, expr := range sc.strip {
+		doomed = append(doomed, selectNodes(root, expr)...)
+	}
+	page := goquery.NewDocumentFromNode(root)
+	if len(sc.stripIDOrClass) > 0 {
+		page.Find("[id], [class]").Each(func(i int, s *goquery.Selection) {
+			id, class := s.AttrOr("id", ""), s.AttrOr("class", "")
+			for _, token := range sc.stripIDOrClass {
+				if strings.Contains(id, token) || strings.Contains(class, token) {
+					doomed = append(doomed, s.Nodes[0])
+					return
+				}
+			}
+		})
+	}
+	if len(sc.stripImageSrc) > 0 {
+		page.Find("img[src]").Each(func(i int, s *goquery.Selection) {
+			src := s.AttrOr("src", "")
+			for _, part := range sc.stripImageSrc {
+				if strings.Contains(src, part) {
+					doomed = append(doomed, s.Nodes[0])
+					return
+				}
+			}
+		})
+	}
+
+	removed := 0
+	for _, n := range doomed {
+		if n.Parent != nil && n.Type == html.ElementNode {
+			n.Parent.RemoveChild(n)
+			removed++
+		}
+	}
+	return removed
+}
+
+// bodyNode returns a container holding copies of the elements the first
+// matching body expression selects, with links and images made absolute,
+// or nil when no expression matches
+func (sc *SiteConfig) bodyNode(root *html.Node, pageURL *nurl.URL) *html.Node {
+	for _, expr := range sc.body {
+		var nodes []*html.Node
+		for _, n := range selectNodes(root, expr) {
+			if n.Type == html.ElementNode {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+		for _, n := range goquery.NewDocumentFromNode(root).FindNodes(nodes...).Clone().Nodes {
+			container.AppendChild(n)
+		}
+		if pageURL != nil {
+			absolutizeURLs(goquery.NewDocumentFromNode(container).Selection, pageURL)
+		}
+		return container
+	}
+	return nil
+}
+
+// field returns the text of the first title, author or date expression that
+// yields one
+func (sc *SiteConfig) field(root *html.Node, exprs []*xpath.Expr) string {
+	for _, expr := range exprs {
+		if text := evalString(root, expr, false); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// SinglePageURL returns the absolute URL of the page's single-page version,
+// or "" when the site config has no single_page_link matching the page
+func (sc *SiteConfig) SinglePageURL(page, pageURL string) string {
+	if sc == nil || len(sc.singlePageLink) == 0 {
+		return ""
+	}
+	root, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	base, err := nurl.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	for _, expr := range sc.singlePageLink {
+		href := evalString(root, expr, true)
+		if href == "" {
+			continue
+		}
+		if ref, err := nurl.Parse(href); err == nil {
+			if u := base.ResolveReference(ref); u.String() != base.String() {
+				return u.String()
+			}
+		}
+	}
+	return ""
+}
+
+// selectNodes returns the nodes an expression selects; expressions that
+// evaluate to a string or number select nothing
+func selectNodes(root *html.Node, expr *xpath.Expr) (nodes []*html.Node) {
+	defer func() {
+		// Some expressions valid at compile time panic on evaluation
+		if recover() != nil {
+			nodes = nil
+		}
+	}()
+	iter, ok := expr.Evaluate(htmlquery.CreateXPathNavigator(root)).(*xpath.NodeIterator)
+	if !ok {
+		return nil
+	}
+	for iter.MoveNext() {
+		nav := iter.Current().(*htmlquery.NodeNavigator)
+		if nav.NodeType() != xpath.AttributeNode {
+			nodes = append(nodes, nav.Current())
+		}
+	}
+	return nodes
+}
+
+// evalString evaluates an expression to trimmed text: a string result, or
+// the first selected node's attribute value or text. With href set, a
+// selected element yields its href instead of its text.
+func evalString(root *html.Node, expr *xpath.Expr, href bool) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+	switch v := expr.Evaluate(htmlquery.CreateXPathNavigator(root)).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case *xpath.NodeIterator:
+		for v.MoveNext() {
+			nav := v.Current().(*htmlquery.NodeNavigator)
+			switch {
+			case nav.NodeType() == xpath.AttributeNode:
+				text = nav.Value()
+			case href:
+				text = htmlquery.SelectAttr(nav.Current(), "href")
+			default:
+				text = htmlquery.InnerText(nav.Current())
+			}
+			if text = strings.Join(strings.Fields(text), " "); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// absolutizeURLs resolves the href and src attributes below s against the
+// page URL, as readability does for the content it extracts
+func absolutizeURLs(s *goquery.Selection, pageURL *nurl.URL) {
+	s.Find("[href], [src]").Each(func(i int, el *goquery.Selection) {
+		for _, attr := range []string{"href", "src"} {
+			value, ok := el.Attr(attr)
+			if !ok || strings.HasPrefix(value, "#") {
+				continue
+			}
+			if ref, err := nurl.Parse(value); err == nil {
+				el.SetAttr(attr, pageURL.ResolveReference(ref).String())
+			}
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSiteConfig = `# example.com
+title: //h1[@class='headline']
+author: //span[@class='byline']
+date: string(//time/@datetime)
+body: //div[@id='missing']
+body: //div[contains(@class, 'story-body')]
+strip: //div[@class='inline-ad']
+strip_id_or_class: promo
+strip_image_src: tracker.gif
+single_page_link: //a[@class='print-view']
+find_string: <p>Teaser
+replace_string: <p>Full
+replace_string(&nbsp;): ` + " " + `
+body: //div[[broken
+prune: no
+test_url: https://example.com/story
+`
+
+const siteConfigPage = `<html><head><title>Site | Wrong title</title></head><body>
+<h1 class="headline">The Real Headline</h1>
+<span class="byline">Jane Roe</span>
+<time datetime="2024-03-01">March 1</time>
+<div class="sidebar"><p>Sidebar text that readability might keep because it is long enough to look like content to a scorer.</p></div>
+<div class="story-body">
+<p>Teaser paragraph about the story, long enough to be a paragraph of real article text for extraction.</p>
+<div class="inline-ad">Buy things now</div>
+<aside class="promo-box">Subscribe today</aside>
+<p>Second paragraph with a <a href="/related">relative link</a> and an image.</p>
+<img src="/img/photo.jpg"><img src="https://ads.example/tracker.gif">
+</div>
+<a class="print-view" href="/story?page=all">Single page</a>
+</body></html>`
+
+func TestParseSiteConfig(t *testing.T) {
+	sc, err := ParseSiteConfig(strings.NewReader(testSiteConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.body) != 2 {
+		t.Errorf("expected the broken body expression to be skipped, got %d", len(sc.body))
+	}
+	if len(sc.replace) != 2 || sc.replace[0].find != "&nbsp;" || sc.replace[1].find != "<p>Teaser" {
+		t.Errorf("unexpected replacements: %+v", sc.replace)
+	}
+	if len(sc.stripIDOrClass) != 1 || len(sc.stripImageSrc) != 1 || len(sc.singlePageLink) != 1 {
+		t.Errorf("unexpected strip or single page rules: %+v", sc)
+	}
+}
+
+func TestProcessWithSiteConfig(t *testing.T) {
+	sc, err := ParseSiteConfig(strings.NewReader(testSiteConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := NewContentProcessor()
+	result, err := cp.ProcessFromReader(strings.NewReader(siteConfigPage), "https://example.com/story", ProcessOptions{
+		IncludeMetadata: true,
+		MetadataFields:  []string{"date"},
+		Site:            sc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "The Real Headline" || result.Author != "Jane Roe" {
+		t.Errorf("expected title and author from the site config, got %q by %q", result.Title, result.Author)
+	}
+	if result.Metadata["date"] != "2024-03-01" {
+		t.Errorf("expected the site config date, got %q", result.Metadata["date"])
+	}
+	for _, gone := range []string{"Sidebar text", "Buy things", "Subscribe today", "tracker.gif", "Teaser"} {
+		if strings.Contains(result.Content, gone) {
+			t.Errorf("expected %q to be gone:\n%s", gone, result.Content)
+		}
+	}
+	for _, kept := range []string{"Full paragraph", "https://example.com/related", "https://example.com/img/photo.jpg"} {
+		if !strings.Contains(result.Content, kept) {
+			t.Errorf("expected %q in the content:\n%s", kept, result.Content)
+		}
+	}
+	if !strings.Contains(result.TextContent, "Second paragraph") {
+		t.Errorf("expected the text content of the body:\n%s", result.TextContent)
+	}
+}
+
+func TestSiteConfigSinglePageURL(t *testing.T) {
+	sc, err := ParseSiteConfig(strings.NewReader(testSiteConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sc.SinglePageURL(siteConfigPage, "https://example.com/story"); got != "https://example.com/story?page=all" {
+		t.Errorf("unexpected single page URL %q", got)
+	}
+	// The single-page version links to itself
+	if got := sc.SinglePageURL(siteConfigPage, "https://example.com/story?page=all"); got != "" {
+		t.Errorf("expected no redirect from the single-page version, got %q", got)
+	}
+	var none *SiteConfig
+	if got := none.SinglePageURL(siteConfigPage, "https://example.com/story"); got != "" {
+		t.Errorf("expected no URL without a site config, got %q", got)
+	}
+}
+
+func TestSiteConfigsLookup(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"example.com.txt":  "body: //article\n",
+		".example.org.txt": "body: //main\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs := NewSiteConfigs(dir)
+
+	tests := []struct {
+		host  string
+		found bool
+	}{
+		{"www.example.com", true},
+		{"example.com", true},
+		{"blog.example.com", false},
+		{"example.org", true},
+		{"news.eu.example.org", true},
+		{"example.net", false},
+	}
+	for _, tt := range tests {
+		if got := configs.For(tt.host) != nil; got != tt.found {
+			t.Errorf("For(%q) found = %v, want %v", tt.host, got, tt.found)
+		}
+	}
+	var disabled *SiteConfigs
+	if disabled.For("example.com") != nil {
+		t.Error("expected no config from a nil store")
+	}
+}
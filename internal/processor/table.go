@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var textAlignRe = regexp.MustCompile(`(?i)text-align\s*:\s*(left|right|center)`)
+
+// blockInTable matches content a pipe table cell cannot hold
+const blockInTable = "table, ul, ol, hr, blockquote, pre, h1, h2, h3, h4, h5, h6"
+
+// newTablePlugin converts tables to GFM pipe tables. Tables without <th>
+// take their first row as the header, line breaks in cells become <br>, and
+// the cells a colspan or rowspan covers are left empty, since pipe tables
+// cannot merge cells.
+func newTablePlugin() converter.Plugin {
+	return table.NewTablePlugin(
+		table.WithHeaderPromotion(true),
+		table.WithSpanCellBehavior(table.SpanBehaviorEmpty),
+		table.WithNewlineBehavior(table.NewlineBehaviorPreserve),
+		table.WithSkipEmptyRows(true),
+	)
+}
+
+// renderComplexTable keeps tables with lists, nested tables and other block
+// content as HTML, which GFM renders as is, rather than flattening them
+func renderComplexTable(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	if goquery.NewDocumentFromNode(n).Find(blockInTable).Length() == 0 {
+		return converter.RenderTryNext
+	}
+	return base.RenderAsHTML(ctx, w, n)
+}
+
+// alignTableCells copies text-align styles of table cells to the align
+// attribute, which the table plugin reads the column alignment from
+func alignTableCells(doc *goquery.Document) {
+	doc.Find("th[style], td[style]").Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("align"); ok {
+			return
+		}
+		if m := textAlignRe.FindStringSubmatch(s.AttrOr("style", "")); m != nil {
+			s.SetAttr("align", strings.ToLower(m[1]))
+		}
+	})
+}
+
+// isTableRow reports whether a markdown line is a pipe table row
+func isTableRow(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) > 1 && strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|")
+}
+
+// renderCellBreak writes line breaks in table cells as a bare newline, which
+// the table plugin turns into <br>, without the trailing spaces of a markdown
+// hard break
+func renderCellBreak(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	if goquery.NewDocumentFromNode(n).Closest("td, th").Length() == 0 {
+		return converter.RenderTryNext
+	}
+	w.WriteString("\n")
+	return converter.RenderSuccess
+}
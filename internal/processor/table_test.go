@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableMarkdown(t *testing.T) {
+	html := `<p>Prices below.</p>
+<table>
+<thead><tr><th align="left">Fruit</th><th style="text-align: right">Price</th><th>Note</th></tr></thead>
+<tbody>
+<tr><td>apple</td><td>1</td><td>red | green</td></tr>
+<tr><td colspan="2">banana split</td><td>x<br>y</td></tr>
+</tbody>
+</table>
+<table><tr><td>no</td><td>header</td></tr><tr><td>a</td><td>b</td></tr></table>
+<table><tr><th>List</th></tr><tr><td><ul><li>one</li></ul></td></tr></table>`
+
+	cp := NewContentProcessor()
+	md := cp.ToMarkdownBody(&ProcessedContent{Content: html}, true)
+
+	for _, want := range []string{
+		"| Fruit        | Price | Note         |",
+		"|:-------------|------:|--------------|",
+		"| apple        | 1     | red \\| green |",
+		"| banana split |       | x<br />y     |",
+		"| no | header |",
+		"<table>",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "one |") {
+		t.Errorf("table with a list was flattened:\n%s", md)
+	}
+}
+
+func TestCleanNewlinesKeepsTables(t *testing.T) {
+	cp := NewContentProcessor()
+	in := "| a  | b  |\n|----|----|\n| x  | y  |\nafter the table"
+	got := cp.CleanNewlines(in)
+	if !strings.HasPrefix(got, "| a  | b  |\n|----|----|\n| x  | y  |\n") {
+		t.Errorf("table rows were joined or repadded:\n%s", got)
+	}
+}
@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tocMinHeadings is the fewest headings worth a table of contents
+const tocMinHeadings = 2
+
+type tocEntry struct {
+	level  int
+	text   string
+	anchor string
+}
+
+// TableOfContents builds a linked, nested list of the ATX headings in a
+// markdown body. Anchors follow GitHub's rules (lowercase, punctuation
+// dropped, spaces to hyphens, -1/-2 for repeats) so the links work on GitHub,
+// Gitea and most markdown previewers; the Obsidian flavor links headings as
+// [[#Heading]] instead. Empty when the body has fewer than two
+// headings.
+func TableOfContents(markdown string, flavor MarkdownFlavor) string {
+	var entries []tocEntry
+	seen := make(map[string]int)
+	minLevel := 6
+	inFence := false
+
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := ansiHeadingRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		text := strings.TrimSpace(stripInline(m[2]))
+		if text == "" {
+			continue
+		}
+
+		anchor := headingAnchor(text)
+		if n := seen[anchor]; n > 0 {
+			seen[anchor] = n + 1
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			seen[anchor] = 1
+		}
+
+		level := len(m[1])
+		minLevel = min(minLevel, level)
+		entries = append(entries, tocEntry{level, text, anchor})
+	}
+
+	if len(entries) < tocMinHeadings {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("**Contents**\n\n")
+	for _, e := range entries {
+		indent := strings.Repeat("  ", e.level-minLevel)
+		if flavor == FlavorObsidian {
+			fmt.Fprintf(&b, "%s- [[#%s]]\n", indent, wikiLinkText(e.text))
+			continue
+		}
+		fmt.Fprintf(&b, "%s- [%s](#%s)\n", indent, escapeTOCText(e.text), e.anchor)
+	}
+	return b.String()
+}
+
+// headingAnchor slugs heading text the way GitHub does
+func headingAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+func escapeTOCText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// SetTOC makes the markdown renderers start the body with a table of contents
+func (cp *ContentProcessor) SetTOC(enabled bool) {
+	cp.toc = enabled
+}
+
+// withTOC prepends the table of contents to a markdown body when enabled
+func (cp *ContentProcessor) withTOC(body string) string {
+	if !cp.toc {
+		return body
+	}
+	if toc := TableOfContents(body, cp.markdownFlavor()); toc != "" {
+		return toc + "\n" + body
+	}
+	return body
+}
@@ -0,0 +1,32 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableOfContents(t *testing.T) {
+	md := "## Getting started\n\ntext\n\n### Install `scrpr`\n\n```sh\n# not a heading\n```\n\n## Getting started\n\n## Q&A: [links](http://x) too\n"
+	want := "**Contents**\n\n" +
+		"- [Getting started](#getting-started)\n" +
+		"  - [Install scrpr](#install-scrpr)\n" +
+		"- [Getting started](#getting-started-1)\n" +
+		"- [Q&A: links too](#qa-links-too)\n"
+	if got := TableOfContents(md, FlavorGFM); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := TableOfContents("## Only one\n\ntext\n", FlavorGFM); got != "" {
+		t.Errorf("single heading produced a toc: %q", got)
+	}
+}
+
+func TestToMarkdownBodyTOC(t *testing.T) {
+	cp := NewContentProcessor()
+	cp.SetTOC(true)
+	content := &ProcessedContent{Content: "<h2>One</h2><p>a</p><h2>Two</h2><p>b</p>"}
+	md := cp.ToMarkdownBody(content, true)
+	if !strings.HasPrefix(md, "**Contents**\n\n- [One](#one)\n- [Two](#two)\n\n## One") {
+		t.Errorf("toc missing or misplaced:\n%s", md)
+	}
+}
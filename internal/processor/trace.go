@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Trace records the article HTML after each pipeline stage, so a stage that
+// drops content can be pinpointed
+type Trace struct {
+	Stages []TraceStage
+}
+
+// TraceStage is the output and cost of one stage
+type TraceStage struct {
+	Name     string // junk, readability, clean, remove-ads
+	HTML     string
+	Duration time.Duration
+}
+
+// record snapshots doc as the output of stage when tracing is enabled
+func (t *Trace) record(name string, doc *goquery.Document, start time.Time) {
+	if t == nil {
+		return
+	}
+	d := time.Since(start)
+	html, _ := goquery.OuterHtml(doc.Selection)
+	t.Stages = append(t.Stages, TraceStage{Name: name, HTML: html, Duration: d})
+}
@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// TruncationMarker ends content cut short by Truncate and TruncateHTML
+const TruncationMarker = "[… truncated]"
+
+// charsPerToken is the rough ratio used to turn a token budget into
+// characters; close enough for English prose with common LLM tokenizers
+const charsPerToken = 4
+
+// sentenceEndRe matches the end of a sentence, with closing quotes or
+// brackets, followed by whitespace
+var sentenceEndRe = regexp.MustCompile(`[.!?…。][)\]"'”’]*\s`)
+
+// EstimateTokens approximates the LLM token count of s
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + charsPerToken - 1) / charsPerToken
+}
+
+// TokensToChars converts a token budget to the character budget of Truncate
+func TokensToChars(tokens int) int {
+	return tokens * charsPerToken
+}
+
+// Truncate shortens text or markdown to at most maxChars characters, marker
+// included. The cut falls on the last paragraph break, else the last
+// sentence end, else the last space; a code fence left open is closed. The
+// second result reports whether anything was cut.
+func Truncate(content string, maxChars int) (string, bool) {
+	if maxChars <= 0 || utf8.RuneCountInString(content) <= maxChars {
+		return content, false
+	}
+	budget := max(maxChars-utf8.RuneCountInString(TruncationMarker)-2, 0)
+	head := strings.TrimSpace(cutAtBoundary(string([]rune(content)[:budget])))
+
+	if strings.Count("\n"+head, "\n```")%2 == 1 {
+		head += "\n```"
+	}
+	if head == "" {
+		return TruncationMarker, true
+	}
+	return head + "\n\n" + TruncationMarker, true
+}
+
+// cutAtBoundary trims s back to a paragraph break, sentence end or space, as
+// long as that keeps at least half of it
+func cutAtBoundary(s string) string {
+	keep := len(s) / 2
+	if i := strings.LastIndex(s, "\n\n"); i >= keep {
+		return s[:i]
+	}
+	if i := lastSentenceEnd(s); i >= keep {
+		return s[:i]
+	}
+	if i := strings.LastIndexAny(s, " \t\n"); i >= keep {
+		return s[:i]
+	}
+	return s
+}
+
+// lastSentenceEnd returns the index just past the last sentence end in s,
+// or -1
+func lastSentenceEnd(s string) int {
+	ends := sentenceEndRe.FindAllStringIndex(s, -1)
+	if len(ends) == 0 {
+		return -1
+	}
+	return ends[len(ends)-1][1]
+}
+
+// TruncateHTML shortens an HTML fragment to about maxChars characters of
+// markup, marker included. Elements are kept whole where they fit; the text
+// of the first one that does not is cut at a sentence end, and dropped when
+// no sentence fits.
+func TruncateHTML(body string, maxChars int) (string, bool) {
+	if maxChars <= 0 || utf8.RuneCountInString(body) <= maxChars {
+		return body, false
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body, false
+	}
+	root := doc.Find("body")
+	if root.Length() == 0 {
+		return body, false
+	}
+
+	marker := "<p>" + TruncationMarker + "</p>"
+	budget := maxChars - utf8.RuneCountInString(marker)
+	fitChildren(root.Nodes[0], &budget)
+	root.AppendHtml(marker)
+
+	out, err := root.Html()
+	if err != nil {
+		return body, false
+	}
+	return strings.TrimSpace(out), true
+}
+
+// fitChildren keeps the children of n that fit in budget and drops the rest,
+// descending into the first child that does not fit
+func fitChildren(n *html.Node, budget *int) {
+	c := n.FirstChild
+	for ; c != nil; c = c.NextSibling {
+		size := renderedLen(c)
+		if size <= *budget {
+			*budget -= size
+			continue
+		}
+		switch {
+		case c.Type == html.TextNode && *budget > 0:
+			if i := lastSentenceEnd(string([]rune(c.Data)[:*budget])); i > 0 {
+				c.Data = c.Data[:i]
+				c = c.NextSibling
+			}
+		case c.Type == html.ElementNode && c.FirstChild != nil:
+			// The tags themselves take part of the budget
+			*budget -= size - innerLen(c)
+			if *budget > 0 {
+				fitChildren(c, budget)
+			}
+			if strings.TrimSpace(nodeText(c)) != "" {
+				c = c.NextSibling
+			}
+		}
+		break
+	}
+	if c == nil {
+		return
+	}
+	for c != nil {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		c = next
+	}
+	*budget = 0
+}
+
+func renderedLen(n *html.Node) int {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return 0
+	}
+	return utf8.RuneCount(buf.Bytes())
+}
+
+func innerLen(n *html.Node) int {
+	size := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		size += renderedLen(c)
+	}
+	return size
+}
+
+func nodeText(n *html.Node) string {
+	return goquery.NewDocumentFromNode(n).Text()
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	content := "First paragraph is here.\n\nSecond paragraph has two sentences. This one is long enough to be cut somewhere in the middle of it."
+
+	if got, cut := Truncate(content, 0); cut || got != content {
+		t.Errorf("no limit should leave content alone")
+	}
+	if got, cut := Truncate(content, len(content)); cut || got != content {
+		t.Errorf("content within the limit should be left alone")
+	}
+
+	got, cut := Truncate(content, 80)
+	if !cut || got != "First paragraph is here.\n\nSecond paragraph has two sentences.\n\n"+TruncationMarker {
+		t.Errorf("expected cut at the sentence end, got %q", got)
+	}
+	if utf8.RuneCountInString(got) > 80 {
+		t.Errorf("truncated content exceeds the limit: %d", utf8.RuneCountInString(got))
+	}
+
+	got, _ = Truncate(content, 50)
+	if got != "First paragraph is here.\n\n"+TruncationMarker {
+		t.Errorf("expected cut at the paragraph break, got %q", got)
+	}
+
+	code := "Intro text here.\n\n```go\nfunc main() {\n\tfmt.Println(\"hello world, this is a long line\")\n}\n```"
+	got, _ = Truncate(code, 70)
+	if strings.Count(got, "```")%2 != 0 {
+		t.Errorf("open code fence was not closed: %q", got)
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	body := `<h2>Title</h2><p>First sentence here. Second sentence follows.</p><p>Another paragraph that will be dropped entirely.</p>`
+
+	got, cut := TruncateHTML(body, 75)
+	if !cut {
+		t.Fatal("expected the body to be truncated")
+	}
+	if !strings.HasPrefix(got, "<h2>Title</h2><p>First sentence here. </p>") {
+		t.Errorf("expected the second paragraph cut at a sentence end, got %q", got)
+	}
+	if strings.Contains(got, "Another") || !strings.HasSuffix(got, "<p>"+TruncationMarker+"</p>") {
+		t.Errorf("expected later blocks dropped and the marker appended, got %q", got)
+	}
+
+	if got, cut := TruncateHTML(body, 1000); cut || got != body {
+		t.Errorf("body within the limit should be left alone")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if n := EstimateTokens("abcdefgh"); n != 2 {
+		t.Errorf("expected 2 tokens, got %d", n)
+	}
+	if n := EstimateTokens("abcdefghi"); n != 3 {
+		t.Errorf("expected 3 tokens, got %d", n)
+	}
+}
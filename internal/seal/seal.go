@@ -0,0 +1,114 @@
+// Package seal encrypts the files a run writes with age, so outputs and raw
+// HTML stores hold no plaintext, and decrypts stored files again with the
+// matching identities.
+package seal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Ext is appended to the names of files scrpr encrypts itself
+const Ext = ".age"
+
+// Sealer encrypts to a set of recipients and decrypts with a set of
+// identities; either may be empty
+type Sealer struct {
+	recipients []age.Recipient
+	identities []age.Identity
+}
+
+// ParseRecipient reads a recipient given as scheme:key. The only scheme is
+// age, with an X25519 public key: age:age1...
+func ParseRecipient(spec string) (age.Recipient, error) {
+	scheme, key, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid recipient %q (want age:age1...)", spec)
+	}
+	if scheme != "age" {
+		return nil, fmt.Errorf("unsupported encryption scheme %q in %q (available: age)", scheme, spec)
+	}
+	r, err := age.ParseX25519Recipient(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient %q: %w", key, err)
+	}
+	return r, nil
+}
+
+// New returns a Sealer encrypting to the recipients, each as ParseRecipient
+// reads them
+func New(recipients []string) (*Sealer, error) {
+	s := &Sealer{}
+	for _, spec := range recipients {
+		r, err := ParseRecipient(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.recipients = append(s.recipients, r)
+	}
+	return s, nil
+}
+
+// LoadIdentities adds the identities of an age identity file, one
+// AGE-SECRET-KEY-1... per line, for Open
+func (s *Sealer) LoadIdentities(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open identity file: %w", err)
+	}
+	defer f.Close()
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return fmt.Errorf("failed to read identity file %s: %w", path, err)
+	}
+	s.identities = append(s.identities, ids...)
+	return nil
+}
+
+// CanSeal reports whether the Sealer has recipients to encrypt to
+func (s *Sealer) CanSeal() bool {
+	return len(s.recipients) > 0
+}
+
+// Writer returns a writer encrypting to w. Closing it writes the end of
+// the encrypted stream but leaves w open.
+func (s *Sealer) Writer(w io.Writer) (io.WriteCloser, error) {
+	if !s.CanSeal() {
+		return nil, errors.New("no recipients to encrypt to")
+	}
+	return age.Encrypt(w, s.recipients...)
+}
+
+// Seal encrypts data
+func (s *Sealer) Seal(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := s.Writer(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open decrypts data sealed to one of the Sealer's identities
+func (s *Sealer) Open(data []byte) ([]byte, error) {
+	if len(s.identities) == 0 {
+		return nil, errors.New("no identity to decrypt with")
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), s.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,123 @@
+package seal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// newKey returns a sealer for a fresh key pair, its identity loaded from a
+// file as --identity does
+func newKey(t *testing.T) *Sealer {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New([]string{"age:" + id.Recipient().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(path, []byte("# test key\n"+id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadIdentities(path); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSealOpen(t *testing.T) {
+	s := newKey(t)
+	plain := []byte("<html>internal wiki</html>")
+	sealed, err := s.Seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("internal wiki")) {
+		t.Error("sealed data contains the plaintext")
+	}
+	got, err := s.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("Open = %q, want %q", got, plain)
+	}
+
+	if _, err := newKey(t).Open(sealed); err == nil {
+		t.Error("expected another key to fail to decrypt")
+	}
+}
+
+func TestWriterLeavesUnderlyingOpen(t *testing.T) {
+	s := newKey(t)
+	var buf bytes.Buffer
+	w, err := s.Writer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"one\n", "two\n"} {
+		if _, err := w.Write([]byte(part)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Open(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "one\ntwo\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseRecipient(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	key := id.Recipient().String()
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{"age:" + key, ""},
+		{" age:" + key + " ", ""},
+		{key, "want age:age1"},
+		{"ssh:" + key, "unsupported encryption scheme"},
+		{"age:age1nope", "invalid age recipient"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRecipient(tt.spec)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("ParseRecipient(%q): %v", tt.spec, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseRecipient(%q) error = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestNoKeys(t *testing.T) {
+	s, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.CanSeal() {
+		t.Error("expected a sealer without recipients not to seal")
+	}
+	if _, err := s.Seal([]byte("x")); err == nil {
+		t.Error("expected Seal without recipients to fail")
+	}
+	if _, err := s.Open([]byte("x")); err == nil {
+		t.Error("expected Open without identities to fail")
+	}
+}
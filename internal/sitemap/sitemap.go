@@ -0,0 +1,95 @@
+// Package sitemap reads XML sitemaps and remembers which of their URLs were
+// already handled, so a site without feeds can be followed by extracting
+// only what its sitemap gained since the last run.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxSize is the largest sitemap read, uncompressed; the protocol allows
+// 50MB
+const MaxSize = 50 << 20
+
+// Entry is a <url> of a sitemap, or a <sitemap> of a sitemap index
+type Entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// Sitemap is a parsed sitemap. An index lists further sitemaps instead of
+// pages.
+type Sitemap struct {
+	URLs     []Entry
+	Sitemaps []Entry // set for a sitemap index
+}
+
+// Parse reads an XML sitemap or sitemap index, gzipped or not, or a text
+// sitemap of one URL per line
+func Parse(data []byte) (*Sitemap, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzipped sitemap: %w", err)
+		}
+		if data, err = io.ReadAll(io.LimitReader(zr, MaxSize+1)); err != nil {
+			return nil, fmt.Errorf("invalid gzipped sitemap: %w", err)
+		}
+		if len(data) > MaxSize {
+			return nil, fmt.Errorf("sitemap exceeds %d bytes", MaxSize)
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parseText(data), nil
+	}
+
+	var doc struct {
+		XMLName  xml.Name
+		URLs     []Entry `xml:"url"`
+		Sitemaps []Entry `xml:"sitemap"`
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+	default:
+		return nil, fmt.Errorf("not a sitemap: <%s>", doc.XMLName.Local)
+	}
+	sm := &Sitemap{}
+	for _, e := range doc.URLs {
+		if e.Loc = strings.TrimSpace(e.Loc); e.Loc != "" {
+			e.LastMod = strings.TrimSpace(e.LastMod)
+			sm.URLs = append(sm.URLs, e)
+		}
+	}
+	for _, e := range doc.Sitemaps {
+		if e.Loc = strings.TrimSpace(e.Loc); e.Loc != "" {
+			e.LastMod = strings.TrimSpace(e.LastMod)
+			sm.Sitemaps = append(sm.Sitemaps, e)
+		}
+	}
+	return sm, nil
+}
+
+func parseText(data []byte) *Sitemap {
+	sm := &Sitemap{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			sm.URLs = append(sm.URLs, Entry{Loc: line})
+		}
+	}
+	return sm
+}
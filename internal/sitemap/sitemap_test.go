@@ -0,0 +1,48 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	sm, err := Parse([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/a </loc><lastmod>2025-01-02</lastmod></url>
+  <url><loc></loc></url>
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.URLs) != 2 || sm.URLs[0].Loc != "https://example.com/a" || sm.URLs[0].LastMod != "2025-01-02" || len(sm.Sitemaps) != 0 {
+		t.Errorf("urlset = %+v", sm)
+	}
+
+	sm, err = Parse([]byte(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/news.xml</loc><lastmod>2025-01-03T10:00:00Z</lastmod></sitemap>
+</sitemapindex>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.Sitemaps) != 1 || sm.Sitemaps[0].Loc != "https://example.com/news.xml" {
+		t.Errorf("index = %+v", sm)
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`<urlset><url><loc>https://example.com/z</loc></url></urlset>`))
+	zw.Close()
+	if sm, err = Parse(gz.Bytes()); err != nil || len(sm.URLs) != 1 {
+		t.Errorf("gzipped sitemap = %+v, %v", sm, err)
+	}
+
+	if sm, err = Parse([]byte("https://example.com/1\n\nnot a url\nhttps://example.com/2\n")); err != nil || len(sm.URLs) != 2 {
+		t.Errorf("text sitemap = %+v, %v", sm, err)
+	}
+
+	if _, err := Parse([]byte(`<html><body>Not found</body></html>`)); err == nil {
+		t.Error("HTML page should not parse as a sitemap")
+	}
+}
@@ -0,0 +1,182 @@
+package sitemap
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// maxKnown bounds the URLs a state remembers; the ones seen first are
+// forgotten first, as sitemaps drop old pages too
+const maxKnown = 200000
+
+// MaxAttempts is how many runs a new URL that failed is retried in
+const MaxAttempts = 3
+
+// State is what the earlier runs saw of one sitemap. Not safe for
+// concurrent use.
+type State struct {
+	path string
+
+	Sitemap string               `json:"sitemap"`
+	Updated time.Time            `json:"updated"`           // last run; zero before the first
+	Known   map[string]time.Time `json:"known"`             // handled URLs and when they were first seen
+	Pending map[string]int       `json:"pending,omitempty"` // new URLs that failed, by runs tried
+	// Lastmod of the child sitemaps of an index when they were last read;
+	// children whose lastmod did not change are not read again
+	Children map[string]string `json:"children,omitempty"`
+}
+
+// StatePath is the file of a sitemap's state in dir
+func StatePath(dir, sitemapURL string) string {
+	sum := sha256.Sum256([]byte(sitemapURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// LoadState reads the state at path, starting empty when the file does not
+// exist yet
+func LoadState(path, sitemapURL string) (*State, error) {
+	s := &State{
+		path:     path,
+		Sitemap:  sitemapURL,
+		Known:    make(map[string]time.Time),
+		Pending:  make(map[string]int),
+		Children: make(map[string]string),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid sitemap state %s: %w", path, err)
+	}
+	if s.Known == nil {
+		s.Known = make(map[string]time.Time)
+	}
+	if s.Pending == nil {
+		s.Pending = make(map[string]int)
+	}
+	if s.Children == nil {
+		s.Children = make(map[string]string)
+	}
+	return s, nil
+}
+
+// First reports whether no run has recorded the sitemap yet
+func (s *State) First() bool {
+	return s.Updated.IsZero()
+}
+
+// New returns the URLs not handled yet: pending ones first, then those of
+// urls not known, in their order and without duplicates
+func (s *State) New(urls []string) []string {
+	var fresh []string
+	seen := make(map[string]bool)
+	pending := make([]string, 0, len(s.Pending))
+	for u := range s.Pending {
+		pending = append(pending, u)
+	}
+	slices.Sort(pending)
+	for _, u := range slices.Concat(pending, urls) {
+		if _, known := s.Known[u]; known || seen[u] {
+			continue
+		}
+		seen[u] = true
+		fresh = append(fresh, u)
+	}
+	return fresh
+}
+
+// ChildChanged reports whether a child sitemap of an index needs reading:
+// its lastmod is missing or differs from the last read
+func (s *State) ChildChanged(loc, lastmod string) bool {
+	last, ok := s.Children[loc]
+	return !ok || lastmod == "" || last != lastmod
+}
+
+// ReadChild records the lastmod of a child sitemap that was read
+func (s *State) ReadChild(loc, lastmod string) {
+	s.Children[loc] = lastmod
+}
+
+// Done records a URL as handled
+func (s *State) Done(u string, now time.Time) {
+	delete(s.Pending, u)
+	if _, ok := s.Known[u]; !ok {
+		s.Known[u] = now
+	}
+}
+
+// Failed records a new URL that could not be handled, to retry it in the
+// next run; after MaxAttempts runs it is given up and recorded as handled
+func (s *State) Failed(u string, now time.Time) {
+	s.Pending[u]++
+	if s.Pending[u] >= MaxAttempts {
+		s.Done(u, now)
+	}
+}
+
+// Defer records a new URL that was not tried, such as when the run's
+// budget ran out, to try it in the next run
+func (s *State) Defer(u string) {
+	if _, ok := s.Pending[u]; !ok {
+		s.Pending[u] = 0
+	}
+}
+
+// Save writes the state atomically, marking the run
+func (s *State) Save(now time.Time) error {
+	s.Updated = now
+	s.forget()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sitemap state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write sitemap state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write sitemap state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write sitemap state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write sitemap state: %w", err)
+	}
+	return nil
+}
+
+// forget drops the oldest known URLs beyond maxKnown
+func (s *State) forget() {
+	if len(s.Known) <= maxKnown {
+		return
+	}
+	urls := make([]string, 0, len(s.Known))
+	for u := range s.Known {
+		urls = append(urls, u)
+	}
+	slices.SortFunc(urls, func(a, b string) int {
+		return cmp.Or(s.Known[a].Compare(s.Known[b]), cmp.Compare(a, b))
+	})
+	for _, u := range urls[:len(urls)-maxKnown] {
+		delete(s.Known, u)
+	}
+}
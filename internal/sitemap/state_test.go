@@ -0,0 +1,69 @@
+package sitemap
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := LoadState(path, "https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.First() {
+		t.Fatal("new state should be the first run")
+	}
+	s.Done("https://example.com/a", now)
+	s.ReadChild("https://example.com/news.xml", "2025-01-01")
+	if err := s.Save(now); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadState(path, "https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.First() {
+		t.Error("saved state should not be the first run")
+	}
+	got := s.New([]string{"https://example.com/a", "https://example.com/b", "https://example.com/b", "https://example.com/c"})
+	want := []string{"https://example.com/b", "https://example.com/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New = %v, want %v", got, want)
+	}
+
+	if s.ChildChanged("https://example.com/news.xml", "2025-01-01") {
+		t.Error("child with the same lastmod should be skipped")
+	}
+	for _, lastmod := range []string{"2025-01-02", ""} {
+		if !s.ChildChanged("https://example.com/news.xml", lastmod) {
+			t.Errorf("child with lastmod %q should be read", lastmod)
+		}
+	}
+	if !s.ChildChanged("https://example.com/other.xml", "2025-01-01") {
+		t.Error("unknown child should be read")
+	}
+
+	// Failed and untried URLs come back first in later runs
+	s.Done("https://example.com/b", now)
+	s.Failed("https://example.com/c", now)
+	s.Defer("https://example.com/d")
+	got = s.New([]string{"https://example.com/e"})
+	want = []string{"https://example.com/c", "https://example.com/d", "https://example.com/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New after failures = %v, want %v", got, want)
+	}
+
+	// A URL failing MaxAttempts runs is given up
+	for range MaxAttempts - 1 {
+		s.Failed("https://example.com/c", now)
+	}
+	if got := s.New(nil); !reflect.DeepEqual(got, []string{"https://example.com/d"}) {
+		t.Errorf("New after giving up = %v", got)
+	}
+}
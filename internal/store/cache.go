@@ -0,0 +1,112 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CachedResponse is a fetched page as kept by ResponseCache
+type CachedResponse struct {
+	URL         string           `json:"url"` // final URL after redirects
+	Redirects   []CachedRedirect `json:"redirects,omitempty"`
+	ContentType string           `json:"content_type"`
+	Fetched     time.Time        `json:"fetched"`
+	Title       string           `json:"title,omitempty"` // set by extraction APIs, which return it apart from Body
+	RobotsTag   []string         `json:"robots_tag,omitempty"`
+	Body        string           `json:"body"`
+}
+
+// CachedRedirect is one hop of the redirect chain that led to a cached
+// response; it mirrors fetcher.Redirect
+type CachedRedirect struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// ResponseCache keeps fetched pages on disk, zstd-compressed, for a limited
+// time. Entries are keyed by URL plus a variant string describing the fetch
+// options that shape the response, so a page fetched with another user agent
+// or language is a separate entry.
+type ResponseCache struct {
+	dir     string
+	ttl     time.Duration
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewResponseCache opens (and creates if needed) a cache rooted at dir whose
+// entries expire after ttl
+func NewResponseCache(dir string, ttl time.Duration) (*ResponseCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create response cache %s: %w", dir, err)
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		encoder.Close()
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return &ResponseCache{dir: dir, ttl: ttl, encoder: encoder, decoder: decoder}, nil
+}
+
+// Path returns the file an entry is stored under
+func (rc *ResponseCache) Path(url, variant string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + variant))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(rc.dir, name[:2], name+".json.zst")
+}
+
+// Get returns the entry for url and variant. A missing or expired entry
+// yields an error satisfying errors.Is(err, os.ErrNotExist).
+func (rc *ResponseCache) Get(url, variant string) (*CachedResponse, error) {
+	data, err := os.ReadFile(rc.Path(url, variant))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rc.decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress cached response for %s: %w", url, err)
+	}
+	var resp CachedResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("invalid cached response for %s: %w", url, err)
+	}
+	if rc.ttl > 0 && time.Since(resp.Fetched) > rc.ttl {
+		return nil, fmt.Errorf("cached response for %s expired: %w", url, os.ErrNotExist)
+	}
+	return &resp, nil
+}
+
+// Put stores resp for url and variant, replacing any earlier entry
+func (rc *ResponseCache) Put(url, variant string, resp *CachedResponse) error {
+	path := rc.Path(url, variant)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create response cache shard: %w", err)
+	}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(path, rc.encoder.EncodeAll(raw, nil)); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	return nil
+}
+
+// Close releases the encoder and decoder
+func (rc *ResponseCache) Close() {
+	rc.encoder.Close()
+	rc.decoder.Close()
+}
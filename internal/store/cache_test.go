@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResponseCache_RoundTripAndVariants(t *testing.T) {
+	rc, err := NewResponseCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	resp := &CachedResponse{URL: "https://example.com/final", ContentType: "text/html", Fetched: time.Now(), Body: "<p>hello</p>"}
+	if err := rc.Put("https://example.com/a", "ua=chrome", resp); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := rc.Get("https://example.com/a", "ua=chrome")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Body != resp.Body || got.URL != resp.URL || got.ContentType != resp.ContentType {
+		t.Errorf("round-tripped response does not match: %+v", got)
+	}
+
+	if _, err := rc.Get("https://example.com/a", "ua=firefox"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected another variant to miss, got %v", err)
+	}
+}
+
+func TestResponseCache_Expiry(t *testing.T) {
+	rc, err := NewResponseCache(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	old := &CachedResponse{URL: "https://example.com/a", Fetched: time.Now().Add(-2 * time.Minute), Body: "stale"}
+	if err := rc.Put("https://example.com/a", "", old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc.Get("https://example.com/a", ""); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected expired entry to miss, got %v", err)
+	}
+}
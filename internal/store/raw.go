@@ -0,0 +1,172 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// RawStore keeps fetched HTML on disk compressed with zstd, one file per URL.
+// Files are sharded by the first byte of the URL hash to keep directories small.
+type RawStore struct {
+	dir     string
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	sealer  Sealer
+}
+
+// Sealer encrypts stored HTML at rest and decrypts it again
+type Sealer interface {
+	Seal(data []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// sealedExt marks encrypted entries, which sit next to plain ones
+const sealedExt = ".age"
+
+// NewRawStore opens (and creates if needed) a raw HTML store rooted at dir
+func NewRawStore(dir string) (*RawStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raw store %s: %w", dir, err)
+	}
+
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		encoder.Close()
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return &RawStore{dir: dir, encoder: encoder, decoder: decoder}, nil
+}
+
+// SetSealer encrypts the entries Put stores from now on, under a .age
+// name, and decrypts them in Get
+func (rs *RawStore) SetSealer(s Sealer) {
+	rs.sealer = s
+}
+
+// Path returns the file a URL is stored under
+func (rs *RawStore) Path(url string) string {
+	if rs.sealer != nil {
+		return rs.plainPath(url) + sealedExt
+	}
+	return rs.plainPath(url)
+}
+
+func (rs *RawStore) plainPath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(rs.dir, name[:2], name+".html.zst")
+}
+
+// Put compresses and stores the HTML for url. The file is written to a
+// temporary name and renamed so readers never observe partial data.
+func (rs *RawStore) Put(url string, html []byte) error {
+	path := rs.Path(url)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create raw store shard: %w", err)
+	}
+
+	data := rs.encoder.EncodeAll(html, make([]byte, 0, len(html)/4))
+	if rs.sealer != nil {
+		var err error
+		if data, err = rs.sealer.Seal(data); err != nil {
+			return fmt.Errorf("failed to encrypt raw HTML: %w", err)
+		}
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write raw HTML: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// into place
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Get returns the stored HTML for url. The compressed file is memory-mapped
+// rather than read so reprocessing large crawls doesn't double the I/O.
+// A missing entry yields an error satisfying errors.Is(err, os.ErrNotExist).
+// With a Sealer set, plain entries stored before are read too; without one,
+// an encrypted entry is an error rather than missing.
+func (rs *RawStore) Get(url string) ([]byte, error) {
+	if rs.sealer == nil {
+		html, err := rs.load(rs.plainPath(url), url)
+		if errors.Is(err, os.ErrNotExist) && rs.exists(rs.plainPath(url)+sealedExt) {
+			return nil, fmt.Errorf("raw HTML for %s is encrypted; an identity is needed to read it", url)
+		}
+		return html, err
+	}
+	html, err := rs.load(rs.Path(url), url)
+	if errors.Is(err, os.ErrNotExist) {
+		return rs.load(rs.plainPath(url), url)
+	}
+	return html, err
+}
+
+// load reads and decompresses the entry for url at path, decrypting it when
+// it is sealed
+func (rs *RawStore) load(path, url string) ([]byte, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+
+	if strings.HasSuffix(path, sealedExt) {
+		if data, err = rs.sealer.Open(data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt raw HTML for %s: %w", url, err)
+		}
+	}
+
+	html, err := rs.decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress raw HTML for %s: %w", url, err)
+	}
+	return html, nil
+}
+
+// Has reports whether url is present in the store, encrypted or not
+func (rs *RawStore) Has(url string) bool {
+	return rs.exists(rs.plainPath(url)) || rs.exists(rs.plainPath(url)+sealedExt)
+}
+
+func (rs *RawStore) exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Close releases the encoder and decoder
+func (rs *RawStore) Close() {
+	rs.encoder.Close()
+	rs.decoder.Close()
+}
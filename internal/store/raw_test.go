@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRawStore_RoundTrip(t *testing.T) {
+	rs, err := NewRawStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	html := []byte("<html><body>" + strings.Repeat("<p>repeated paragraph</p>", 1000) + "</body></html>")
+	if err := rs.Put("https://example.com/a", html); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(rs.Path("https://example.com/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= int64(len(html)) {
+		t.Errorf("expected compressed file smaller than %d bytes, got %d", len(html), info.Size())
+	}
+
+	got, err := rs.Get("https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(html) {
+		t.Error("round-tripped HTML does not match")
+	}
+}
+
+func TestRawStore_Missing(t *testing.T) {
+	rs, err := NewRawStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	if rs.Has("https://example.com/missing") {
+		t.Error("expected missing entry")
+	}
+	if _, err := rs.Get("https://example.com/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
+
+// xorSealer stands in for age: reversible, and not the plaintext
+type xorSealer struct{}
+
+func (xorSealer) Seal(data []byte) ([]byte, error) { return xorSealer{}.Open(data) }
+
+func (xorSealer) Open(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0x5a
+	}
+	return out, nil
+}
+
+func TestRawStore_Sealed(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := NewRawStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if err := plain.Put("https://example.com/old", []byte("<p>old</p>")); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := NewRawStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sealed.Close()
+	sealed.SetSealer(xorSealer{})
+	if err := sealed.Put("https://example.com/new", []byte("<p>new</p>")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sealed.Path("https://example.com/new"), ".html.zst.age") {
+		t.Errorf("sealed entry stored as %s", sealed.Path("https://example.com/new"))
+	}
+
+	for url, want := range map[string]string{
+		"https://example.com/new": "<p>new</p>",
+		"https://example.com/old": "<p>old</p>", // stored before, in the clear
+	} {
+		got, err := sealed.Get(url)
+		if err != nil || string(got) != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", url, got, err, want)
+		}
+	}
+
+	// Without the sealer the encrypted entry is there, but unreadable
+	if !plain.Has("https://example.com/new") {
+		t.Error("expected Has to see the encrypted entry")
+	}
+	if _, err := plain.Get("https://example.com/new"); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected an encrypted-entry error, got %v", err)
+	}
+}
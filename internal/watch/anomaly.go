@@ -0,0 +1,104 @@
+// Package watch compares repeated extractions of the same URLs, telling
+// genuine content changes apart from suspicious drops in extracted length
+// (a paywall, a block page or an extraction regression)
+package watch
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Sample is one earlier extraction of a URL
+type Sample struct {
+	Time   time.Time `json:"time"`
+	Length int       `json:"length"` // characters of extracted text
+	Hash   string    `json:"hash"`   // of the extracted text
+}
+
+// Thresholds configure Classify
+type Thresholds struct {
+	DropRatio  float64 // a length below (1 - DropRatio) × baseline is an anomaly, e.g. 0.5
+	MinHistory int     // earlier samples needed before drops are flagged
+	Window     int     // samples kept per URL; the baseline is their median
+}
+
+// DefaultThresholds flag a halving against the median of the last five runs
+// once there are two to compare with
+func DefaultThresholds() Thresholds {
+	return Thresholds{DropRatio: 0.5, MinHistory: 2, Window: 5}
+}
+
+// Validate reports thresholds Classify cannot work with
+func (t Thresholds) Validate() error {
+	if t.DropRatio <= 0 || t.DropRatio >= 1 {
+		return fmt.Errorf("drop ratio must be between 0 and 1, got %g", t.DropRatio)
+	}
+	if t.MinHistory < 1 {
+		return fmt.Errorf("min history must be at least 1, got %d", t.MinHistory)
+	}
+	if t.Window < t.MinHistory {
+		return fmt.Errorf("window (%d) must be at least min history (%d)", t.Window, t.MinHistory)
+	}
+	return nil
+}
+
+// Verdict classifies a new extraction against the URL's history
+type Verdict string
+
+const (
+	New       Verdict = "new"       // no history yet
+	Unchanged Verdict = "unchanged" // same text as the last run
+	Changed   Verdict = "changed"   // different text of plausible length
+	Anomaly   Verdict = "anomaly"   // length dropped far below the baseline
+)
+
+// Result is the outcome of Classify
+type Result struct {
+	Verdict  Verdict
+	Baseline int     // median length of the compared samples (0 = none)
+	Drop     float64 // fraction of the baseline lost, 0 when the text grew
+}
+
+func (r Result) String() string {
+	if r.Verdict == Anomaly {
+		return fmt.Sprintf("anomaly: extracted length dropped %.0f%% below the usual %d characters", r.Drop*100, r.Baseline)
+	}
+	return string(r.Verdict)
+}
+
+// Classify compares an extraction with the URL's earlier samples, oldest
+// first. A drop is measured against the median of the last Window samples,
+// so one odd run in the history neither causes nor hides an alert. A drop
+// is only flagged once MinHistory samples exist; before that a shorter text
+// counts as a change.
+func Classify(history []Sample, current Sample, t Thresholds) Result {
+	if len(history) == 0 {
+		return Result{Verdict: New}
+	}
+	if t.Window > 0 && len(history) > t.Window {
+		history = history[len(history)-t.Window:]
+	}
+
+	lengths := make([]int, len(history))
+	for i, s := range history {
+		lengths[i] = s.Length
+	}
+	slices.Sort(lengths)
+	baseline := lengths[len(lengths)/2]
+	if len(lengths)%2 == 0 {
+		baseline = (lengths[len(lengths)/2-1] + baseline) / 2
+	}
+
+	result := Result{Verdict: Changed, Baseline: baseline}
+	if baseline > 0 && current.Length < baseline {
+		result.Drop = float64(baseline-current.Length) / float64(baseline)
+	}
+	switch {
+	case current.Hash != "" && current.Hash == history[len(history)-1].Hash:
+		result.Verdict = Unchanged
+	case len(history) >= t.MinHistory && result.Drop >= t.DropRatio:
+		result.Verdict = Anomaly
+	}
+	return result
+}
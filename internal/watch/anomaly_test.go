@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func samples(lengths ...int) []Sample {
+	var s []Sample
+	for i, n := range lengths {
+		s = append(s, Sample{Length: n, Hash: string(rune('a' + i))})
+	}
+	return s
+}
+
+func TestClassify(t *testing.T) {
+	th := DefaultThresholds()
+	cases := []struct {
+		name    string
+		history []Sample
+		current Sample
+		want    Verdict
+	}{
+		{"first run", nil, Sample{Length: 100, Hash: "x"}, New},
+		{"same text", samples(5000, 5000), Sample{Length: 5000, Hash: "b"}, Unchanged},
+		{"edited text", samples(5000, 5100), Sample{Length: 4800, Hash: "z"}, Changed},
+		{"paywall", samples(5000, 5100, 4900), Sample{Length: 400, Hash: "z"}, Anomaly},
+		{"too little history", samples(5000), Sample{Length: 400, Hash: "z"}, Changed},
+		{"one odd run in history", samples(5000, 300, 5100), Sample{Length: 4900, Hash: "z"}, Changed},
+		{"drop after odd run", samples(5000, 300, 5100), Sample{Length: 500, Hash: "z"}, Anomaly},
+		{"growth", samples(5000, 5000), Sample{Length: 20000, Hash: "z"}, Changed},
+	}
+	for _, c := range cases {
+		if got := Classify(c.history, c.current, th); got.Verdict != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestClassify_Window(t *testing.T) {
+	// Old long versions fall out of the window, so a shorter page that has
+	// stayed short is no longer an anomaly
+	history := samples(9000, 9000, 9000, 9000, 2000, 2000, 2000, 2000, 2000)
+	got := Classify(history, Sample{Length: 1900, Hash: "z"}, Thresholds{DropRatio: 0.5, MinHistory: 2, Window: 5})
+	if got.Verdict != Changed || got.Baseline != 2000 {
+		t.Errorf("got %+v, want changed against baseline 2000", got)
+	}
+}
+
+func TestResultString(t *testing.T) {
+	r := Classify(samples(1000, 1000), Sample{Length: 250, Hash: "z"}, DefaultThresholds())
+	if !strings.Contains(r.String(), "dropped 75%") {
+		t.Errorf("String() = %q", r.String())
+	}
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	if err := DefaultThresholds().Validate(); err != nil {
+		t.Errorf("defaults invalid: %v", err)
+	}
+	bad := []Thresholds{
+		{DropRatio: 0, MinHistory: 1, Window: 1},
+		{DropRatio: 1.5, MinHistory: 1, Window: 1},
+		{DropRatio: 0.5, MinHistory: 0, Window: 1},
+		{DropRatio: 0.5, MinHistory: 3, Window: 2},
+	}
+	for _, th := range bad {
+		if th.Validate() == nil {
+			t.Errorf("%+v: expected error", th)
+		}
+	}
+}
+
+func TestHistory_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch", "history.json")
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th := Thresholds{DropRatio: 0.5, MinHistory: 2, Window: 3}
+	body := strings.Repeat("word ", 500)
+	for i := range 4 {
+		if got := h.Check("https://example.com/", body+string(rune('a'+i)), th); i > 0 && got.Verdict != Changed {
+			t.Errorf("run %d: got %s, want changed", i, got)
+		}
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Samples("https://example.com/")); n != 3 {
+		t.Fatalf("kept %d samples, want 3", n)
+	}
+	if got := h.Check("https://example.com/", "Subscribe to continue reading", th); got.Verdict != Anomaly {
+		t.Errorf("got %s, want anomaly", got)
+	}
+	if s := NewSample("héllo", time.Now()); s.Length != 5 {
+		t.Errorf("length counts runes: got %d", s.Length)
+	}
+}
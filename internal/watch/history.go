@@ -0,0 +1,89 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// History keeps the last samples of every URL in one JSON file. Not safe
+// for concurrent use.
+type History struct {
+	path   string
+	window int
+	urls   map[string][]Sample
+}
+
+// LoadHistory reads the history at path, starting empty when the file does
+// not exist yet; window is the number of samples kept per URL
+func LoadHistory(path string, window int) (*History, error) {
+	h := &History{path: path, window: window, urls: make(map[string][]Sample)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.urls); err != nil {
+		return nil, fmt.Errorf("invalid watch history %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Samples returns the samples of a URL, oldest first
+func (h *History) Samples(url string) []Sample {
+	return h.urls[url]
+}
+
+// Check classifies an extraction of url against its history and records it
+func (h *History) Check(url, text string, t Thresholds) Result {
+	current := NewSample(text, time.Now())
+	result := Classify(h.urls[url], current, t)
+	h.Record(url, current)
+	return result
+}
+
+// Record appends a sample, dropping the oldest beyond the window
+func (h *History) Record(url string, s Sample) {
+	samples := append(h.urls[url], s)
+	if h.window > 0 && len(samples) > h.window {
+		samples = samples[len(samples)-h.window:]
+	}
+	h.urls[url] = samples
+}
+
+// Save writes the history atomically
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h.urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create watch history directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write watch history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write watch history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write watch history: %w", err)
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
+
+// NewSample describes an extracted text
+func NewSample(text string, at time.Time) Sample {
+	sum := sha256.Sum256([]byte(text))
+	return Sample{Time: at.UTC(), Length: len([]rune(text)), Hash: hex.EncodeToString(sum[:8])}
+}
@@ -3,11 +3,14 @@ package extractor
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/byteowlz/scrpr/internal/browser"
 	"github.com/byteowlz/scrpr/internal/config"
 	"github.com/byteowlz/scrpr/internal/fetcher"
+	"github.com/byteowlz/scrpr/internal/paths"
 	"github.com/byteowlz/scrpr/internal/processor"
 )
 
@@ -16,6 +19,15 @@ type Extractor struct {
 	fetcher   *fetcher.ContentFetcher
 	processor *processor.ContentProcessor
 	cookies   *browser.CookieExtractor
+	device    *fetcher.Device       // nil = desktop
+	viewport  *fetcher.Viewport     // nil = the device's or Chrome's
+	junk      *processor.JunkFilter // nil = keep junk
+	expand    *fetcher.ExpandRules  // nil = no click pass
+	browsers  *fetcher.BrowserPool  // nil = a new Chrome per JS fetch
+	scroll    *fetcher.ScrollConfig // nil = no scrolling
+	scripts   []fetcher.PageScript
+	scriptErr error // extraction.eval_js could not be read
+	wait      fetcher.WaitStrategy
 }
 
 type ExtractOptions struct {
@@ -33,19 +45,131 @@ type ExtractResult struct {
 	ProcessingTime time.Duration
 	ContentLength  int
 	Metadata       map[string]string
+	FinalURL       string             // URL the page was served from after redirects
+	Redirects      []fetcher.Redirect // followed redirects, first hop first
 }
 
 func New(cfg *config.Config) *Extractor {
+	contentFetcher := fetcher.NewContentFetcher()
+	contentFetcher.SetTimeouts(fetcher.Timeouts{
+		Connect:        time.Duration(cfg.Network.ConnectTimeout) * time.Second,
+		TLSHandshake:   time.Duration(cfg.Network.TLSHandshakeTimeout) * time.Second,
+		ResponseHeader: time.Duration(cfg.Network.ResponseHeaderTimeout) * time.Second,
+		Total:          time.Duration(cfg.Network.Timeout) * time.Second,
+		Render:         time.Duration(cfg.Extraction.JSTimeout) * time.Second,
+	})
+
+	uaDataset := ""
+	if dataDir, err := config.DataDir(); err == nil {
+		uaDataset = filepath.Join(dataDir, fetcher.UserAgentDatasetFile)
+	}
+	// An unreadable dataset still yields the built-in pool
+	pools, _ := fetcher.UserAgentPools(uaDataset, cfg.Network.UserAgentPools)
+	contentFetcher.SetUserAgentPools(pools)
+	contentFetcher.SetStickyUserAgent(cfg.Network.StickyUserAgent)
+	contentFetcher.SetRedirects(cfg.Network.FollowRedirects, cfg.Network.MaxRedirects)
+
+	// An unknown wait condition waits for the load event
+	wait, _ := fetcher.ParseWaitStrategy(cfg.Extraction.WaitUntil)
+
+	var scriptFiles []string
+	for _, f := range cfg.Extraction.EvalJS {
+		scriptFiles = append(scriptFiles, paths.ExpandHome(f))
+	}
+	scripts, scriptErr := fetcher.LoadPageScripts(scriptFiles)
+
+	var scroll *fetcher.ScrollConfig
+	if ex := cfg.Extraction; ex.Scroll > 0 || ex.ScrollUntilStable {
+		scroll = &fetcher.ScrollConfig{
+			Rounds:      ex.Scroll,
+			UntilStable: ex.ScrollUntilStable,
+			MaxHeight:   ex.ScrollMaxHeight,
+			Timeout:     time.Duration(ex.ScrollTimeout) * time.Second,
+		}
+	}
+
+	// Chrome starts on the first JS fetch and keeps running until Close
+	var browsers *fetcher.BrowserPool
+	if pool := cfg.Parallel.BrowserPool; pool.Enabled {
+		browsers = fetcher.NewBrowserPool(fetcher.BrowserPoolOptions{
+			Browsers:       pool.Instances,
+			Tabs:           pool.Tabs,
+			MaxNavigations: pool.MaxNavigations,
+		})
+		contentFetcher.SetBrowserPool(browsers)
+	}
+
+	// New has no error return; an unknown device name falls back to desktop
+	var device *fetcher.Device
+	if cfg.Network.MobileDevice != "" {
+		device, _ = fetcher.LookupDevice(cfg.Network.MobileDevice)
+	}
+	// An invalid viewport keeps Chrome's window size
+	viewport, _ := fetcher.ParseViewport(cfg.Network.Viewport)
+
+	cookies := browser.NewCookieExtractor(browser.BrowserType(cfg.Browser.Default), cfg.Browser.Paths)
+	cookies.SetDomainFilter(cfg.Browser.Cookies.Domains, cfg.Browser.Cookies.Exclude)
+	cookies.SetCacheTTL(time.Duration(cfg.Browser.Cookies.CacheTTL) * time.Second)
+	if cfg.Browser.Cookies.File != "" {
+		cookies.SetCookieFile(cfg.Browser.Cookies.File)
+	}
+
+	contentProcessor := processor.NewContentProcessor()
+	contentProcessor.SetTOC(cfg.Output.TOC)
+	if flavor, err := processor.ParseMarkdownFlavor(cfg.Output.MarkdownFlavor); err == nil {
+		contentProcessor.SetMarkdownFlavor(flavor)
+	}
+
+	// An invalid [junk] config disables junk removal rather than failing New
+	var junk *processor.JunkFilter
+	if cfg.Junk.Enabled {
+		opts := processor.JunkOptions{Groups: cfg.Junk.Groups, Selectors: cfg.Junk.Selectors, Text: cfg.Junk.Text}
+		for _, site := range cfg.Junk.Sites {
+			opts.Sites = append(opts.Sites, processor.JunkSite(site))
+		}
+		junk, _ = processor.NewJunkFilter(opts)
+	}
+
+	// Likewise an invalid [expand] config turns the click pass off
+	var expand *fetcher.ExpandRules
+	if cfg.Expand.Enabled {
+		var sites []fetcher.ExpandSite
+		for _, site := range cfg.Expand.Sites {
+			sites = append(sites, fetcher.ExpandSite(site))
+		}
+		expand, _ = fetcher.NewExpandRules(cfg.Expand.Selectors, sites)
+	}
+
 	return &Extractor{
 		config:    cfg,
-		fetcher:   fetcher.NewContentFetcher(),
-		processor: processor.NewContentProcessor(),
-		cookies:   browser.NewCookieExtractor(browser.BrowserType(cfg.Browser.Default), cfg.Browser.Paths),
+		fetcher:   contentFetcher,
+		processor: contentProcessor,
+		cookies:   cookies,
+		device:    device,
+		viewport:  viewport,
+		junk:      junk,
+		expand:    expand,
+		browsers:  browsers,
+		scroll:    scroll,
+		scripts:   scripts,
+		scriptErr: scriptErr,
+		wait:      wait,
+	}
+}
+
+// Close stops the Chrome instances kept for JS fetches. The Extractor must
+// not be used afterwards.
+func (e *Extractor) Close() {
+	if e.browsers != nil {
+		e.browsers.Close()
 	}
 }
 
 func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions) (*ExtractResult, error) {
 	start := time.Now()
+	if e.scriptErr != nil {
+		return nil, e.scriptErr
+	}
 
 	// Extract cookies for the URL
 	cookies, err := e.cookies.ExtractCookies(url)
@@ -68,11 +192,25 @@ func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions
 	fetchOpts := fetcher.FetchOptions{
 		Mode:            fetchMode,
 		Timeout:         opts.Timeout,
+		RenderTimeout:   time.Duration(e.config.Extraction.JSTimeout) * time.Second,
 		UserAgent:       e.config.Network.UserAgent,
 		Cookies:         cookies,
+		AcceptLanguage:  e.config.Network.AcceptLanguage,
+		Referer:         e.config.Network.Referer,
+		Device:          e.device,
+		Viewport:        e.viewport,
+		Timezone:        e.config.Network.Timezone,
 		SkipBanners:     e.config.Extraction.SkipCookieBanners,
 		BannerTimeout:   time.Duration(e.config.Extraction.BannerTimeout) * time.Second,
 		WaitForSelector: e.config.Extraction.WaitForSelector,
+		WaitForText:     e.config.Extraction.WaitForText,
+		WaitUntil:       e.wait.Until,
+		NetworkIdle:     e.wait.Idle,
+		PrintMedia:      e.config.Extraction.PrintMedia,
+		Stealth:         e.config.Network.Stealth,
+		Scroll:          e.scroll,
+		Scripts:         e.scripts,
+		Expand:          e.expand,
 	}
 
 	// Fetch content
@@ -80,18 +218,30 @@ func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch content: %w", err)
 	}
+	if fetchResult.ConsentWall != "" {
+		return nil, fmt.Errorf("failed to fetch content: %w", &fetcher.ConsentWallError{Provider: fetchResult.ConsentWall})
+	}
 
 	// Set up processing options
 	processOpts := processor.ProcessOptions{
 		RemoveAds:        e.config.Extraction.RemoveAds,
 		CleanHTML:        e.config.Extraction.CleanHTML,
 		MinContentLength: e.config.Extraction.MinContentLength,
-		IncludeMetadata:  opts.IncludeMetadata,
+		IncludeMetadata:  opts.IncludeMetadata || e.config.Output.FrontMatter,
 		MetadataFields:   e.config.Output.MetadataFields,
+		Junk:             e.junk,
+		Language:         e.config.Extraction.Language,
 	}
 
 	// Process content
-	processed, err := e.processor.Process(fetchResult.HTML, url, processOpts)
+	processCtx := ctx
+	if e.config.Extraction.ProcessTimeout > 0 {
+		var cancel context.CancelFunc
+		processCtx, cancel = context.WithTimeout(ctx, time.Duration(e.config.Extraction.ProcessTimeout)*time.Second)
+		defer cancel()
+	}
+
+	processed, err := e.processor.ProcessContext(processCtx, strings.NewReader(fetchResult.HTML), url, processOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process content: %w", err)
 	}
@@ -100,7 +250,11 @@ func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions
 	var content string
 	switch opts.Format {
 	case "markdown":
-		content = e.processor.ToMarkdown(processed, opts.IncludeMetadata, e.config.Output.PreserveLinks)
+		if e.config.Output.FrontMatter {
+			content = e.processor.ToMarkdownWithFrontMatter(processed, url, e.config.Output.PreserveLinks)
+		} else {
+			content = e.processor.ToMarkdown(processed, opts.IncludeMetadata, e.config.Output.PreserveLinks)
+		}
 	case "text":
 		content = e.processor.ToText(processed, e.config.Output.LineWidth)
 	default:
@@ -109,6 +263,15 @@ func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions
 
 	processingTime := time.Since(start)
 
+	// Record a consent wall the browser got past
+	if provider := fetchResult.Metadata["consent_wall"]; provider != "" {
+		if processed.Metadata == nil {
+			processed.Metadata = make(map[string]string)
+		}
+		processed.Metadata["consent_wall"] = provider
+		processed.Metadata["consent_action"] = fetchResult.Metadata["consent_action"]
+	}
+
 	return &ExtractResult{
 		URL:            url,
 		Title:          processed.Title,
@@ -117,5 +280,7 @@ func (e *Extractor) Extract(ctx context.Context, url string, opts ExtractOptions
 		ProcessingTime: processingTime,
 		ContentLength:  len(content),
 		Metadata:       processed.Metadata,
+		FinalURL:       fetchResult.FinalURL,
+		Redirects:      fetchResult.Redirects,
 	}, nil
 }
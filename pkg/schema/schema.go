@@ -0,0 +1,123 @@
+// Package schema describes the JSON scrpr writes: results of --format json
+// (one object per line on stdout), the --json-envelope object and the
+// manifest.json of directory and archive output. Every top-level object
+// carries SchemaVersion.
+//
+// Adding a field keeps the version, so consumers should ignore fields they
+// do not know. Renaming, removing or retyping a field, or changing what it
+// means, bumps it.
+package schema
+
+// Version is the current schema_version
+const Version = 1
+
+// Result is one extracted URL
+type Result struct {
+	SchemaVersion int               `json:"schema_version"`
+	URL           string            `json:"url"`
+	FinalURL      string            `json:"final_url"` // after redirects ("" = not known)
+	Redirects     []Redirect        `json:"redirects"` // followed redirects, first hop first; never null
+	Title         string            `json:"title"`
+	Content       string            `json:"content"` // in the chosen format
+	Metadata      map[string]string `json:"metadata"`
+	Images        []string          `json:"images"`
+	Links         []Link            `json:"links"`
+	Media         []Media           `json:"media"`
+	Timing        Timing            `json:"timing"`
+	UsedJS        bool              `json:"used_js"`
+	Backend       string            `json:"backend"` // readability, tavily or jina
+	Labels        []string          `json:"labels"`  // from the input file and config; never null
+
+	Provenance Provenance `json:"provenance"`
+}
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	URL    string `json:"url"`    // URL that answered with the redirect
+	Status int    `json:"status"` // 301, 302, 303, 307 or 308
+}
+
+// Link is a link in the extracted content
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Media is an embedded video, audio or iframe
+type Media struct {
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// Timing is how long each stage took
+type Timing struct {
+	FetchMS   int64 `json:"fetch_ms"`
+	ProcessMS int64 `json:"process_ms"`
+	TotalMS   int64 `json:"total_ms"`
+}
+
+// Provenance records how a result was produced, to reproduce it and to
+// debug how fetch options and rules interacted
+type Provenance struct {
+	Fetcher   string `json:"fetcher"`              // http, chrome, jina, tavily, or local for files and stdin
+	Source    string `json:"source"`               // network, cache, raw-store, file or stdin
+	Mode      string `json:"mode"`                 // static, javascript or api
+	Backend   string `json:"backend"`              // extraction backend
+	UserAgent string `json:"user_agent,omitempty"` // as sent; empty when not fetched here
+	Proxy     string `json:"proxy,omitempty"`      // proxy used ("" = direct)
+	CacheHit  bool   `json:"cache_hit"`
+	Attempts  int    `json:"attempts"`           // requests made, retries included; 0 when not fetched
+	Fallback  string `json:"fallback,omitempty"` // why the requested backend was replaced
+}
+
+// Envelope is the single object --json-envelope writes, whether the URL
+// succeeded or not
+type Envelope struct {
+	SchemaVersion int            `json:"schema_version"`
+	Status        string         `json:"status"` // ok, error or skipped
+	URL           string         `json:"url"`
+	ExitCode      int            `json:"exit_code"`
+	Error         *EnvelopeError `json:"error"`
+	Skip          *EnvelopeSkip  `json:"skip,omitempty"` // set when status is skipped
+	Result        *Result        `json:"result"`
+}
+
+// EnvelopeError describes a failed URL
+type EnvelopeError struct {
+	Class   string `json:"class"` // network, process, invalid_input, config or file_io
+	Message string `json:"message"`
+}
+
+// EnvelopeSkip explains a URL that was left out on purpose
+type EnvelopeSkip struct {
+	Reason  string `json:"reason"` // non_html, budget, filtered or robots
+	Message string `json:"message"`
+}
+
+// Manifest lists every URL of a batch and where its output went
+type Manifest struct {
+	SchemaVersion int             `json:"schema_version"`
+	Generated     string          `json:"generated"` // RFC 3339
+	Format        string          `json:"format"`
+	Entries       []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is one URL of a Manifest
+type ManifestEntry struct {
+	Index    int    `json:"index"` // 1-based position in the input
+	URL      string `json:"url"`
+	FinalURL string `json:"final_url,omitempty"` // after redirects, when they led elsewhere
+	File     string `json:"file,omitempty"`      // slash-separated, relative to the output
+	Title    string `json:"title,omitempty"`
+	Fetched  string `json:"fetched,omitempty"` // RFC 3339
+	Status   string `json:"status"`            // ok, error or skipped
+	Skip     string `json:"skip,omitempty"`    // reason of a skipped URL: non_html, budget, filtered or robots
+	Error    string `json:"error,omitempty"`   // failure, or details of a skip
+
+	Labels []string `json:"labels,omitempty"` // from the input file and config
+	Watch  string   `json:"watch,omitempty"`  // --watch verdict: new, unchanged, changed or anomaly
+
+	Provenance *Provenance `json:"provenance,omitempty"` // how the output was produced
+}
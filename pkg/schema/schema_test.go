@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var provenance = Provenance{
+	Fetcher: "http", Source: "network", Mode: "static", Backend: "readability",
+	UserAgent: "scrpr", Proxy: "http://proxy:8080", CacheHit: true, Attempts: 2, Fallback: "js timeout",
+}
+
+var result = Result{
+	SchemaVersion: Version,
+	URL:           "https://example.com/a",
+	FinalURL:      "https://example.com/b",
+	Redirects:     []Redirect{{URL: "https://example.com/a", Status: 301}},
+	Title:         "Title",
+	Content:       "Content",
+	Metadata:      map[string]string{"author": "Ann"},
+	Images:        []string{"https://example.com/i.png"},
+	Links:         []Link{{Text: "Home", URL: "https://example.com/"}},
+	Media:         []Media{{Type: "video", URL: "https://example.com/v", Title: "Clip", Provider: "youtube"}},
+	Timing:        Timing{FetchMS: 1, ProcessMS: 2, TotalMS: 3},
+	UsedJS:        true,
+	Backend:       "readability",
+	Labels:        []string{"news"},
+	Provenance:    provenance,
+}
+
+// roundTrip encodes v, checks the top-level keys and decodes it again
+func roundTrip[T any](t *testing.T, v T, keys []string) T {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for k := range fields {
+		got = append(got, k)
+	}
+	slices.Sort(got)
+	slices.Sort(keys)
+	if !slices.Equal(got, keys) {
+		t.Errorf("%T keys:\ngot  %q\nwant %q", v, got, keys)
+	}
+	var back T
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	return back
+}
+
+func TestResult_RoundTrip(t *testing.T) {
+	keys := []string{"schema_version", "url", "final_url", "redirects", "title", "content", "metadata", "images",
+		"links", "media", "timing", "used_js", "backend", "labels", "provenance"}
+	if back := roundTrip(t, result, keys); !reflect.DeepEqual(back, result) {
+		t.Errorf("got %+v\nwant %+v", back, result)
+	}
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope Envelope
+		keys     []string
+	}{
+		{
+			name:     "ok",
+			envelope: Envelope{SchemaVersion: Version, Status: "ok", URL: result.URL, Result: &result},
+			keys:     []string{"schema_version", "status", "url", "exit_code", "error", "result"},
+		},
+		{
+			name: "error",
+			envelope: Envelope{SchemaVersion: Version, Status: "error", URL: result.URL, ExitCode: 2,
+				Error: &EnvelopeError{Class: "network", Message: "HTTP error: 404"}},
+			keys: []string{"schema_version", "status", "url", "exit_code", "error", "result"},
+		},
+		{
+			name: "skipped",
+			envelope: Envelope{SchemaVersion: Version, Status: "skipped", URL: result.URL,
+				Skip: &EnvelopeSkip{Reason: "non_html", Message: "application/pdf"}},
+			keys: []string{"schema_version", "status", "url", "exit_code", "error", "skip", "result"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if back := roundTrip(t, tt.envelope, tt.keys); !reflect.DeepEqual(back, tt.envelope) {
+				t.Errorf("got %+v\nwant %+v", back, tt.envelope)
+			}
+		})
+	}
+}
+
+func TestManifest_RoundTrip(t *testing.T) {
+	m := Manifest{
+		SchemaVersion: Version,
+		Generated:     "2025-06-01T00:00:00Z",
+		Format:        "markdown",
+		Entries: []ManifestEntry{
+			{Index: 1, URL: result.URL, FinalURL: result.FinalURL, File: "example.com/b.md", Title: "Title",
+				Fetched: "2025-06-01T00:00:01Z", Status: "ok", Labels: []string{"news"}, Watch: "new", Provenance: &provenance},
+			{Index: 2, URL: "https://example.com/c", Status: "skipped", Skip: "robots", Error: "noindex"},
+			{Index: 3, URL: "https://example.com/d", Status: "error", Error: "HTTP error: 500"},
+		},
+	}
+	back := roundTrip(t, m, []string{"schema_version", "generated", "format", "entries"})
+	if !reflect.DeepEqual(back, m) {
+		t.Errorf("got %+v\nwant %+v", back, m)
+	}
+
+	// Unset optional entry fields are left out
+	data, err := json.Marshal(m.Entries[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"index":3,"url":"https://example.com/d","status":"error","error":"HTTP error: 500"}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}